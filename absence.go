@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// absenceConfig は担当者の不在情報の取得元
+type absenceConfig struct {
+	// 不在の担当者 (名前またはメールアドレス) → 代理の担当者 (代理なしは空文字)
+	Static map[string]string
+	// この絵文字を Slack ステータスに設定している担当者を不在とみなす (空なら確認しない)
+	SlackStatusEmoji string
+}
+
+// AbsenceNote はタスクの担当者が不在であることを表す
+type AbsenceNote struct {
+	Assignee string
+	Delegate string
+}
+
+func (c absenceConfig) enabled() bool {
+	return len(c.Static) > 0 || c.SlackStatusEmoji != ""
+}
+
+// parseAbsences は "Alice" や "Alice=Bob" (Bob が代理) 形式の指定を読み込む
+func parseAbsences(entries []string) map[string]string {
+	absences := map[string]string{}
+	for _, entry := range entries {
+		name, delegate, _ := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		absences[name] = strings.TrimSpace(delegate)
+	}
+	return absences
+}
+
+// applyAbsences は不在の担当者を持つタスクに AbsenceNote を付ける
+// slackClient が nil の場合は Slack ステータスを確認しない
+func applyAbsences(ctx context.Context, tasks []Task, cfg absenceConfig, slackClient *slack.Client, now time.Time) {
+	if !cfg.enabled() {
+		return
+	}
+
+	statusCache := map[string]bool{}
+	isAwayOnSlack := func(email string) bool {
+		if cfg.SlackStatusEmoji == "" || slackClient == nil || email == "" {
+			return false
+		}
+		if away, ok := statusCache[email]; ok {
+			return away
+		}
+		away := false
+		user, err := slackClient.GetUserByEmailContext(ctx, email)
+		if err != nil {
+			log.Printf("Warning: Unable to look up Slack status for %s: %v", email, err)
+		} else {
+			profile := user.Profile
+			notExpired := profile.StatusExpiration == 0 || time.Unix(int64(profile.StatusExpiration), 0).After(now)
+			away = profile.StatusEmoji == cfg.SlackStatusEmoji && notExpired
+		}
+		statusCache[email] = away
+		return away
+	}
+
+	for i := range tasks {
+		for _, assignee := range tasks[i].Assignees {
+			delegate, absent := cfg.Static[assignee.Name]
+			if !absent && assignee.Email != "" {
+				delegate, absent = cfg.Static[assignee.Email]
+			}
+			if !absent {
+				absent = isAwayOnSlack(assignee.Email)
+			}
+			if absent {
+				tasks[i].Absences = append(tasks[i].Absences, AbsenceNote{Assignee: assignee.Name, Delegate: delegate})
+			}
+		}
+	}
+}
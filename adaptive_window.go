@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// adaptiveWindow はタスクの数に合わせて先読みの日数 (--daysLater) を変える方針 (設定ファイルの adaptive_window)
+// 期限切れが溜まってタスクが多すぎれば日数を減らし、少なすぎれば増やして、ダイジェストの大きさを目安の範囲に収める
+// 期限切れとピン留めのタスクは日数に関係なく載せるため、日数を最小にしても max_tasks を超えることがある
+type adaptiveWindow struct {
+	MinTasks int `yaml:"min_tasks"` // これより少なければ日数を増やす (0 なら増やさない)
+	MaxTasks int `yaml:"max_tasks"` // これより多ければ日数を減らす (0 なら減らさない)
+	MinDays  int `yaml:"min_days"`
+	MaxDays  int `yaml:"max_days"` // 0 なら 3 日
+}
+
+// 設定ファイルの先読みの日数の方針 (min_tasks と max_tasks が 0 なら使わない)
+var configuredAdaptiveWindow adaptiveWindow
+
+func (w adaptiveWindow) enabled() bool {
+	return w.MinTasks > 0 || w.MaxTasks > 0
+}
+
+// maxDays は日数の上限を返す
+func (w adaptiveWindow) maxDays() int {
+	if w.MaxDays == 0 {
+		return 3
+	}
+	return w.MaxDays
+}
+
+func validateAdaptiveWindow(w adaptiveWindow) error {
+	if !w.enabled() {
+		return nil
+	}
+	if w.MinTasks < 0 || w.MaxTasks < 0 {
+		return fmt.Errorf("adaptive_window: min_tasks and max_tasks must not be negative")
+	}
+	if w.MaxTasks > 0 && w.MinTasks > w.MaxTasks {
+		return fmt.Errorf("adaptive_window: min_tasks (%d) must not exceed max_tasks (%d)", w.MinTasks, w.MaxTasks)
+	}
+	if w.MinDays < 0 || w.MinDays > w.maxDays() {
+		return fmt.Errorf("adaptive_window: days must satisfy 0 <= min_days <= max_days, got %d and %d", w.MinDays, w.maxDays())
+	}
+	if err := checkDaysLater(w.maxDays()); err != nil {
+		return fmt.Errorf("adaptive_window: max_days: %w", err)
+	}
+	return nil
+}
+
+// choose は days 日後までのタスクの数を見て、目安の範囲に収まる日数を返す
+// tasks は最大の日数まで取得したもの。範囲に収まらなければ上限か下限の日数にする
+func (w adaptiveWindow) choose(tasks []Task, now time.Time, days int) int {
+	days = min(max(days, w.MinDays), w.maxDays())
+	count := func(d int) int { return len(filterTasksDueBy(tasks, endOfDay(now, d))) }
+	if w.MaxTasks > 0 {
+		for days > w.MinDays && count(days) > w.MaxTasks {
+			days--
+		}
+	}
+	if w.MinTasks > 0 {
+		// 増やして上限を超えるなら増やさない
+		for days < w.maxDays() && count(days) < w.MinTasks && (w.MaxTasks == 0 || count(days+1) <= w.MaxTasks) {
+			days++
+		}
+	}
+	return days
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/jomei/notionapi"
+	"github.com/slack-go/slack"
+	"github.com/spf13/cobra"
+)
+
+// alertTask はアラートの判定のために記録する、前回確認したときのタスクの優先度とステータス
+type alertTask struct {
+	Priority string `json:"priority"`
+	Status   string `json:"status"`
+}
+
+// taskAlert はすぐに知らせるタスクの変化
+type taskAlert struct {
+	Task   Task
+	Reason string
+}
+
+// alertConfig は --alert-interval と --alert-statuses の設定
+type alertConfig struct {
+	Interval time.Duration
+	Statuses []string
+}
+
+// alertConfigFromFlags はアラートの設定を読み込む。--alert-interval が 0 ならアラートを使わない
+func alertConfigFromFlags(cmd *cobra.Command) (alertConfig, error) {
+	var c alertConfig
+	c.Interval, _ = cmd.Flags().GetDuration("alert-interval")
+	c.Statuses, _ = cmd.Flags().GetStringSlice("alert-statuses")
+	if c.Interval == 0 {
+		if len(c.Statuses) > 0 {
+			return c, configErrorf("--alert-statuses requires --alert-interval")
+		}
+		return c, nil
+	}
+	if c.Interval < 10*time.Second {
+		return c, configErrorf("--alert-interval must be at least 10s, got %s", c.Interval)
+	}
+	return c, nil
+}
+
+// topPriority は最も高い優先度の名前を返す
+func topPriority() string {
+	return priorityNames()[0]
+}
+
+// detectAlerts は前回の記録と比べ、最も高い優先度に上がったタスクとアラートのステータスに変わったタスクを返す
+// 前回の記録に無いタスク (初回や新しく期間に入ったタスク) は知らせない
+func detectAlerts(prev map[string]*alertTask, tasks []Task, statuses []string) []taskAlert {
+	top := topPriority()
+	var alerts []taskAlert
+	for _, task := range tasks {
+		p, ok := prev[task.Key()]
+		if !ok && task.UniqueID != "" {
+			// ユニーク ID をキーにしていた頃の記録
+			p, ok = prev[task.UniqueID]
+		}
+		if !ok {
+			continue
+		}
+		if task.Priority == top && p.Priority != top {
+			alerts = append(alerts, taskAlert{Task: task, Reason: tr("alert.priority", alertValue(p.Priority), task.Priority)})
+		}
+		if task.ScheduleStatus != p.Status && slices.Contains(statuses, task.ScheduleStatus) {
+			alerts = append(alerts, taskAlert{Task: task, Reason: tr("alert.status", alertValue(p.Status), task.ScheduleStatus)})
+		}
+	}
+	return alerts
+}
+
+// alertValue は空の優先度やステータスを「なし」と表示する
+func alertValue(v string) string {
+	if v == "" {
+		return tr("alert.none")
+	}
+	return v
+}
+
+// buildAlertBlocks はアラートのメッセージを作る
+func buildAlertBlocks(alerts []taskAlert) []slack.Block {
+	blocks := []slack.Block{slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, tr("alert.header"), true, false))}
+	for _, a := range alerts {
+		text := fmt.Sprintf("*<%s|%s>*\n%s", a.Task.URL, a.Task.Title, a.Reason)
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil))
+	}
+	return blocks
+}
+
+// checkAlerts はタスクを取得して前回の記録と比べ、変化があれば投稿先にすぐ知らせる
+// 記録は状態ファイルに残すので、再起動をまたいでも変化を見逃さない
+func checkAlerts(ctx context.Context, job digestJob, config alertConfig, now time.Time) error {
+	usage := newAPIUsage(time.Now())
+	notionClient := notionapi.NewClient(notionapi.Token(job.NotionToken), notionapi.WithHTTPClient(usage.Client("notion")))
+	tasks, err := fetchNotionTasks(ctx, notionClient, job.DatabaseID, endOfDay(now, job.DaysLater))
+	if err != nil {
+		return fmt.Errorf("get Notion tasks: %w", err)
+	}
+	if job.LinkDomain != "" {
+		if err := rewriteTaskURLs(tasks, job.LinkDomain); err != nil {
+			return err
+		}
+	}
+
+	st, err := job.Store.Load()
+	if err != nil {
+		return err
+	}
+	tasks, _ = filterMutedTasks(tasks, st, now)
+	alerts := detectAlerts(st.AlertTasks, tasks, config.Statuses)
+
+	if !job.DryRun {
+		err := job.Store.Update(func(st *state) error {
+			st.AlertTasks = map[string]*alertTask{}
+			for _, task := range tasks {
+				st.AlertTasks[task.Key()] = &alertTask{Priority: task.Priority, Status: task.ScheduleStatus}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+	log.Printf("[%s] %d task alerts", job.Name, len(alerts))
+
+	failed := 0
+	for _, dest := range job.Destinations {
+		var destAlerts []taskAlert
+		for _, a := range alerts {
+			if dest.allows(a.Task.Priority, a.Task.Type) {
+				destAlerts = append(destAlerts, a)
+			}
+		}
+		if len(destAlerts) == 0 {
+			continue
+		}
+		blocks := buildAlertBlocks(destAlerts)
+		if job.DryRun {
+			if err := printDryRun(os.Stdout, dest, blocks); err != nil {
+				return err
+			}
+			continue
+		}
+		slackClient, err := dest.Tokens.Client(ctx, slack.OptionHTTPClient(usage.Client("slack:"+dest.Name)))
+		if err != nil {
+			log.Printf("[%s] Slack client error (%s): %v", job.Name, dest.Name, err)
+			failed++
+			continue
+		}
+		if err := resolveChannel(ctx, slackClient, job.Store, now, &dest); err != nil {
+			log.Printf("[%s] Slack channel error (%s): %v", job.Name, dest.Name, err)
+			failed++
+			continue
+		}
+		if _, err := postBlocks(ctx, slackClient, dest.ChannelID, blocks); err != nil {
+			log.Printf("[%s] Slack alert send error (%s): %v", job.Name, dest.Name, err)
+			failed++
+			continue
+		}
+		log.Printf("[%s] Alert sent to channel %s (%s)", job.Name, dest.ChannelID, dest.Name)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to send alerts to %d destinations", failed)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.Flags().Duration("alert-interval", 0, "With --watch, check Notion this often and alert right away when a task is raised to the top priority or enters an --alert-statuses status (0 to disable)")
+	rootCmd.Flags().StringSlice("alert-statuses", nil, "Schedule Status values that trigger an alert when a task changes to them (e.g. Blocked)")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+
+	"rainierrr/notion-notifyer/pkg/task"
+)
+
+// assigneeNames は担当者の名前をカンマ区切りにする (名前の無いユーザーはメールアドレス)
+func assigneeNames(t Task) string {
+	var names []string
+	for _, a := range t.Assignees {
+		name := a.Name
+		if name == "" {
+			name = a.Email
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// matchesAssignee は担当者が名前かメールアドレス (大文字と小文字は区別しない) で who に一致するかを判定する
+func matchesAssignee(a task.Assignee, who string) bool {
+	return strings.EqualFold(a.Name, who) || (a.Email != "" && strings.EqualFold(a.Email, who))
+}
+
+// filterTasksByAssignee は people のいずれかが担当するタスクだけを返す
+func filterTasksByAssignee(tasks []Task, people []string) []Task {
+	var filtered []Task
+	for _, t := range tasks {
+	match:
+		for _, a := range t.Assignees {
+			for _, who := range people {
+				if matchesAssignee(a, who) {
+					filtered = append(filtered, t)
+					break match
+				}
+			}
+		}
+	}
+	return filtered
+}
+
+func init() {
+	rootCmd.Flags().StringSlice("assignee", nil, "Only notify tasks assigned to these people (Notion user name or email)")
+}
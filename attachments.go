@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/slack-go/slack"
+
+	"rainierrr/notion-notifyer/pkg/task"
+)
+
+// 添付ファイルのプロパティ (ファイル&メディア)。空なら使わない
+var filesProp string
+
+// Slack の画像のアクセサリーで表示できる拡張子
+var imageExtensions = []string{".png", ".jpg", ".jpeg", ".gif", ".webp"}
+
+// fileLink はファイル名を表示したリンクにする
+func fileLink(f task.File) string {
+	name := f.Name
+	if name == "" {
+		name = path.Base(f.URL)
+	}
+	return fmt.Sprintf("<%s|%s>", f.URL, name)
+}
+
+// isImageFile はファイル名か URL の拡張子で画像かどうかを判定する (URL の署名のクエリは見ない)
+func isImageFile(f task.File) bool {
+	ext := strings.ToLower(path.Ext(f.Name))
+	if u, err := url.Parse(f.URL); ext == "" && err == nil {
+		ext = strings.ToLower(path.Ext(u.Path))
+	}
+	return slices.Contains(imageExtensions, ext)
+}
+
+// attachmentImage は最初の添付ファイルが画像で、タスクの行のアクセサリーが空いていればその画像を返す
+// アクセサリーには「開く」や「通知しない」のボタンを優先して置く
+func attachmentImage(t Task, opts renderOptions) *slack.ImageBlockElement {
+	if len(t.Files) == 0 || opts.TrackSeen || opts.MuteButton || !isImageFile(t.Files[0]) {
+		return nil
+	}
+	alt := t.Files[0].Name
+	if alt == "" {
+		alt = tr("task.attachment") // Slack は代替テキストの無い画像を受け付けない
+	}
+	return slack.NewImageBlockElement(t.Files[0].URL, alt)
+}
+
+// attachmentDetail は最初の添付ファイルを詳細のリンクにする (画像のアクセサリーで表示するときは空)
+func attachmentDetail(t Task, opts renderOptions) string {
+	if len(t.Files) == 0 || attachmentImage(t, opts) != nil {
+		return ""
+	}
+	link := fileLink(t.Files[0])
+	if len(t.Files) > 1 {
+		link += fmt.Sprintf(" (+%d)", len(t.Files)-1)
+	}
+	return fmt.Sprintf("*%s:* %s", tr("task.attachment"), link)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jomei/notionapi"
+	"github.com/spf13/cobra"
+)
+
+// backfillTask は履歴の再構成に使うタスクと、その作成・完了日時
+type backfillTask struct {
+	Task
+	CreatedAt time.Time
+	ClosedAt  *time.Time // 未完了のステータスでなくなった日時 (最終更新日時で近似する)
+}
+
+// openOn は at の時点でタスクが未完了だったかを返す
+func (t backfillTask) openOn(at time.Time) bool {
+	if t.CreatedAt.After(at) {
+		return false
+	}
+	return t.ClosedAt == nil || t.ClosedAt.After(at)
+}
+
+// fetchBackfillTasks は期限日が since 以降のタスクをステータスに関係なく、未完了のタスクはすべて取得する
+func fetchBackfillTasks(ctx context.Context, client *notionapi.Client, dbID string, since time.Time) ([]backfillTask, error) {
+	var tasks []backfillTask
+	statuses := statusFetcher(ctx, client, dbID)
+	// 期限日が since より前でも未完了のタスクは期限切れとして掲載されていたため含める
+	request := &notionapi.DatabaseQueryRequest{
+		Filter: notionapi.OrCompoundFilter{
+			&notionapi.PropertyFilter{
+				Property: dueProp,
+				Date: &notionapi.DateFilterCondition{
+					OnOrAfter: (*notionapi.Date)(&since),
+				},
+			},
+			statuses.StatusFilter(),
+		},
+		PageSize: queryPageSize,
+	}
+	// 履歴の再構成にはすべてのタスクが要るため、--max-results の上限は使わない
+	for {
+		resp, err := client.Database.Query(ctx, notionapi.DatabaseID(dbID), request)
+		if err != nil {
+			return nil, newNotionError("failed to query database", err)
+		}
+		for _, page := range resp.Results {
+			task := parseNotionPage(page)
+			if task == nil {
+				continue
+			}
+			bt := backfillTask{Task: *task, CreatedAt: page.CreatedTime}
+			if !statuses.HasStatus(task.ScheduleStatus) {
+				closedAt := page.LastEditedTime
+				bt.ClosedAt = &closedAt
+			}
+			tasks = append(tasks, bt)
+		}
+		if !resp.HasMore {
+			break
+		}
+		request.StartCursor = resp.NextCursor
+	}
+	return tasks, nil
+}
+
+// reconstructRuns は since から now の前日まで、毎日 now と同じ時刻に実行していた場合の記録を作る
+// 期限日とステータスは現在の値を使うため、途中で期限が変わったタスクは正確ではない
+func reconstructRuns(tasks []backfillTask, since, now time.Time, daysLater int) []historyRun {
+	var runs []historyRun
+	timeOfDay := now.Sub(startOfDay(now))
+	for day := startOfDay(since); day.Before(startOfDay(now)); day = day.AddDate(0, 0, 1) {
+		at := day.Add(timeOfDay)
+		target := endOfDay(at, daysLater)
+		var listed []Task
+		for _, t := range tasks {
+			due := getTargetDueDate(t.Task)
+			if due == nil || due.After(target) || !t.openOn(at) {
+				continue
+			}
+			listed = append(listed, t.Task)
+		}
+		// 実際の実行と同じく、タスクが無い日は記録しない
+		if len(listed) > 0 {
+			runs = append(runs, newHistoryRun(at, listed))
+		}
+	}
+	return runs
+}
+
+var backfillCmd = &cobra.Command{
+	Use:   "backfill",
+	Short: "Reconstruct the digest history from Notion's created and edited timestamps.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sinceStr, _ := cmd.Flags().GetString("since")
+		daysLater, err := daysLaterFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		clock, err := clockFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		now := clock.Now()
+		since, err := time.ParseInLocation("2006-01-02", sinceStr, now.Location())
+		if err != nil {
+			return fmt.Errorf("invalid --since value %q (expected YYYY-MM-DD): %w", sinceStr, err)
+		}
+		if !since.Before(startOfDay(now)) {
+			return fmt.Errorf("--since must be before today")
+		}
+		if now.Sub(since) > historyRetention {
+			log.Printf("Warning: Runs older than %d days will be dropped by the history retention", int(historyRetention.Hours()/24))
+		}
+
+		store := historyStoreFromEnv()
+		if store == nil {
+			return fmt.Errorf("%s must be set", historyFileEnv)
+		}
+		notionToken, dbID, err := notionSourceFromEnv(stateStoreFromEnv())
+		if err != nil {
+			return err
+		}
+
+		client := notionapi.NewClient(notionapi.Token(notionToken))
+		dbs, err := resolveDatabases(cmd.Context(), client, dbID)
+		if err != nil {
+			return err
+		}
+		var tasks []backfillTask
+		for _, db := range dbs {
+			dbTasks, err := fetchBackfillTasks(cmd.Context(), client, db.ID, since)
+			if err != nil {
+				return err
+			}
+			tasks = append(tasks, dbTasks...)
+		}
+		log.Printf("Get %d tasks due since %s from Notion", len(tasks), since.Format("2006-01-02"))
+
+		runs := reconstructRuns(tasks, since, now, daysLater)
+		added, err := store.Backfill(runs, now)
+		if err != nil {
+			return err
+		}
+		log.Printf("Backfilled %d runs (%d days already had a run)", added, len(runs)-added)
+		return nil
+	},
+}
+
+func init() {
+	backfillCmd.Flags().String("since", "", "First day to reconstruct (YYYY-MM-DD)")
+	_ = backfillCmd.MarkFlagRequired("since")
+	rootCmd.AddCommand(backfillCmd)
+}
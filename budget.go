@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+)
+
+// Slack の上限に収めるための段階的な省略
+// 本文を途中で切ったり投稿に失敗したりしないよう、長すぎるタスクの行はメモ → 詳細の順に省き、
+// ブロックが多すぎるダイジェストはタスクを 1 行ずつ詰めたブロック (コンパクト表示) に切り替える
+const (
+	// 1 回のダイジェストで投稿するブロックの合計 (50 ブロックのメッセージ 3 通分)
+	maxDigestBlocks = 150
+	// コンパクト表示のブロックの block_id の接頭辞
+	compactRowsBlockPrefix = "compact_rows:"
+)
+
+// detailLevel はタスクの詳細をどこまで載せるか
+type detailLevel int
+
+const (
+	detailFull    detailLevel = iota // すべて載せる
+	detailNoMemo                     // メモを省く
+	detailDueOnly                    // 期限日だけにする
+)
+
+// fitTaskDetails はタスクの行が 1 つのテキストの上限に収まるまで詳細を省く
+func fitTaskDetails(task Task, opts renderOptions) (string, error) {
+	budget := maxTextLength - utf8.RuneCountInString(taskLinkText(task)) - 1
+	for level := detailFull; ; level++ {
+		details, err := taskDetailsAt(task, opts, level)
+		if err != nil || level == detailDueOnly || utf8.RuneCountInString(details) <= budget {
+			return details, err
+		}
+	}
+}
+
+// taskLinkText はタスクの行の先頭のリンクを返す
+func taskLinkText(task Task) string {
+	return fmt.Sprintf("*<%s|%s>*", task.URL, taskTitle(task))
+}
+
+// fitRowText は上限を超えるタスクの行をリンクだけにする (独自のテンプレートで長い行を作った場合)
+// リンクだけでも超える場合はタイトルを文字の区切りで短くする
+func fitRowText(task Task, text string) string {
+	if utf8.RuneCountInString(text) <= maxTextLength {
+		return text
+	}
+	link := taskLinkText(task)
+	if over := utf8.RuneCountInString(link) - maxTextLength; over > 0 {
+		task.Title = truncateText(task.Title, max(utf8.RuneCountInString(task.Title)-over-1, 0), "…")
+		link = taskLinkText(task)
+	}
+	return link
+}
+
+// compactTaskLine はコンパクト表示の 1 行 (リンクと期限日) を作る
+func compactTaskLine(task Task) (string, error) {
+	due, err := formatDueDate(task)
+	if err != nil {
+		return "", fmt.Errorf("failed to format due date for task %s: %w", task.Title, err)
+	}
+	return fmt.Sprintf("• <%s|%s> (%s)", task.URL, taskTitle(task), due), nil
+}
+
+// appendCompactRow はタスクを直前のコンパクト表示のブロックに追記する。収まらなければ新しいブロックにする
+func appendCompactRow(blocks []slack.Block, task Task) ([]slack.Block, error) {
+	line, err := compactTaskLine(task)
+	if err != nil {
+		return blocks, err
+	}
+	line = fitRowText(task, line)
+	if n := len(blocks); n > 0 {
+		if last, ok := blocks[n-1].(*slack.SectionBlock); ok && strings.HasPrefix(last.BlockID, compactRowsBlockPrefix) &&
+			utf8.RuneCountInString(last.Text.Text)+1+utf8.RuneCountInString(line) <= maxTextLength {
+			last.Text.Text += "\n" + line
+			return blocks, nil
+		}
+	}
+	return append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, line, false, false), nil, nil,
+		slack.SectionBlockOptionBlockID(fmt.Sprintf("%s%d", compactRowsBlockPrefix, len(blocks))))), nil
+}
+
+// renderWithinBudget はブロックが maxDigestBlocks を超えたらコンパクト表示で描画し直す
+// それでも超える場合は末尾を省き、省いたことを書き添える
+func renderWithinBudget(render func(opts renderOptions) ([]slack.Block, error), opts renderOptions) ([]slack.Block, error) {
+	blocks, err := render(opts)
+	if err != nil || len(blocks) <= maxDigestBlocks {
+		return blocks, err
+	}
+	log.Printf("Message has %d blocks (limit %d); switching to compact rows", len(blocks), maxDigestBlocks)
+	opts.CompactRows = true
+	if blocks, err = render(opts); err != nil || len(blocks) <= maxDigestBlocks {
+		return blocks, err
+	}
+	log.Printf("Compact message still has %d blocks; dropping the rest", len(blocks))
+	blocks = blocks[:maxDigestBlocks-1]
+	return append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject(slack.PlainTextType, tr("digest.truncated"), false, false))), nil
+}
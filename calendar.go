@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// カレンダーに表示する日数
+const calendarDays = 7
+
+// dueDay はタスクの期限日を loc の日付 (0:00) にする
+// 日付のみの値は UTC の 0:00 で表されるため、タイムゾーンを変換せず日付をそのまま使う
+func dueDay(t time.Time, loc *time.Location) time.Time {
+	if isDateOnly(t) {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+	return startOfDay(t.In(loc))
+}
+
+// buildCalendarStrip は今日から 7 日間について、1 日 1 行で件数と最優先のタスクを表示する
+func buildCalendarStrip(tasks []Task, now time.Time) []slack.Block {
+	today := startOfDay(now)
+	byDay := map[string][]Task{}
+	for _, task := range tasks {
+		due := getTargetDueDate(task)
+		if due == nil {
+			continue
+		}
+		key := dueDay(*due, now.Location()).Format("2006-01-02")
+		byDay[key] = append(byDay[key], task)
+	}
+
+	weekdays := strings.Split(tr("calendar.weekdays"), ",")
+	blocks := []slack.Block{slack.NewDividerBlock()}
+	for i := 0; i < calendarDays; i++ {
+		day := today.AddDate(0, 0, i)
+		label := fmt.Sprintf("*%02d/%02d(%s)*", int(day.Month()), day.Day(), weekdays[day.Weekday()])
+		dayTasks := byDay[day.Format("2006-01-02")]
+
+		text := label + " —"
+		if len(dayTasks) > 0 {
+			sortTasks(dayTasks)
+			text = fmt.Sprintf("%s %s — <%s|%s>", label, tr("calendar.count", len(dayTasks)), dayTasks[0].URL, dayTasks[0].Title)
+		}
+		blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, text, false, false)))
+	}
+	return blocks
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/jomei/notionapi"
+)
+
+// 完了の確認のために Notion から取得するタスクの上限
+const maxCompletionChecks = 50
+
+// taskChange は期間中のタスクの変更 1 件
+type taskChange struct {
+	ID     string // タスクのキー (ページ ID)
+	Title  string
+	Detail string // 例: "10/15 → 10/18"
+}
+
+// backlogChanges は期間中のタスクの変更の一覧
+type backlogChanges struct {
+	New            []taskChange // 期間中に初めて掲載されたタスク
+	DueMoved       []taskChange // 期限日が変わったタスク
+	PriorityRaised []taskChange // 優先度が上がったタスク
+	Completed      []taskChange // 完了したタスク (掲載されなくなり、Notion で未完了のステータスでなくなったもの)
+}
+
+func (c backlogChanges) empty() bool {
+	return len(c.New)+len(c.DueMoved)+len(c.PriorityRaised)+len(c.Completed) == 0
+}
+
+// backlogChanges は履歴のスナップショットから since 以降の変更を求める
+// 期間の前に記録があればそれを、無ければ期間中の最初の記録を変更前の値とする
+// 期間の直前か期間中に掲載され、最後の実行で掲載されなかったタスクも返す (完了したかは Notion で確認する)
+func (h *history) backlogChanges(since, now time.Time) (backlogChanges, []historyTask) {
+	before := map[string]historyTask{} // 期間の前の最後の記録
+	first := map[string]historyTask{}  // 期間中の最初の記録
+	latest := map[string]historyTask{} // 期間中の最後の記録
+	var order []string                 // 期間中に初めて記録された順
+	var baseRun, lastRun *historyRun
+	for i, run := range h.Runs {
+		if run.At.After(now) {
+			continue
+		}
+		for _, t := range run.Tasks {
+			if run.At.Before(since) {
+				before[t.key()] = t
+				continue
+			}
+			if _, ok := first[t.key()]; !ok {
+				first[t.key()] = t
+				order = append(order, t.key())
+			}
+			latest[t.key()] = t
+		}
+		if run.At.Before(since) {
+			baseRun = &h.Runs[i]
+		} else {
+			lastRun = &h.Runs[i]
+		}
+	}
+
+	var changes backlogChanges
+	for _, id := range order {
+		cur := latest[id]
+		prev, seenBefore := before[id]
+		if !seenBefore {
+			prev = first[id]
+			change := taskChange{ID: id, Title: cur.Title}
+			if cur.Due != nil {
+				change.Detail = tr("report.due", formatHistoryDue(cur.Due))
+			}
+			changes.New = append(changes.New, change)
+		}
+		if prev.Due != nil && cur.Due != nil && !prev.Due.Equal(*cur.Due) {
+			changes.DueMoved = append(changes.DueMoved, taskChange{ID: id, Title: cur.Title, Detail: formatHistoryDue(prev.Due) + " → " + formatHistoryDue(cur.Due)})
+		}
+		curRank, ok1 := priorityRank(cur.Priority)
+		prevRank, ok2 := priorityRank(prev.Priority)
+		if ok1 && ok2 && curRank < prevRank {
+			from := prev.Priority
+			if from == "" {
+				from = tr("task.no_due")
+			}
+			changes.PriorityRaised = append(changes.PriorityRaised, taskChange{ID: id, Title: cur.Title, Detail: from + " → " + cur.Priority})
+		}
+	}
+
+	if lastRun == nil {
+		return changes, nil
+	}
+	listed := func(run *historyRun, id string) bool {
+		return slices.ContainsFunc(run.Tasks, func(t historyTask) bool { return t.key() == id })
+	}
+	var gone []historyTask
+	if baseRun != nil {
+		for _, t := range baseRun.Tasks {
+			if _, ok := latest[t.key()]; !ok {
+				gone = append(gone, t)
+			}
+		}
+	}
+	for _, id := range order {
+		if !listed(lastRun, id) {
+			gone = append(gone, latest[id])
+		}
+	}
+	return changes, gone
+}
+
+func formatHistoryDue(due *time.Time) string {
+	if due == nil {
+		return tr("task.no_due")
+	}
+	return timeFormat(*due)
+}
+
+// findCompletedTasks は掲載されなくなったタスクのうち、Notion で未完了のステータスでなくなったものを返す
+func findCompletedTasks(ctx context.Context, client *notionapi.Client, gone []historyTask) []taskChange {
+	if len(gone) > maxCompletionChecks {
+		log.Printf("Warning: Checking only %d of %d tasks that left the digest for completion", maxCompletionChecks, len(gone))
+		gone = gone[:maxCompletionChecks]
+	}
+	var completed []taskChange
+	for _, t := range gone {
+		page, err := client.Page.Get(ctx, notionapi.PageID(t.ID))
+		if err != nil {
+			log.Printf("Warning: Unable to get task %s: %v", t.Title, err)
+			continue
+		}
+		status := ""
+		if p, ok := page.Properties[scheduleStatusProp].(*notionapi.StatusProperty); ok {
+			status = p.Status.Name
+		}
+		if page.Archived || !statusFetcher(ctx, client, string(page.Parent.DatabaseID)).HasStatus(status) {
+			detail := status
+			if page.Archived {
+				detail = tr("report.deleted")
+			}
+			completed = append(completed, taskChange{ID: t.key(), Title: t.Title, Detail: detail})
+		}
+	}
+	return completed
+}
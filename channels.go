@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// 解決したチャンネル名を使い回す期間 (チャンネル名の変更に追従できるよう、期限が切れたら引き直す)
+const channelCacheTTL = 7 * 24 * time.Hour
+
+// Slack のチャンネル ID (C…, G…) と DM の ID (D…)
+var channelIDPattern = regexp.MustCompile(`^[CGD][A-Z0-9]{6,}$`)
+
+// channelCache は名前から解決したチャンネル ID
+type channelCache struct {
+	ID         string    `json:"id"`
+	ResolvedAt time.Time `json:"resolved_at"`
+}
+
+// channelName は投稿先がチャンネル名 (#general や general) ならその名前を返す
+func channelName(channel string) (string, bool) {
+	if channel == "" || channelIDPattern.MatchString(channel) {
+		return "", false
+	}
+	return strings.ToLower(strings.TrimPrefix(channel, "#")), true
+}
+
+// teamScope は投稿先のワークスペースを表すキーを返す (team ID がわからない環境変数のトークンでは投稿先の名前)
+// チャンネル名やユーザー ID はワークスペースごとに違うので、キャッシュやメンションをこのキーで分ける
+func (d slackDestination) teamScope() string {
+	if d.TeamID == "" {
+		return "token:" + d.Name
+	}
+	return d.TeamID
+}
+
+// channelCacheKey はチャンネル名のキャッシュのキーを返す
+func channelCacheKey(dest slackDestination, name string) string {
+	return dest.teamScope() + "/" + name
+}
+
+// resolveChannel は投稿先がチャンネル名なら conversations.list で ID を引いて dest.ChannelID を置き換える
+// 解決した ID は状態ファイルにキャッシュし、次回からは API を呼ばない
+func resolveChannel(ctx context.Context, client *slack.Client, store *stateStore, now time.Time, dest *slackDestination) error {
+	name, ok := channelName(dest.ChannelID)
+	if !ok {
+		return nil
+	}
+	key := channelCacheKey(*dest, name)
+	if store != nil {
+		st, err := store.Load()
+		if err != nil {
+			return err
+		}
+		if c := st.ChannelIDs[key]; c != nil && now.Sub(c.ResolvedAt) < channelCacheTTL {
+			dest.ChannelID = c.ID
+			return nil
+		}
+	}
+
+	id, err := lookupChannelID(ctx, client, dest.TeamID, name)
+	if err != nil {
+		return err
+	}
+	log.Printf("Resolved channel #%s to %s (%s)", name, id, dest.Name)
+	dest.ChannelID = id
+	if store == nil {
+		return nil
+	}
+	return store.Update(func(st *state) error {
+		if st.ChannelIDs == nil {
+			st.ChannelIDs = map[string]*channelCache{}
+		}
+		st.ChannelIDs[key] = &channelCache{ID: id, ResolvedAt: now}
+		return nil
+	})
+}
+
+// lookupChannelID は Bot が見えるチャンネルを順に取得して名前が一致するものの ID を返す
+// Enterprise Grid の組織全体のトークンでは teamID のワークスペースのチャンネルから探す
+func lookupChannelID(ctx context.Context, client *slack.Client, teamID, name string) (string, error) {
+	params := &slack.GetConversationsParameters{
+		ExcludeArchived: true,
+		Limit:           1000,
+		Types:           []string{"public_channel", "private_channel"},
+		TeamID:          teamID,
+	}
+	for {
+		channels, cursor, err := client.GetConversationsContext(ctx, params)
+		if err != nil {
+			if slackErrorCode(err) == "missing_scope" {
+				return "", newSlackError(fmt.Sprintf("failed to list channels to resolve #%s (the app needs the channels:read scope, or use the channel ID)", name), err)
+			}
+			return "", newSlackError(fmt.Sprintf("failed to list channels to resolve #%s", name), err)
+		}
+		for _, ch := range channels {
+			if strings.EqualFold(ch.Name, name) {
+				return ch.ID, nil
+			}
+		}
+		if cursor == "" {
+			return "", fmt.Errorf("channel #%s not found (invite the app to private channels first)", name)
+		}
+		params.Cursor = cursor
+	}
+}
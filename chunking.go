@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	// Slack の 1 メッセージあたりのブロック数の上限
+	maxBlocksPerMessage = 50
+	// セクションの見出しブロックの block_id の接頭辞 (分割したときに見出しを続きのメッセージにも付ける)
+	sectionHeaderBlockPrefix = "section_header:"
+)
+
+// splitBlocks はブロックを maxBlocks 件以下のメッセージに分ける
+// タスクとその下のボタンは同じメッセージに入れ、見出しだけがメッセージの末尾に残らないようにする
+// セクションの途中で分けたときは、続きのメッセージの先頭に「(続き)」を付けた見出しを入れる
+func splitBlocks(blocks []slack.Block, maxBlocks int) [][]slack.Block {
+	if len(blocks) <= maxBlocks {
+		return [][]slack.Block{blocks}
+	}
+
+	// 分けてはいけないブロックのまとまり (区切り線と見出し、タスクとボタン) を作る
+	var units [][]slack.Block
+	for i, block := range blocks {
+		if i > 0 && (isTaskActionsBlock(block) || isSectionHeader(block) && isDivider(blocks[i-1])) {
+			units[len(units)-1] = append(units[len(units)-1], block)
+			continue
+		}
+		units = append(units, []slack.Block{block})
+	}
+
+	var chunks [][]slack.Block
+	var current []slack.Block
+	var header *slack.SectionBlock // 直前のセクションの見出し
+	for i, unit := range units {
+		size := len(unit)
+		// 見出しは次のタスクと同じメッセージに入れる
+		if unitHeader(unit) != nil && i+1 < len(units) {
+			size += len(units[i+1])
+		}
+		if len(current) > 0 && len(current)+size > maxBlocks {
+			chunks = append(chunks, current)
+			current = nil
+			if header != nil && unitHeader(unit) == nil && !isDivider(unit[0]) {
+				current = append(current, continuedHeader(header))
+			}
+		}
+		if h := unitHeader(unit); h != nil {
+			header = h
+		} else if isDivider(unit[0]) {
+			header = nil
+		}
+		current = append(current, unit...)
+	}
+	return append(chunks, current)
+}
+
+// postBlocks はブロックを上限ごとに分けて順に投稿し、最初のメッセージのタイムスタンプを返す
+func postBlocks(ctx context.Context, client *slack.Client, channelID string, blocks []slack.Block, options ...slack.MsgOption) (string, error) {
+	timestamps, err := postChunks(ctx, client, channelID, splitBlocks(blocks, maxBlocksPerMessage), options...)
+	if len(timestamps) == 0 {
+		return "", err
+	}
+	return timestamps[0], err
+}
+
+// postChunks は分けたメッセージを順に投稿し、投稿できたメッセージの ts を返す
+func postChunks(ctx context.Context, client *slack.Client, channelID string, chunks [][]slack.Block, options ...slack.MsgOption) ([]string, error) {
+	var timestamps []string
+	for _, chunk := range chunks {
+		_, ts, err := client.PostMessageContext(ctx, channelID, append([]slack.MsgOption{slack.MsgOptionBlocks(chunk...)}, options...)...)
+		if err != nil {
+			return timestamps, newSlackError("", err)
+		}
+		timestamps = append(timestamps, ts)
+	}
+	return timestamps, nil
+}
+
+func isDivider(block slack.Block) bool {
+	_, ok := block.(*slack.DividerBlock)
+	return ok
+}
+
+func isSectionHeader(block slack.Block) bool {
+	b, ok := block.(*slack.SectionBlock)
+	return ok && strings.HasPrefix(b.BlockID, sectionHeaderBlockPrefix)
+}
+
+func isTaskActionsBlock(block slack.Block) bool {
+	b, ok := block.(*slack.ActionBlock)
+	return ok && strings.HasPrefix(b.BlockID, taskActionsBlockPrefix)
+}
+
+// unitHeader はまとまりがセクションの見出しならその見出しを返す
+func unitHeader(unit []slack.Block) *slack.SectionBlock {
+	for _, block := range unit {
+		if isSectionHeader(block) {
+			return block.(*slack.SectionBlock)
+		}
+	}
+	return nil
+}
+
+// continuedHeader は続きのメッセージの先頭に付ける見出しを作る
+// block_id はメッセージ内で重複できないため付けない
+func continuedHeader(header *slack.SectionBlock) *slack.SectionBlock {
+	text := strings.TrimSuffix(strings.TrimPrefix(header.Text.Text, "*"), "*")
+	return slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, "*"+tr("section.continued", text)+"*", false, false), nil, nil)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"time"
+
+	"rainierrr/notion-notifyer/pkg/task"
+)
+
+// Clock は現在時刻を提供する
+// 実行全体で同じ基準時刻を使えるよう、time.Now を直接呼ばずにこのインターフェースを経由する
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock はシステム時刻を返す Clock
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// fixedClock は常に同じ時刻を返す Clock
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+// startOfDay は t と同じ日の 0:00 を t のロケーションで返す
+// time.Date で組み立て直すため、夏時間の切り替え日でも日付がずれない
+func startOfDay(t time.Time) time.Time {
+	return task.StartOfDay(t)
+}
+
+// daysBetween は from の日付から to の日付までの日数を返す (同じ日なら 0、to が前なら負)
+// 日付だけを UTC の 0:00 に組み立て直して数えるため、夏時間で 1 日が 24 時間でなくてもずれない
+func daysBetween(from, to time.Time) int {
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a) / (24 * time.Hour))
+}
+
+// endOfDay は t から days 日後の 23:59:59 を返す
+// 月末・年末をまたぐ場合も time.Date が正規化する
+func endOfDay(t time.Time, days int) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+days, 23, 59, 59, 59, t.Location())
+}
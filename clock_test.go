@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/jomei/notionapi"
+
+	"rainierrr/notion-notifyer/pkg/task"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestStartOfDay(t *testing.T) {
+	ny := mustLoadLocation(t, "America/New_York")
+	tokyo := mustLoadLocation(t, "Asia/Tokyo")
+	tests := []struct {
+		name string
+		in   time.Time
+		want time.Time
+	}{
+		{"midnight stays", time.Date(2026, 10, 17, 0, 0, 0, 0, tokyo), time.Date(2026, 10, 17, 0, 0, 0, 0, tokyo)},
+		{"just before midnight", time.Date(2026, 10, 17, 23, 59, 59, 999999999, tokyo), time.Date(2026, 10, 17, 0, 0, 0, 0, tokyo)},
+		{"spring forward day (23 hours)", time.Date(2026, 3, 8, 12, 0, 0, 0, ny), time.Date(2026, 3, 8, 0, 0, 0, 0, ny)},
+		{"fall back day (25 hours)", time.Date(2026, 11, 1, 23, 30, 0, 0, ny), time.Date(2026, 11, 1, 0, 0, 0, 0, ny)},
+		{"new year's eve", time.Date(2026, 12, 31, 23, 59, 0, 0, ny), time.Date(2026, 12, 31, 0, 0, 0, 0, ny)},
+		{"keeps the location of t", time.Date(2026, 10, 17, 1, 0, 0, 0, tokyo).In(time.UTC), time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := startOfDay(tt.in)
+			if !got.Equal(tt.want) || got.Location() != tt.want.Location() {
+				t.Errorf("startOfDay(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	// 夏時間の切り替え日の 0:00 は、前日の 0:00 から 24 時間後とは限らない
+	if got := startOfDay(time.Date(2026, 3, 9, 12, 0, 0, 0, ny)).Sub(startOfDay(time.Date(2026, 3, 8, 12, 0, 0, 0, ny))); got != 23*time.Hour {
+		t.Errorf("spring forward day is %v long, want 23h", got)
+	}
+}
+
+func TestEndOfDay(t *testing.T) {
+	ny := mustLoadLocation(t, "America/New_York")
+	tokyo := mustLoadLocation(t, "Asia/Tokyo")
+	tests := []struct {
+		name string
+		in   time.Time
+		days int
+		want time.Time
+	}{
+		{"same day", time.Date(2026, 10, 17, 0, 0, 0, 0, tokyo), 0, time.Date(2026, 10, 17, 23, 59, 59, 59, tokyo)},
+		{"from just before midnight", time.Date(2026, 10, 17, 23, 59, 59, 0, tokyo), 1, time.Date(2026, 10, 18, 23, 59, 59, 59, tokyo)},
+		{"across spring forward", time.Date(2026, 3, 7, 10, 0, 0, 0, ny), 1, time.Date(2026, 3, 8, 23, 59, 59, 59, ny)},
+		{"across fall back", time.Date(2026, 10, 31, 10, 0, 0, 0, ny), 2, time.Date(2026, 11, 2, 23, 59, 59, 59, ny)},
+		{"new year's eve", time.Date(2026, 12, 31, 23, 59, 0, 0, tokyo), 0, time.Date(2026, 12, 31, 23, 59, 59, 59, tokyo)},
+		{"across the year end", time.Date(2026, 12, 30, 9, 0, 0, 0, tokyo), 3, time.Date(2027, 1, 2, 23, 59, 59, 59, tokyo)},
+		{"across the month end", time.Date(2026, 2, 28, 9, 0, 0, 0, ny), 1, time.Date(2026, 3, 1, 23, 59, 59, 59, ny)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := endOfDay(tt.in, tt.days); !got.Equal(tt.want) {
+				t.Errorf("endOfDay(%v, %d) = %v, want %v", tt.in, tt.days, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupTasksBySection(t *testing.T) {
+	ny := mustLoadLocation(t, "America/New_York")
+	tokyo := mustLoadLocation(t, "Asia/Tokyo")
+	// 日付のみの期限日は Notion から UTC の 0:00 として届く
+	dateOnly := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name        string
+		now         time.Time
+		due         time.Time
+		withinHours int
+		want        string
+	}{
+		{"a second before midnight is still today", time.Date(2026, 10, 17, 23, 59, 59, 0, tokyo), time.Date(2026, 10, 17, 23, 59, 59, 0, tokyo), 0, "today"},
+		{"yesterday just before midnight is overdue", time.Date(2026, 10, 17, 0, 0, 0, 0, tokyo), time.Date(2026, 10, 16, 23, 59, 59, 0, tokyo), 0, "overdue"},
+		{"tomorrow at midnight is soon", time.Date(2026, 10, 17, 23, 59, 0, 0, tokyo), time.Date(2026, 10, 18, 0, 0, 0, 0, tokyo), 0, "soon"},
+		{"date-only today west of UTC", time.Date(2026, 10, 17, 21, 0, 0, 0, ny), dateOnly(2026, 10, 17), 0, "today"},
+		{"date-only tomorrow east of UTC", time.Date(2026, 10, 17, 8, 0, 0, 0, tokyo), dateOnly(2026, 10, 18), 0, "soon"},
+		{"date-only yesterday is overdue", time.Date(2026, 10, 17, 0, 30, 0, 0, ny), dateOnly(2026, 10, 16), 0, "overdue"},
+		{"spring forward day evening", time.Date(2026, 3, 8, 1, 0, 0, 0, ny), time.Date(2026, 3, 8, 23, 30, 0, 0, ny), 0, "today"},
+		{"after spring forward midnight", time.Date(2026, 3, 8, 23, 0, 0, 0, ny), time.Date(2026, 3, 9, 0, 30, 0, 0, ny), 0, "soon"},
+		{"fall back repeated hour", time.Date(2026, 11, 1, 1, 30, 0, 0, ny).Add(time.Hour), time.Date(2026, 11, 1, 1, 45, 0, 0, ny), 0, "today"},
+		{"fall back day last minute", time.Date(2026, 11, 1, 0, 0, 0, 0, ny), time.Date(2026, 11, 1, 23, 59, 0, 0, ny), 0, "today"},
+		{"new year's eve task on new year's day", time.Date(2027, 1, 1, 0, 0, 0, 0, tokyo), time.Date(2026, 12, 31, 23, 0, 0, 0, tokyo), 0, "overdue"},
+		{"new year's day task on new year's eve", time.Date(2026, 12, 31, 23, 0, 0, 0, tokyo), dateOnly(2027, 1, 1), 0, "soon"},
+		{"within hours across midnight", time.Date(2026, 12, 31, 23, 0, 0, 0, tokyo), time.Date(2027, 1, 1, 1, 0, 0, 0, tokyo), 3, sectionHours},
+		{"within hours ignores date-only", time.Date(2026, 12, 31, 23, 0, 0, 0, tokyo), dateOnly(2027, 1, 1), 3, "soon"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			due := notionapi.Date(tt.due)
+			groups := groupTasksBySection([]Task{{Task: task.Task{ID: "page", Title: "task", DueStart: &due}}}, tt.now, tt.withinHours)
+			var got []string
+			for name, tasks := range groups {
+				if len(tasks) > 0 {
+					got = append(got, name)
+				}
+			}
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("task due %v at %v is in %v, want [%s]", tt.due, tt.now, got, tt.want)
+			}
+		})
+	}
+}
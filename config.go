@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// 設定ファイルのパス (--config が優先)
+const configFileEnv = "NOTIFYER_CONFIG"
+
+// 既定の設定。設定ファイルの項目はこの値を上書きする
+//
+//go:embed defaults.yaml
+var defaultConfigYAML []byte
+
+// 読み込んだ設定ファイル (config print-effective で使う)
+var (
+	loadedConfigPath string
+	loadedConfig     *fileConfig
+)
+
+// fileConfig は設定ファイル (YAML) の内容
+type fileConfig struct {
+	Properties propertyNames `yaml:"properties"`
+	// タスクを取得するデータベースの一覧 (NOTION_DB_ID が優先)
+	Databases []notionDatabase `yaml:"databases"`
+	// 名前付きの実行プロファイル (--profile で選ぶ)
+	Profiles map[string]runProfile `yaml:"profiles"`
+	// 期限日で分けるセクション (指定すると既定のセクションをすべて置き換える)
+	Buckets []urgencyBucket `yaml:"buckets"`
+	// 優先度や種類でタスクを別のチャンネルに送るルーティング表 (SLACK_CHANNEL_ID の投稿先に適用する)
+	Routes []taskRoute `yaml:"routes"`
+	// 優先度の順序 (高い順) と絵文字 (空なら Notion のセレクトの選択肢の順序)
+	Priorities []priorityLevel `yaml:"priorities"`
+	// タスクの数に合わせて先読みの日数を変える方針
+	AdaptiveWindow adaptiveWindow `yaml:"adaptive_window"`
+	// 取得する (未完了として扱う) スケジュールステータス
+	Statuses statusConfig `yaml:"statuses"`
+}
+
+// propertyNames は Task のフィールドに対応する Notion のプロパティ名
+// 空の項目は既定の名前のままにする
+type propertyNames struct {
+	Name           string `yaml:"name"`
+	UniqueID       string `yaml:"unique_id"`
+	Due            string `yaml:"due"`
+	Priority       string `yaml:"priority"`
+	Type           string `yaml:"type"`
+	ScheduleStatus string `yaml:"schedule_status"`
+	Workload       string `yaml:"workload"`
+	Memo           string `yaml:"memo"`
+	Assignee       string `yaml:"assignee"`
+	Tags           string `yaml:"tags"`
+	Project        string `yaml:"project"`
+	Link           string `yaml:"link"`
+	Email          string `yaml:"email"`
+	Phone          string `yaml:"phone"`
+	Pinned         string `yaml:"pinned"`
+	Progress       string `yaml:"progress"`
+	LastNotified   string `yaml:"last_notified"`
+	Files          string `yaml:"files"`
+	// 詳細に表示する追加のプロパティ (表示のしかたはプロパティの種類で決まる)
+	Extra []string `yaml:"extra"`
+}
+
+// loadConfigFile は設定ファイルを読み込む。知らない項目は書き間違いとしてエラーにする
+func loadConfigFile(path string) (*fileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	c, err := parseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return c, nil
+}
+
+func parseConfig(data []byte) (*fileConfig, error) {
+	var c fileConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// apply は設定ファイルのプロパティ名を反映する
+func (p propertyNames) apply() {
+	set := func(target *string, value string) {
+		if value != "" {
+			*target = value
+		}
+	}
+	set(&nameProp, p.Name)
+	set(&uniqueIDProp, p.UniqueID)
+	set(&dueProp, p.Due)
+	set(&priorityProp, p.Priority)
+	set(&typeProp, p.Type)
+	set(&scheduleStatusProp, p.ScheduleStatus)
+	set(&workloadProp, p.Workload)
+	set(&memoProp, p.Memo)
+	set(&assigneeProp, p.Assignee)
+	set(&tagsProp, p.Tags)
+	set(&projectProp, p.Project)
+	set(&linkProp, p.Link)
+	set(&emailProp, p.Email)
+	set(&phoneProp, p.Phone)
+	set(&pinnedProp, p.Pinned)
+	set(&progressProp, p.Progress)
+	set(&lastNotifiedProp, p.LastNotified)
+	set(&filesProp, p.Files)
+	if len(p.Extra) > 0 {
+		extraProps = p.Extra
+	}
+}
+
+// setupFromFlags はすべてのコマンドの前にタイムゾーンと設定ファイルを反映する
+func setupFromFlags(cmd *cobra.Command, args []string) error {
+	if err := applyFlagEnv(cmd); err != nil {
+		return asConfigError(err)
+	}
+	tz, _ := cmd.Flags().GetString("tz")
+	if err := applyTimezone(tz); err != nil {
+		return asConfigError(err)
+	}
+	lang, _ := cmd.Flags().GetString("lang")
+	if err := applyLanguage(lang); err != nil {
+		return asConfigError(err)
+	}
+	if queryPageSize < 1 || queryPageSize > 100 {
+		return configErrorf("--page-size must be between 1 and 100, got %d", queryPageSize)
+	}
+	if maxQueryResults < 0 {
+		return configErrorf("--max-results must not be negative, got %d", maxQueryResults)
+	}
+	if maxTextLength < 1 || maxTextLength > MAX_MESSAGE_LENGTH {
+		return configErrorf("--text-length must be between 1 and %d, got %d", MAX_MESSAGE_LENGTH, maxTextLength)
+	}
+	if maxMemoLength < 0 {
+		return configErrorf("--memo-length must not be negative, got %d", maxMemoLength)
+	}
+	if err := loadConfigFromFlags(cmd); err != nil {
+		return asConfigError(err)
+	}
+	return asConfigError(applyStatusFlags(cmd))
+}
+
+// loadConfigFromFlags は --config (未指定なら NOTIFYER_CONFIG、それも無ければ OS ごとの設定ディレクトリ) の設定ファイルを読み込んで反映する
+// NN_ の環境変数 (env_config.go) は設定ファイルの同じ項目を上書きし、設定ファイルが無くても使える
+// コマンドラインのフラグは設定ファイルより優先する
+func loadConfigFromFlags(cmd *cobra.Command) error {
+	path, _ := cmd.Flags().GetString("config")
+	if path == "" {
+		path = os.Getenv(configFileEnv)
+	}
+	if path == "" {
+		path = discoverConfigFile()
+	}
+	c := &fileConfig{}
+	if path != "" {
+		var err error
+		if c, err = loadConfigFile(path); err != nil {
+			return err
+		}
+	}
+	fromEnv, err := applyConfigEnv(c)
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		if !fromEnv {
+			return nil
+		}
+		path = "environment"
+	}
+
+	if err := applyFileConfig(cmd, c, path); err != nil {
+		return err
+	}
+	loadedConfigPath, loadedConfig = path, c
+	log.Printf("Loaded config from %s", path)
+	return nil
+}
+
+// applyFileConfig は設定ファイルの内容を検証して反映する (--pinned-property の指定は設定ファイルより優先する)
+func applyFileConfig(cmd *cobra.Command, c *fileConfig, path string) error {
+	pinnedFromFlag := pinnedProp
+	c.Properties.apply()
+	if cmd.Flags().Changed("pinned-property") {
+		pinnedProp = pinnedFromFlag
+	}
+	if err := validateDatabases(c.Databases); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if len(c.Databases) > 0 {
+		configuredDatabases = c.Databases
+	}
+	if err := validateProfiles(c.Profiles); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if err := validateBuckets(c.Buckets); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if len(c.Buckets) > 0 {
+		configuredBuckets = c.Buckets
+	}
+	if err := validateRoutes(c.Routes); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if len(c.Routes) > 0 {
+		configuredRoutes = c.Routes
+	}
+	if err := validatePriorities(c.Priorities); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if len(c.Priorities) > 0 {
+		applyPriorities(c.Priorities)
+	}
+	if err := validateAdaptiveWindow(c.AdaptiveWindow); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if c.AdaptiveWindow.enabled() {
+		configuredAdaptiveWindow = c.AdaptiveWindow
+	}
+	if err := validateStatuses(c.Statuses); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if !c.Statuses.empty() {
+		configuredStatuses = c.Statuses
+	}
+	maps.Copy(configuredProfiles, c.Profiles)
+	return nil
+}
+
+// resetConfig はプロパティ名・データベース・セクション・ルーティング表・優先度・先読みの方針・取得するステータスを既定 (defaults.yaml) に戻す
+func resetConfig() {
+	defaults, err := parseConfig(defaultConfigYAML)
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded defaults.yaml: %v", err))
+	}
+	// 既定の空の値は apply で反映されないため、先に空にする
+	pinnedProp, progressProp, lastNotifiedProp, filesProp, emailProp, phoneProp, projectProp, uniqueIDProp = "", "", "", "", "", "", "", ""
+	extraProps = nil
+	defaults.Properties.apply()
+	configuredDatabases = defaults.Databases
+	configuredBuckets = defaults.Buckets
+	configuredRoutes = defaults.Routes
+	applyPriorities(defaults.Priorities)
+	configuredAdaptiveWindow = defaults.AdaptiveWindow
+	configuredStatuses = defaults.Statuses
+}
+
+func init() {
+	defaults, err := parseConfig(defaultConfigYAML)
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded defaults.yaml: %v", err))
+	}
+	resetConfig()
+	if err := validateProfiles(defaults.Profiles); err != nil {
+		panic(fmt.Sprintf("invalid embedded defaults.yaml: %v", err))
+	}
+	maps.Copy(configuredProfiles, defaults.Profiles)
+	if err := validateBuckets(defaults.Buckets); err != nil {
+		panic(fmt.Sprintf("invalid embedded defaults.yaml: %v", err))
+	}
+
+	rootCmd.PersistentFlags().String("config", "", "Config file (YAML) mapping Notion property names (default $NOTIFYER_CONFIG, then config.yaml in the user config directory)")
+	rootCmd.PersistentFlags().StringVar(&stateDir, "state-dir", "", "Directory for state, history and token files given as relative paths (default $NOTIFYER_STATE_DIR or the current directory)")
+	rootCmd.PersistentFlags().String("tz", "", "IANA time zone for day boundaries, e.g. Asia/Tokyo (default $NOTIFYER_TZ or the system zone)")
+	rootCmd.PersistentPreRunE = setupFromFlags
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+
+	"rainierrr/notion-notifyer/pkg/notion"
+)
+
+// 設定に関わる環境変数。secret の値は表示するときに伏せる
+var configEnvVars = []struct {
+	name   string
+	secret bool
+}{
+	{configFileEnv, false},
+	{stateDirEnv, false},
+	{stateFileEnv, false},
+	{historyFileEnv, false},
+	{tenantsFileEnv, false},
+	{timezoneEnv, false},
+	{langEnv, false},
+	{profileEnv, false},
+	{notionTokenEnv, true},
+	{notionDBIDEnv, false},
+	{notionClientIDEnv, false},
+	{notionClientSecretEnv, true},
+	{slackTokenEnv, true},
+	{slackChannelEnv, false},
+	{slackClientIDEnv, false},
+	{slackClientSecretEnv, true},
+	{slackRefreshTokenEnv, true},
+	{slackAppTokenEnv, true},
+	{slackTokenFileEnv, false},
+	{slackRedirectURLEnv, false},
+	{slackSigningSecretEnv, true},
+	{githubTokenEnv, true},
+	{jiraBaseURLEnv, false},
+	{jiraEmailEnv, false},
+	{jiraAPITokenEnv, true},
+	{todoistTokenEnv, true},
+	{webhookURLEnv, false},
+	{webhookSecretEnv, true},
+	{webhookTokenEnv, true},
+	{discordWebhookEnv, true},
+	{teamsWebhookEnv, true},
+	{smtpHostEnv, false},
+	{smtpPortEnv, false},
+	{smtpUsernameEnv, false},
+	{smtpPasswordEnv, true},
+	{emailFromEnv, false},
+	{emailToEnv, false},
+	{mqttURLEnv, true},
+	{mqttUsernameEnv, false},
+	{mqttPasswordEnv, true},
+	{mqttTopicEnv, false},
+	{mockURLEnv, false},
+}
+
+// maskSecret は秘密の値を先頭の数文字だけ残して伏せる (トークンの種類は接頭辞でわかる)
+func maskSecret(v string) string {
+	if len(v) <= 8 {
+		return "****"
+	}
+	return v[:4] + "****"
+}
+
+// yamlMap は順序とコメントを保ったまま YAML のマッピングを組み立てる
+type yamlMap struct {
+	node *yaml.Node
+}
+
+func newYAMLMap() yamlMap {
+	return yamlMap{node: &yaml.Node{Kind: yaml.MappingNode}}
+}
+
+func (m yamlMap) set(key, value, comment string) {
+	m.node.Content = append(m.node.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Value: value, LineComment: comment},
+	)
+}
+
+func (m yamlMap) setNode(key string, value *yaml.Node, comment string) {
+	m.node.Content = append(m.node.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: key, LineComment: comment},
+		value,
+	)
+}
+
+// effectiveProperties は各プロパティ名と、その値がどこから来たかを返す
+func effectiveProperties(cmd *cobra.Command) yamlMap {
+	var file propertyNames
+	if loadedConfig != nil {
+		file = loadedConfig.Properties
+	}
+	m := newYAMLMap()
+	for _, p := range []struct {
+		key, value, fromFile, flag string
+	}{
+		{"name", nameProp, file.Name, ""},
+		{"unique_id", uniqueIDProp, file.UniqueID, ""},
+		{"due", dueProp, file.Due, ""},
+		{"priority", priorityProp, file.Priority, ""},
+		{"type", typeProp, file.Type, ""},
+		{"schedule_status", scheduleStatusProp, file.ScheduleStatus, ""},
+		{"workload", workloadProp, file.Workload, ""},
+		{"memo", memoProp, file.Memo, ""},
+		{"assignee", assigneeProp, file.Assignee, ""},
+		{"tags", tagsProp, file.Tags, ""},
+		{"project", projectProp, file.Project, ""},
+		{"link", linkProp, file.Link, ""},
+		{"email", emailProp, file.Email, ""},
+		{"phone", phoneProp, file.Phone, ""},
+		{"pinned", pinnedProp, file.Pinned, "pinned-property"},
+		{"progress", progressProp, file.Progress, ""},
+		{"last_notified", lastNotifiedProp, file.LastNotified, ""},
+		{"files", filesProp, file.Files, ""},
+	} {
+		source := "default"
+		switch {
+		case p.flag != "" && cmd.Flags().Changed(p.flag):
+			source = "flag --" + p.flag
+		case p.fromFile != "":
+			source = "file"
+		}
+		m.set(p.key, p.value, source)
+	}
+	extra := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+	for _, name := range extraProps {
+		extra.Content = append(extra.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name})
+	}
+	extra.LineComment = "default"
+	if len(file.Extra) > 0 {
+		extra.LineComment = "file"
+	}
+	m.setNode("extra", extra, "")
+	return m
+}
+
+// envSource は値が空でなければ環境変数から、空なら既定値とする
+func envSource(env string) string {
+	if os.Getenv(env) != "" {
+		return "env " + env
+	}
+	return "default"
+}
+
+// effectiveTenants はテナント定義を表示用のノードにする。ファイルが無ければ nil を返す
+func effectiveTenants() (*yaml.Node, string, error) {
+	path := os.Getenv(tenantsFileEnv)
+	if path == "" {
+		path = defaultTenantsFile
+	}
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil, path, nil
+	}
+	tenants, err := loadTenants(path)
+	if err != nil {
+		return nil, path, err
+	}
+	// json のタグどおりの名前と順序で出すため、一度 JSON を経由する (JSON は YAML として読める)
+	data, err := json.Marshal(tenants)
+	if err != nil {
+		return nil, path, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, path, err
+	}
+	node := doc.Content[0]
+	blockStyle(node)
+	return node, path, nil
+}
+
+// blockStyle は JSON 由来のフロースタイルをブロックスタイルに直す
+func blockStyle(n *yaml.Node) {
+	n.Style &^= yaml.FlowStyle | yaml.DoubleQuotedStyle
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+var configPrintEffectiveCmd = &cobra.Command{
+	Use:   "print-effective",
+	Short: "Print the merged configuration (defaults, config file, environment and flags) with secrets masked.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		doc := newYAMLMap()
+
+		if loadedConfigPath != "" {
+			doc.set("config_file", loadedConfigPath, "")
+		} else {
+			doc.set("config_file", "", "none found; using embedded defaults")
+		}
+		tzSource := envSource(timezoneEnv)
+		if cmd.Flags().Changed("tz") {
+			tzSource = "flag --tz"
+		}
+		doc.set("timezone", time.Local.String(), tzSource)
+		stateDirSource := envSource(stateDirEnv)
+		if cmd.Flags().Changed("state-dir") {
+			stateDirSource = "flag --state-dir"
+		}
+		doc.set("state_dir", statePath("."), stateDirSource)
+
+		files := newYAMLMap()
+		files.set("state", stateStoreFromEnv().path, envSource(stateFileEnv))
+		if store := historyStoreFromEnv(); store != nil {
+			files.set("history", store.path, envSource(historyFileEnv))
+		} else {
+			files.set("history", "", "disabled")
+		}
+		if path := os.Getenv(slackTokenFileEnv); path != "" {
+			files.set("slack_tokens", statePath(path), envSource(slackTokenFileEnv))
+		} else {
+			files.set("slack_tokens", "", "disabled")
+		}
+		doc.setNode("files", files.node, "")
+
+		doc.setNode("properties", effectiveProperties(cmd).node, "")
+
+		dbIDs, dbSource := os.Getenv(notionDBIDEnv), "env "+notionDBIDEnv
+		if dbIDs == "" {
+			dbIDs, dbSource = configuredDatabaseIDs(), "file"
+		}
+		databases := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, db := range parseDatabaseIDs(dbIDs) {
+			m := newYAMLMap()
+			if db.TitlePattern != "" {
+				m.set("title_pattern", db.TitlePattern, "resolved at runtime")
+			} else {
+				m.set("id", db.ID, "")
+			}
+			if db.Name != "" {
+				m.set("name", db.Name, "")
+			}
+			databases.Content = append(databases.Content, m.node)
+		}
+		if len(databases.Content) == 0 {
+			dbSource = "none"
+		}
+		doc.setNode("databases", databases, dbSource)
+
+		buckets := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, b := range configuredBuckets {
+			m := newYAMLMap()
+			m.set("name", b.Name, "")
+			if b.Title != "" {
+				m.set("title", b.Title, "")
+			}
+			if b.Emoji != "" {
+				m.set("emoji", b.Emoji, "")
+			}
+			if b.Color != "" {
+				m.set("color", b.Color, "")
+			}
+			if b.Days != nil {
+				m.set("days", strconv.Itoa(*b.Days), "")
+			}
+			buckets.Content = append(buckets.Content, m.node)
+		}
+		bucketSource := "default"
+		if loadedConfig != nil && len(loadedConfig.Buckets) > 0 {
+			bucketSource = "file"
+		}
+		doc.setNode("buckets", buckets, bucketSource)
+
+		if len(configuredRoutes) > 0 {
+			routes := &yaml.Node{Kind: yaml.SequenceNode}
+			for _, r := range configuredRoutes {
+				m := newYAMLMap()
+				if len(r.Priorities) > 0 {
+					m.set("priorities", strings.Join(r.Priorities, ", "), "")
+				}
+				if len(r.Types) > 0 {
+					m.set("types", strings.Join(r.Types, ", "), "")
+				}
+				m.set("channel", r.Channel, "")
+				routes.Content = append(routes.Content, m.node)
+			}
+			doc.setNode("routes", routes, "file")
+		}
+
+		if len(configuredPriorities) > 0 {
+			priorities := &yaml.Node{Kind: yaml.SequenceNode}
+			for _, p := range configuredPriorities {
+				m := newYAMLMap()
+				m.set("name", p.Name, "")
+				if p.Emoji != "" {
+					m.set("emoji", p.Emoji, "")
+				}
+				priorities.Content = append(priorities.Content, m.node)
+			}
+			doc.setNode("priorities", priorities, "file")
+		} else {
+			doc.set("priorities", "", "Notion select options of "+priorityProp)
+		}
+
+		if w := configuredAdaptiveWindow; w.enabled() {
+			window := newYAMLMap()
+			window.set("min_tasks", strconv.Itoa(w.MinTasks), "")
+			window.set("max_tasks", strconv.Itoa(w.MaxTasks), "")
+			window.set("min_days", strconv.Itoa(w.MinDays), "")
+			window.set("max_days", strconv.Itoa(w.maxDays()), "")
+			doc.setNode("adaptive_window", window.node, "file")
+		} else {
+			doc.set("adaptive_window", "", "disabled")
+		}
+
+		if s := configuredStatuses; !s.empty() {
+			statuses := newYAMLMap()
+			for _, field := range []struct {
+				key    string
+				values []string
+			}{{"include", s.Include}, {"exclude", s.Exclude}, {"groups", s.Groups}} {
+				if len(field.values) > 0 {
+					statuses.set(field.key, strings.Join(field.values, ", "), "")
+				}
+			}
+			source := "file"
+			if slices.ContainsFunc([]string{"statuses", "exclude-statuses", "status-groups"}, cmd.Flags().Changed) {
+				source = "flag"
+			}
+			doc.setNode("statuses", statuses.node, source)
+		} else {
+			doc.set("statuses", strings.Join(notion.DefaultStatuses, ", "), "default")
+		}
+
+		profiles := newYAMLMap()
+		for _, name := range slices.Sorted(maps.Keys(configuredProfiles)) {
+			features := newYAMLMap()
+			p := configuredProfiles[name]
+			for _, feature := range slices.Sorted(maps.Keys(p.Features)) {
+				features.set(feature, strconv.FormatBool(p.Features[feature]), "")
+			}
+			profile := newYAMLMap()
+			profile.setNode("features", features.node, "")
+			profiles.setNode(name, profile.node, "")
+		}
+		doc.setNode("profiles", profiles.node, "")
+
+		env := newYAMLMap()
+		for _, e := range configEnvVars {
+			v, ok := os.LookupEnv(e.name)
+			if !ok {
+				continue
+			}
+			if e.secret && v != "" {
+				v = maskSecret(v)
+			}
+			env.set(e.name, v, "")
+		}
+		for _, kv := range slices.Sorted(slices.Values(os.Environ())) {
+			if name, v, _ := strings.Cut(kv, "="); strings.HasPrefix(name, envPrefix) {
+				env.set(name, v, "")
+			}
+		}
+		doc.setNode("environment", env.node, "")
+
+		flags := newYAMLMap()
+		cmd.InheritedFlags().VisitAll(func(f *pflag.Flag) {
+			source := "default"
+			if name, ok := envSetFlags[f.Name]; ok {
+				source = "env " + name
+			} else if f.Changed {
+				source = "flag"
+			}
+			flags.set(f.Name, f.Value.String(), source)
+		})
+		doc.setNode("flags", flags.node, "")
+
+		tenants, path, err := effectiveTenants()
+		if err != nil {
+			return err
+		}
+		if tenants != nil {
+			doc.setNode("tenants", tenants, path)
+		}
+
+		enc := yaml.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent(2)
+		if err := enc.Encode(doc.node); err != nil {
+			return fmt.Errorf("failed to print config: %w", err)
+		}
+		return enc.Close()
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configPrintEffectiveCmd)
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jomei/notionapi"
+	"github.com/slack-go/slack"
+	"github.com/spf13/cobra"
+)
+
+// lintFinding は設定の問題 1 件
+type lintFinding struct {
+	Tenant  string
+	Message string
+}
+
+func (f lintFinding) String() string {
+	return fmt.Sprintf("[%s] %s", f.Tenant, f.Message)
+}
+
+// key は投稿先を同一視するためのキー
+func (d tenantDestination) key() string {
+	if d.TokenEnv != "" {
+		return "token_env:" + d.TokenEnv + "/" + d.ChannelID
+	}
+	return "team:" + d.TeamID + "/" + d.ChannelID
+}
+
+func (d tenantDestination) label() string {
+	if d.TokenEnv != "" {
+		return fmt.Sprintf("%s %s", d.TokenEnv, d.ChannelID)
+	}
+	if d.ChannelID == "" {
+		return fmt.Sprintf("team %s (install channel)", d.TeamID)
+	}
+	return fmt.Sprintf("team %s %s", d.TeamID, d.ChannelID)
+}
+
+// lintTenants はテナント定義の矛盾を検出する
+// types はテナントごとの Notion の種類 (Type) の選択肢で、取得できなかったテナントは含まない
+func lintTenants(tenants []tenant, types map[string][]string) []lintFinding {
+	var findings []lintFinding
+	add := func(t tenant, format string, args ...any) {
+		findings = append(findings, lintFinding{Tenant: t.Name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// 投稿先の重複 (同じチャンネルに複数のダイジェストが届く)
+	owners := map[string][]string{}
+	for _, t := range tenants {
+		for _, d := range t.Slack {
+			if !slices.Contains(owners[d.key()], t.Name) {
+				owners[d.key()] = append(owners[d.key()], t.Name)
+			}
+		}
+	}
+
+	for _, t := range tenants {
+		seen := map[string]bool{}
+		for _, d := range t.Slack {
+			if seen[d.key()] {
+				add(t, "destination %s is listed twice", d.label())
+			}
+			seen[d.key()] = true
+			if others := owners[d.key()]; len(others) > 1 && others[0] == t.Name {
+				add(t, "destination %s is shared with tenants %s and will receive several digests", d.label(), strings.Join(others[1:], ", "))
+			}
+
+			// 使われないルール
+			for _, typ := range d.IncludeTypes {
+				if slices.Contains(d.ExcludeTypes, typ) {
+					add(t, "destination %s both includes and excludes Type %q", d.label(), typ)
+				}
+			}
+			if len(d.IncludeTypes) > 0 {
+				for _, typ := range d.ExcludeTypes {
+					if !slices.Contains(d.IncludeTypes, typ) {
+						add(t, "destination %s excludes Type %q, which include_types already leaves out", d.label(), typ)
+					}
+				}
+			}
+			if options, ok := types[t.Name]; ok {
+				for _, typ := range append(append([]string{}, d.IncludeTypes...), d.ExcludeTypes...) {
+					if !slices.Contains(options, typ) {
+						add(t, "destination %s filters on Type %q, which is not an option in the database", d.label(), typ)
+					}
+				}
+			}
+		}
+
+		// どの投稿先にも届かないタスク
+		if options, ok := types[t.Name]; ok {
+			for _, typ := range append([]string{""}, options...) {
+				received := slices.ContainsFunc(t.Slack, func(d tenantDestination) bool {
+					return taskTypeFilter{Include: d.IncludeTypes, Exclude: d.ExcludeTypes}.allowsType(typ)
+				})
+				if received {
+					continue
+				}
+				if typ == "" {
+					add(t, "tasks without a Type reach no destination")
+				} else {
+					add(t, "tasks of Type %q reach no destination", typ)
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// routeOther はルートに書かれていない優先度や種類の値の代表 (どの値とも一致しない)
+const routeOther = "\x00other"
+
+// lintRoutes は設定ファイルの routes の矛盾を検出する
+// priorities は優先度の順序、types は Notion の種類の選択肢 (取得できなければ nil で、種類の値の確認を省く)
+// channelExists はルートのチャンネルが存在するかを返す (nil ならチャンネルを確認しない)
+func lintRoutes(routes []taskRoute, priorities, types []string, channelExists func(channel string) (bool, error)) []lintFinding {
+	var findings []lintFinding
+	add := func(format string, args ...any) {
+		findings = append(findings, lintFinding{Tenant: "routes", Message: fmt.Sprintf(format, args...)})
+	}
+
+	// 一致しうる値の組み合わせ (書かれていない値は routeOther で代表する)
+	priorityValues := []string{"", routeOther}
+	typeValues := []string{"", routeOther}
+	for _, r := range routes {
+		priorityValues = appendMissing(priorityValues, r.Priorities...)
+		typeValues = appendMissing(typeValues, r.Types...)
+	}
+	priorityValues = appendMissing(priorityValues, priorities...)
+	typeValues = appendMissing(typeValues, types...)
+
+	for i, r := range routes {
+		label := fmt.Sprintf("routes[%d] (%s)", i, r.Channel)
+
+		// 存在しない値だけを条件にしたルートには何も届かない
+		unknownPriorities := slices.DeleteFunc(slices.Clone(r.Priorities), func(p string) bool { return slices.Contains(priorities, p) })
+		var unknownTypes []string
+		if types != nil {
+			unknownTypes = slices.DeleteFunc(slices.Clone(r.Types), func(t string) bool { return slices.Contains(types, t) })
+		}
+		for _, p := range unknownPriorities {
+			add("%s matches priority %q, which is not a configured priority", label, p)
+		}
+		for _, t := range unknownTypes {
+			add("%s matches Type %q, which is not an option in the database", label, t)
+		}
+		if (len(r.Priorities) > 0 && len(unknownPriorities) == len(r.Priorities)) || (len(r.Types) > 0 && len(unknownTypes) == len(r.Types)) {
+			add("%s can never match a task", label)
+			continue
+		}
+
+		// 一致するすべての組み合わせが前のルートに取られていれば、このルートには何も届かない
+		var shadowedBy []int
+		reached := false
+		for _, p := range priorityValues {
+			for _, t := range typeValues {
+				if !r.matches(p, t) {
+					continue
+				}
+				j := routeIndex(routes, p, t)
+				if j == i {
+					reached = true
+				} else if !slices.Contains(shadowedBy, j) {
+					shadowedBy = append(shadowedBy, j)
+				}
+			}
+		}
+		if !reached {
+			slices.Sort(shadowedBy)
+			earlier := make([]string, len(shadowedBy))
+			for k, j := range shadowedBy {
+				earlier[k] = fmt.Sprintf("routes[%d]", j)
+			}
+			add("%s is shadowed by %s and never receives tasks", label, strings.Join(earlier, ", "))
+		}
+	}
+
+	if channelExists != nil {
+		var checked []string
+		for i, r := range routes {
+			if slices.Contains(checked, r.Channel) {
+				continue
+			}
+			checked = append(checked, r.Channel)
+			ok, err := channelExists(r.Channel)
+			if err != nil {
+				add("routes[%d]: could not check channel %s: %v", i, r.Channel, err)
+			} else if !ok {
+				add("routes[%d] sends to unknown channel %s", i, r.Channel)
+			}
+		}
+	}
+	return findings
+}
+
+// appendMissing は values のうち list に無いものを加える
+func appendMissing(list []string, values ...string) []string {
+	for _, v := range values {
+		if !slices.Contains(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// slackChannelChecker は SLACK_BOT_TOKEN のワークスペースにチャンネルがあるかを返す関数を作る
+// チャンネル名は conversations.list、ID は conversations.info で確かめる
+func slackChannelChecker(ctx context.Context, client *slack.Client) func(channel string) (bool, error) {
+	return func(channel string) (bool, error) {
+		if name, ok := channelName(channel); ok {
+			if _, err := lookupChannelID(ctx, client, "", name); err != nil {
+				if slackErrorCode(err) != "" {
+					return false, err
+				}
+				return false, nil
+			}
+			return true, nil
+		}
+		_, err := client.GetConversationInfoContext(ctx, &slack.GetConversationInfoInput{ChannelID: channel})
+		if slackErrorCode(err) == "channel_not_found" {
+			return false, nil
+		}
+		return err == nil, err
+	}
+}
+
+// fetchTypeOptions はデータベースの種類 (Type) プロパティの選択肢を返す
+// カンマ区切りで複数のデータベースが指定されていれば、すべての選択肢を合わせる
+func fetchTypeOptions(ctx context.Context, client *notionapi.Client, dbIDs string) ([]string, error) {
+	var options []string
+	for _, d := range parseDatabaseIDs(dbIDs) {
+		db, err := client.Database.Get(ctx, notionapi.DatabaseID(d.ID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get database %s: %w", d.ID, err)
+		}
+		config, ok := db.Properties[typeProp].(*notionapi.SelectPropertyConfig)
+		if !ok {
+			return nil, fmt.Errorf("database %s has no select property %q", d.ID, typeProp)
+		}
+		for _, option := range config.Select.Options {
+			if !slices.Contains(options, option.Name) {
+				options = append(options, option.Name)
+			}
+		}
+	}
+	sort.Strings(options)
+	return options, nil
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the notifyer configuration.",
+}
+
+var configLintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Detect dead filters, overlapping destinations and tasks no destination would receive in tenants.json, and shadowed or dead routes in the config file.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// tenants.json が無ければ (--file を指定していなければ) routes だけを確認する
+		tenants, err := tenantsFromFlags(cmd)
+		if errors.Is(err, os.ErrNotExist) && !cmd.Flags().Changed("file") && os.Getenv(tenantsFileEnv) == "" {
+			tenants, err = nil, nil
+		}
+		if err != nil {
+			return err
+		}
+		offline, _ := cmd.Flags().GetBool("offline")
+
+		// 種類の選択肢は Notion から取得する。取得できないテナントは選択肢に関する確認を省く
+		types := map[string][]string{}
+		var routeTypes []string
+		var channelExists func(string) (bool, error)
+		if !offline {
+			st, err := stateStoreFromEnv().Load()
+			if err != nil {
+				return err
+			}
+			for _, t := range tenants {
+				token := t.notionToken(st)
+				if token == "" {
+					fmt.Fprintf(cmd.OutOrStdout(), "[%s] skipped Type checks: no Notion token\n", t.Name)
+					continue
+				}
+				options, err := fetchTypeOptions(cmd.Context(), notionapi.NewClient(notionapi.Token(token)), t.Notion.DatabaseID)
+				if err != nil {
+					fmt.Fprintf(cmd.OutOrStdout(), "[%s] skipped Type checks: %v\n", t.Name, err)
+					continue
+				}
+				types[t.Name] = options
+			}
+
+			if len(configuredRoutes) > 0 {
+				if token, dbID, err := notionSourceFromEnv(stateStoreFromEnv()); err != nil {
+					fmt.Fprintf(cmd.OutOrStdout(), "[routes] skipped Type checks: %v\n", err)
+				} else if routeTypes, err = fetchTypeOptions(cmd.Context(), notionapi.NewClient(notionapi.Token(token)), dbID); err != nil {
+					fmt.Fprintf(cmd.OutOrStdout(), "[routes] skipped Type checks: %v\n", err)
+				}
+				if token := os.Getenv(slackTokenEnv); token == "" {
+					fmt.Fprintf(cmd.OutOrStdout(), "[routes] skipped channel checks: %s is not set\n", slackTokenEnv)
+				} else {
+					channelExists = slackChannelChecker(cmd.Context(), slack.New(token))
+				}
+			}
+		}
+
+		findings := lintTenants(tenants, types)
+		findings = append(findings, lintRoutes(configuredRoutes, priorityNames(), routeTypes, channelExists)...)
+		for _, f := range findings {
+			fmt.Fprintln(cmd.OutOrStdout(), f)
+		}
+		if len(findings) > 0 {
+			return fmt.Errorf("%d problems found", len(findings))
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%d tenants and %d routes OK\n", len(tenants), len(configuredRoutes))
+		return nil
+	},
+}
+
+func init() {
+	configLintCmd.Flags().String("file", "", "Tenants definition file (default $NOTIFYER_TENANTS_FILE or tenants.json)")
+	configLintCmd.Flags().StringSlice("tenant", nil, "Only lint the given tenants")
+	configLintCmd.Flags().Bool("offline", false, "Skip checks that need the Notion database schema")
+	configCmd.AddCommand(configLintCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLintRoutes(t *testing.T) {
+	priorities := []string{"High", "Mid", "Low"}
+	types := []string{"Work", "Personal"}
+	tests := []struct {
+		name   string
+		routes []taskRoute
+		types  []string
+		want   []string
+	}{
+		{
+			name:   "disjoint routes",
+			routes: []taskRoute{{Priorities: []string{"High"}, Channel: "C1"}, {Types: []string{"Work"}, Channel: "C2"}},
+			types:  types,
+		},
+		{
+			name:   "narrower route after a broader one",
+			routes: []taskRoute{{Types: []string{"Work"}, Channel: "C1"}, {Priorities: []string{"High"}, Types: []string{"Work"}, Channel: "C2"}},
+			types:  types,
+			want:   []string{"routes[1] (C2) is shadowed by routes[0] and never receives tasks"},
+		},
+		{
+			name: "covered by several earlier routes",
+			routes: []taskRoute{
+				{Priorities: []string{"High"}, Channel: "C1"},
+				{Priorities: []string{"Mid", "Low"}, Types: []string{"Work"}, Channel: "C2"},
+				{Priorities: []string{"High", "Mid"}, Types: []string{"Work"}, Channel: "C3"},
+			},
+			types: types,
+			want:  []string{"routes[2] (C3) is shadowed by routes[0], routes[1] and never receives tasks"},
+		},
+		{
+			name:   "tasks with an unlisted type still reach a route on priority",
+			routes: []taskRoute{{Priorities: []string{"High"}, Types: []string{"Work", "Personal"}, Channel: "C1"}, {Priorities: []string{"High"}, Channel: "C2"}},
+			types:  types,
+		},
+		{
+			name:   "unknown priority",
+			routes: []taskRoute{{Priorities: []string{"Urgent"}, Channel: "C1"}},
+			types:  types,
+			want:   []string{`routes[0] (C1) matches priority "Urgent", which is not a configured priority`, "routes[0] (C1) can never match a task"},
+		},
+		{
+			name:   "unknown type among known ones",
+			routes: []taskRoute{{Types: []string{"Work", "Study"}, Channel: "C1"}},
+			types:  types,
+			want:   []string{`routes[0] (C1) matches Type "Study", which is not an option in the database`},
+		},
+		{
+			name:   "types are not checked without the schema",
+			routes: []taskRoute{{Types: []string{"Study"}, Channel: "C1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range lintRoutes(tt.routes, priorities, tt.types, nil) {
+				got = append(got, f.Message)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintRoutesUnknownChannel(t *testing.T) {
+	routes := []taskRoute{
+		{Priorities: []string{"High"}, Channel: "#urgent"},
+		{Priorities: []string{"Mid"}, Channel: "#gone"},
+		{Priorities: []string{"Low"}, Channel: "#gone"},
+	}
+	var checked []string
+	exists := func(channel string) (bool, error) {
+		checked = append(checked, channel)
+		return channel == "#urgent", nil
+	}
+	findings := lintRoutes(routes, []string{"High", "Mid", "Low"}, nil, exists)
+	if len(findings) != 1 || findings[0].Message != "routes[1] sends to unknown channel #gone" {
+		t.Errorf("got %v, want one unknown channel", findings)
+	}
+	if !slices.Equal(checked, []string{"#urgent", "#gone"}) {
+		t.Errorf("checked %v, want each channel once", checked)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// その日のメッセージの記録を保持する期間 (使われなくなった投稿先の記録を消す)
+const dailyMessageRetention = 7 * 24 * time.Hour
+
+// dailyMessage はその日に投稿したダイジェストのメッセージ (--update-in-place)
+type dailyMessage struct {
+	Day        string    `json:"day"`        // 投稿した日 (2006-01-02)
+	Timestamps []string  `json:"timestamps"` // 分けて投稿したメッセージの ts (先頭が最初のメッセージ)
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// dailyMessageKey はその日のメッセージの記録のキーを返す (ジョブと投稿先ごと)
+func dailyMessageKey(jobName string, dest slackDestination) string {
+	return jobName + ":" + dest.teamScope() + "/" + dest.ChannelID
+}
+
+// upsertDailyMessage は今日のメッセージがあれば chat.update で書き換え、無ければ新しく投稿する
+// 分けたメッセージが前回より増えたら続きを投稿し、減ったら余ったメッセージを消す
+// 最初のメッセージの ts と、書き換えたかどうかを返す
+func upsertDailyMessage(ctx context.Context, client *slack.Client, store *stateStore, jobName string, dest slackDestination, blocks []slack.Block, now time.Time) (string, bool, error) {
+	st, err := store.Load()
+	if err != nil {
+		return "", false, err
+	}
+	key := dailyMessageKey(jobName, dest)
+	day := now.Format(time.DateOnly)
+	chunks := splitBlocks(blocks, maxBlocksPerMessage)
+
+	var timestamps []string
+	updated := false
+	if prev := st.DailyMessages[key]; prev != nil && prev.Day == day && len(prev.Timestamps) > 0 {
+		timestamps, err = updateChunks(ctx, client, dest.ChannelID, prev.Timestamps, chunks)
+		if len(timestamps) == 0 {
+			// 最初のメッセージが消されたなどで書き換えられなければ、新しく投稿し直す
+			log.Printf("Warning: Unable to update today's message in %s, posting a new one: %v", dest.ChannelID, err)
+		} else {
+			// 書き換えた後で続きを投稿できなかった場合も、投稿し直すと同じ内容が重複するので
+			// 書き換えたメッセージと投稿できた分を記録してエラーを返す
+			updated = true
+		}
+	}
+	if timestamps == nil {
+		if timestamps, err = postChunks(ctx, client, dest.ChannelID, chunks); len(timestamps) == 0 {
+			return "", false, err
+		}
+	}
+
+	saveErr := store.Update(func(st *state) error {
+		if st.DailyMessages == nil {
+			st.DailyMessages = map[string]*dailyMessage{}
+		}
+		for k, m := range st.DailyMessages {
+			if now.Sub(m.UpdatedAt) > dailyMessageRetention {
+				delete(st.DailyMessages, k)
+			}
+		}
+		st.DailyMessages[key] = &dailyMessage{Day: day, Timestamps: timestamps, UpdatedAt: now}
+		return nil
+	})
+	if saveErr != nil {
+		log.Printf("Warning: Unable to record today's message in %s: %v", dest.ChannelID, saveErr)
+	}
+	return timestamps[0], updated, err
+}
+
+// updateChunks は前回のメッセージを順に書き換え、足りない分は投稿し、余った分は消す
+// 最初のメッセージを書き換えられなければ何も変えずにエラーを返す
+// 続きを投稿できなかった場合は、書き換えたメッセージと投稿できた分の ts をエラーと一緒に返す
+func updateChunks(ctx context.Context, client *slack.Client, channelID string, prev []string, chunks [][]slack.Block) ([]string, error) {
+	var timestamps []string
+	for i, chunk := range chunks {
+		if i >= len(prev) {
+			posted, err := postChunks(ctx, client, channelID, chunks[i:])
+			return append(timestamps, posted...), err
+		}
+		_, ts, _, err := client.UpdateMessageContext(ctx, channelID, prev[i], slack.MsgOptionBlocks(chunk...))
+		if err != nil {
+			if i == 0 {
+				return nil, newSlackError("", err)
+			}
+			log.Printf("Warning: Unable to update message %s in %s: %v", prev[i], channelID, err)
+			ts = prev[i]
+		}
+		timestamps = append(timestamps, ts)
+	}
+	for _, ts := range prev[len(chunks):] {
+		if _, _, err := client.DeleteMessageContext(ctx, channelID, ts); err != nil {
+			log.Printf("Warning: Unable to delete message %s in %s: %v", ts, channelID, err)
+		}
+	}
+	return timestamps, nil
+}
+
+func init() {
+	rootCmd.Flags().Bool("update-in-place", false, "Update today's digest message with chat.update on later runs instead of posting a new one, so each channel keeps one task board per day")
+}
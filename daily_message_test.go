@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// TestUpsertDailyMessageKeepsPartialUpdate は書き換えた後で続きを投稿できなかったとき、
+// ダイジェスト全体を投稿し直さずに書き換えたメッセージを記録してエラーを返すことを確かめる
+func TestUpsertDailyMessageKeepsPartialUpdate(t *testing.T) {
+	discardLogs(t)
+	var updated []string
+	posts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/chat.update":
+			updated = append(updated, r.Form.Get("ts"))
+			_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "channel": r.Form.Get("channel"), "ts": r.Form.Get("ts")})
+		case "/chat.postMessage":
+			posts++
+			_ = json.NewEncoder(w).Encode(map[string]any{"ok": false, "error": "channel_not_found"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	client := slack.New("xoxb-test", slack.OptionAPIURL(server.URL+"/"))
+
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	store := newStateStore(filepath.Join(t.TempDir(), "state.json"))
+	dest := slackDestination{Name: "test", ChannelID: "C0123456"}
+	key := dailyMessageKey("job", dest)
+	if err := store.Update(func(st *state) error {
+		st.DailyMessages = map[string]*dailyMessage{key: {Day: now.Format(time.DateOnly), Timestamps: []string{"1.0"}, UpdatedAt: now}}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 2 つのメッセージに分かれるブロック
+	var blocks []slack.Block
+	for range maxBlocksPerMessage + 1 {
+		blocks = append(blocks, slack.NewDividerBlock())
+	}
+	ts, isUpdate, err := upsertDailyMessage(context.Background(), client, store, "job", dest, blocks, now.Add(time.Hour))
+	if err == nil {
+		t.Fatal("expected the failed overflow post to be reported")
+	}
+	if ts != "1.0" || !isUpdate {
+		t.Errorf("got ts %q (updated %v), want the updated first message", ts, isUpdate)
+	}
+	if !slices.Equal(updated, []string{"1.0"}) || posts != 1 {
+		t.Errorf("updated %v and posted %d messages, want one update and only the overflow post", updated, posts)
+	}
+	st, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := st.DailyMessages[key].Timestamps; !slices.Equal(got, []string{"1.0"}) {
+		t.Errorf("recorded %v, want the updated message", got)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jomei/notionapi"
+)
+
+// notionDatabase はタスクの取得元のデータベース
+// ID の代わりに TitlePattern を指定すると、実行のたびにタイトルが一致するデータベースを検索して使う
+type notionDatabase struct {
+	ID           string `yaml:"id,omitempty"`
+	Name         string `yaml:"name,omitempty"`          // メッセージに表示する名前 (空ならデータベースのタイトル)
+	TitlePattern string `yaml:"title_pattern,omitempty"` // タイトルの正規表現 (例: ^Tasks - .*)
+}
+
+// 設定ファイルの databases。NOTION_DB_ID が無ければこの一覧から取得する
+var configuredDatabases []notionDatabase
+
+// validateDatabases は設定ファイルの databases を検証する
+func validateDatabases(dbs []notionDatabase) error {
+	for i, db := range dbs {
+		if (db.ID == "") == (db.TitlePattern == "") {
+			return fmt.Errorf("databases[%d]: set either id or title_pattern", i)
+		}
+		if db.TitlePattern == "" {
+			continue
+		}
+		if strings.Contains(db.TitlePattern, ",") {
+			return fmt.Errorf("databases[%d]: title_pattern cannot contain a comma", i)
+		}
+		if _, err := regexp.Compile(db.TitlePattern); err != nil {
+			return fmt.Errorf("databases[%d]: invalid title_pattern: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// parseDatabaseIDs はカンマ区切りの DB ID を分け、設定ファイルにある名前を付ける
+// /^Tasks - .*/ のようにスラッシュで囲んだ値はタイトルの正規表現として扱う
+func parseDatabaseIDs(s string) []notionDatabase {
+	var dbs []notionDatabase
+	for _, id := range strings.Split(s, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if len(id) > 2 && strings.HasPrefix(id, "/") && strings.HasSuffix(id, "/") {
+			dbs = append(dbs, notionDatabase{TitlePattern: id[1 : len(id)-1]})
+			continue
+		}
+		db := notionDatabase{ID: id}
+		for _, c := range configuredDatabases {
+			if c.ID == id {
+				db.Name = c.Name
+			}
+		}
+		dbs = append(dbs, db)
+	}
+	return dbs
+}
+
+// configuredDatabaseIDs は設定ファイルの databases をカンマ区切りの DB ID にする
+func configuredDatabaseIDs() string {
+	var ids []string
+	for _, db := range configuredDatabases {
+		if db.TitlePattern != "" {
+			ids = append(ids, "/"+db.TitlePattern+"/")
+		} else {
+			ids = append(ids, db.ID)
+		}
+	}
+	return strings.Join(ids, ",")
+}
+
+// fetchNotionTasks はカンマ区切りの各データベースから並行してタスクを取得し、1 つにまとめる
+// 複数のデータベースから取得した場合は、どのデータベースのタスクかを Source に設定する
+func fetchNotionTasks(ctx context.Context, client *notionapi.Client, dbIDs string, onOrBeforeDate time.Time) ([]Task, error) {
+	dbs, err := resolveDatabases(ctx, client, dbIDs)
+	if err != nil {
+		return nil, err
+	}
+	loadSchemaPriorities(ctx, client, dbs)
+	if len(dbs) == 1 {
+		return fetchDatabaseTasks(ctx, client, dbs[0].ID, onOrBeforeDate)
+	}
+
+	results := make([][]Task, len(dbs))
+	errs := make([]error, len(dbs))
+	var wg sync.WaitGroup
+	for i, db := range dbs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tasks, err := fetchDatabaseTasks(ctx, client, db.ID, onOrBeforeDate)
+			if err != nil {
+				errs[i] = fmt.Errorf("database %s: %w", db.ID, err)
+				return
+			}
+			name := db.Name
+			if name == "" {
+				name = fetchDatabaseTitle(ctx, client, db.ID)
+			}
+			for j := range tasks {
+				tasks[j].Source = name
+			}
+			results[i] = tasks
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	// データベースの指定順にまとめる (セクション内の並びは表示時にソートする)
+	var all []Task
+	for _, tasks := range results {
+		all = append(all, tasks...)
+	}
+	return all, nil
+}
+
+// resolveDatabases はカンマ区切りの DB ID を分け、タイトルの正規表現は Notion の検索 API で一致するデータベースに置き換える
+// 正規表現に一致するデータベースが無くても、他に取得元があればエラーにしない
+func resolveDatabases(ctx context.Context, client *notionapi.Client, dbIDs string) ([]notionDatabase, error) {
+	var dbs, patterns []notionDatabase
+	for _, db := range parseDatabaseIDs(dbIDs) {
+		if db.TitlePattern != "" {
+			patterns = append(patterns, db)
+		} else {
+			dbs = append(dbs, db)
+		}
+	}
+	if len(patterns) > 0 {
+		found, err := searchDatabases(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range patterns {
+			re, err := regexp.Compile(p.TitlePattern)
+			if err != nil {
+				return nil, configErrorf("invalid database title pattern %q: %w", p.TitlePattern, err)
+			}
+			matched := 0
+			for _, db := range found {
+				if !re.MatchString(db.Name) {
+					continue
+				}
+				matched++
+				if !slices.ContainsFunc(dbs, func(d notionDatabase) bool { return sameNotionID(d.ID, db.ID) }) {
+					dbs = append(dbs, db)
+				}
+			}
+			if matched == 0 {
+				log.Printf("Warning: No database title matches /%s/", p.TitlePattern)
+			} else {
+				log.Printf("Found %d databases matching /%s/", matched, p.TitlePattern)
+			}
+		}
+	}
+	if len(dbs) == 0 && len(patterns) > 0 {
+		return nil, configErrorf("no database title matches the given patterns")
+	}
+	if len(dbs) == 0 {
+		return nil, configErrorf("no database ID is given")
+	}
+	return dbs, nil
+}
+
+// searchDatabases はインテグレーションと共有されているデータベースをタイトル付きですべて返す (アーカイブ済みは除く)
+func searchDatabases(ctx context.Context, client *notionapi.Client) ([]notionDatabase, error) {
+	var dbs []notionDatabase
+	request := &notionapi.SearchRequest{
+		Filter:   notionapi.SearchFilter{Property: "object", Value: "database"},
+		PageSize: queryPageSize,
+	}
+	for {
+		resp, err := client.Search.Do(ctx, request)
+		if err != nil {
+			return nil, newNotionError("failed to search databases", err)
+		}
+		for _, obj := range resp.Results {
+			db, ok := obj.(*notionapi.Database)
+			if !ok || db.Archived {
+				continue
+			}
+			var title strings.Builder
+			for _, rt := range db.Title {
+				title.WriteString(rt.PlainText)
+			}
+			dbs = append(dbs, notionDatabase{ID: db.ID.String(), Name: title.String()})
+		}
+		if !resp.HasMore {
+			return dbs, nil
+		}
+		request.StartCursor = resp.NextCursor
+	}
+}
+
+// fetchDatabaseTitle はデータベースのタイトルを返す。取得できなければ ID を返す
+func fetchDatabaseTitle(ctx context.Context, client *notionapi.Client, dbID string) string {
+	db, err := client.Database.Get(ctx, notionapi.DatabaseID(dbID))
+	if err != nil {
+		log.Printf("Warning: Failed to get the title of database %s: %v", dbID, err)
+		return dbID
+	}
+	var title strings.Builder
+	for _, rt := range db.Title {
+		title.WriteString(rt.PlainText)
+	}
+	if title.Len() == 0 {
+		return dbID
+	}
+	return title.String()
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// ログに出すボディの最大長
+const debugHTTPBodyLimit = 1024
+
+// --debug-http が指定されていれば、すべての外部 API 呼び出しをログに出す
+var debugHTTP bool
+
+// ログに出すレート制限関連のヘッダー
+var rateLimitHeaders = []string{
+	"Retry-After",
+	"X-RateLimit-Limit",
+	"X-RateLimit-Remaining",
+	"X-RateLimit-Reset",
+	"X-RateLimit-Used",
+}
+
+// ログから取り除く値 (トークン、シークレット、認可コード)
+var (
+	secretFieldPattern = regexp.MustCompile(`(?i)("(?:[a-z_]*token|client_secret|secret|password|code)"\s*:\s*)"[^"]*"`)
+	secretParamPattern = regexp.MustCompile(`(?i)((?:^|[?&])(?:[a-z_]*token|client_secret|secret|password|code)=)[^&\s]*`)
+	secretValuePattern = regexp.MustCompile(`\b(?:xox[a-z]|xapp)-[A-Za-z0-9-]+|\b(?:secret|ntn)_[A-Za-z0-9]+`)
+)
+
+// sanitizeForLog はトークンなどの秘密の値を伏せる
+func sanitizeForLog(s string) string {
+	s = secretFieldPattern.ReplaceAllString(s, `$1"[REDACTED]"`)
+	s = secretParamPattern.ReplaceAllString(s, `${1}[REDACTED]`)
+	return secretValuePattern.ReplaceAllString(s, "[REDACTED]")
+}
+
+// debugTransport はリクエストとレスポンスの概要をログに出す http.RoundTripper
+// 認証ヘッダーは出さず、ボディは秘密の値を伏せて切り詰める
+type debugTransport struct {
+	next http.RoundTripper
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody := peekRequestBody(req)
+	started := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	latency := time.Since(started).Round(time.Millisecond)
+
+	target := sanitizeForLog(req.URL.Redacted())
+	if err != nil {
+		log.Printf("HTTP %s %s -> error after %s: %v", req.Method, target, latency, err)
+		return resp, err
+	}
+
+	var limits []string
+	for _, h := range rateLimitHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			limits = append(limits, h+"="+v)
+		}
+	}
+	line := []string{req.Method, target, "->", resp.Status, "(" + latency.String() + ")"}
+	if len(limits) > 0 {
+		line = append(line, "["+strings.Join(limits, " ")+"]")
+	}
+	log.Printf("HTTP %s", strings.Join(line, " "))
+	if reqBody != "" {
+		log.Printf("HTTP   request: %s", reqBody)
+	}
+	if respBody := peekResponseBody(resp); respBody != "" {
+		log.Printf("HTTP   response: %s", respBody)
+	}
+	return resp, nil
+}
+
+// peekRequestBody はリクエストのボディを読み、送信できるように戻す
+func peekRequestBody(req *http.Request) string {
+	if req.Body == nil || req.Body == http.NoBody {
+		return ""
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return ""
+	}
+	return truncateForLog(data)
+}
+
+// peekResponseBody はレスポンスのボディの先頭を読み、呼び出し元が全体を読めるように戻す
+func peekResponseBody(resp *http.Response) string {
+	if resp.Body == nil {
+		return ""
+	}
+	head := make([]byte, debugHTTPBodyLimit+1)
+	n, _ := io.ReadFull(resp.Body, head)
+	head = head[:n]
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+	return truncateForLog(head)
+}
+
+func truncateForLog(data []byte) string {
+	// 読んだ先頭の最後で多バイト文字が切れていることがあるため、不正な UTF-8 を除いてから文字の区切りで切る
+	s := truncateText(strings.ToValidUTF8(string(data), ""), debugHTTPBodyLimit, "...(truncated)")
+	return sanitizeForLog(strings.Join(strings.Fields(s), " "))
+}
+
+// installDebugTransport は既定の Transport を差し替え、Notion・Slack を含むすべての呼び出しを記録する
+func installDebugTransport() {
+	if !debugHTTP {
+		return
+	}
+	http.DefaultTransport = &debugTransport{next: http.DefaultTransport}
+	log.Println("Logging outbound HTTP requests (--debug-http)")
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&debugHTTP, "debug-http", false, "Log sanitized summaries of every outbound API request (status, latency, rate-limit headers, truncated bodies)")
+	cobra.OnInitialize(installDebugTransport)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// デスクトップ通知に載せるタスクの件数
+const desktopTopTasks = 3
+
+// desktopSummary は期限切れと今日が期限のタスクから上位のものを通知用にまとめる
+// 該当するタスクが無ければ ok は false
+func desktopSummary(tasks []Task, now time.Time) (title, body string, ok bool) {
+	overdue, today, _ := groupTasksByUrgency(tasks, now)
+	sortTasks(overdue)
+	sortTasks(today)
+	urgent := append(overdue, today...)
+	if len(urgent) == 0 {
+		return "", "", false
+	}
+
+	title = tr("desktop.title", len(overdue), len(today))
+	var lines []string
+	for i, task := range urgent {
+		if i == desktopTopTasks {
+			lines = append(lines, tr("more", len(urgent)-desktopTopTasks))
+			break
+		}
+		line := "・" + taskTitle(task)
+		if task.Priority != "" {
+			line += " (" + task.Priority + ")"
+		}
+		lines = append(lines, line)
+	}
+	return title, strings.Join(lines, "\n"), true
+}
+
+// sendDesktopNotification は OS のネイティブ通知を表示する
+func sendDesktopNotification(title, body string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(body), appleScriptString(title))
+		cmd = exec.Command("osascript", "-e", script)
+	case "linux", "freebsd", "openbsd", "netbsd":
+		cmd = exec.Command("notify-send", "--app-name=notion-notifyer", title, body)
+	default:
+		return fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to show desktop notification: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// appleScriptString は文字列を AppleScript の文字列リテラルにする
+func appleScriptString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/jomei/notionapi"
+	"github.com/slack-go/slack"
+)
+
+// digestJob は 1 回のダイジェスト送信に必要な設定
+type digestJob struct {
+	Name         string
+	NotionToken  string
+	DatabaseID   string
+	DaysLater    int
+	Destinations []slackDestination
+	RunNumber    string
+	Absences     absenceConfig
+	Assignees    []string           // 空でなければ、このいずれか (名前かメールアドレス) が担当するタスクだけを送る
+	Tags         tagFilter          // タグでタスクを絞り込む
+	GroupBy      string             // groupByUrgency か groupByProject
+	GitHubStatus bool               // Memo と Link の GitHub Issue/PR の状態を表示する
+	Jira         *jiraClient        // 設定されていれば Jira の課題の状態を表示する
+	Desktop      bool               // 今日までのタスクをデスクトップ通知でも表示する
+	Notifiers    []namedNotifier    // 送り先 (--target で選んだもの。Slack の投稿先は Destinations)
+	Output       Notifier           // 設定されていればどこにも送らずにタスクを標準出力に書き出す (--output)
+	Focus        bool               // 上位のタスクだけを載せた短いメッセージにする
+	Calendar     bool               // 7 日間のカレンダーを表示する
+	TrackSeen    bool               // 担当者がタスクを開いたかを追跡し、開いていないタスクを翌日以降に表示する
+	Sections     []string           // 表示するセクションとその順序 (nil なら既定の順序)
+	WithinHours  int                // 0 より大きければ「あと N 時間以内」のセクションを使う
+	LinkDomain   string             // 設定されていればページ URL をこのドメインに書き換える
+	ShowPageID   bool               // タスクのページ ID を表示する
+	ShortCodes   bool               // タスクの短いコードを表示し、掲載したコードを記録する
+	MuteButton   bool               // タスクに「通知しない」ボタンを付ける
+	Template     *template.Template // メッセージのテンプレート (nil なら組み込みのテンプレート)
+	Collapsed    []string           // 要約の 1 行と「詳細を表示」ボタンだけにするセクション
+	TimeOfDay    []string           // 午前・午後・夜の小見出しに分けるセクション
+	ActionDays   int                // 0 より大きければタスクに「完了」と「N 日延ばす」のボタンを付ける
+	AssigneeDM   string             // also か only なら担当者ごとに自分のタスクだけを DM で送る
+	SlackUserMap map[string]string  // 担当者のメールアドレスまたは名前 → Slack ユーザー ID (DM の送り先)
+	DryRun       bool               // 投稿せずに Block Kit の JSON とプレビューを標準出力に出す (通知・記録もしない)
+	Window       adaptiveWindow     // 設定されていればタスクの数に合わせて DaysLater を変える (設定ファイルの adaptive_window)
+	FailOverdue  *int               // 設定されていれば、期限切れのタスクがこの数を超えたときに送信後に overdueGateError を返す
+	Store        *stateStore
+	History      *historyStore // 設定されていれば掲載履歴を記録し、連続掲載日数と期限の延期を表示する
+	Usage        *apiUsage     // 設定されていればほかのジョブと API の呼び出しとレート予算の目安を共有する (--profiles)
+	UsageReport  *usageReport  // 設定されていれば API の呼び出しのレポートを運用チャンネルにも投稿する (--usage-channel)
+}
+
+// runDigest は Notion からタスクを取得し、不在や GitHub・Jira の状態を反映してから各送り先に送る
+// 実行後に API の呼び出し回数とレート予算の目安をログに出す (--usage-channel があればそのチャンネルにも投稿する)
+func runDigest(ctx context.Context, job digestJob, now time.Time) (err error) {
+	// 呼び出し回数の計測は基準時刻 (--now) ではなく実際の経過時間で行う
+	// 複数のジョブで共有する場合は、呼び出した側がまとめてログに出す
+	usage := job.Usage
+	if usage == nil {
+		usage = newAPIUsage(time.Now())
+		defer func() { reportUsage(ctx, job.UsageReport, job.DryRun, "["+job.Name+"]", usage, time.Now()) }()
+	}
+
+	notionClient := notionapi.NewClient(notionapi.Token(job.NotionToken), notionapi.WithHTTPClient(usage.Client("notion")))
+
+	targetDate := endOfDay(now, job.DaysLater)
+
+	log.Printf("[%s] Get tasks due by %s", job.Name, targetDate.Format("2006-01-02"))
+
+	// カレンダーを表示する場合は 7 日先まで取得し、詳細なセクションには期限内のものだけを載せる
+	fetchUntil := targetDate
+	// 先読みの日数を変える場合は、上限の日数まで取得してから日数を決める
+	if job.Window.enabled() {
+		if windowEnd := endOfDay(now, job.Window.maxDays()); windowEnd.After(fetchUntil) {
+			fetchUntil = windowEnd
+		}
+	}
+	if job.Calendar {
+		if calendarEnd := endOfDay(now, calendarDays-1); calendarEnd.After(fetchUntil) {
+			fetchUntil = calendarEnd
+		}
+	}
+
+	// Notionからタスクを取得
+	fetched, err := fetchNotionTasks(ctx, notionClient, job.DatabaseID, fetchUntil)
+	if err != nil {
+		return fmt.Errorf("get Notion tasks: %w", err)
+	}
+	if job.LinkDomain != "" {
+		if err := rewriteTaskURLs(fetched, job.LinkDomain); err != nil {
+			return err
+		}
+	}
+	// Slack でスヌーズされたタスクは Notion の期限日に関係なくスヌーズの期限まで、ミュートされたタスクは取り消すまで載せない
+	if job.Store != nil {
+		st, err := job.Store.Load()
+		if err != nil {
+			return err
+		}
+		var muted int
+		if fetched, muted = filterMutedTasks(fetched, st, now); muted > 0 {
+			log.Printf("[%s] Skip %d snoozed or muted tasks", job.Name, muted)
+		}
+	}
+	if len(job.Assignees) > 0 {
+		fetched = filterTasksByAssignee(fetched, job.Assignees)
+		log.Printf("[%s] %d tasks are assigned to %s", job.Name, len(fetched), strings.Join(job.Assignees, ", "))
+	}
+	if job.Tags.active() {
+		fetched = job.Tags.apply(fetched)
+		log.Printf("[%s] %d tasks match the tag filters", job.Name, len(fetched))
+	}
+	// 期限切れのタスクの確認は送信を終えてから結果に反映する (送信のエラーを優先する)
+	if job.FailOverdue != nil {
+		if gateErr := checkOverdue(fetched, now, *job.FailOverdue); gateErr != nil {
+			defer func() {
+				if err == nil {
+					err = gateErr
+				}
+			}()
+		}
+	}
+	if job.Window.enabled() {
+		if days := job.Window.choose(fetched, now, job.DaysLater); days != job.DaysLater {
+			log.Printf("[%s] Adaptive window: using daysLater %d instead of %d", job.Name, days, job.DaysLater)
+			job.DaysLater = days
+			targetDate = endOfDay(now, days)
+		}
+	}
+	tasks := fetched
+	if job.Calendar || job.Window.enabled() {
+		tasks = filterTasksDueBy(fetched, targetDate)
+	}
+	if job.Sections != nil {
+		tasks = filterVisibleTasks(tasks, job.Sections, now, job.WithinHours)
+	}
+	log.Printf("[%s] Get %d tasks from Notion", job.Name, len(tasks))
+
+	// 出力 (--output blocks や GET /digest) で Slack と同じメッセージを組み立てられるようにする
+	run := digestRun{JobName: job.Name, Now: now, RunNumber: job.RunNumber, DryRun: job.DryRun, Destinations: job.Destinations, usage: usage}
+	run.blocks = func(outputTasks []Task) ([]slack.Block, error) {
+		if job.Focus {
+			return buildFocusBlocks(outputTasks, job.RunNumber, job.DaysLater)
+		}
+		return buildSlackBlocks(outputTasks, job.renderOptions(now))
+	}
+
+	if len(tasks) == 0 && job.Output == nil {
+		log.Printf("[%s] No tasks found.", job.Name)
+		return sendEmptyState(ctx, run, job)
+	}
+
+	if projectProp != "" {
+		// ドライランでは状態ファイルに書き込まないよう、キャッシュを使わない
+		store := job.Store
+		if job.DryRun {
+			store = nil
+		}
+		if err := resolveProjects(ctx, notionClient, store, tasks, now); err != nil {
+			log.Printf("[%s] Warning: %v", job.Name, err)
+		}
+	}
+
+	if job.History != nil {
+		h, err := job.History.Load()
+		if err != nil {
+			log.Printf("[%s] Warning: %v", job.Name, err)
+		} else {
+			streaks := h.appearanceStreaks(tasks, now)
+			slips := h.dueSlips(tasks)
+			for i := range tasks {
+				id := tasks[i].Key()
+				tasks[i].Streak = streaks[id]
+				if slip, ok := slips[id]; ok {
+					tasks[i].Slip = &slip
+				}
+			}
+		}
+	}
+
+	if job.Desktop && !job.DryRun {
+		if title, body, ok := desktopSummary(tasks, now); ok {
+			if err := sendDesktopNotification(title, body); err != nil {
+				log.Printf("[%s] Warning: %v", job.Name, err)
+			}
+		}
+	}
+
+	// 担当者の不在と GitHub・Jira の状態は、すべての送り先に同じ内容を送るよう送信の前に反映する
+	// Slack ステータスは最初の投稿先のワークスペースで確認する
+	if job.Absences.enabled() {
+		var statusClient *slack.Client
+		if job.Absences.SlackStatusEmoji != "" && len(job.Destinations) > 0 && job.Destinations[0].Tokens != nil {
+			statusClient, err = job.Destinations[0].Tokens.Client(ctx, slack.OptionHTTPClient(usage.Client("slack:"+job.Destinations[0].Name)))
+			if err != nil {
+				log.Printf("[%s] Warning: Slack status lookup is unavailable: %v", job.Name, err)
+			}
+		}
+		applyAbsences(ctx, tasks, job.Absences, statusClient, now)
+	}
+
+	if job.GitHubStatus {
+		github := newGitHubClientFromEnv()
+		github.httpClient = usage.Client("github")
+		applyGitHubStatus(ctx, tasks, github)
+	}
+
+	if job.Jira != nil {
+		job.Jira.httpClient = usage.Client("jira")
+		applyJiraStatus(ctx, tasks, job.Jira)
+	}
+
+	var groups []TaskGroup
+	if job.GroupBy == groupByProject {
+		groups = projectTaskGroups(tasks)
+	} else {
+		groups = buildTaskGroups(tasks, job.Sections, job.TimeOfDay, now, job.WithinHours)
+	}
+	if job.Output != nil {
+		return job.Output.Send(ctx, run, groups)
+	}
+
+	var unopened []unopenedTask
+	if job.TrackSeen && job.Store != nil {
+		if st, err := job.Store.Load(); err != nil {
+			log.Printf("[%s] Warning: %v", job.Name, err)
+		} else {
+			unopened = findUnopenedTasks(st, tasks, now)
+		}
+	}
+
+	// メモのユーザーのメンションは投稿先のワークスペースの Slack ユーザーにする
+	// 対応表 (--slack-user-map) を優先し、無ければメールアドレスで引く (DM とドライランでは対応表だけを使う)
+	mentionResolvers := map[string]*slackUserResolver{}
+	mentionResolver := func(dest slackDestination) *slackUserResolver {
+		scope := dest.teamScope()
+		if r, ok := mentionResolvers[scope]; ok {
+			return r
+		}
+		r := &slackUserResolver{mapping: job.SlackUserMap, cache: map[string]string{}}
+		if dest.Tokens != nil && !job.DryRun {
+			if client, err := dest.Tokens.Client(ctx, slack.OptionHTTPClient(usage.Client("slack:"+dest.Name))); err != nil {
+				log.Printf("[%s] Warning: Unable to look up mentioned Slack users for %s: %v", job.Name, dest.Name, err)
+			} else {
+				r.client = client
+			}
+		}
+		mentionResolvers[scope] = r
+		return r
+	}
+
+	// 担当者への DM (personal) にはカレンダーと未読のタスクを載せない
+	renderBlocks := func(destTasks []Task, dest slackDestination, personal bool) ([]slack.Block, error) {
+		if job.Focus {
+			return buildFocusBlocks(destTasks, job.RunNumber, job.DaysLater)
+		}
+		opts := job.renderOptions(now)
+		opts.SlackUsers = memoMentions(ctx, destTasks, mentionResolver(dest))
+		if job.Calendar && !personal {
+			opts.CalendarTasks = dest.filterTasks(fetched)
+		}
+		if job.TrackSeen && job.Store != nil {
+			opts.TrackSeen = true
+			for _, u := range unopened {
+				if dest.allows(u.Priority, u.Type) && !personal {
+					opts.Unopened = append(opts.Unopened, u.forTeam(dest.teamScope()))
+				}
+			}
+		}
+		return buildSlackBlocks(destTasks, opts)
+	}
+	run.slackBlocks = func(destTasks []Task, dest slackDestination) ([]slack.Block, error) {
+		return renderBlocks(destTasks, dest, false)
+	}
+
+	// 1 つの送り先が失敗しても残りには送り、最後にエラーを返す (Slack のエラーを優先する)
+	var slackErr, notifyErr error
+	posted, slackPosted := false, false
+	for _, n := range job.Notifiers {
+		err := n.Send(ctx, run, groups)
+		if err != nil {
+			log.Printf("[%s] %s send error: %v", job.Name, n.Name, err)
+			if n.Name == targetSlack {
+				slackErr = err
+			} else {
+				notifyErr = err
+			}
+		}
+		if delivered(err) && !job.DryRun {
+			posted = true
+			slackPosted = slackPosted || n.Name == targetSlack
+		}
+	}
+	dmSent, dmFailed := 0, 0
+	if job.AssigneeDM != "" {
+		dmSent, dmFailed, err = sendAssigneeDMs(ctx, job, tasks, func(userTasks []Task) ([]slack.Block, error) {
+			return renderBlocks(userTasks, slackDestination{}, true)
+		}, usage.Client("slack:dm"))
+		if err != nil {
+			log.Printf("[%s] Slack DM error: %v", job.Name, err)
+			dmFailed++
+		}
+	}
+
+	if job.DryRun {
+		log.Printf("[%s] Dry run: nothing was posted or recorded", job.Name)
+		return nil
+	}
+	// 1 つでも送れていれば掲載したものとして履歴に記録する
+	posted = posted || dmSent > 0
+	// Slack に投稿できたときだけ、Notion のページに投稿日時を書き込む
+	slackPosted = slackPosted || dmSent > 0
+	if lastNotifiedProp != "" && slackPosted {
+		written := writeLastNotified(ctx, notionClient, tasks, now)
+		log.Printf("[%s] Wrote %s to %d of %d tasks", job.Name, lastNotifiedProp, written, len(tasks))
+	}
+	if job.History != nil && posted {
+		if err := job.History.Record(now, tasks); err != nil {
+			log.Printf("[%s] Warning: %v", job.Name, err)
+		}
+	}
+	if job.ShortCodes && job.Store != nil && posted {
+		if err := recordShortCodes(job.Store, tasks, now); err != nil {
+			log.Printf("[%s] Warning: %v", job.Name, err)
+		}
+	}
+	if job.TrackSeen && !job.Focus && job.Store != nil && posted {
+		// 担当者は投稿先のワークスペースごとに探す
+		var teams []teamClient
+		for _, dest := range job.Destinations {
+			scope := dest.teamScope()
+			if slices.ContainsFunc(teams, func(t teamClient) bool { return t.Scope == scope }) {
+				continue
+			}
+			slackClient, err := dest.Tokens.Client(ctx, slack.OptionHTTPClient(usage.Client("slack:"+dest.Name)))
+			if err != nil {
+				log.Printf("[%s] Warning: %v", job.Name, err)
+				continue
+			}
+			teams = append(teams, teamClient{Scope: scope, Client: slackClient})
+		}
+		if err := recordSeenTasks(ctx, job.Store, teams, tasks, now); err != nil {
+			log.Printf("[%s] Warning: %v", job.Name, err)
+		}
+	}
+
+	if slackErr != nil {
+		return slackErr
+	}
+	if dmFailed > 0 {
+		return fmt.Errorf("failed to send Slack DMs to %d assignees", dmFailed)
+	}
+	return notifyErr
+}
+
+// sendEmptyState はタスクが無いときに、最新の状態を保持させる送り先 (MQTT など) にだけ 0 件を送る
+// チャットの送り先には何も送らない
+func sendEmptyState(ctx context.Context, run digestRun, job digestJob) error {
+	var err error
+	for _, n := range job.Notifiers {
+		if _, ok := n.Notifier.(stateNotifier); !ok {
+			continue
+		}
+		if sendErr := n.Send(ctx, run, nil); sendErr != nil {
+			log.Printf("[%s] %s send error: %v", job.Name, n.Name, sendErr)
+			err = sendErr
+		}
+	}
+	return err
+}
+
+// renderOptions はジョブの設定から Slack メッセージの表示の設定を作る (カレンダーと未読のタスクは投稿先ごとに加える)
+func (job digestJob) renderOptions(now time.Time) renderOptions {
+	return renderOptions{RunNumber: job.RunNumber, Now: now, Sections: job.Sections, WithinHours: job.WithinHours, ShowPageID: job.ShowPageID, ShortCodes: job.ShortCodes, MuteButton: job.MuteButton, SnoozeDays: job.ActionDays, CollapsedSections: job.Collapsed, TimeOfDaySections: job.TimeOfDay, DaysLater: job.DaysLater, Template: job.Template, GroupByProject: job.GroupBy == groupByProject}
+}
+
+// filterTasksDueBy は期限日が until 以前のタスクとピン留めのタスクだけを返す
+func filterTasksDueBy(tasks []Task, until time.Time) []Task {
+	var filtered []Task
+	for _, task := range tasks {
+		if due := task.DueDateIn(until.Location()); task.Pinned || (due != nil && !due.After(until)) {
+			filtered = append(filtered, task)
+		}
+	}
+	return filtered
+}
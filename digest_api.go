@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// GET /digest で選べる形式 (format が無ければ JSON)
+var digestAPIFormats = []string{outputJSON, outputMarkdown, outputBlocks}
+
+// digestAPI は --watch の間、その時点のダイジェストを GET /digest?format=json|markdown|blocks で返す (--serve-addr)
+// 壁掛けの表示や Home Assistant などが Slack を通さずにタスクを取りに来られるようにする。どこにも送らず、記録もしない
+type digestAPI struct {
+	mu  sync.Mutex // Notion への問い合わせが重ならないよう、要求は 1 つずつ処理する
+	cmd *cobra.Command
+}
+
+func (a *digestAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = outputJSON
+	}
+	if !slices.Contains(digestAPIFormats, format) {
+		http.Error(w, fmt.Sprintf("unknown format %q (available: %s)", format, strings.Join(digestAPIFormats, ", ")), http.StatusBadRequest)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	job, err := rootDigestJob(a.cmd, systemClock{})
+	if err != nil {
+		log.Printf("Digest API error: %v", err)
+		http.Error(w, "failed to prepare the digest", http.StatusInternalServerError)
+		return
+	}
+	includeTypes, _ := a.cmd.Flags().GetStringSlice("include-types")
+	excludeTypes, _ := a.cmd.Flags().GetStringSlice("exclude-types")
+	var body bytes.Buffer
+	job.Name = "api"
+	job.Output = outputNotifier{Format: format, Types: taskTypeFilter{Include: includeTypes, Exclude: excludeTypes}, W: &body}
+	job.Destinations, job.Notifiers = nil, nil
+	job.Desktop, job.DryRun = false, true
+	job.FailOverdue = nil
+	if err := runDigest(r.Context(), job, time.Now()); err != nil {
+		log.Printf("Digest API error: %v", err)
+		status := http.StatusInternalServerError
+		var outage *notionOutageError
+		if errors.As(err, &outage) {
+			status = http.StatusServiceUnavailable
+		}
+		http.Error(w, "failed to build the digest", status)
+		return
+	}
+
+	contentType := "application/json"
+	if format == outputMarkdown {
+		contentType = "text/markdown; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(body.Bytes())
+}
+
+// serveDigestAPI は addr で GET /digest の HTTP サーバーを起動し、ctx が終わったら止める
+// 待ち受けに失敗したときはエラーを返し、--watch を始めない
+func serveDigestAPI(ctx context.Context, cmd *cobra.Command, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s for the digest API: %w", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/digest", &digestAPI{cmd: cmd})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+	go func() {
+		log.Printf("Digest API listening on %s (GET /digest?format=%s)", listener.Addr(), strings.Join(digestAPIFormats, "|"))
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Digest API error: %v", err)
+		}
+	}()
+	return nil
+}
+
+func init() {
+	rootCmd.Flags().String("serve-addr", "", "With --watch, also serve the current digest read-only at GET /digest?format=json|markdown|blocks on this address (e.g. :8080)")
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// recordingNotifier は受け取った送信の情報とタスクを記録する
+type recordingNotifier struct {
+	runs   []digestRun
+	groups [][]TaskGroup
+}
+
+func (r *recordingNotifier) Send(ctx context.Context, run digestRun, groups []TaskGroup) error {
+	r.runs = append(r.runs, run)
+	r.groups = append(r.groups, groups)
+	return nil
+}
+
+// useMockServer は API の呼び出しを mockserver の既定のフィクスチャに向ける
+func useMockServer(t *testing.T, now time.Time) *mockServer {
+	t.Helper()
+	fixtures, err := loadMockFixtures("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := &mockServer{fixtures: fixtures, clock: fixedClock(now)}
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+	base, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := http.DefaultTransport
+	http.DefaultTransport = &mockTransport{base: base, next: transport}
+	t.Cleanup(func() { http.DefaultTransport = transport })
+	return mock
+}
+
+// TestNotifiersReceiveEnrichedTasks は Slack 以外の送り先にも、不在の担当者を反映したタスクが渡ることを確かめる
+func TestNotifiersReceiveEnrichedTasks(t *testing.T) {
+	discardLogs(t)
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	useMockServer(t, now)
+
+	rec := &recordingNotifier{}
+	job := digestJob{
+		Name:        "test",
+		NotionToken: "mock",
+		DatabaseID:  "mock",
+		DaysLater:   3,
+		RunNumber:   "7",
+		Absences:    absenceConfig{Static: map[string]string{"taro@example.com": "hanako@example.com"}},
+		Notifiers:   []namedNotifier{{Name: "record", Notifier: rec}},
+	}
+	if err := runDigest(context.Background(), job, now); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.runs) != 1 {
+		t.Fatalf("notifier was called %d times, want 1", len(rec.runs))
+	}
+	if run := rec.runs[0]; run.JobName != "test" || run.RunNumber != "7" || !run.Now.Equal(now) {
+		t.Errorf("run = %+v, want the job's name, run number and time", run)
+	}
+	absent := 0
+	for _, task := range groupedTasks(rec.groups[0]) {
+		for _, a := range task.Assignees {
+			if a.Email == "taro@example.com" {
+				if len(task.Absences) == 0 {
+					t.Errorf("task %q was sent without the absence of its assignee", task.Title)
+				}
+				absent++
+			}
+		}
+	}
+	if absent == 0 {
+		t.Fatal("no task assigned to taro@example.com was sent")
+	}
+}
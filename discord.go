@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/pflag"
+)
+
+// Discord 関連
+const (
+	discordWebhookEnv = "DISCORD_WEBHOOK_URL"
+
+	// 1 つのメッセージに載せられる embed の数と、embed の文字数の上限
+	maxDiscordEmbeds           = 10
+	maxDiscordMessageChars     = 6000
+	maxDiscordDescriptionChars = 4096
+)
+
+// セクションの embed の色 (期限日で分けるセクションは設定ファイルの color を使う)
+var sectionColors = map[string]int{
+	sectionPinned: 0x3498DB,
+	sectionHours:  0x9B59B6,
+}
+
+// 色を設定していないセクションの色
+const defaultSectionColor = 0x95A5A6
+
+// parseColor は "#e74c3c" 形式の色を数値にする
+func parseColor(s string) (int, error) {
+	hex, ok := strings.CutPrefix(s, "#")
+	if !ok || len(hex) != 6 {
+		return 0, fmt.Errorf("invalid color %q (expected #rrggbb)", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid color %q (expected #rrggbb)", s)
+	}
+	return int(v), nil
+}
+
+func validColor(s string) bool {
+	_, err := parseColor(s)
+	return err == nil
+}
+
+// sectionColor はセクションの embed の色を返す
+func sectionColor(name string) int {
+	if b, ok := findBucket(name); ok && b.Color != "" {
+		if color, err := parseColor(b.Color); err == nil {
+			return color
+		}
+	}
+	if color, ok := sectionColors[name]; ok {
+		return color
+	}
+	return defaultSectionColor
+}
+
+// discordEmbed は Discord の embed (使う項目のみ)
+type discordEmbed struct {
+	Title       string              `json:"title,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Color       int                 `json:"color,omitempty"`
+	Footer      *discordEmbedFooter `json:"footer,omitempty"`
+}
+
+type discordEmbedFooter struct {
+	Text string `json:"text"`
+}
+
+// discordMessage は Discord の Webhook に送るメッセージ
+type discordMessage struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []discordEmbed `json:"embeds"`
+}
+
+func (e discordEmbed) chars() int {
+	n := utf8.RuneCountInString(e.Title) + utf8.RuneCountInString(e.Description)
+	if e.Footer != nil {
+		n += utf8.RuneCountInString(e.Footer.Text)
+	}
+	return n
+}
+
+// discordTaskLine はタスクを Discord の Markdown で 1 行にする
+func discordTaskLine(task Task) (string, error) {
+	details, err := plainTaskDetails(task)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("**[%s](%s)**\n%s", taskTitle(task), task.URL, details), nil
+}
+
+// buildDiscordMessages はセクションごとの embed にし、上限に収まるメッセージに分ける
+// embed の本文に収まらないタスクは「他 N件」にまとめる
+// 時間帯で分けたセクションは小見出しを挟む
+func buildDiscordMessages(groups []TaskGroup, runNumber string) ([]discordMessage, error) {
+	var embeds []discordEmbed
+	for _, group := range groups {
+		embed := discordEmbed{Title: group.Title, Color: sectionColor(group.Name)}
+		var lines []string
+		size := 0
+		i := 0
+		for _, task := range flattenParts(group.Parts) {
+			line, err := discordTaskLine(task.task)
+			if err != nil {
+				return nil, err
+			}
+			if task.title != "" {
+				line = "__" + task.title + "__\n" + line
+			}
+			more := tr("more", len(group.Tasks)-i)
+			if size+utf8.RuneCountInString(line)+utf8.RuneCountInString(more)+2 > maxDiscordDescriptionChars {
+				lines = append(lines, more)
+				break
+			}
+			lines = append(lines, line)
+			size += utf8.RuneCountInString(line) + 1
+			i++
+		}
+		embed.Description = strings.Join(lines, "\n")
+		embeds = append(embeds, embed)
+	}
+	if len(embeds) == 0 {
+		return nil, nil
+	}
+	if footer := footerText(runNumber); footer != "" {
+		embeds[len(embeds)-1].Footer = &discordEmbedFooter{Text: footer}
+	}
+
+	messages := []discordMessage{{Content: tr("digest.header")}}
+	chars := 0
+	for _, embed := range embeds {
+		last := &messages[len(messages)-1]
+		if len(last.Embeds) > 0 && (len(last.Embeds) >= maxDiscordEmbeds || chars+embed.chars() > maxDiscordMessageChars) {
+			messages = append(messages, discordMessage{})
+			last = &messages[len(messages)-1]
+			chars = 0
+		}
+		last.Embeds = append(last.Embeds, embed)
+		chars += embed.chars()
+	}
+	return messages, nil
+}
+
+// discordTarget は Discord の Webhook の送信先
+type discordTarget struct {
+	URL string
+}
+
+func newDiscordTargetFromEnv() *discordTarget {
+	url := os.Getenv(discordWebhookEnv)
+	if url == "" {
+		return nil
+	}
+	return &discordTarget{URL: url}
+}
+
+// Send はダイジェストを Discord に送る (ドライランでは JSON を出力する)
+func (d *discordTarget) Send(ctx context.Context, run digestRun, groups []TaskGroup) error {
+	messages, err := buildDiscordMessages(groups, run.RunNumber)
+	if err != nil {
+		return fmt.Errorf("build Discord messages: %w", err)
+	}
+	if run.DryRun {
+		return printDiscordDryRun(os.Stdout, messages)
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	if err := d.post(ctx, run.httpClient("discord"), messages); err != nil {
+		return err
+	}
+	log.Printf("[%s] Discord message sent", run.JobName)
+	return nil
+}
+
+// post はメッセージを順に Webhook に POST する
+func (d *discordTarget) post(ctx context.Context, client *http.Client, messages []discordMessage) error {
+	for _, msg := range messages {
+		body, err := json.Marshal(msg)
+		if err != nil {
+			return fmt.Errorf("failed to encode Discord message: %w", err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to post to Discord: %w", err)
+		}
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("discord returned %s: %s", resp.Status, bytes.TrimSpace(errBody))
+		}
+	}
+	return nil
+}
+
+// printDiscordDryRun は Discord に送るメッセージの JSON を出力する
+func printDiscordDryRun(w io.Writer, messages []discordMessage) error {
+	for i, msg := range messages {
+		part := ""
+		if len(messages) > 1 {
+			part = fmt.Sprintf(" [%d/%d]", i+1, len(messages))
+		}
+		fmt.Fprintf(w, "===== discord%s =====\n", part)
+		data, err := json.MarshalIndent(msg, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode Discord message: %w", err)
+		}
+		fmt.Fprintln(w, string(data))
+	}
+	return nil
+}
+
+func init() {
+	RegisterNotifier(targetDiscord, func(flags *pflag.FlagSet) (Notifier, error) {
+		d := newDiscordTargetFromEnv()
+		if d == nil {
+			return nil, fmt.Errorf("--target discord requires %s", discordWebhookEnv)
+		}
+		return d, nil
+	})
+}
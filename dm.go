@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"gopkg.in/yaml.v3"
+)
+
+// 担当者への DM の送り方 (--assignee-dm)
+const (
+	assigneeDMAlso = "also" // チャンネルのダイジェストに加えて送る
+	assigneeDMOnly = "only" // チャンネルには投稿せず DM だけを送る
+)
+
+func parseAssigneeDM(v string) (string, error) {
+	switch v {
+	case "", assigneeDMAlso, assigneeDMOnly:
+		return v, nil
+	}
+	return "", fmt.Errorf("unknown mode %q (expected %s or %s)", v, assigneeDMAlso, assigneeDMOnly)
+}
+
+// loadSlackUserMap は Notion の担当者 (メールアドレスまたは名前) → Slack ユーザー ID の対応表を読み込む
+// YAML か JSON のマッピングで書く (例: taro@example.com: U012ABCDEF)
+func loadSlackUserMap(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Slack user map: %w", err)
+	}
+	var raw map[string]string
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse Slack user map %s: %w", path, err)
+	}
+	mapping := map[string]string{}
+	for key, userID := range raw {
+		mapping[strings.ToLower(strings.TrimSpace(key))] = userID
+	}
+	return mapping, nil
+}
+
+// slackUserResolver は Notion の担当者を Slack ユーザー ID にする
+// 対応表を優先し、無ければメールアドレスで Slack を検索する (users:read.email が必要)
+type slackUserResolver struct {
+	client  *slack.Client // nil なら対応表だけを使う
+	mapping map[string]string
+	cache   map[string]string
+}
+
+func (r *slackUserResolver) resolve(ctx context.Context, a Assignee) string {
+	for _, key := range []string{a.Email, a.Name} {
+		if id, ok := r.mapping[strings.ToLower(key)]; ok && key != "" {
+			return id
+		}
+	}
+	if a.Email == "" || r.client == nil {
+		return ""
+	}
+	if id, ok := r.cache[a.Email]; ok {
+		return id
+	}
+	user, err := r.client.GetUserByEmailContext(ctx, a.Email)
+	id := ""
+	if err != nil {
+		log.Printf("Warning: Unable to find Slack user for %s: %v", a.Email, err)
+	} else {
+		id = user.ID
+	}
+	r.cache[a.Email] = id
+	return id
+}
+
+// assigneeTasks は 1 人の担当者に送るタスク
+type assigneeTasks struct {
+	UserID string
+	Name   string
+	Tasks  []Task
+}
+
+// groupTasksByAssignee はタスクを Slack ユーザーごとに分ける。複数の担当者がいるタスクは全員に送る
+// Slack ユーザーがわからない担当者だけのタスクの件数も返す
+func groupTasksByAssignee(ctx context.Context, tasks []Task, r *slackUserResolver) ([]assigneeTasks, int) {
+	byUser := map[string]*assigneeTasks{}
+	unassigned := 0
+	for _, task := range tasks {
+		delivered := false
+		for _, a := range task.Assignees {
+			id := r.resolve(ctx, a)
+			if id == "" {
+				continue
+			}
+			group, ok := byUser[id]
+			if !ok {
+				group = &assigneeTasks{UserID: id, Name: a.Name}
+				byUser[id] = group
+			}
+			// 同じ人が 2 回担当者に入っていても 1 回だけ載せる
+			if n := len(group.Tasks); n == 0 || group.Tasks[n-1].ID != task.ID {
+				group.Tasks = append(group.Tasks, task)
+			}
+			delivered = true
+		}
+		if !delivered {
+			unassigned++
+		}
+	}
+
+	groups := make([]assigneeTasks, 0, len(byUser))
+	for _, g := range byUser {
+		groups = append(groups, *g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].UserID < groups[j].UserID })
+	return groups, unassigned
+}
+
+// sendAssigneeDMs は担当者ごとに自分のタスクだけを載せた DM を送り、送れた人数と失敗した人数を返す
+// DM は最初の投稿先のワークスペースから送る
+func sendAssigneeDMs(ctx context.Context, job digestJob, tasks []Task, render func([]Task) ([]slack.Block, error), httpClient *http.Client) (sent, failed int, err error) {
+	resolver := &slackUserResolver{mapping: job.SlackUserMap, cache: map[string]string{}}
+	var dest slackDestination
+	if len(job.Destinations) > 0 {
+		dest = job.Destinations[0]
+	}
+	if dest.Tokens != nil {
+		if resolver.client, err = dest.Tokens.Client(ctx, slack.OptionHTTPClient(httpClient)); err != nil {
+			return 0, 0, fmt.Errorf("slack client error for DMs: %w", err)
+		}
+	} else if !job.DryRun {
+		return 0, 0, fmt.Errorf("no Slack workspace to send DMs from")
+	}
+
+	groups, unassigned := groupTasksByAssignee(ctx, tasks, resolver)
+	if unassigned > 0 {
+		log.Printf("[%s] %d tasks have no assignee with a Slack user and are not sent as DMs", job.Name, unassigned)
+	}
+	for _, g := range groups {
+		blocks, err := render(g.Tasks)
+		if err != nil {
+			return sent, failed, &renderError{Err: fmt.Errorf("build Slack blocks: %w", err)}
+		}
+		if job.DryRun {
+			if err := printDryRun(os.Stdout, slackDestination{Name: "DM " + g.Name, ChannelID: g.UserID}, blocks); err != nil {
+				return sent, failed, err
+			}
+			continue
+		}
+		// ユーザー ID を channel に指定すると、アプリとの DM に投稿される
+		if _, err := postBlocks(ctx, resolver.client, g.UserID, blocks); err != nil {
+			log.Printf("[%s] Slack DM send error (%s): %v", job.Name, g.Name, err)
+			failed++
+			continue
+		}
+		log.Printf("[%s] Slack DM sent to %s (%d tasks)", job.Name, g.Name, len(g.Tasks))
+		sent++
+	}
+	return sent, failed, nil
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jomei/notionapi"
+	"github.com/slack-go/slack"
+	"github.com/spf13/cobra"
+)
+
+// doctorReport は doctor の確認結果を 1 行ずつ表示し、問題の数を数える
+type doctorReport struct {
+	w        io.Writer
+	problems int
+	warnings int
+}
+
+func (r *doctorReport) section(title string) {
+	fmt.Fprintf(r.w, "%s\n", title)
+}
+
+func (r *doctorReport) ok(format string, args ...any) {
+	fmt.Fprintf(r.w, "  ok    %s\n", fmt.Sprintf(format, args...))
+}
+
+// warn は動くが一部の機能が使えない設定を表示する。hint はどう直すか
+func (r *doctorReport) warn(hint, format string, args ...any) {
+	r.warnings++
+	r.print("WARN", hint, fmt.Sprintf(format, args...))
+}
+
+// fail は通知が届かない設定を表示する。hint はどう直すか
+func (r *doctorReport) fail(hint, format string, args ...any) {
+	r.problems++
+	r.print("FAIL", hint, fmt.Sprintf(format, args...))
+}
+
+func (r *doctorReport) print(level, hint, msg string) {
+	fmt.Fprintf(r.w, "  %-5s %s\n", level, msg)
+	if hint != "" {
+		fmt.Fprintf(r.w, "        -> %s\n", hint)
+	}
+}
+
+// mappedProperty はタスクのプロパティとして使える Notion のプロパティの種類
+type mappedProperty struct {
+	Key      string // 設定ファイルの properties の項目
+	Name     string
+	Types    []notionapi.PropertyConfigType // 空なら種類を問わない
+	Required bool                           // 無ければタスクを取得できない
+}
+
+// mappedProperties は設定されたプロパティと、ParsePage が読める種類を返す
+// 数式とロールアップは中の値を読むため、値の種類が合っていれば使える
+func mappedProperties() []mappedProperty {
+	computed := []notionapi.PropertyConfigType{notionapi.PropertyConfigTypeFormula, notionapi.PropertyConfigTypeRollup}
+	withComputed := func(types ...notionapi.PropertyConfigType) []notionapi.PropertyConfigType {
+		return append(types, computed...)
+	}
+	props := []mappedProperty{
+		{"name", nameProp, []notionapi.PropertyConfigType{notionapi.PropertyConfigTypeTitle}, true},
+		{"due", dueProp, []notionapi.PropertyConfigType{notionapi.PropertyConfigTypeDate}, true},
+		{"schedule_status", scheduleStatusProp, []notionapi.PropertyConfigType{notionapi.PropertyConfigStatus}, true},
+		{"priority", priorityProp, withComputed(notionapi.PropertyConfigTypeSelect, notionapi.PropertyConfigStatus, notionapi.PropertyConfigTypeRichText), false},
+		{"type", typeProp, withComputed(notionapi.PropertyConfigTypeSelect, notionapi.PropertyConfigStatus, notionapi.PropertyConfigTypeRichText), false},
+		{"workload", workloadProp, withComputed(notionapi.PropertyConfigTypeNumber, notionapi.PropertyConfigTypeSelect, notionapi.PropertyConfigTypeRichText), false},
+		{"memo", memoProp, []notionapi.PropertyConfigType{notionapi.PropertyConfigTypeRichText}, false},
+		{"assignee", assigneeProp, []notionapi.PropertyConfigType{notionapi.PropertyConfigTypePeople}, false},
+		{"tags", tagsProp, []notionapi.PropertyConfigType{notionapi.PropertyConfigTypeMultiSelect}, false},
+		{"unique_id", uniqueIDProp, []notionapi.PropertyConfigType{notionapi.PropertyConfigUniqueID}, false},
+		{"project", projectProp, []notionapi.PropertyConfigType{notionapi.PropertyConfigTypeRelation}, false},
+		{"link", linkProp, withComputed(notionapi.PropertyConfigTypeURL, notionapi.PropertyConfigTypeRichText), false},
+		{"email", emailProp, withComputed(notionapi.PropertyConfigTypeEmail, notionapi.PropertyConfigTypeRichText), false},
+		{"phone", phoneProp, withComputed(notionapi.PropertyConfigTypePhoneNumber, notionapi.PropertyConfigTypeRichText), false},
+		{"pinned", pinnedProp, []notionapi.PropertyConfigType{notionapi.PropertyConfigTypeCheckbox, notionapi.PropertyConfigTypeFormula}, false},
+		{"progress", progressProp, withComputed(notionapi.PropertyConfigTypeNumber), false},
+		// 通知した日時を書き込むので日付でなければならない
+		{"last_notified", lastNotifiedProp, []notionapi.PropertyConfigType{notionapi.PropertyConfigTypeDate}, false},
+		{"files", filesProp, []notionapi.PropertyConfigType{notionapi.PropertyConfigTypeFiles}, false},
+	}
+	for _, name := range extraProps {
+		props = append(props, mappedProperty{Key: "extra", Name: name})
+	}
+	return props
+}
+
+// doctorNotion は Notion のトークン、データベースへのアクセス、プロパティの名前と種類を確かめる
+func doctorNotion(ctx context.Context, r *doctorReport, store *stateStore) {
+	r.section("Notion")
+	token, dbIDs, err := notionSourceFromEnv(store)
+	if err != nil {
+		r.fail("set NOTION_TOKEN (or run init) and NOTION_DB_ID (or databases in the config file)", "%v", err)
+		return
+	}
+	client := notionapi.NewClient(notionapi.Token(token))
+	me, err := client.User.Me(ctx)
+	if err != nil {
+		r.fail(notionDoctorHint(err, ""), "token is not accepted: %v", err)
+		return
+	}
+	r.ok("token is valid (integration %q)", me.Name)
+
+	dbs, err := resolveDatabases(ctx, client, dbIDs)
+	if err != nil {
+		r.fail("check NOTION_DB_ID and share the databases with the integration", "failed to resolve databases: %v", err)
+		return
+	}
+	for _, d := range dbs {
+		db, err := client.Database.Get(ctx, notionapi.DatabaseID(d.ID))
+		if err != nil {
+			r.fail(notionDoctorHint(err, d.ID), "database %s is not accessible: %v", d.ID, err)
+			continue
+		}
+		var title strings.Builder
+		for _, rt := range db.Title {
+			title.WriteString(rt.PlainText)
+		}
+		r.ok("database %s (%s) is accessible", d.ID, title.String())
+		doctorSchema(r, db)
+	}
+}
+
+// notionDoctorHint は Notion API のエラーの直し方を返す
+func notionDoctorHint(err error, dbID string) string {
+	var apiErr *notionapi.Error
+	if !errors.As(err, &apiErr) {
+		return "check the network connection to api.notion.com"
+	}
+	switch apiErr.Status {
+	case http.StatusUnauthorized:
+		return "the token is invalid or revoked: copy the integration secret again from https://www.notion.so/my-integrations into NOTION_TOKEN, or run init again"
+	case http.StatusNotFound, http.StatusForbidden:
+		if dbID != "" {
+			return "check the database ID, then open the database in Notion and add the integration under ... > Connections"
+		}
+	}
+	return ""
+}
+
+// doctorSchema はデータベースに設定されたプロパティがあり、使える種類であることを確かめる
+func doctorSchema(r *doctorReport, db *notionapi.Database) {
+	for _, p := range mappedProperties() {
+		if p.Name == "" {
+			continue
+		}
+		config, ok := db.Properties[p.Name]
+		if !ok {
+			hint := fmt.Sprintf("rename the property in Notion or set properties.%s in the config file to its name", p.Key)
+			if p.Key == "extra" {
+				hint = "remove it from properties.extra or fix the name"
+			}
+			if p.Required {
+				r.fail(hint, "property %q (%s) is missing, so no task can be fetched", p.Name, p.Key)
+			} else {
+				r.warn(hint, "property %q (%s) is missing and will be left out", p.Name, p.Key)
+			}
+			continue
+		}
+		if len(p.Types) > 0 && !slices.Contains(p.Types, config.GetType()) {
+			types := make([]string, len(p.Types))
+			for i, t := range p.Types {
+				types[i] = string(t)
+			}
+			r.fail(fmt.Sprintf("change the property type in Notion or point properties.%s at a %s property", p.Key, strings.Join(types, "/")),
+				"property %q (%s) is a %s property", p.Name, p.Key, config.GetType())
+			continue
+		}
+		r.ok("property %q (%s) is a %s property", p.Name, p.Key, config.GetType())
+	}
+
+	// 取得するステータスが 1 つも選択肢に無ければ、どのタスクも取得されない
+	if status, ok := db.Properties[scheduleStatusProp].(*notionapi.StatusPropertyConfig); ok {
+		f := notionFetcher(nil)
+		if len(configuredStatuses.Groups) > 0 {
+			options, unknown := statusGroupOptions(status, configuredStatuses.Groups)
+			for _, name := range unknown {
+				r.warn("fix statuses.groups (or --status-groups); the schema command lists the groups", "status group %q is not in %q", name, scheduleStatusProp)
+			}
+			configuredStatuses.apply(f, options)
+		}
+		known := slices.ContainsFunc(status.Status.Options, func(o notionapi.Option) bool { return f.HasStatus(o.Name) })
+		if !known {
+			r.fail("set statuses in the config file (or --statuses) to options of the property; the schema command lists them",
+				"no option of %q is a status the digest fetches, so no task will be fetched", scheduleStatusProp)
+		}
+	}
+}
+
+// slackAuth は auth.test で分かったトークンの情報
+type slackAuth struct {
+	OK     bool     `json:"ok"`
+	Error  string   `json:"error"`
+	Team   string   `json:"team"`
+	User   string   `json:"user"`
+	UserID string   `json:"user_id"`
+	Scopes []string `json:"-"` // 分からなければ nil
+}
+
+// slackAuthTest は auth.test を呼び、応答のヘッダー (X-OAuth-Scopes) からトークンのスコープも読む
+// slack-go の AuthTest はヘッダーを返さないため、HTTP を直接使う
+func slackAuthTest(ctx context.Context, token string) (*slackAuth, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, slack.APIURL+"auth.test", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("auth.test returned HTTP %d", resp.StatusCode)
+	}
+	var auth slackAuth
+	if err := json.NewDecoder(resp.Body).Decode(&auth); err != nil {
+		return nil, fmt.Errorf("failed to decode auth.test response: %w", err)
+	}
+	if header := resp.Header.Get("X-OAuth-Scopes"); header != "" {
+		for _, scope := range strings.Split(header, ",") {
+			auth.Scopes = append(auth.Scopes, strings.TrimSpace(scope))
+		}
+	}
+	return &auth, nil
+}
+
+const slackTokenHint = "create a bot token (xoxb-...) under OAuth & Permissions and set SLACK_BOT_TOKEN, or run install again"
+
+// doctorSlack は投稿先ごとに Slack のトークン、スコープ、チャンネルへの参加を確かめる
+func doctorSlack(ctx context.Context, r *doctorReport, store *stateStore, channels string) {
+	r.section("Slack")
+	destinations, err := loadSlackDestinations(systemClock{}, store, channels)
+	if err != nil {
+		r.fail("check SLACK_BOT_TOKEN and SLACK_CHANNEL_ID", "%v", err)
+		return
+	}
+	if len(destinations) == 0 {
+		r.warn("set SLACK_BOT_TOKEN and SLACK_CHANNEL_ID, or run install, to post digests to Slack", "no Slack destination is configured")
+		return
+	}
+	destinations = routeDestinations(destinations, configuredRoutes)
+
+	// 同じトークンの投稿先はトークンとスコープを 1 回だけ確かめる
+	auths := map[string]*slackAuth{}
+	for _, dest := range destinations {
+		token, err := dest.Tokens.Token(ctx)
+		if err != nil {
+			r.fail(slackTokenHint, "%s: failed to get the token: %v", dest.Name, err)
+			continue
+		}
+		auth, checked := auths[token]
+		if !checked {
+			auth = doctorSlackToken(ctx, r, dest, token)
+			auths[token] = auth
+		}
+		if auth == nil {
+			continue
+		}
+		doctorSlackChannel(ctx, r, dest, auth)
+	}
+}
+
+// doctorSlackToken はトークンとスコープを確かめる。使えないトークンなら nil を返す
+func doctorSlackToken(ctx context.Context, r *doctorReport, dest slackDestination, token string) *slackAuth {
+	auth, err := slackAuthTest(ctx, token)
+	if err != nil {
+		r.fail("check the network connection to slack.com", "%s: auth.test failed: %v", dest.Name, err)
+		return nil
+	}
+	if !auth.OK {
+		r.fail(slackTokenHint, "%s: token is not accepted (%s)", dest.Name, auth.Error)
+		return nil
+	}
+	r.ok("%s: token is valid (bot @%s in %s)", dest.Name, auth.User, auth.Team)
+
+	if auth.Scopes == nil {
+		r.ok("%s: scopes are not reported for this token, skipping the scope check", dest.Name)
+		return auth
+	}
+	scopeHint := func(scope string) string {
+		return fmt.Sprintf("add the %s scope under OAuth & Permissions and reinstall the app", scope)
+	}
+	missing := false
+	if !slices.Contains(auth.Scopes, "chat:write") {
+		missing = true
+		r.fail(scopeHint("chat:write"), "%s: the chat:write scope is missing, so digests cannot be posted", dest.Name)
+	}
+	if !slices.Contains(auth.Scopes, "channels:read") {
+		missing = true
+		r.warn(scopeHint("channels:read"), "%s: the channels:read scope is missing, so channel names cannot be resolved", dest.Name)
+	}
+	if !missing {
+		r.ok("%s: scopes %s", dest.Name, strings.Join(auth.Scopes, ", "))
+	}
+	return auth
+}
+
+// doctorSlackChannel は投稿先のチャンネルがあり、アプリが参加していることを確かめる
+func doctorSlackChannel(ctx context.Context, r *doctorReport, dest slackDestination, auth *slackAuth) {
+	client, err := dest.Tokens.Client(ctx)
+	if err != nil {
+		r.fail("", "%s: %v", dest.Name, err)
+		return
+	}
+	channelID := dest.ChannelID
+	if name, ok := channelName(channelID); ok {
+		id, err := lookupChannelID(ctx, client, dest.TeamID, name)
+		if err != nil {
+			r.fail("check the channel name, or use the channel ID (Channel details > About)", "%s: %v", dest.Name, err)
+			return
+		}
+		channelID = id
+	}
+	if strings.HasPrefix(channelID, "D") {
+		r.ok("%s: posts to the direct message %s", dest.Name, channelID)
+		return
+	}
+	info, err := client.GetConversationInfoContext(ctx, &slack.GetConversationInfoInput{ChannelID: channelID})
+	if err != nil {
+		switch slackErrorCode(err) {
+		case "channel_not_found":
+			r.fail("check the channel ID; for a private channel, invite the app first with /invite @"+auth.User, "%s: channel %s not found", dest.Name, channelID)
+		case "missing_scope":
+			r.warn("add the channels:read and groups:read scopes to check channel membership", "%s: cannot read channel %s", dest.Name, channelID)
+		default:
+			r.fail("", "%s: conversations.info for %s failed: %v", dest.Name, channelID, err)
+		}
+		return
+	}
+	if !info.IsMember {
+		if !info.IsPrivate && slices.Contains(auth.Scopes, "chat:write.public") {
+			r.ok("%s: not a member of #%s, posting with chat:write.public", dest.Name, info.Name)
+			return
+		}
+		r.fail(fmt.Sprintf("run /invite @%s in #%s", auth.User, info.Name), "%s: the app is not a member of #%s (%s)", dest.Name, info.Name, channelID)
+		return
+	}
+	r.ok("%s: the app is a member of #%s (%s)", dest.Name, info.Name, channelID)
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the Notion token, database schema, Slack token scopes and channel membership.",
+	Long: `Check the configuration against the Notion and Slack APIs and print what to fix.
+
+doctor verifies that the Notion token is valid, every database is shared with the
+integration and has the mapped properties with usable types, and that each Slack
+destination's token works, has the needed scopes and is a member of its channel.
+It exits with an error if anything would keep digests from being delivered.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := stateStoreFromEnv()
+		r := &doctorReport{w: cmd.OutOrStdout()}
+		doctorNotion(cmd.Context(), r, store)
+		doctorSlack(cmd.Context(), r, store, profileChannels(cmd))
+
+		if r.problems > 0 {
+			return fmt.Errorf("%d problems found (%d warnings)", r.problems, r.warnings)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "No problems found (%d warnings)\n", r.warnings)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// printDryRun は投稿する代わりに、投稿先ごとのプレビューと Block Kit の JSON を出力する
+// JSON は Block Kit Builder にそのまま貼り付けられる形 ({"blocks": [...]}) にする
+// ブロック数の上限を超える場合は、実際の投稿と同じようにメッセージごとに分けて出力する
+func printDryRun(w io.Writer, dest slackDestination, blocks []slack.Block) error {
+	channel := dest.ChannelID
+	if channel == "" {
+		channel = "none"
+	}
+	chunks := splitBlocks(blocks, maxBlocksPerMessage)
+	for i, chunk := range chunks {
+		part := ""
+		if len(chunks) > 1 {
+			part = fmt.Sprintf(" [%d/%d]", i+1, len(chunks))
+		}
+		fmt.Fprintf(w, "===== %s (channel %s)%s =====\n", dest.Name, channel, part)
+		fmt.Fprintln(w, previewBlocks(chunk))
+		data, err := json.MarshalIndent(map[string]slack.Blocks{"blocks": {BlockSet: chunk}}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal blocks: %w", err)
+		}
+		fmt.Fprintf(w, "----- Block Kit JSON -----\n%s\n", data)
+	}
+	return nil
+}
+
+// previewBlocks はブロックを端末で読めるテキストにする
+func previewBlocks(blocks []slack.Block) string {
+	var lines []string
+	text := func(t *slack.TextBlockObject) {
+		if t != nil && t.Text != "" {
+			lines = append(lines, t.Text)
+		}
+	}
+	for _, block := range blocks {
+		switch b := block.(type) {
+		case *slack.HeaderBlock:
+			text(b.Text)
+		case *slack.DividerBlock:
+			lines = append(lines, strings.Repeat("─", 40))
+		case *slack.SectionBlock:
+			text(b.Text)
+			for _, f := range b.Fields {
+				text(f)
+			}
+			if b.Accessory != nil && b.Accessory.ButtonElement != nil {
+				lines = append(lines, "  "+previewButton(b.Accessory.ButtonElement))
+			}
+		case *slack.ContextBlock:
+			var parts []string
+			for _, e := range b.ContextElements.Elements {
+				if t, ok := e.(*slack.TextBlockObject); ok {
+					parts = append(parts, t.Text)
+				}
+			}
+			lines = append(lines, strings.Join(parts, " "))
+		case *slack.ActionBlock:
+			var buttons []string
+			for _, e := range b.Elements.ElementSet {
+				if button, ok := e.(*slack.ButtonBlockElement); ok {
+					buttons = append(buttons, previewButton(button))
+				}
+			}
+			lines = append(lines, strings.Join(buttons, " "))
+		default:
+			lines = append(lines, fmt.Sprintf("(%s block)", block.BlockType()))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func previewButton(b *slack.ButtonBlockElement) string {
+	if b.Text == nil {
+		return "[button]"
+	}
+	return "[" + b.Text.Text + "]"
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// メールの送信設定
+const (
+	smtpHostEnv     = "SMTP_HOST"
+	smtpPortEnv     = "SMTP_PORT" // 既定は 587 (STARTTLS)。465 なら最初から TLS で接続する
+	smtpUsernameEnv = "SMTP_USERNAME"
+	smtpPasswordEnv = "SMTP_PASSWORD"
+	emailFromEnv    = "EMAIL_FROM"
+	emailToEnv      = "EMAIL_TO" // カンマ区切りで複数指定できる
+
+	defaultSMTPPort = 587
+)
+
+// emailTarget は SMTP でダイジェストを送るメールの送信先
+type emailTarget struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// newEmailTargetFromEnv は環境変数からメールの送信先を作る。SMTP_HOST が無ければ nil を返す
+func newEmailTargetFromEnv() (*emailTarget, error) {
+	host := os.Getenv(smtpHostEnv)
+	if host == "" {
+		return nil, nil
+	}
+	t := &emailTarget{Host: host, Port: defaultSMTPPort, Username: os.Getenv(smtpUsernameEnv), Password: os.Getenv(smtpPasswordEnv), From: os.Getenv(emailFromEnv)}
+	if port := os.Getenv(smtpPortEnv); port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil || p < 1 || p > 65535 {
+			return nil, fmt.Errorf("invalid %s: %q", smtpPortEnv, port)
+		}
+		t.Port = p
+	}
+	for _, to := range strings.Split(os.Getenv(emailToEnv), ",") {
+		if to = strings.TrimSpace(to); to != "" {
+			t.To = append(t.To, to)
+		}
+	}
+	if t.From == "" || len(t.To) == 0 {
+		return nil, fmt.Errorf("email requires %s and %s", emailFromEnv, emailToEnv)
+	}
+	return t, nil
+}
+
+// emailSection はメールに載せるセクション
+type emailSection struct {
+	Title string
+	Color string // 見出しの色 (#rrggbb)
+	Parts []emailPart
+}
+
+// emailPart は時間帯の小見出しとタスク (時間帯で分けないセクションでは Title が空の 1 つだけ)
+type emailPart struct {
+	Title string
+	Tasks []emailTask
+}
+
+type emailTask struct {
+	Title   string
+	URL     string
+	Details string
+}
+
+// emailDigest はメールの本文に渡す値
+type emailDigest struct {
+	Header   string
+	Sections []emailSection
+	Footer   string
+}
+
+var emailHTMLTemplate = template.Must(template.New("email").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: -apple-system, 'Segoe UI', 'Hiragino Sans', Meiryo, sans-serif; color: #1d1c1d;">
+<h2>{{.Header}}</h2>
+{{- range .Sections}}
+<h3 style="border-left: 4px solid {{.Color}}; padding-left: 8px;">{{.Title}}</h3>
+{{- range .Parts}}
+{{- if .Title}}
+<p style="color: #616061; font-weight: bold; margin-bottom: 4px;">{{.Title}}</p>
+{{- end}}
+<ul>
+{{- range .Tasks}}
+<li><a href="{{.URL}}"><strong>{{.Title}}</strong></a><br><span style="color: #616061; font-size: 90%;">{{.Details}}</span></li>
+{{- end}}
+</ul>
+{{- end}}
+{{- end}}
+{{- if .Footer}}
+<hr>
+<p style="color: #616061; font-size: 80%;">{{.Footer}}</p>
+{{- end}}
+</body>
+</html>
+`))
+
+// buildEmailDigest はメールの本文に渡す値を作る
+func buildEmailDigest(groups []TaskGroup, runNumber string) (emailDigest, error) {
+	digest := emailDigest{Header: tr("digest.header"), Footer: footerText(runNumber)}
+	for _, group := range groups {
+		section := emailSection{Title: group.Title, Color: fmt.Sprintf("#%06x", sectionColor(group.Name))}
+		for _, part := range group.Parts {
+			p := emailPart{Title: part.Title}
+			for _, task := range part.Tasks {
+				details, err := plainTaskDetails(task)
+				if err != nil {
+					return digest, err
+				}
+				p.Tasks = append(p.Tasks, emailTask{Title: taskTitle(task), URL: task.URL, Details: details})
+			}
+			section.Parts = append(section.Parts, p)
+		}
+		digest.Sections = append(digest.Sections, section)
+	}
+	return digest, nil
+}
+
+// plainText はメールのテキスト版の本文を作る (HTML を表示できないメールソフト用)
+func (d emailDigest) plainText() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", d.Header)
+	for _, section := range d.Sections {
+		fmt.Fprintf(&b, "\n■ %s\n", section.Title)
+		for _, part := range section.Parts {
+			if part.Title != "" {
+				fmt.Fprintf(&b, "[%s]\n", part.Title)
+			}
+			for _, task := range part.Tasks {
+				fmt.Fprintf(&b, "- %s\n  %s\n  %s\n", task.Title, task.URL, task.Details)
+			}
+		}
+	}
+	if d.Footer != "" {
+		fmt.Fprintf(&b, "\n--\n%s\n", d.Footer)
+	}
+	return b.String()
+}
+
+// buildEmailMessage は HTML とテキストの multipart/alternative のメールを作る
+func buildEmailMessage(from string, to []string, subject string, digest emailDigest, now time.Time) ([]byte, error) {
+	var html bytes.Buffer
+	if err := emailHTMLTemplate.Execute(&html, digest); err != nil {
+		return nil, &renderError{Err: fmt.Errorf("failed to render email: %w", err)}
+	}
+	var random [12]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		return nil, err
+	}
+	boundary := "notifyer-" + hex.EncodeToString(random[:])
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", digest.plainText()},
+		{"text/html", html.String()},
+	} {
+		fmt.Fprintf(&msg, "--%s\r\n", boundary)
+		fmt.Fprintf(&msg, "Content-Type: %s; charset=utf-8\r\n", part.contentType)
+		fmt.Fprintf(&msg, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		qp := quotedprintable.NewWriter(&msg)
+		if _, err := io.WriteString(qp, part.body); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&msg, "\r\n")
+	}
+	fmt.Fprintf(&msg, "--%s--\r\n", boundary)
+	return msg.Bytes(), nil
+}
+
+// deliver はメールを SMTP サーバーに送る。465 番ポートでは最初から TLS で接続し、それ以外ではサーバーが対応していれば STARTTLS を使う
+func (t *emailTarget) deliver(ctx context.Context, msg []byte) error {
+	addr := net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	var conn net.Conn
+	var err error
+	if t.Port == 465 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: t.Host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server %s: %w", addr, err)
+	}
+	client, err := smtp.NewClient(conn, t.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && t.Port != 465 {
+		if err := client.StartTLS(&tls.Config{ServerName: t.Host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if t.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", t.Username, t.Password, t.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+	if err := client.Mail(t.From); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM failed: %w", err)
+	}
+	for _, to := range t.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP RCPT TO %s failed: %w", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return client.Quit()
+}
+
+// Send はダイジェストをメールで送る (ドライランではメールの内容を出力する)
+func (t *emailTarget) Send(ctx context.Context, run digestRun, groups []TaskGroup) error {
+	digest, err := buildEmailDigest(groups, run.RunNumber)
+	if err != nil {
+		return fmt.Errorf("build email: %w", err)
+	}
+	subject := tr("email.subject", tr("digest.header"), run.Now.Format("1/2"))
+	msg, err := buildEmailMessage(t.From, t.To, subject, digest, run.Now)
+	if err != nil {
+		return err
+	}
+	if run.DryRun {
+		fmt.Fprintf(os.Stdout, "===== email to %s =====\n%s\n", strings.Join(t.To, ", "), digest.plainText())
+		return nil
+	}
+	if err := t.deliver(ctx, msg); err != nil {
+		return err
+	}
+	log.Printf("[%s] Email sent to %s", run.JobName, strings.Join(t.To, ", "))
+	return nil
+}
+
+func init() {
+	RegisterNotifier(targetEmail, func(flags *pflag.FlagSet) (Notifier, error) {
+		t, err := newEmailTargetFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		if t == nil {
+			return nil, fmt.Errorf("--target email requires %s, %s and %s", smtpHostEnv, emailFromEnv, emailToEnv)
+		}
+		return t, nil
+	})
+}
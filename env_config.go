@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// 設定ファイルとフラグの代わりに使える環境変数の接頭辞
+// コンテナでファイルを置かずに設定できるよう、すべてのフラグと設定ファイルの項目に対応する環境変数がある
+//
+//	フラグ:             --daysLater → NN_DAYS_LATER、--alert-statuses → NN_ALERT_STATUSES (スライスはカンマ区切り)
+//	設定ファイルの値:   properties.priority → NN_PROPERTIES_PRIORITY
+//	設定ファイルの一覧: buckets → NN_BUCKETS (YAML または JSON)
+const envPrefix = "NN_"
+
+// 環境変数で設定されたフラグ (フラグ名 → 環境変数名、config print-effective で出所を表示する)
+var envSetFlags = map[string]string{}
+
+// envName はフラグ名や設定ファイルのキーから環境変数名を作る (daysLater → NN_DAYS_LATER、alert-statuses → NN_ALERT_STATUSES)
+func envName(parts ...string) string {
+	var b strings.Builder
+	b.WriteString(envPrefix)
+	for i, part := range parts {
+		if i > 0 {
+			b.WriteByte('_')
+		}
+		for j, r := range part {
+			switch {
+			case r == '-' || r == '.':
+				b.WriteByte('_')
+			case unicode.IsUpper(r) && j > 0:
+				b.WriteByte('_')
+				b.WriteRune(r)
+			default:
+				b.WriteRune(unicode.ToUpper(r))
+			}
+		}
+	}
+	return b.String()
+}
+
+// applyFlagEnv はコマンドラインで指定していないフラグに NN_ の環境変数の値を設定する
+// 優先順位はコマンドライン > 環境変数 > プロファイル > 既定値
+func applyFlagEnv(cmd *cobra.Command) error {
+	var err error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || f.Name == "help" || f.Name == "version" {
+			return
+		}
+		name := envName(f.Name)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		if setErr := cmd.Flags().Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid %s: %w", name, setErr)
+			return
+		}
+		envSetFlags[f.Name] = name
+	})
+	return err
+}
+
+// configEnvField は設定ファイルの項目と対応する環境変数
+type configEnvField struct {
+	Env   string
+	Key   string // 設定ファイルのキー (例: properties.priority)
+	field func(c *fileConfig) reflect.Value
+}
+
+// configEnvFields は fileConfig の yaml タグから環境変数の一覧を作る
+// 構造体の項目は値ごと (NN_PROPERTIES_PRIORITY)、一覧やマップは項目全体 (NN_BUCKETS) を 1 つの環境変数にする
+func configEnvFields() []configEnvField {
+	var fields []configEnvField
+	t := reflect.TypeOf(fileConfig{})
+	for i := range t.NumField() {
+		top := t.Field(i)
+		topKey := yamlKey(top)
+		if top.Type.Kind() != reflect.Struct {
+			fields = append(fields, configEnvField{Env: envName(topKey), Key: topKey, field: func(c *fileConfig) reflect.Value {
+				return reflect.ValueOf(c).Elem().Field(i)
+			}})
+			continue
+		}
+		for j := range top.Type.NumField() {
+			sub := top.Type.Field(j)
+			key := yamlKey(sub)
+			fields = append(fields, configEnvField{Env: envName(topKey, key), Key: topKey + "." + key, field: func(c *fileConfig) reflect.Value {
+				return reflect.ValueOf(c).Elem().Field(i).Field(j)
+			}})
+		}
+	}
+	return fields
+}
+
+func yamlKey(f reflect.StructField) string {
+	key, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	return key
+}
+
+// applyConfigEnv は NN_ の環境変数の値を設定ファイルの内容に上書きする。1 つでも設定されていれば true を返す
+func applyConfigEnv(c *fileConfig) (bool, error) {
+	applied := false
+	for _, f := range configEnvFields() {
+		value, ok := os.LookupEnv(f.Env)
+		if !ok {
+			continue
+		}
+		target := f.field(c)
+		if target.Kind() == reflect.String {
+			target.SetString(value)
+		} else {
+			decoded := reflect.New(target.Type())
+			dec := yaml.NewDecoder(strings.NewReader(value))
+			dec.KnownFields(true)
+			if err := dec.Decode(decoded.Interface()); err != nil {
+				return applied, fmt.Errorf("invalid %s (expected YAML or JSON for %s): %w", f.Env, f.Key, err)
+			}
+			target.Set(decoded.Elem())
+		}
+		applied = true
+	}
+	return applied, nil
+}
+
+var configEnvCmd = &cobra.Command{
+	Use:   "env",
+	Short: "List the NN_ environment variables that stand in for every flag and config file setting.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "# config file settings (lists and maps take YAML or JSON)")
+		for _, f := range configEnvFields() {
+			fmt.Fprintf(w, "%s\t%s\n", f.Env, f.Key)
+		}
+		fmt.Fprintln(w, "# flags (slices take comma-separated values; the command line wins)")
+		// 同じ名前のフラグは複数のコマンドで 1 つの環境変数を共有する
+		scopes := map[string][]string{}
+		var visit func(c *cobra.Command)
+		visit = func(c *cobra.Command) {
+			c.LocalNonPersistentFlags().VisitAll(func(f *pflag.Flag) {
+				scopes[f.Name] = append(scopes[f.Name], c.CommandPath())
+			})
+			c.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+				scopes[f.Name] = append(scopes[f.Name], c.CommandPath()+" (all subcommands)")
+			})
+			for _, sub := range c.Commands() {
+				visit(sub)
+			}
+		}
+		visit(rootCmd)
+		delete(scopes, "help")
+		delete(scopes, "version")
+		for _, name := range slices.Sorted(maps.Keys(scopes)) {
+			fmt.Fprintf(w, "%s\t--%s\t%s\n", envName(name), name, strings.Join(scopes[name], ", "))
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configEnvCmd)
+}
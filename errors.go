@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/jomei/notionapi"
+	"github.com/slack-go/slack"
+	"github.com/spf13/cobra"
+)
+
+// 終了コード (exitOverdue は overdue_gate.go)
+const (
+	exitError  = 1 // 分類できないエラー
+	exitConfig = 3 // フラグ・環境変数・設定ファイルの誤り
+	exitNotion = 4 // Notion API のエラー
+	exitSlack  = 5 // Slack API のエラー
+	exitRender = 6 // メッセージの描画のエラー
+)
+
+// configError はフラグ・環境変数・設定ファイルの誤りを表す。実行し直しても直らない
+type configError struct {
+	Err error
+}
+
+func (e *configError) Error() string { return e.Err.Error() }
+func (e *configError) Unwrap() error { return e.Err }
+
+// configErrorf は fmt.Errorf と同じ書式で configError を作る
+func configErrorf(format string, args ...any) error {
+	return &configError{Err: fmt.Errorf(format, args...)}
+}
+
+// asConfigError は err を configError で包む (nil はそのまま、包み済みなら包み直さない)
+func asConfigError(err error) error {
+	var ce *configError
+	if err == nil || errors.As(err, &ce) {
+		return err
+	}
+	return &configError{Err: err}
+}
+
+// notionError は Notion API の呼び出しの失敗を表す。Status は HTTP ステータス (分からなければ 0)
+type notionError struct {
+	Op     string // 何をしていたか (空なら Err のメッセージだけを出す)
+	Status int
+	Err    error
+}
+
+func (e *notionError) Error() string {
+	if e.Op == "" {
+		return e.Err.Error()
+	}
+	return e.Op + ": " + e.Err.Error()
+}
+
+func (e *notionError) Unwrap() error { return e.Err }
+
+// newNotionError は err を notionError で包み、API の応答から HTTP ステータスを取り出す (nil はそのまま返す)
+func newNotionError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	e := &notionError{Op: op, Err: err}
+	var apiErr *notionapi.Error
+	var rateLimited *notionapi.RateLimitedError
+	var outage *notionOutageError
+	switch {
+	case errors.As(err, &outage):
+		e.Status = outage.Status
+	case errors.As(err, &apiErr):
+		e.Status = apiErr.Status
+	case errors.As(err, &rateLimited):
+		e.Status = http.StatusTooManyRequests
+	}
+	return e
+}
+
+// slackError は Slack API の呼び出しの失敗を表す。Code は Slack のエラーコード (invalid_auth など、分からなければ空)
+type slackError struct {
+	Op   string // 何をしていたか (空なら Err のメッセージだけを出す)
+	Code string
+	Err  error
+}
+
+func (e *slackError) Error() string {
+	if e.Op == "" {
+		return e.Err.Error()
+	}
+	return e.Op + ": " + e.Err.Error()
+}
+
+func (e *slackError) Unwrap() error { return e.Err }
+
+// newSlackError は err を slackError で包み、API の応答からエラーコードを取り出す (nil はそのまま返す)
+func newSlackError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &slackError{Op: op, Code: slackErrorCode(err), Err: err}
+}
+
+// slackErrorCode は Slack API のエラーのエラーコードを返す (HTTP のエラーは http_503 のように返す)
+func slackErrorCode(err error) string {
+	var se *slackError
+	var apiErr slack.SlackErrorResponse
+	var rateLimited *slack.RateLimitedError
+	var status slack.StatusCodeError
+	switch {
+	case errors.As(err, &se) && se.Code != "":
+		return se.Code
+	case errors.As(err, &apiErr):
+		return apiErr.Err
+	case errors.As(err, &rateLimited):
+		return "ratelimited"
+	case errors.As(err, &status):
+		return fmt.Sprintf("http_%d", status.Code)
+	}
+	return ""
+}
+
+// renderError はメッセージ (Block Kit・テンプレート・メール) を組み立てられなかったことを表す
+type renderError struct {
+	Err error
+}
+
+func (e *renderError) Error() string { return e.Err.Error() }
+func (e *renderError) Unwrap() error { return e.Err }
+
+// exitCode はエラーの種類から終了コードを決める
+func exitCode(err error) int {
+	var gate *overdueGateError
+	var ce *configError
+	var ne *notionError
+	var se *slackError
+	var re *renderError
+	switch {
+	case errors.As(err, &gate):
+		return exitOverdue
+	case errors.As(err, &ce):
+		return exitConfig
+	case errors.As(err, &ne):
+		return exitNotion
+	case errors.As(err, &se):
+		return exitSlack
+	case errors.As(err, &re):
+		return exitRender
+	default:
+		return exitError
+	}
+}
+
+func init() {
+	// 知らないフラグや値の誤りも設定の誤りとして終了コードを分ける
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &configError{Err: err}
+	})
+}
+
+// fatal はエラーをログに出し、種類に応じた終了コードで終了する
+func fatal(format string, err error) {
+	log.Printf(format, err)
+	os.Exit(exitCode(err))
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/jomei/notionapi"
+	"github.com/spf13/cobra"
+)
+
+// Todoist 関連
+const (
+	todoistTokenEnv = "TODOIST_API_TOKEN"
+	todoistAPIURL   = "https://api.todoist.com/rest/v2"
+)
+
+// todoistPriority は Notion の優先度を Todoist の優先度 (4 が最も高く 1 が既定) にする
+// 設定した優先度の上位 3 つを 4・3・2 にし、それより低いものと順序の無いものは 1 にする
+func todoistPriority(name string) int {
+	i := slices.Index(priorityNames(), name)
+	if i < 0 || i > 2 || name == "" {
+		return 1
+	}
+	return 4 - i
+}
+
+// errTodoistNotFound は Todoist 側でタスクが削除されていることを表す
+var errTodoistNotFound = errors.New("todoist task not found")
+
+// todoistTask は Todoist REST API のタスク作成・更新リクエスト
+type todoistTask struct {
+	Content     string `json:"content"`
+	Description string `json:"description,omitempty"`
+	Priority    int    `json:"priority,omitempty"`
+	DueDate     string `json:"due_date,omitempty"`
+	DueDatetime string `json:"due_datetime,omitempty"`
+}
+
+type todoistClient struct {
+	token      string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// newTodoistTask は Notion のタスクを Todoist のタスクに変換する
+// 説明に Notion ページへのリンクを入れ、Notion を正として辿れるようにする
+func newTodoistTask(task Task) todoistTask {
+	t := todoistTask{
+		Content:     task.Title,
+		Description: fmt.Sprintf("[%s](%s)", tr("todoist.open_in_notion"), task.URL),
+		Priority:    todoistPriority(task.Priority),
+	}
+	if due := getTargetDueDate(task); due != nil {
+		if due.Hour() != 0 || due.Minute() != 0 {
+			t.DueDatetime = due.Format(time.RFC3339)
+		} else {
+			t.DueDate = due.Format("2006-01-02")
+		}
+	}
+	return t
+}
+
+// upsert は id が空ならタスクを作成し、そうでなければ更新する。Todoist のタスク ID を返す
+func (c *todoistClient) upsert(ctx context.Context, id string, task todoistTask) (string, error) {
+	path := "/tasks"
+	if id != "" {
+		path += "/" + id
+	}
+	body, err := json.Marshal(task)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call Todoist: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", errTodoistNotFound
+	}
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("todoist returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", fmt.Errorf("failed to decode Todoist response: %w", err)
+	}
+	return created.ID, nil
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Mirror due Notion tasks into other task managers or export views of the digest history.",
+}
+
+var exportTodoistCmd = &cobra.Command{
+	Use:   "todoist",
+	Short: "Create or update Todoist tasks for due Notion tasks, linking back to Notion.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		todoistToken := os.Getenv(todoistTokenEnv)
+		if todoistToken == "" {
+			return fmt.Errorf("%s must be set", todoistTokenEnv)
+		}
+		daysLater, _ := cmd.Flags().GetInt("daysLater")
+		clock, err := clockFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		store := stateStoreFromEnv()
+		notionToken, dbID, err := notionSourceFromEnv(store)
+		if err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		notionClient := notionapi.NewClient(notionapi.Token(notionToken))
+		tasks, err := fetchNotionTasks(ctx, notionClient, dbID, endOfDay(clock.Now(), daysLater))
+		if err != nil {
+			return fmt.Errorf("get Notion tasks: %w", err)
+		}
+
+		todoist := &todoistClient{token: todoistToken, baseURL: todoistAPIURL, httpClient: http.DefaultClient}
+		failed := 0
+		err = store.Update(func(st *state) error {
+			if st.TodoistTasks == nil {
+				st.TodoistTasks = map[string]string{}
+			}
+			for _, task := range tasks {
+				pageID := task.Key()
+				id, err := todoist.upsert(ctx, st.TodoistTasks[pageID], newTodoistTask(task))
+				// Todoist 側で削除されていたら作り直す
+				if errors.Is(err, errTodoistNotFound) {
+					id, err = todoist.upsert(ctx, "", newTodoistTask(task))
+				}
+				if err != nil {
+					log.Printf("Todoist export error (%s): %v", task.Title, err)
+					failed++
+					continue
+				}
+				st.TodoistTasks[pageID] = id
+			}
+			// 失敗したタスクがあっても、成功した分の対応は保存する
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("Exported %d of %d tasks to Todoist", len(tasks)-failed, len(tasks))
+		if failed > 0 {
+			return fmt.Errorf("%d tasks failed to export to Todoist", failed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	exportCmd.AddCommand(exportTodoistCmd)
+	rootCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"rainierrr/notion-notifyer/pkg/task"
+)
+
+// TestTodoistPriorityFollowsConfiguredOrder は Todoist の優先度を設定した優先度の順序から決めることを確かめる
+func TestTodoistPriorityFollowsConfiguredOrder(t *testing.T) {
+	t.Cleanup(func() { setPriorityOrder(task.DefaultPriorities) })
+	tests := []struct {
+		order []string
+		want  map[string]int
+	}{
+		{task.DefaultPriorities, map[string]int{"High": 4, "Mid": 3, "Low": 2, "": 1, "Other": 1}},
+		{[]string{"Urgent", "Normal"}, map[string]int{"Urgent": 4, "Normal": 3, "": 1, "High": 1}},
+		{[]string{"P0", "P1", "P2", "P3"}, map[string]int{"P0": 4, "P1": 3, "P2": 2, "P3": 1}},
+	}
+	for _, tt := range tests {
+		setPriorityOrder(tt.order)
+		for name, want := range tt.want {
+			if got := newTodoistTask(Task{Task: task.Task{Priority: name}}).Priority; got != want {
+				t.Errorf("order %v: priority %q exported as %d, want %d", tt.order, name, got, want)
+			}
+		}
+	}
+}
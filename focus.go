@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jomei/notionapi"
+	"github.com/slack-go/slack"
+)
+
+const (
+	// フォーカスモードで表示するタスクの件数
+	focusTaskCount = 3
+	// 残りのタスクをスレッドに表示するボタンの action_id
+	showAllActionID = "show_all"
+)
+
+// showAllValue は「残りを見る」ボタンに埋め込む、タスクを取得し直すための条件
+type showAllValue struct {
+	DaysLater int `json:"days_later"`
+}
+
+// buildFocusBlocks は優先度と期限日で上位のタスクだけを載せた短いメッセージを作る
+// 残りのタスクがある場合は、スレッドに全件を表示するボタンを付ける
+func buildFocusBlocks(tasks []Task, runNumber string, daysLater int) ([]slack.Block, error) {
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("no tasks to build slack blocks")
+	}
+	sorted := append([]Task(nil), tasks...)
+	sortTasks(sorted)
+
+	top := sorted
+	if len(top) > focusTaskCount {
+		top = top[:focusTaskCount]
+	}
+
+	var blocks []slack.Block
+	blocks = append(blocks, slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, tr("focus.header"), true, false)))
+	for _, task := range top {
+		strTime, err := formatDueDate(task)
+		if err != nil {
+			return blocks, fmt.Errorf("failed to format due date for task %s: %w", task.Title, err)
+		}
+		text := fmt.Sprintf("*<%s|%s>*\n*%s:* %s", task.URL, taskTitle(task), tr("task.due"), strTime)
+		if task.Priority != "" {
+			text += fmt.Sprintf(" | *%s:* %s", tr("task.priority"), priorityLabel(task.Priority))
+		}
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil))
+	}
+
+	if rest := len(sorted) - len(top); rest > 0 {
+		value, err := json.Marshal(showAllValue{DaysLater: daysLater})
+		if err != nil {
+			return blocks, err
+		}
+		button := slack.NewButtonBlockElement(showAllActionID, string(value),
+			slack.NewTextBlockObject(slack.PlainTextType, tr("focus.more", rest), true, false))
+		blocks = append(blocks, slack.NewActionBlock("focus_actions", button))
+	}
+
+	if footer := footerText(runNumber); footer != "" {
+		blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject(slack.PlainTextType, footer, false, false)))
+	}
+	return blocks, nil
+}
+
+// handleShowAll はタスクを取得し直し、全件のダイジェストをスレッドに投稿する
+func handleShowAll(ctx context.Context, env *interactionEnv, callback slack.InteractionCallback, action *slack.BlockAction) error {
+	var value showAllValue
+	if err := json.Unmarshal([]byte(action.Value), &value); err != nil {
+		return fmt.Errorf("invalid button value: %w", err)
+	}
+
+	tasks, now, err := refetchTasks(ctx, env, value.DaysLater)
+	if err != nil {
+		return err
+	}
+
+	slackClient, err := env.slackClient(ctx, callback.Team.ID)
+	if err != nil {
+		return err
+	}
+
+	channelID := callback.Container.ChannelID
+	threadTS := callback.Container.MessageTs
+	if len(tasks) == 0 {
+		_, _, err = slackClient.PostMessageContext(ctx, channelID, slack.MsgOptionText(tr("no_tasks"), false), slack.MsgOptionTS(threadTS))
+		return err
+	}
+	blocks, err := buildSlackBlocks(tasks, renderOptions{Now: now})
+	if err != nil {
+		return err
+	}
+	_, err = postBlocks(ctx, slackClient, channelID, blocks, slack.MsgOptionTS(threadTS))
+	return err
+}
+
+// refetchTasks はボタンが押された時点で daysLater 日後までのタスクを取得し直す (スヌーズ中・ミュート中のものは除く)
+func refetchTasks(ctx context.Context, env *interactionEnv, daysLater int) ([]Task, time.Time, error) {
+	notionToken, dbID, err := notionSourceFromEnv(env.store)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	now := env.clock.Now()
+	notionClient := notionapi.NewClient(notionapi.Token(notionToken))
+	tasks, err := fetchNotionTasks(ctx, notionClient, dbID, endOfDay(now, daysLater))
+	if err != nil {
+		return nil, now, fmt.Errorf("get Notion tasks: %w", err)
+	}
+	st, err := env.store.Load()
+	if err != nil {
+		return nil, now, err
+	}
+	tasks, _ = filterMutedTasks(tasks, st, now)
+	return tasks, now, nil
+}
+
+func init() {
+	registerInteraction(showAllActionID, handleShowAll)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// completionForecast は今週が期限のタスクを今のペースで終えられるかの見込み
+type completionForecast struct {
+	Remaining int       // 今週末までが期限の未完了のタスクの数 (期限切れを含む)
+	Deadline  time.Time // その中で最も遅い期限日
+	Rate      float64   // 期間中の 1 日あたりの完了数
+	Finish    time.Time // 今のペースで終わる日 (Rate が 0 なら zero)
+}
+
+// forecastCompletion は最後の実行で掲載された今週末 (日曜) までが期限のタスクと、期間中の完了数から見込みを求める
+// 未完了のタスクが無ければ nil を返す
+func (h *history) forecastCompletion(completed, days int, now time.Time) *completionForecast {
+	var lastRun *historyRun
+	for i := range h.Runs {
+		if !h.Runs[i].At.After(now) {
+			lastRun = &h.Runs[i]
+		}
+	}
+	if lastRun == nil {
+		return nil
+	}
+
+	weekEnd := endOfDay(now, (7-int(now.Weekday()))%7)
+	f := &completionForecast{Rate: float64(completed) / float64(days)}
+	for _, t := range lastRun.Tasks {
+		if t.Due == nil || t.Due.After(weekEnd) {
+			continue
+		}
+		f.Remaining++
+		if due := startOfDay(*t.Due); due.After(f.Deadline) {
+			f.Deadline = due
+		}
+	}
+	if f.Remaining == 0 {
+		return nil
+	}
+	if f.Rate > 0 {
+		f.Finish = startOfDay(now).AddDate(0, 0, int(math.Ceil(float64(f.Remaining)/f.Rate))-1)
+	}
+	return f
+}
+
+// text は見込みを 1 行の文にする
+func (f *completionForecast) text() string {
+	if f.Rate == 0 {
+		return tr("report.forecast_no_pace", f.Remaining, forecastDay(f.Deadline))
+	}
+	rate := strconv.FormatFloat(f.Rate, 'f', 1, 64)
+	if !f.Finish.After(f.Deadline) {
+		return tr("report.forecast_on_track", rate, f.Remaining, forecastDay(f.Deadline), forecastDay(f.Finish))
+	}
+	late := int(math.Round(f.Finish.Sub(f.Deadline).Hours() / 24))
+	return tr("report.forecast_behind", rate, f.Remaining, forecastDay(f.Deadline), forecastDay(f.Finish), late)
+}
+
+// forecastDay は日付を 10/16(木) の形にする
+func forecastDay(t time.Time) string {
+	weekdays := strings.Split(tr("calendar.weekdays"), ",")
+	return t.Format("1/2") + "(" + weekdays[t.Weekday()] + ")"
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+)
+
+// GitHub 関連
+const (
+	githubTokenEnv = "GITHUB_TOKEN"
+	githubAPIURL   = "https://api.github.com"
+)
+
+var githubURLPattern = regexp.MustCompile(`https://github\.com/([\w.-]+)/([\w.-]+)/(issues|pull)/(\d+)`)
+
+// GitHubRef はタスクに含まれる GitHub の Issue/PR とその状態
+type GitHubRef struct {
+	URL   string
+	Repo  string // owner/repo
+	Kind  string // issues または pull
+	Num   string
+	State string // open, closed, merged (取得できなければ空)
+}
+
+// Label は "owner/repo#123" 形式の表示名を返す
+func (r GitHubRef) Label() string {
+	return fmt.Sprintf("%s#%s", r.Repo, r.Num)
+}
+
+// Chip は状態を表す絵文字付きの表示を返す
+func (r GitHubRef) Chip() string {
+	switch r.State {
+	case "open":
+		return "🟢 open"
+	case "merged":
+		return "🟣 merged"
+	case "closed":
+		return "✅ closed"
+	}
+	return "❔ unknown"
+}
+
+// findGitHubRefs はテキストから GitHub の Issue/PR の URL を重複なく取り出す
+func findGitHubRefs(texts ...string) []GitHubRef {
+	var refs []GitHubRef
+	seen := map[string]bool{}
+	for _, text := range texts {
+		for _, m := range githubURLPattern.FindAllStringSubmatch(text, -1) {
+			if seen[m[0]] {
+				continue
+			}
+			seen[m[0]] = true
+			refs = append(refs, GitHubRef{URL: m[0], Repo: m[1] + "/" + m[2], Kind: m[3], Num: m[4]})
+		}
+	}
+	return refs
+}
+
+type githubClient struct {
+	token      string
+	httpClient *http.Client
+	cache      map[string]string
+}
+
+func newGitHubClientFromEnv() *githubClient {
+	return &githubClient{token: os.Getenv(githubTokenEnv), httpClient: http.DefaultClient, cache: map[string]string{}}
+}
+
+// state は Issue/PR の状態を返す。PR はマージ済みかどうかも確認する
+func (c *githubClient) state(ctx context.Context, ref GitHubRef) (string, error) {
+	if st, ok := c.cache[ref.URL]; ok {
+		return st, nil
+	}
+
+	var issue struct {
+		State string `json:"state"`
+	}
+	if err := c.get(ctx, fmt.Sprintf("/repos/%s/issues/%s", ref.Repo, ref.Num), &issue); err != nil {
+		return "", err
+	}
+	st := issue.State
+	if ref.Kind == "pull" && st == "closed" {
+		var pull struct {
+			Merged bool `json:"merged"`
+		}
+		if err := c.get(ctx, fmt.Sprintf("/repos/%s/pulls/%s", ref.Repo, ref.Num), &pull); err != nil {
+			return "", err
+		}
+		if pull.Merged {
+			st = "merged"
+		}
+	}
+
+	c.cache[ref.URL] = st
+	return st, nil
+}
+
+func (c *githubClient) get(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, githubAPIURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("github returned %s for %s", resp.Status, path)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// applyGitHubStatus は Memo と Link に含まれる Issue/PR の状態を取得してタスクに設定する
+func applyGitHubStatus(ctx context.Context, tasks []Task, client *githubClient) {
+	for i := range tasks {
+		refs := findGitHubRefs(tasks[i].Link, tasks[i].Memo)
+		for j := range refs {
+			st, err := client.state(ctx, refs[j])
+			if err != nil {
+				log.Printf("Warning: Unable to get GitHub status for %s: %v", refs[j].URL, err)
+				continue
+			}
+			refs[j].State = st
+		}
+		tasks[i].GitHubRefs = refs
+	}
+}
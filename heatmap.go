@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// agingBucket は期限切れの日数の列 (Min 日以上 Max 日以下、Max が 0 なら上限なし)
+type agingBucket struct {
+	Min, Max int
+}
+
+// ヒートマップの列。最初の列は期限切れでないタスク
+var agingBuckets = []agingBucket{{0, 0}, {1, 2}, {3, 6}, {7, 13}, {14, 29}, {30, 0}}
+
+func (b agingBucket) label() string {
+	switch {
+	case b.Min == 0:
+		return tr("heatmap.not_overdue")
+	case b.Max == 0:
+		return tr("heatmap.days", fmt.Sprintf("%d+", b.Min))
+	default:
+		return tr("heatmap.days", fmt.Sprintf("%d–%d", b.Min, b.Max))
+	}
+}
+
+// agingBucketIndex は期限切れの日数が入る列を返す
+func agingBucketIndex(overdueDays int) int {
+	for i, b := range agingBuckets[1:] {
+		if overdueDays >= b.Min && (b.Max == 0 || overdueDays <= b.Max) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// agingHeatmap は優先度 (行) と期限切れの日数 (列) ごとのタスクの数
+type agingHeatmap struct {
+	At         time.Time // 元にした実行の時刻
+	Priorities []string  // 優先度の高い順。優先度の無いタスクがあれば最後に空文字列
+	Counts     [][]int   // [優先度][列]
+	Max        int
+	Total      int
+}
+
+// agingHeatmap は now までの最後の実行で掲載された期限のあるタスクを、優先度と期限切れの日数で数える
+func (h *history) agingHeatmap(now time.Time) (*agingHeatmap, error) {
+	var lastRun *historyRun
+	for i := range h.Runs {
+		if !h.Runs[i].At.After(now) {
+			lastRun = &h.Runs[i]
+		}
+	}
+	if lastRun == nil {
+		return nil, fmt.Errorf("no digest history before %s", now.Format(time.DateOnly))
+	}
+
+	m := &agingHeatmap{At: lastRun.At}
+	m.Priorities = priorityNames()
+	rows := map[string][]int{}
+	for _, t := range lastRun.Tasks {
+		if t.Due == nil {
+			continue
+		}
+		if _, ok := rows[t.Priority]; !ok {
+			rows[t.Priority] = make([]int, len(agingBuckets))
+			if _, known := priorityRank(t.Priority); !known {
+				m.Priorities = append(m.Priorities, t.Priority)
+			}
+		}
+		// 日付だけの期限日は now のタイムゾーンの日付として数える
+		overdueDays := daysBetween(dueDay(*t.Due, now.Location()), now)
+		rows[t.Priority][agingBucketIndex(overdueDays)]++
+		m.Total++
+	}
+	for _, p := range m.Priorities {
+		counts := rows[p]
+		if counts == nil {
+			counts = make([]int, len(agingBuckets))
+		}
+		m.Counts = append(m.Counts, counts)
+		m.Max = max(m.Max, slices.Max(counts))
+	}
+	return m, nil
+}
+
+// priorityLabel は行の見出しを返す
+func (m *agingHeatmap) priorityLabel(i int) string {
+	if m.Priorities[i] == "" {
+		return tr("heatmap.no_priority")
+	}
+	return m.Priorities[i]
+}
+
+// cellColor は数が多いほど濃い赤にする (0 は白)
+func (m *agingHeatmap) cellColor(count int) color.RGBA {
+	if count == 0 || m.Max == 0 {
+		return color.RGBA{255, 255, 255, 255}
+	}
+	shade := uint8(225 - 175*count/m.Max)
+	return color.RGBA{255, shade, shade, 255}
+}
+
+// legend は行と列の並びを 1 行で返す (ラベルの無い PNG に添える)
+func (m *agingHeatmap) legend() string {
+	var rows, cols []string
+	for i := range m.Priorities {
+		rows = append(rows, m.priorityLabel(i))
+	}
+	for _, b := range agingBuckets {
+		cols = append(cols, b.label())
+	}
+	return tr("heatmap.legend", strings.Join(rows, ", "), strings.Join(cols, ", "))
+}
+
+var heatmapHTMLTemplate = template.Must(template.New("heatmap").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 24px; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 8px 12px; text-align: center; }
+td.zero { color: #bbb; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Caption}}</p>
+<table>
+<tr><th>{{.Corner}}</th>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr><th>{{.Label}}</th>{{range .Cells}}<td{{if eq .Count 0}} class="zero"{{end}} style="background: {{.Color}}">{{.Count}}</td>{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// writeHTML は見出し付きの表としてヒートマップを書き出す
+func (m *agingHeatmap) writeHTML(w io.Writer) error {
+	type cell struct {
+		Count int
+		Color template.CSS
+	}
+	type row struct {
+		Label string
+		Cells []cell
+	}
+	data := struct {
+		Title, Caption, Corner string
+		Columns                []string
+		Rows                   []row
+	}{
+		Title:   tr("heatmap.title"),
+		Caption: tr("heatmap.caption", m.Total, m.At.Format("2006-01-02 15:04")),
+		Corner:  tr("task.priority"),
+	}
+	for _, b := range agingBuckets {
+		data.Columns = append(data.Columns, b.label())
+	}
+	for i, counts := range m.Counts {
+		r := row{Label: m.priorityLabel(i)}
+		for _, n := range counts {
+			c := m.cellColor(n)
+			r.Cells = append(r.Cells, cell{Count: n, Color: template.CSS(fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B))})
+		}
+		data.Rows = append(data.Rows, r)
+	}
+	return heatmapHTMLTemplate.Execute(w, data)
+}
+
+// PNG のマス目の大きさと数字の描画
+const (
+	heatmapCell       = 48
+	heatmapDigitScale = 3
+)
+
+// 3x5 の数字のビットマップ (PNG のマス目に数を描く)
+var heatmapDigits = [10][5]string{
+	{"###", "#.#", "#.#", "#.#", "###"},
+	{".#.", "##.", ".#.", ".#.", "###"},
+	{"###", "..#", "###", "#..", "###"},
+	{"###", "..#", "###", "..#", "###"},
+	{"#.#", "#.#", "###", "..#", "..#"},
+	{"###", "#..", "###", "..#", "###"},
+	{"###", "#..", "###", "#.#", "###"},
+	{"###", "..#", "..#", "..#", "..#"},
+	{"###", "#.#", "###", "#.#", "###"},
+	{"###", "#.#", "###", "..#", "###"},
+}
+
+// writePNG はマス目に数を描いた画像を書き出す。行と列の見出しは描かない (legend を添える)
+func (m *agingHeatmap) writePNG(w io.Writer) error {
+	cols, rows := len(agingBuckets), len(m.Counts)
+	img := image.NewRGBA(image.Rect(0, 0, cols*heatmapCell+1, rows*heatmapCell+1))
+	grid := color.RGBA{204, 204, 204, 255}
+	for y := range img.Bounds().Dy() {
+		for x := range img.Bounds().Dx() {
+			img.Set(x, y, grid)
+		}
+	}
+	for r, counts := range m.Counts {
+		for c, n := range counts {
+			x0, y0 := c*heatmapCell, r*heatmapCell
+			fill := m.cellColor(n)
+			for y := y0 + 1; y < y0+heatmapCell; y++ {
+				for x := x0 + 1; x < x0+heatmapCell; x++ {
+					img.Set(x, y, fill)
+				}
+			}
+			if n > 0 {
+				drawHeatmapNumber(img, n, x0+heatmapCell/2, y0+heatmapCell/2)
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// drawHeatmapNumber は (cx, cy) を中心に数を描く
+func drawHeatmapNumber(img *image.RGBA, n, cx, cy int) {
+	text := fmt.Sprint(n)
+	digitW := 4 * heatmapDigitScale // 3 ドットと 1 ドットの間隔
+	x0 := cx - (len(text)*digitW-heatmapDigitScale)/2
+	y0 := cy - 5*heatmapDigitScale/2
+	ink := color.RGBA{40, 40, 40, 255}
+	for i, ch := range text {
+		for row, bits := range heatmapDigits[ch-'0'] {
+			for col, bit := range bits {
+				if bit != '#' {
+					continue
+				}
+				for dy := range heatmapDigitScale {
+					for dx := range heatmapDigitScale {
+						img.Set(x0+i*digitW+col*heatmapDigitScale+dx, y0+row*heatmapDigitScale+dy, ink)
+					}
+				}
+			}
+		}
+	}
+}
+
+// encode は拡張子 (.html か .png) に合わせてヒートマップを書き出す
+func (m *agingHeatmap) encode(path string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		err = m.writeHTML(&buf)
+	case ".png":
+		err = m.writePNG(&buf)
+	default:
+		return nil, fmt.Errorf("unknown heatmap format %q (use .html or .png)", filepath.Ext(path))
+	}
+	return buf.Bytes(), err
+}
+
+var exportHeatmapCmd = &cobra.Command{
+	Use:   "heatmap",
+	Short: "Write a heatmap of overdue days by priority from the digest history as HTML or PNG.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out, _ := cmd.Flags().GetString("out")
+		clock, err := clockFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		historyStore := historyStoreFromEnv()
+		if historyStore == nil {
+			return fmt.Errorf("%s must be set", historyFileEnv)
+		}
+		h, err := historyStore.Load()
+		if err != nil {
+			return err
+		}
+		m, err := h.agingHeatmap(clock.Now())
+		if err != nil {
+			return err
+		}
+		data, err := m.encode(out)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(out, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write heatmap: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s (%d tasks)\n", out, m.Total)
+		return nil
+	},
+}
+
+func init() {
+	exportHeatmapCmd.Flags().String("out", "heatmap.html", "Output file; the extension (.html or .png) selects the format")
+	exportCmd.AddCommand(exportHeatmapCmd)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestAgingHeatmapCountsCalendarDays は期限切れの日数を実行したタイムゾーンの日付で数えることを確かめる
+func TestAgingHeatmapCountsCalendarDays(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	date := func(y int, m time.Month, d int) *time.Time {
+		v := time.Date(y, m, d, 0, 0, 0, 0, time.UTC) // 日付だけの期限日
+		return &v
+	}
+	tests := []struct {
+		name string
+		now  time.Time
+		due  *time.Time
+		want int // 列
+	}{
+		{"JST due today", time.Date(2026, 10, 17, 8, 0, 0, 0, jst), date(2026, 10, 17), 0},
+		{"JST one day overdue", time.Date(2026, 10, 17, 8, 0, 0, 0, jst), date(2026, 10, 16), 1},
+		{"JST three days overdue", time.Date(2026, 10, 17, 23, 0, 0, 0, jst), date(2026, 10, 14), 2},
+		{"JST timed task due yesterday evening", time.Date(2026, 10, 17, 8, 0, 0, 0, jst), ptr(time.Date(2026, 10, 16, 22, 0, 0, 0, jst)), 1},
+		{"across the end of daylight saving time", time.Date(2026, 11, 8, 9, 0, 0, 0, ny), date(2026, 11, 1), 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &history{Runs: []historyRun{{At: tt.now, Tasks: []historyTask{{ID: "page-1", Priority: topPriority(), Due: tt.due}}}}}
+			m, err := h.agingHeatmap(tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := slices.Index(m.Counts[0], 1); got != tt.want {
+				t.Errorf("task is in column %d (%v), want %d", got, m.Counts[0], tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 履歴ファイル
+const (
+	historyFileEnv = "NOTIFYER_HISTORY_FILE"
+	// 履歴を保持する期間
+	historyRetention = 180 * 24 * time.Hour
+	// 履歴ファイルのスキーマバージョン
+	historyVersion = 1
+)
+
+// history はダイジェストに掲載したタスクの実行ごとの記録
+type history struct {
+	Version int          `json:"version"`
+	Runs    []historyRun `json:"runs"`
+}
+
+// historyRun は 1 回の実行で掲載したタスク
+type historyRun struct {
+	At    time.Time     `json:"at"`
+	Tasks []historyTask `json:"tasks"`
+}
+
+// historyTask は掲載時点のタスクのスナップショット
+type historyTask struct {
+	ID       string     `json:"id"`
+	Key      string     `json:"key,omitempty"` // ユニーク ID (properties.unique_id を設定した場合のみ。照合には ID を使う)
+	Title    string     `json:"title"`
+	Due      *time.Time `json:"due,omitempty"`
+	Priority string     `json:"priority,omitempty"`
+	Status   string     `json:"status,omitempty"`
+}
+
+// historyStore は history を JSON ファイルとして読み書きする
+type historyStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// historyStoreFromEnv は NOTIFYER_HISTORY_FILE が設定されていれば historyStore を返す
+func historyStoreFromEnv() *historyStore {
+	path := os.Getenv(historyFileEnv)
+	if path == "" {
+		return nil
+	}
+	return &historyStore{path: statePath(path)}
+}
+
+// Load は履歴を読み込む。ファイルが無い場合は空の履歴を返す
+func (s *historyStore) Load() (*history, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+// Record は実行の記録を追加し、保持期間を過ぎた記録を削除する
+func (s *historyStore) Record(at time.Time, tasks []Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	h.Runs = append(h.Runs, newHistoryRun(at, tasks))
+	return s.save(h, at)
+}
+
+// Backfill は再構成した実行の記録を追加する
+// 既に記録がある日の実行は追加せず、追加した件数を返す
+func (s *historyStore) Backfill(runs []historyRun, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, err := s.load()
+	if err != nil {
+		return 0, err
+	}
+
+	recorded := map[string]bool{}
+	for _, r := range h.Runs {
+		recorded[r.At.In(now.Location()).Format("2006-01-02")] = true
+	}
+	added := 0
+	for _, r := range runs {
+		if recorded[r.At.In(now.Location()).Format("2006-01-02")] {
+			continue
+		}
+		h.Runs = append(h.Runs, r)
+		added++
+	}
+	sort.SliceStable(h.Runs, func(i, j int) bool { return h.Runs[i].At.Before(h.Runs[j].At) })
+
+	return added, s.save(h, now)
+}
+
+// save は保持期間を過ぎた記録を削除して保存する
+func (s *historyStore) save(h *history, now time.Time) error {
+	cutoff := now.Add(-historyRetention)
+	kept := h.Runs[:0]
+	for _, r := range h.Runs {
+		if r.At.After(cutoff) {
+			kept = append(kept, r)
+		}
+	}
+	h.Runs = kept
+
+	data, err := json.Marshal(h)
+	if err != nil {
+		return fmt.Errorf("failed to encode history: %w", err)
+	}
+	if err := writeFileAtomic(s.path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to save history: %w", err)
+	}
+	return nil
+}
+
+func newHistoryRun(at time.Time, tasks []Task) historyRun {
+	run := historyRun{At: at, Tasks: []historyTask{}}
+	for _, task := range tasks {
+		run.Tasks = append(run.Tasks, historyTask{
+			ID:       string(task.ID),
+			Key:      task.UniqueID,
+			Title:    task.Title,
+			Due:      getTargetDueDate(task),
+			Priority: task.Priority,
+			Status:   task.ScheduleStatus,
+		})
+	}
+	return run
+}
+
+// key は記録したタスクを見分けるキーを返す (Task.Key と同じくページ ID。ユニーク ID を設定する前の記録とも照合できる)
+func (t historyTask) key() string {
+	return t.ID
+}
+
+func (s *historyStore) load() (*history, error) {
+	h := &history{Version: historyVersion}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("failed to parse history file %s: %w", s.path, err)
+	}
+	// 新しいバイナリが書いた履歴は、知らない項目を消してしまわないよう読まない
+	if h.Version > historyVersion {
+		return nil, fmt.Errorf("history file %s has version %d, newer than this binary supports (%d)", s.path, h.Version, historyVersion)
+	}
+	return h, nil
+}
+
+// appearanceStreaks はタスクごとに、now の日を含めて何日連続で掲載されているかを返す
+// 今回の実行で掲載するタスクは now の日に掲載されたものとして数える
+func (h *history) appearanceStreaks(tasks []Task, now time.Time) map[string]int {
+	days := map[string]map[string]bool{} // 日付 → 掲載したタスクのキー
+	for _, run := range h.Runs {
+		day := run.At.In(now.Location()).Format("2006-01-02")
+		if days[day] == nil {
+			days[day] = map[string]bool{}
+		}
+		for _, t := range run.Tasks {
+			days[day][t.key()] = true
+		}
+	}
+
+	streaks := map[string]int{}
+	for _, task := range tasks {
+		id := task.Key()
+		streak := 1
+		for d := startOfDay(now).AddDate(0, 0, -1); days[d.Format("2006-01-02")][id]; d = d.AddDate(0, 0, -1) {
+			streak++
+		}
+		streaks[id] = streak
+	}
+	return streaks
+}
+
+// taskSlip は期限の延期回数と最初に記録された期限
+type taskSlip struct {
+	Count       int
+	OriginalDue time.Time
+}
+
+// dueSlips はタスクごとに、履歴に記録された期限から何回後ろにずれたかを返す
+// 現在の期限も最後の記録として数える。延期されていないタスクは含まない
+func (h *history) dueSlips(tasks []Task) map[string]taskSlip {
+	recorded := map[string][]time.Time{}
+	for _, run := range h.Runs {
+		for _, t := range run.Tasks {
+			if t.Due != nil {
+				recorded[t.key()] = append(recorded[t.key()], *t.Due)
+			}
+		}
+	}
+
+	slips := map[string]taskSlip{}
+	for _, task := range tasks {
+		id := task.Key()
+		dues := recorded[id]
+		if len(dues) == 0 {
+			continue
+		}
+		if due := getTargetDueDate(task); due != nil {
+			dues = append(dues, *due)
+		}
+		slip := taskSlip{OriginalDue: dues[0]}
+		for i := 1; i < len(dues); i++ {
+			if dues[i].After(dues[i-1]) {
+				slip.Count++
+			}
+		}
+		if slip.Count > 0 {
+			slips[id] = slip
+		}
+	}
+	return slips
+}
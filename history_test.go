@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jomei/notionapi"
+
+	"rainierrr/notion-notifyer/pkg/task"
+)
+
+// TestHistoryMatchesRecordsWithoutUniqueID は properties.unique_id を後から設定しても、それ以前の記録と照合できることを確かめる
+func TestHistoryMatchesRecordsWithoutUniqueID(t *testing.T) {
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	due := func(days int) *time.Time { d := now.AddDate(0, 0, days); return &d }
+	h := &history{Runs: []historyRun{
+		{At: now.AddDate(0, 0, -2), Tasks: []historyTask{{ID: "page-1", Title: "Task", Due: due(-1)}}},
+		{At: now.AddDate(0, 0, -1), Tasks: []historyTask{{ID: "page-1", Key: "TASK-1", Title: "Task", Due: due(0)}}},
+	}}
+	current := notionapi.Date(*due(1))
+	tasks := []Task{{Task: task.Task{ID: "page-1", UniqueID: "TASK-1", Title: "Task", DueStart: &current}}}
+
+	if got := h.appearanceStreaks(tasks, now)[tasks[0].Key()]; got != 3 {
+		t.Errorf("streak = %d, want 3", got)
+	}
+	if got := h.dueSlips(tasks)[tasks[0].Key()]; got.Count != 2 || !got.OriginalDue.Equal(*due(-1)) {
+		t.Errorf("slip = %+v, want 2 slips from %v", got, *due(-1))
+	}
+}
+
+func TestDetectAlertsReadsUniqueIDKeyedRecords(t *testing.T) {
+	tasks := []Task{{Task: task.Task{ID: "page-1", UniqueID: "TASK-1", Priority: topPriority()}}}
+	for name, prev := range map[string]map[string]*alertTask{
+		"page ID":   {"page-1": {Priority: "Low"}},
+		"unique ID": {"TASK-1": {Priority: "Low"}},
+	} {
+		if got := detectAlerts(prev, tasks, nil); len(got) != 1 {
+			t.Errorf("%s: got %d alerts, want 1", name, len(got))
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// メッセージの言語
+const (
+	langEnv     = "NOTIFYER_LANG"
+	defaultLang = "ja"
+)
+
+// 言語ごとのメッセージカタログ (locales/<言語>.yaml)
+//
+//go:embed locales/*.yaml
+var localeFiles embed.FS
+
+// catalogs は言語 → キー → メッセージ
+// 他のファイルの init (埋め込みの defaults.yaml の検証) から使うのでパッケージ変数の初期化で読み込む
+var catalogs = loadCatalogs()
+
+// 実行中のメッセージの言語
+var currentLang = defaultLang
+
+// availableLangs は使える言語の一覧を返す
+func availableLangs() []string {
+	var langs []string
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	slices.Sort(langs)
+	return langs
+}
+
+// applyLanguage は --lang (未指定なら NOTIFYER_LANG) の言語をメッセージに使う
+func applyLanguage(lang string) error {
+	if lang == "" {
+		lang = os.Getenv(langEnv)
+	}
+	if lang == "" {
+		lang = defaultLang
+	}
+	if _, ok := catalogs[lang]; !ok {
+		return fmt.Errorf("unsupported language %q (available: %s)", lang, strings.Join(availableLangs(), ", "))
+	}
+	currentLang = lang
+	return nil
+}
+
+// hasMessage はカタログにキーがあるかを返す
+func hasMessage(key string) bool {
+	_, ok := catalogs[defaultLang][key]
+	return ok
+}
+
+// tr は実行中の言語のメッセージを返す。args があればメッセージを書式として使う
+// 実行中の言語のカタログに無ければ既定の言語、それにも無ければキーをそのまま返す
+func tr(key string, args ...any) string {
+	format, ok := catalogs[currentLang][key]
+	if !ok {
+		format, ok = catalogs[defaultLang][key]
+	}
+	if !ok {
+		format = key
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// loadCatalogs は埋め込みのメッセージカタログを読み込む
+func loadCatalogs() map[string]map[string]string {
+	catalogs := map[string]map[string]string{}
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("failed to read embedded locales: %v", err))
+	}
+	for _, entry := range entries {
+		data, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("failed to read embedded locale %s: %v", entry.Name(), err))
+		}
+		catalog := map[string]string{}
+		if err := yaml.Unmarshal(data, &catalog); err != nil {
+			panic(fmt.Sprintf("invalid embedded locale %s: %v", entry.Name(), err))
+		}
+		catalogs[strings.TrimSuffix(entry.Name(), ".yaml")] = catalog
+	}
+	return catalogs
+}
+
+func init() {
+	rootCmd.PersistentFlags().String("lang", "", fmt.Sprintf("Language of the messages (%s; default $%s or %s)", strings.Join(availableLangs(), ", "), langEnv, defaultLang))
+}
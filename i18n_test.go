@@ -0,0 +1,25 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestCatalogsHaveSameKeys はどの言語のカタログにも既定の言語と同じキーがあることを確かめる
+func TestCatalogsHaveSameKeys(t *testing.T) {
+	for lang, catalog := range catalogs {
+		for key := range catalogs[defaultLang] {
+			if _, ok := catalog[key]; !ok {
+				t.Errorf("%s catalog is missing %s", lang, key)
+			}
+		}
+		for key := range catalog {
+			if !hasMessage(key) {
+				t.Errorf("%s catalog has %s, which is not in the %s catalog", lang, key, defaultLang)
+			}
+		}
+	}
+	if !slices.Contains(availableLangs(), "en") {
+		t.Error("en catalog is not embedded")
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/spf13/cobra"
+)
+
+// Slack のインタラクション (ボタンやリアクションなど) 関連
+const (
+	slackSigningSecretEnv = "SLACK_SIGNING_SECRET"
+	// ハンドラーの実行に許す時間
+	interactionTimeout = 2 * time.Minute
+)
+
+// interactionHandler はボタンなどの操作を処理する
+// Slack は 3 秒以内の応答を求めるため、ハンドラーは応答を返した後に非同期で実行する
+type interactionHandler func(ctx context.Context, env *interactionEnv, callback slack.InteractionCallback, action *slack.BlockAction) error
+
+// interactionHandlers は action_id ごとのハンドラー
+var interactionHandlers = map[string]interactionHandler{}
+
+// registerInteraction は action_id のハンドラーを登録する
+func registerInteraction(actionID string, handler interactionHandler) {
+	interactionHandlers[actionID] = handler
+}
+
+// interactionEnv はハンドラーが使う設定
+type interactionEnv struct {
+	store *stateStore
+	clock Clock
+}
+
+// slackClient は操作が行われたワークスペースの Slack クライアントを返す
+// install で追加されたワークスペース (または Enterprise Grid の組織) ならそのトークン、そうでなければ環境変数のトークンを使う
+func (e *interactionEnv) slackClient(ctx context.Context, teamID string) (*slack.Client, error) {
+	tokens, err := e.tokenSource(teamID)
+	if err != nil {
+		return nil, err
+	}
+	return tokens.Client(ctx)
+}
+
+// tokenSource は操作が行われたワークスペースのトークンを返す slackTokenSource を作る
+func (e *interactionEnv) tokenSource(teamID string) (*slackTokenSource, error) {
+	st, err := e.store.Load()
+	if err != nil {
+		return nil, err
+	}
+	if key, inst := st.installationFor(teamID); inst != nil {
+		return newInstallationTokenSource(e.clock, e.store, key, inst.Tokens), nil
+	}
+	return newSlackTokenSourceFromEnv(e.clock)
+}
+
+type interactionServer struct {
+	signingSecret string
+	env           *interactionEnv
+}
+
+func (s *interactionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	// 署名を検証し、Slack 以外からのリクエストを拒否する
+	verifier, err := slack.NewSecretsVerifier(r.Header, s.signingSecret)
+	if err != nil {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	if _, err := verifier.Write(body); err != nil || verifier.Ensure() != nil {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	var callback slack.InteractionCallback
+	if err := json.Unmarshal([]byte(r.PostFormValue("payload")), &callback); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	s.dispatch(callback)
+}
+
+// dispatch は操作に対応するハンドラーを非同期で実行する (HTTP と Socket Mode で共通)
+func (s *interactionServer) dispatch(callback slack.InteractionCallback) {
+	switch {
+	case callback.Type == slack.InteractionTypeWorkflowStepEdit:
+		s.handleAsync("workflow_step_edit", func(ctx context.Context) error { return openWorkflowStepConfig(ctx, s.env, callback) })
+		return
+	case callback.Type == slack.InteractionTypeViewSubmission && callback.View.Type == slack.VTWorkflowStep:
+		s.handleAsync("workflow_step_save", func(ctx context.Context) error { return saveWorkflowStepConfig(ctx, s.env, callback) })
+		return
+	case callback.Type != slack.InteractionTypeBlockActions:
+		return
+	}
+	for _, action := range callback.ActionCallback.BlockActions {
+		handler, ok := interactionHandlers[action.ActionID]
+		if !ok {
+			log.Printf("Warning: No handler for action %s", action.ActionID)
+			continue
+		}
+		s.handleAsync(action.ActionID, func(ctx context.Context) error { return handler(ctx, s.env, callback, action) })
+	}
+}
+
+// handleAsync は応答を返した後にハンドラーを実行する
+func (s *interactionServer) handleAsync(name string, fn func(ctx context.Context) error) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), interactionTimeout)
+		defer cancel()
+		if err := fn(ctx); err != nil {
+			log.Printf("Interaction error (%s): %v", name, err)
+		}
+	}()
+}
+
+var interactionsCmd = &cobra.Command{
+	Use:   "interactions",
+	Short: "Handle Slack interactions (buttons, reactions and workflow steps) on digest messages and the open slash command, over HTTP or Socket Mode.",
+	Long: `Handle Slack interactions on digest messages and the open slash command.
+
+By default an HTTP server receives them at /slack/interactions, /slack/events and /slack/commands
+and verifies them with SLACK_SIGNING_SECRET. When SLACK_APP_TOKEN (xapp-...) is set, they are
+received over Socket Mode instead, so no public Request URL or signing secret is needed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		addr, _ := cmd.Flags().GetString("addr")
+		reactionStatuses, _ := cmd.Flags().GetStringToString("reaction-status")
+		appToken := os.Getenv(slackAppTokenEnv)
+		signingSecret := os.Getenv(slackSigningSecretEnv)
+		if appToken == "" && signingSecret == "" {
+			return fmt.Errorf("%s (HTTP) or %s (Socket Mode) must be set", slackSigningSecretEnv, slackAppTokenEnv)
+		}
+
+		env := &interactionEnv{store: stateStoreFromEnv(), clock: systemClock{}}
+		interactions := &interactionServer{signingSecret: signingSecret, env: env}
+		events := &eventsServer{signingSecret: signingSecret, env: env, statuses: parseReactionStatuses(reactionStatuses)}
+		commands := &commandsServer{signingSecret: signingSecret, env: env}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if appToken != "" {
+			return runSocketMode(ctx, appToken, interactions, events, commands)
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("/slack/interactions", interactions)
+		mux.Handle("/slack/events", events)
+		mux.Handle("/slack/commands", commands)
+		server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = server.Shutdown(shutdownCtx)
+		}()
+
+		log.Printf("Slack interaction server listening on %s (Request URLs: /slack/interactions, /slack/events, /slack/commands)", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		log.Println("Slack interaction server stopped.")
+		return nil
+	},
+}
+
+func init() {
+	interactionsCmd.Flags().String("addr", ":3001", "Address for the interaction HTTP server (ignored in Socket Mode)")
+	interactionsCmd.Flags().StringToString("reaction-status", defaultReactionStatuses, "Reaction emoji to Schedule Status mapping for task thread messages")
+	rootCmd.AddCommand(interactionsCmd)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"rainierrr/notion-notifyer/pkg/notion"
+)
+
+// Jira 関連
+const (
+	jiraBaseURLEnv  = "JIRA_BASE_URL" // 例: https://example.atlassian.net
+	jiraEmailEnv    = "JIRA_EMAIL"
+	jiraAPITokenEnv = "JIRA_API_TOKEN"
+)
+
+var jiraKeyPattern = regexp.MustCompile(`\b([A-Z][A-Z0-9]+)-(\d+)\b`)
+
+// Jira のキーを探すプロパティ (--jira-properties、空ならタイトル・メモ・リンクのプロパティ)
+var jiraProps []string
+
+// jiraLookupProps はタイトル・メモ・リンク以外に、Jira のキーを探すために取得するプロパティを返す
+func jiraLookupProps() []string {
+	var props []string
+	for _, name := range jiraProps {
+		if name != nameProp && name != memoProp && name != linkProp && !slices.Contains(props, name) {
+			props = append(props, name)
+		}
+	}
+	return props
+}
+
+// jiraSearchTexts はタスクの Jira のキーを探すプロパティの文字列を返す
+// タイトル・メモ・リンク以外のプロパティは種類に合わせて文字列にする (notion.PlainText)
+func jiraSearchTexts(t Task) []string {
+	if len(jiraProps) == 0 {
+		return []string{t.Title, t.Memo, t.Link}
+	}
+	var texts []string
+	for _, name := range jiraProps {
+		switch name {
+		case nameProp:
+			texts = append(texts, t.Title)
+		case memoProp:
+			texts = append(texts, t.Memo)
+		case linkProp:
+			texts = append(texts, t.Link)
+		default:
+			for _, field := range slices.Concat(t.Lookup, t.Extra) {
+				if field.Name == name {
+					if text, ok := notion.PlainText(field.Value); ok {
+						texts = append(texts, text)
+					}
+					break
+				}
+			}
+		}
+	}
+	return texts
+}
+
+// JiraRef はタスクに含まれる Jira の課題とその状態
+type JiraRef struct {
+	Key      string
+	URL      string
+	Status   string // Jira 上のステータス名 (取得できなければ空)
+	Category string // new, indeterminate, done
+}
+
+// Chip はステータスカテゴリを表す絵文字付きの表示を返す
+func (r JiraRef) Chip() string {
+	if r.Status == "" {
+		return "❔ unknown"
+	}
+	switch r.Category {
+	case "done":
+		return "✅ " + r.Status
+	case "indeterminate":
+		return "🔵 " + r.Status
+	}
+	return "⚪ " + r.Status
+}
+
+// findJiraKeys はテキストから Jira の課題キーを重複なく取り出す
+// projects が空でなければ、そのプロジェクトのキーだけを対象にする (UTF-8 などの誤検出を防ぐ)
+func findJiraKeys(projects map[string]bool, texts ...string) []string {
+	var keys []string
+	seen := map[string]bool{}
+	for _, text := range texts {
+		for _, m := range jiraKeyPattern.FindAllStringSubmatch(text, -1) {
+			if len(projects) > 0 && !projects[m[1]] {
+				continue
+			}
+			if seen[m[0]] {
+				continue
+			}
+			seen[m[0]] = true
+			keys = append(keys, m[0])
+		}
+	}
+	return keys
+}
+
+type jiraClient struct {
+	baseURL    string
+	email      string
+	token      string
+	projects   map[string]bool
+	httpClient *http.Client
+	cache      map[string]JiraRef
+}
+
+func newJiraClientFromEnv(projects []string) (*jiraClient, error) {
+	c := &jiraClient{
+		baseURL:    strings.TrimRight(os.Getenv(jiraBaseURLEnv), "/"),
+		email:      os.Getenv(jiraEmailEnv),
+		token:      os.Getenv(jiraAPITokenEnv),
+		projects:   map[string]bool{},
+		httpClient: http.DefaultClient,
+		cache:      map[string]JiraRef{},
+	}
+	if c.baseURL == "" || c.email == "" || c.token == "" {
+		return nil, fmt.Errorf("%s, %s and %s must be set", jiraBaseURLEnv, jiraEmailEnv, jiraAPITokenEnv)
+	}
+	for _, p := range projects {
+		c.projects[strings.ToUpper(strings.TrimSpace(p))] = true
+	}
+	return c, nil
+}
+
+// issue は課題のステータスを取得する
+func (c *jiraClient) issue(ctx context.Context, key string) (JiraRef, error) {
+	if ref, ok := c.cache[key]; ok {
+		return ref, nil
+	}
+
+	ref := JiraRef{Key: key, URL: c.baseURL + "/browse/" + key}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/rest/api/2/issue/"+url.PathEscape(key)+"?fields=status", nil)
+	if err != nil {
+		return ref, err
+	}
+	req.SetBasicAuth(c.email, c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return ref, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ref, fmt.Errorf("jira returned %s for %s", resp.Status, key)
+	}
+
+	var body struct {
+		Fields struct {
+			Status struct {
+				Name           string `json:"name"`
+				StatusCategory struct {
+					Key string `json:"key"`
+				} `json:"statusCategory"`
+			} `json:"status"`
+		} `json:"fields"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return ref, err
+	}
+	ref.Status = body.Fields.Status.Name
+	ref.Category = body.Fields.Status.StatusCategory.Key
+
+	c.cache[key] = ref
+	return ref, nil
+}
+
+// applyJiraStatus はタスクのプロパティ (--jira-properties、既定はタイトル・Memo・Link) に含まれる Jira の課題の状態を取得してタスクに設定する
+// 存在しないキー (誤検出) は表示しない
+func applyJiraStatus(ctx context.Context, tasks []Task, client *jiraClient) {
+	for i := range tasks {
+		var refs []JiraRef
+		for _, key := range findJiraKeys(client.projects, jiraSearchTexts(tasks[i])...) {
+			ref, err := client.issue(ctx, key)
+			if err != nil {
+				log.Printf("Warning: Unable to get Jira status for %s: %v", key, err)
+				continue
+			}
+			refs = append(refs, ref)
+		}
+		tasks[i].JiraRefs = refs
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jomei/notionapi"
+
+	"rainierrr/notion-notifyer/pkg/task"
+)
+
+// TestJiraSearchTexts は --jira-properties のプロパティから Jira のキーを探すことを確かめる
+func TestJiraSearchTexts(t *testing.T) {
+	t.Cleanup(func() { jiraProps = nil })
+	tk := Task{Task: task.Task{
+		Title:  "Fix PROJ-1",
+		Memo:   "see PROJ-2",
+		Link:   "https://example.atlassian.net/browse/PROJ-3",
+		Lookup: []task.Field{{Name: "Jira", Value: &notionapi.RichTextProperty{Type: notionapi.PropertyTypeRichText, RichText: []notionapi.RichText{{PlainText: "PROJ-4"}}}}},
+		Extra:  []task.Field{{Name: "Ticket", Value: &notionapi.URLProperty{Type: notionapi.PropertyTypeURL, URL: "https://example.atlassian.net/browse/OPS-5"}}},
+	}}
+	tests := []struct {
+		props []string
+		want  []string
+	}{
+		{nil, []string{"PROJ-1", "PROJ-2", "PROJ-3"}},
+		{[]string{"Jira"}, []string{"PROJ-4"}},
+		{[]string{nameProp, "Jira", "Ticket", "Missing"}, []string{"PROJ-1", "PROJ-4", "OPS-5"}},
+	}
+	for _, tt := range tests {
+		jiraProps = tt.props
+		if got := findJiraKeys(nil, jiraSearchTexts(tk)...); !slices.Equal(got, tt.want) {
+			t.Errorf("--jira-properties %v: keys %v, want %v", tt.props, got, tt.want)
+		}
+	}
+	jiraProps = []string{nameProp, memoProp, "Jira", "Jira"}
+	if got := jiraLookupProps(); !slices.Equal(got, []string{"Jira"}) {
+		t.Errorf("lookup properties %v, want only Jira", got)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jomei/notionapi"
+)
+
+// Slack に投稿したタスクに投稿日時を書き込む日付プロパティ (properties.last_notified、空なら書き込まない)
+// Notion 側でどのタスクが通知済みかを確認したり、通知の頻度で絞り込むビューを作ったりできる
+var lastNotifiedProp string
+
+// writeLastNotified は各タスクの lastNotifiedProp に now を書き込み、書き込めた数を返す
+// 1 件の失敗で止めずに残りにも書き込む
+func writeLastNotified(ctx context.Context, client *notionapi.Client, tasks []Task, now time.Time) int {
+	at := notionapi.Date(now)
+	written := 0
+	for _, task := range tasks {
+		_, err := client.Page.Update(ctx, notionapi.PageID(task.ID), &notionapi.PageUpdateRequest{
+			Properties: notionapi.Properties{
+				lastNotifiedProp: notionapi.DateProperty{
+					Type: notionapi.PropertyTypeDate,
+					Date: &notionapi.DateObject{Start: &at},
+				},
+			},
+		})
+		if err != nil {
+			log.Printf("Warning: Unable to write %s to task %s: %v", lastNotifiedProp, task.Title, err)
+			continue
+		}
+		written++
+	}
+	return written
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/jomei/notionapi"
+)
+
+// rewriteTaskURLs はタスクのページ URL をワークスペース固有のドメイン (notion.site や独自ドメイン) に書き換える
+// 既定の notion.so の URL を開けない受信者がいる場合に使う
+func rewriteTaskURLs(tasks []Task, domain string) error {
+	base, err := parseLinkDomain(domain)
+	if err != nil {
+		return err
+	}
+	for i := range tasks {
+		u, err := url.Parse(tasks[i].URL)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		u.Scheme = base.Scheme
+		u.Host = base.Host
+		u.Path = strings.TrimSuffix(base.Path, "/") + u.Path
+		tasks[i].URL = u.String()
+	}
+	return nil
+}
+
+// parseLinkDomain は "example.notion.site" や "https://wiki.example.com/notion" の形式を受け付ける
+func parseLinkDomain(domain string) (*url.URL, error) {
+	if !strings.Contains(domain, "://") {
+		domain = "https://" + domain
+	}
+	u, err := url.Parse(domain)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid link domain %q", domain)
+	}
+	return u, nil
+}
+
+// リンクの表示名の最大の文字数
+const maxLinkLabelLength = 40
+
+// urlLink は URL をホストとパスを表示名にしたリンクにする (https:// と www. は省き、長ければ切り詰める)
+func urlLink(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "<" + raw + ">"
+	}
+	label := truncateText(strings.TrimPrefix(u.Host, "www.")+strings.TrimSuffix(u.Path, "/"), maxLinkLabelLength-1, "…")
+	return fmt.Sprintf("<%s|%s>", raw, label)
+}
+
+// contactDetails はリンク・メール・電話のプロパティを詳細の「*名前:* 値」にする
+func contactDetails(t Task) []string {
+	var details []string
+	if t.Link != "" {
+		details = append(details, fmt.Sprintf("*%s:* %s", tr("task.link"), urlLink(t.Link)))
+	}
+	if t.Email != "" {
+		details = append(details, fmt.Sprintf("*%s:* %s", tr("task.email"), emailLink(t.Email)))
+	}
+	if t.Phone != "" {
+		details = append(details, fmt.Sprintf("*%s:* %s", tr("task.phone"), t.Phone))
+	}
+	return details
+}
+
+func emailLink(email string) string {
+	return fmt.Sprintf("<mailto:%s|%s>", email, email)
+}
+
+func renderEmailProperty(value notionapi.Property) string {
+	if p, ok := value.(*notionapi.EmailProperty); ok && p.Email != "" {
+		return emailLink(p.Email)
+	}
+	return ""
+}
+
+// 電話番号はリンクにせず、そのまま表示する
+func renderPhoneProperty(value notionapi.Property) string {
+	if p, ok := value.(*notionapi.PhoneNumberProperty); ok {
+		return p.PhoneNumber
+	}
+	return ""
+}
+
+func init() {
+	registerPropertyRenderer(notionapi.PropertyTypeEmail, renderEmailProperty)
+	registerPropertyRenderer(notionapi.PropertyTypePhoneNumber, renderPhoneProperty)
+}
+
+// pageIDText はコピーしやすいようにハイフン無しのページ ID を返す
+func pageIDText(task Task) string {
+	return strings.ReplaceAll(string(task.ID), "-", "")
+}
@@ -2,12 +2,15 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
-	"github.com/jomei/notionapi"
-	"github.com/slack-go/slack"
 	"github.com/spf13/cobra"
 )
 
@@ -16,99 +19,353 @@ const (
 	notionTokenEnv  = "NOTION_TOKEN"
 	notionDBIDEnv   = "NOTION_DB_ID" // DB ID は環境変数から取得する想定に変更
 	slackTokenEnv   = "SLACK_BOT_TOKEN"
-	slackChannelEnv = "SLACK_CHANNEL_ID"
+	slackChannelEnv = "SLACK_CHANNEL_ID" // チャンネル ID か #チャンネル名
 )
 
 // Notion タスクのプロパティ名
-const (
-	priorityProp       = "Priority"
-	typeProp           = "Type"
-	scheduleStatusProp = "Schedule Status"
-	workloadProp       = "Workload"
-	memoProp           = "Memo"
-	nameProp           = "Name"
-	dueProp            = "Due"
+// 既定の名前は defaults.yaml にあり、設定ファイルの properties で変更できる
+var (
+	priorityProp       string
+	typeProp           string
+	scheduleStatusProp string
+	workloadProp       string
+	memoProp           string
+	nameProp           string
+	dueProp            string
+	assigneeProp       string
+	tagsProp           string
+	linkProp           string
+	emailProp          string
+	phoneProp          string
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "notion-notifyer",
 	Short: "Notion Notifyer sends Slack notifications for Notion tasks.",
+	// サブコマンドの実行時エラーで使い方を表示しない
+	SilenceUsage: true,
 	Run: func(cmd *cobra.Command, args []string) {
 		log.Println("Starting Notion Notifyer...")
 
+		profiles, _ := cmd.Flags().GetStringSlice("profiles")
+		if len(profiles) > 0 && cmd.Flags().Changed("profile") {
+			fatal("%v", configErrorf("--profiles cannot be combined with --profile"))
+		}
+		if len(profiles) == 0 {
+			if err := applyProfile(cmd); err != nil {
+				fatal("%v", asConfigError(err))
+			}
+		}
+
 		// GitHub Actions Run Numberを取得
 		runNumber := os.Getenv("GITHUB_RUN_NUMBER")
 		if runNumber != "" {
 			log.Printf("GitHub Actions Run Number: %s", runNumber)
 		}
 
-		daysLater, _ := cmd.Flags().GetInt("daysLater")
-		if daysLater > 3 {
-			log.Printf("Warning: daysLater is limited to 3 days maximum. Using 3 instead of %d", daysLater)
-			daysLater = 3
+		watch, _ := cmd.Flags().GetBool("watch")
+		if !watch && (cmd.Flags().Changed("alert-interval") || cmd.Flags().Changed("alert-statuses")) {
+			fatal("%v", configErrorf("--alert-interval and --alert-statuses require --watch"))
+		}
+		if !watch && cmd.Flags().Changed("serve-addr") {
+			fatal("%v", configErrorf("--serve-addr requires --watch"))
+		}
+		if watch {
+			if len(profiles) > 0 {
+				fatal("%v", configErrorf("--profiles cannot be combined with --watch"))
+			}
+			if cmd.Flags().Changed("output") {
+				fatal("%v", configErrorf("--output cannot be combined with --watch"))
+			}
+			if err := watchDigest(cmd); err != nil {
+				fatal("%v", err)
+			}
+			log.Println("Notion Notifyer stopped.")
+			return
 		}
 
-		notionToken := os.Getenv(notionTokenEnv)
-		dbID := os.Getenv(notionDBIDEnv)
-		slackToken := os.Getenv(slackTokenEnv)
-		slackChannelID := os.Getenv(slackChannelEnv)
-
-		if notionToken == "" || dbID == "" || slackToken == "" || slackChannelID == "" {
-			log.Fatalf("Don't set all environment variables: %s, %s, %s, %s", notionTokenEnv, notionDBIDEnv, slackTokenEnv, slackChannelEnv)
+		// 実行中の基準時刻は 1 度だけ取得し、日付の境界計算とグループ分けで共有する
+		clock, err := clockFromFlags(cmd)
+		if err != nil {
+			fatal("%v", err)
 		}
+		retry, err := outageRetryFromFlags(cmd)
+		if err != nil {
+			fatal("%v", err)
+		}
+		// Notion の障害で実行し直すのを待っている間は SIGINT/SIGTERM で止められる
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		makeJob := func() (digestJob, error) {
+			job, err := rootDigestJob(cmd, clock)
+			job.RunNumber = runNumber
+			return job, err
+		}
+		if len(profiles) > 0 {
+			if cmd.Flags().Changed("output") {
+				fatal("%v", configErrorf("--profiles cannot be combined with --output"))
+			}
+			err = runProfiles(ctx, cmd, profiles, clock, retry, runNumber)
+		} else {
+			err = runDigestWithOutageRetry(ctx, makeJob, clock, retry)
+		}
+		if err != nil {
+			var gate *overdueGateError
+			if errors.As(err, &gate) {
+				fatal("Failing because of %v", err)
+			}
+			fatal("Digest error: %v", err)
+		}
+
+		log.Println("Notion Notifyer finished.")
+	},
+}
 
-		notionClient := notionapi.NewClient(notionapi.Token(notionToken))
-		ctx := context.Background()
+// rootDigestJob は環境変数とフラグからルートコマンドのジョブを作る
+// 状態ファイルはトークン更新のたびに変わるため、実行の直前に呼ぶこと
+func rootDigestJob(cmd *cobra.Command, clock Clock) (digestJob, error) {
+	daysLater, err := daysLaterFromFlags(cmd)
+	if err != nil {
+		return digestJob{}, err
+	}
 
-		targetDate := time.Date(
-			time.Now().Year(),
-			time.Now().Month(),
-			time.Now().Day()+daysLater,
-			23, 59, 59, 59,
-			time.Now().Location(),
-		)
+	store := stateStoreFromEnv()
 
-		log.Printf("Get tasks due by %s", targetDate.Format("2006-01-02"))
+	notionToken, dbID, err := notionSourceFromEnv(store)
+	if err != nil {
+		return digestJob{}, asConfigError(err)
+	}
 
-		// Notionからタスクを取得
-		tasks, err := fetchNotionTasks(ctx, notionClient, dbID, targetDate)
-		if err != nil {
-			log.Fatalf("Get Notion tasks error: %v", err)
+	// SLACK_CHANNEL_ID (プロファイルに channel があればそのチャンネル) の投稿先と、install で追加されたワークスペースの投稿先
+	destinations, err := loadSlackDestinations(clock, store, profileChannels(cmd))
+	if err != nil {
+		return digestJob{}, fmt.Errorf("slack destination error: %w", err)
+	}
+	// 種類の絞り込みは環境変数の投稿先にだけ適用する (投稿先ごとの設定は tenants.json で行う)
+	includeTypes, _ := cmd.Flags().GetStringSlice("include-types")
+	excludeTypes, _ := cmd.Flags().GetStringSlice("exclude-types")
+	for i := range destinations {
+		if destinations[i].FromEnv {
+			destinations[i].Types = taskTypeFilter{Include: includeTypes, Exclude: excludeTypes}
 		}
-		log.Printf("Get %d tasks from Notion", len(tasks))
-
-		if len(tasks) == 0 {
-			log.Println("No tasks found.")
-			return
+	}
+	targets, err := targetsFromFlags(cmd.Flags())
+	if err != nil {
+		return digestJob{}, configErrorf("invalid --target: %w", err)
+	}
+	desktop, _ := cmd.Flags().GetBool("desktop")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	// --output ではどこにも送らず、記録もしない
+	var output Notifier
+	if format, _ := cmd.Flags().GetString("output"); format != "" {
+		if format, err = parseOutputFormat(format); err != nil {
+			return digestJob{}, configErrorf("invalid --output: %w", err)
+		}
+		output = outputNotifier{Format: format, Types: taskTypeFilter{Include: includeTypes, Exclude: excludeTypes}, W: os.Stdout}
+		targets = map[string]bool{}
+		desktop, dryRun = false, true
+	}
+	notifiers, err := newNotifiers(targets, cmd.Flags())
+	if err != nil {
+		return digestJob{}, asConfigError(err)
+	}
+	if !targets[targetSlack] {
+		destinations = nil
+	}
+	if len(destinations) == 0 && dryRun && targets[targetSlack] {
+		// 投稿先が無くてもメッセージを確認できるようにする
+		destinations = []slackDestination{{Name: "dry-run", FromEnv: true, Types: taskTypeFilter{Include: includeTypes, Exclude: excludeTypes}}}
+	}
+	// 設定ファイルの routes で、環境変数の投稿先からルートのチャンネルにタスクを分ける
+	destinations = routeDestinations(destinations, configuredRoutes)
+	if len(destinations) == 0 {
+		notifiers = withoutNotifier(notifiers, targetSlack)
+	}
+	if len(destinations) == 0 && !desktop && len(notifiers) == 0 && output == nil {
+		return digestJob{}, configErrorf("no Slack destination: set %s or install the app with the install command", slackChannelEnv)
+	}
+	if output == nil {
+		names := make([]string, 0, len(notifiers)+1)
+		for _, n := range notifiers {
+			names = append(names, n.Name)
 		}
+		if desktop {
+			names = append(names, "desktop")
+		}
+		log.Printf("Sending the digest to: %s", strings.Join(names, ", "))
+	}
 
-		builtedTasks, err := buildSlackBlocks(tasks, runNumber)
+	job := digestJob{
+		Name:         "default",
+		NotionToken:  notionToken,
+		DatabaseID:   dbID,
+		DaysLater:    daysLater,
+		Destinations: destinations,
+		Absences:     absenceConfigFromFlags(cmd),
+		Desktop:      desktop,
+		Notifiers:    notifiers,
+		Output:       output,
+		History:      historyStoreFromEnv(),
+		DryRun:       dryRun,
+	}
+	// --daysLater を指定したときはその日数のままにする
+	if !cmd.Flags().Changed("daysLater") {
+		job.Window = configuredAdaptiveWindow
+	}
+	if job.UsageReport, err = usageReportFromFlags(cmd, clock); err != nil {
+		return digestJob{}, err
+	}
+	job.Assignees, _ = cmd.Flags().GetStringSlice("assignee")
+	job.Tags.Include, _ = cmd.Flags().GetStringSlice("include-tag")
+	job.Tags.Exclude, _ = cmd.Flags().GetStringSlice("exclude-tag")
+	job.Focus, _ = cmd.Flags().GetBool("focus")
+	job.Calendar, _ = cmd.Flags().GetBool("calendar")
+	job.TrackSeen, _ = cmd.Flags().GetBool("track-seen")
+	sectionNames, _ := cmd.Flags().GetStringSlice("sections")
+	if job.Sections, err = parseSections(sectionNames); err != nil {
+		return digestJob{}, configErrorf("invalid --sections: %w", err)
+	}
+	if path, _ := cmd.Flags().GetString("template"); path != "" {
+		if job.Template, err = loadMessageTemplate(path); err != nil {
+			return digestJob{}, asConfigError(err)
+		}
+	}
+	collapsed, _ := cmd.Flags().GetStringSlice("collapse-sections")
+	if job.Collapsed, err = parseCollapsedSections(collapsed); err != nil {
+		return digestJob{}, configErrorf("invalid --collapse-sections: %w", err)
+	}
+	timeOfDay, _ := cmd.Flags().GetStringSlice("time-of-day")
+	if job.TimeOfDay, err = parseTimeOfDaySections(timeOfDay); err != nil {
+		return digestJob{}, configErrorf("invalid --time-of-day: %w", err)
+	}
+	groupBy, _ := cmd.Flags().GetString("group-by")
+	if job.GroupBy, err = parseGroupBy(groupBy); err != nil {
+		return digestJob{}, configErrorf("invalid --group-by: %w", err)
+	}
+	job.WithinHours, _ = cmd.Flags().GetInt("within-hours")
+	job.LinkDomain, _ = cmd.Flags().GetString("link-domain")
+	if job.LinkDomain != "" {
+		if _, err := parseLinkDomain(job.LinkDomain); err != nil {
+			return digestJob{}, configErrorf("invalid --link-domain: %w", err)
+		}
+	}
+	job.ShowPageID, _ = cmd.Flags().GetBool("show-page-id")
+	job.ShortCodes, _ = cmd.Flags().GetBool("short-codes")
+	assigneeDM, _ := cmd.Flags().GetString("assignee-dm")
+	if job.AssigneeDM, err = parseAssigneeDM(assigneeDM); err != nil {
+		return digestJob{}, configErrorf("invalid --assignee-dm: %w", err)
+	}
+	// DM だけを送る場合はチャンネルに投稿しない (投稿先は DM を送るワークスペースとして残す)
+	if job.AssigneeDM == assigneeDMOnly {
+		job.Notifiers = withoutNotifier(job.Notifiers, targetSlack)
+	}
+	if path, _ := cmd.Flags().GetString("slack-user-map"); path != "" {
+		if job.SlackUserMap, err = loadSlackUserMap(path); err != nil {
+			return digestJob{}, asConfigError(err)
+		}
+	}
+	job.MuteButton, _ = cmd.Flags().GetBool("mute-button")
+	if job.MuteButton && job.TrackSeen {
+		return digestJob{}, configErrorf("--mute-button cannot be combined with --track-seen (both use the task's button)")
+	}
+	if actionButtons, _ := cmd.Flags().GetBool("action-buttons"); actionButtons {
+		job.ActionDays, _ = cmd.Flags().GetInt("snooze-days")
+		if job.ActionDays < 1 {
+			return digestJob{}, configErrorf("--snooze-days must be at least 1")
+		}
+	}
+	if job.FailOverdue, err = overdueThresholdFromFlags(cmd); err != nil {
+		return digestJob{}, err
+	}
+	job.Store = store
+	job.GitHubStatus, _ = cmd.Flags().GetBool("github-status")
+	if jiraStatus, _ := cmd.Flags().GetBool("jira-status"); jiraStatus {
+		projects, _ := cmd.Flags().GetStringSlice("jira-projects")
+		job.Jira, err = newJiraClientFromEnv(projects)
 		if err != nil {
-			log.Fatalf("Build Slack blocks error: %v", err)
+			return digestJob{}, configErrorf("jira error: %w", err)
 		}
+	}
+	return job, nil
+}
 
-		slackClient := slack.New(slackToken)
-		_, timestamp, err := slackClient.PostMessage(
-			slackChannelID,
-			slack.MsgOptionBlocks(builtedTasks...),
-		)
+// notionSourceFromEnv は環境変数から Notion のトークンと DB ID を取得する
+// NOTION_TOKEN が無ければ init で保存した OAuth トークンを使う
+// DB ID はカンマ区切りで複数指定でき、NOTION_DB_ID が無ければ設定ファイルの databases を使う
+// /^Tasks - .*/ のようにスラッシュで囲むと、タイトルが一致するデータベースを実行時に検索する
+func notionSourceFromEnv(store *stateStore) (token, dbID string, err error) {
+	token, err = resolveNotionToken(store)
+	if err != nil {
+		return "", "", fmt.Errorf("notion token error: %w", err)
+	}
+	dbID = os.Getenv(notionDBIDEnv)
+	if dbID == "" {
+		dbID = configuredDatabaseIDs()
+	}
+	if token == "" || dbID == "" {
+		return "", "", fmt.Errorf("don't set all environment variables: %s (or run init), %s", notionTokenEnv, notionDBIDEnv)
+	}
+	return token, dbID, nil
+}
 
-		if err != nil {
-			log.Fatalf("Slack message send error: %v", err)
-		}
+// clockFromFlags は --now が指定されていれば固定時刻の Clock を返す
+func clockFromFlags(cmd *cobra.Command) (Clock, error) {
+	nowStr, _ := cmd.Flags().GetString("now")
+	if nowStr == "" {
+		return systemClock{}, nil
+	}
+	fixed, err := time.Parse(time.RFC3339, nowStr)
+	if err != nil {
+		return nil, configErrorf("invalid --now value %q (expected RFC3339, e.g. 2025-07-01T09:00:00+09:00): %w", nowStr, err)
+	}
+	log.Printf("Overriding reference time with --now: %s", fixed.Format(time.RFC3339))
+	return fixedClock(fixed), nil
+}
 
-		log.Printf("Slack message sent to channel %s at %s", slackChannelID, timestamp)
-		log.Println("Notion Notifyer finished.")
-	},
+// absenceConfigFromFlags は --absent と --absence-status-emoji から不在情報の取得元を作る
+func absenceConfigFromFlags(cmd *cobra.Command) absenceConfig {
+	entries, _ := cmd.Flags().GetStringSlice("absent")
+	emoji, _ := cmd.Flags().GetString("absence-status-emoji")
+	return absenceConfig{Static: parseAbsences(entries), SlackStatusEmoji: emoji}
 }
 
 func init() {
-	rootCmd.PersistentFlags().IntP("daysLater", "d", 0, "Number of days later to check for due tasks (e.g., 0 for today, 3 for 3 days later)")
+	rootCmd.PersistentFlags().IntP("daysLater", "d", 0, "Number of days later to check for due tasks (e.g., 0 for today, 3 for 3 days later; default: through the last bucket with days)")
+	rootCmd.PersistentFlags().String("now", "", "Override the reference time for the run (RFC3339, e.g. 2025-07-01T09:00:00+09:00)")
+	_ = rootCmd.PersistentFlags().MarkHidden("now")
+	rootCmd.PersistentFlags().IntVar(&queryPageSize, "page-size", queryPageSize, "Number of results per Notion query request (1-100)")
+	rootCmd.PersistentFlags().IntVar(&maxQueryResults, "max-results", maxQueryResults, "Maximum number of pages read from each Notion database (0 for no limit)")
+	rootCmd.PersistentFlags().StringVar(&pinnedProp, "pinned-property", "", "Checkbox property whose tasks are always shown in a pinned section regardless of due date")
+	rootCmd.Flags().StringSlice("absent", nil, "Absent assignees by name or email, optionally with a delegate (e.g. Alice or Alice=Bob)")
+	rootCmd.Flags().Bool("dry-run", false, "Build the message but print the Block Kit JSON and a text preview instead of posting (nothing is recorded)")
+	rootCmd.Flags().Bool("focus", false, "Send only the top three tasks, with a button that posts the full list in-thread (requires the interactions server)")
+	rootCmd.Flags().Bool("calendar", false, "Show a 7-day calendar strip (task count and top task per day) above the sections")
+	rootCmd.Flags().Bool("thread-tasks", false, "Post each task as a thread reply so emoji reactions can update its status (requires the interactions server)")
+	rootCmd.Flags().StringSlice("sections", nil, "Sections to show, in order (pinned, hours and the buckets from the config file, by default overdue, today, soon); omitted sections are hidden")
+	rootCmd.Flags().String("template", "", "Message template file (Go text/template) replacing the built-in layout, or only the parts it defines (header, section, section_header, task, footer); print the built-in one with the template command")
+	rootCmd.Flags().StringSlice("collapse-sections", nil, "Sections to show as a one-line summary with a button that posts the full section in-thread (requires the interactions server)")
+	rootCmd.Flags().StringSlice("time-of-day", nil, "Sections whose tasks are split into all-day, morning, afternoon and evening sub-sections by their time (e.g. today)")
+	rootCmd.Flags().StringSlice("include-types", nil, "Only post tasks of these Types to the SLACK_CHANNEL_ID destination")
+	rootCmd.Flags().StringSlice("exclude-types", nil, "Never post tasks of these Types to the SLACK_CHANNEL_ID destination")
+	rootCmd.Flags().String("link-domain", "", "Rewrite task links to this domain (e.g. myteam.notion.site or a custom domain)")
+	rootCmd.Flags().Bool("show-page-id", false, "Show each task's page ID for copy/paste")
+	rootCmd.Flags().Int("within-hours", 0, "Show timed tasks due within this many hours in their own section (0 to disable)")
+	rootCmd.Flags().String("assignee-dm", "", "Also (\"also\") or only (\"only\") send each assignee a DM with just their tasks (requires users:read.email unless --slack-user-map covers everyone)")
+	rootCmd.Flags().String("slack-user-map", "", "YAML/JSON file mapping Notion assignee emails or names to Slack user IDs for --assignee-dm")
+	rootCmd.Flags().Bool("mute-button", false, "Add a button to each task that mutes it in future digests (requires the interactions server; manage with the mutes command)")
+	rootCmd.Flags().Bool("action-buttons", false, "Add Done and Snooze buttons under each task that update the Notion page and the message (requires the interactions server)")
+	rootCmd.Flags().Int("snooze-days", snoozeDays, "Number of days the Snooze button pushes the due date forward")
+	rootCmd.Flags().Bool("track-seen", false, "Add an open button to each task and list tasks whose assignees never opened them (requires the interactions server and users:read.email)")
+	rootCmd.Flags().Bool("desktop", false, "Also show a desktop notification (notify-send/osascript) for overdue and today's top tasks; Slack becomes optional")
+	rootCmd.Flags().Bool("github-status", false, "Show the status of GitHub issues/PRs linked in Memo or Link (uses $GITHUB_TOKEN if set)")
+	rootCmd.Flags().Bool("jira-status", false, "Show the status of Jira issue keys found in Title, Memo or Link, or in --jira-properties (requires JIRA_BASE_URL, JIRA_EMAIL, JIRA_API_TOKEN)")
+	rootCmd.Flags().StringSlice("jira-projects", nil, "Only recognize Jira keys of these projects (e.g. PROJ,OPS)")
+	rootCmd.Flags().StringSliceVar(&jiraProps, "jira-properties", nil, "Notion properties to scan for Jira issue keys, of any text-like type (default: the title, memo and link properties)")
+	rootCmd.Flags().String("absence-status-emoji", "", "Treat assignees whose Slack status uses this emoji as absent (e.g. :palm_tree:)")
 }
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
-		log.Fatalf("Error executing command: %v", err)
-		os.Exit(1)
+		fatal("Error executing command: %v", err)
 	}
 }
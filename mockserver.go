@@ -0,0 +1,837 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jomei/notionapi"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// 設定されていれば Notion と Slack の API 呼び出しをこの URL の mockserver に向ける
+const mockURLEnv = "NOTIFYER_MOCK_URL"
+
+// mockserver の既定のフィクスチャ
+//
+//go:embed mockdata/fixtures.yaml
+var defaultMockFixtures []byte
+
+// mockFixtures は mockserver が返すデータ
+type mockFixtures struct {
+	Types      []string      `yaml:"types"`
+	Priorities []string      `yaml:"priorities"`
+	Users      []mockUser    `yaml:"users"`
+	Channels   []mockChannel `yaml:"channels"`
+	Tasks      []mockTask    `yaml:"tasks"`
+	// タスクのリレーション (project) の先のページ
+	Projects []mockProject `yaml:"projects"`
+}
+
+type mockProject struct {
+	ID    string `yaml:"id"`
+	Title string `yaml:"title"`
+}
+
+type mockUser struct {
+	Name    string `yaml:"name"`
+	Email   string `yaml:"email"`
+	SlackID string `yaml:"slack_id"`
+}
+
+type mockChannel struct {
+	ID   string `yaml:"id"`
+	Name string `yaml:"name"`
+}
+
+// mockMessage は mockserver に投稿されたメッセージ
+type mockMessage struct {
+	Channel string
+	TS      string
+	Text    string
+	Blocks  json.RawMessage
+}
+
+type mockTask struct {
+	ID        string   `yaml:"id"`
+	Number    int      `yaml:"number"` // ユニーク ID の番号 (TASK-1 など、省略すると並び順)
+	Title     string   `yaml:"title"`
+	Due       string   `yaml:"due"`
+	DueEnd    string   `yaml:"due_end"`
+	Priority  string   `yaml:"priority"`
+	Type      string   `yaml:"type"`
+	Status    string   `yaml:"status"`
+	Workload  string   `yaml:"workload"`
+	Memo      string   `yaml:"memo"`
+	Link      string   `yaml:"link"`
+	Assignees []string `yaml:"assignees"` // users のメールアドレス
+	Tags      []string `yaml:"tags"`
+	Projects  []string `yaml:"projects"` // projects の ID
+	Pinned    bool     `yaml:"pinned"`
+	Progress  *float64 `yaml:"progress"` // 進捗率のプロパティを設定したときの値 (0〜1)
+	// そのままページに載せる追加のプロパティ (Notion API の JSON と同じ形、properties.extra の確認に使う)
+	Properties map[string]any `yaml:"properties"`
+}
+
+func loadMockFixtures(path string) (*mockFixtures, error) {
+	data := defaultMockFixtures
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read fixtures: %w", err)
+		}
+	}
+	var f mockFixtures
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures: %w", err)
+	}
+	for i := range f.Tasks {
+		if f.Tasks[i].ID == "" {
+			f.Tasks[i].ID = fmt.Sprintf("00000000-0000-4000-8000-%012d", i+1)
+		}
+		if f.Tasks[i].Number == 0 {
+			f.Tasks[i].Number = i + 1
+		}
+	}
+	return &f, nil
+}
+
+var relativeDatePattern = regexp.MustCompile(`^today([+-]\d+)?(?: (\d{1,2}):(\d{2}))?$`)
+
+// resolveMockDate は "today+1 15:00" のような相対指定を Notion の日付の文字列にする
+func resolveMockDate(value string, now time.Time) string {
+	m := relativeDatePattern.FindStringSubmatch(value)
+	if m == nil {
+		return value
+	}
+	offset, _ := strconv.Atoi(m[1])
+	day := startOfDay(now).AddDate(0, 0, offset)
+	if m[2] == "" {
+		return day.Format("2006-01-02")
+	}
+	hour, _ := strconv.Atoi(m[2])
+	minute, _ := strconv.Atoi(m[3])
+	return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute).Format(time.RFC3339)
+}
+
+// mockServer は Notion・Slack・Discord の API のうち、このツールが使う部分だけを真似る
+type mockServer struct {
+	mu       sync.Mutex
+	fixtures *mockFixtures
+	clock    Clock
+	messages int
+	// conversations.history で返す投稿済みのメッセージ (古い順)
+	posted []mockMessage
+	// この時刻までは Notion の API にメンテナンスの 503 を返す (--notion-outage)
+	outageUntil time.Time
+}
+
+func (s *mockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v1/") && time.Now().Before(s.outageUntil):
+		// Notion のメンテナンス中の応答は JSON ではなく HTML で返る
+		log.Printf("Notion outage: 503 for %s", r.URL.Path)
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = io.WriteString(w, "<html><body>Notion is down for maintenance</body></html>")
+	case strings.HasPrefix(r.URL.Path, "/v1/databases/") && strings.HasSuffix(r.URL.Path, "/query"):
+		s.queryDatabase(w, r)
+	case strings.HasPrefix(r.URL.Path, "/v1/databases/"):
+		s.getDatabase(w, r)
+	case r.URL.Path == "/v1/users/me":
+		writeMockJSON(w, http.StatusOK, map[string]any{"object": "user", "id": "00000000-0000-4000-8000-0000000000b0", "type": "bot", "name": "Mock Integration", "bot": map[string]any{}})
+	case r.URL.Path == "/v1/search":
+		s.search(w)
+	case r.URL.Path == "/v1/pages" && r.Method == http.MethodPost:
+		s.createPage(w, r)
+	case strings.HasPrefix(r.URL.Path, "/v1/pages/") && r.Method == http.MethodPatch:
+		s.updatePage(w, r)
+	case strings.HasPrefix(r.URL.Path, "/v1/pages/"):
+		s.getPage(w, r)
+	case r.URL.Path == "/teams":
+		s.teamsWebhook(w, r)
+	case strings.HasPrefix(r.URL.Path, "/api/webhooks/"):
+		s.discordWebhook(w, r)
+	case strings.HasPrefix(r.URL.Path, "/api/"):
+		s.slackAPI(w, r, strings.TrimPrefix(r.URL.Path, "/api/"))
+	case strings.HasPrefix(r.URL.Path, "/upload/"):
+		// files.getUploadURLExternal で返したアップロード先
+		body, _ := io.ReadAll(r.Body)
+		fmt.Printf("----- file upload %s (%d bytes) -----\n", strings.TrimPrefix(r.URL.Path, "/upload/"), len(body))
+		writeMockJSON(w, http.StatusOK, map[string]any{"ok": true})
+	default:
+		writeMockJSON(w, http.StatusNotFound, map[string]any{"object": "error", "status": 404, "code": "object_not_found", "message": "mockserver does not implement " + r.URL.Path})
+	}
+}
+
+func (s *mockServer) queryDatabase(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Filter      map[string]any `json:"filter"`
+		StartCursor string         `json:"start_cursor"`
+		PageSize    int            `json:"page_size"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+
+	now := s.clock.Now()
+	var matched []mockTask
+	for _, task := range s.fixtures.Tasks {
+		if req.Filter == nil || s.matches(task, req.Filter, now) {
+			matched = append(matched, task)
+		}
+	}
+
+	start, _ := strconv.Atoi(req.StartCursor)
+	size := req.PageSize
+	if size <= 0 || size > 100 {
+		size = 100
+	}
+	end := min(start+size, len(matched))
+	results := []any{}
+	for _, task := range matched[min(start, end):end] {
+		results = append(results, s.page(task, now))
+	}
+	resp := map[string]any{"object": "list", "results": results, "has_more": end < len(matched), "next_cursor": nil}
+	if end < len(matched) {
+		resp["next_cursor"] = strconv.Itoa(end)
+	}
+	log.Printf("Notion query: %d of %d tasks matched", len(matched), len(s.fixtures.Tasks))
+	writeMockJSON(w, http.StatusOK, resp)
+}
+
+// matches は Notion のフィルターのうち、このツールが使う条件だけを評価する
+// 知らない条件は満たすものとして扱う
+func (s *mockServer) matches(task mockTask, filter map[string]any, now time.Time) bool {
+	if and, ok := filter["and"].([]any); ok {
+		for _, f := range and {
+			if m, ok := f.(map[string]any); ok && !s.matches(task, m, now) {
+				return false
+			}
+		}
+		return true
+	}
+	if or, ok := filter["or"].([]any); ok {
+		for _, f := range or {
+			if m, ok := f.(map[string]any); ok && s.matches(task, m, now) {
+				return true
+			}
+		}
+		return len(or) == 0
+	}
+
+	property, _ := filter["property"].(string)
+	switch {
+	case filter["status"] != nil:
+		cond, _ := filter["status"].(map[string]any)
+		if equals, ok := cond["equals"].(string); ok {
+			return task.Status == equals
+		}
+		if notEquals, ok := cond["does_not_equal"].(string); ok {
+			return task.Status != notEquals
+		}
+		if cond["is_not_empty"] == true {
+			return task.Status != ""
+		}
+	case filter["select"] != nil:
+		cond, _ := filter["select"].(map[string]any)
+		if equals, ok := cond["equals"].(string); ok {
+			return mockSelectValue(task, property) == equals
+		}
+	case filter["checkbox"] != nil:
+		cond, _ := filter["checkbox"].(map[string]any)
+		if equals, ok := cond["equals"].(bool); ok {
+			return (property == pinnedProp && task.Pinned) == equals
+		}
+	case filter["date"] != nil && property == dueProp:
+		cond, _ := filter["date"].(map[string]any)
+		due, err := parseMockDate(resolveMockDate(task.Due, now))
+		if err != nil {
+			return false
+		}
+		if v, ok := cond["on_or_before"].(string); ok {
+			if limit, err := parseMockDate(v); err == nil && due.After(limit) {
+				return false
+			}
+		}
+		if v, ok := cond["on_or_after"].(string); ok {
+			if limit, err := parseMockDate(v); err == nil && due.Before(limit) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func mockSelectValue(task mockTask, property string) string {
+	switch property {
+	case priorityProp:
+		return task.Priority
+	case typeProp:
+		return task.Type
+	case workloadProp:
+		return task.Workload
+	}
+	return ""
+}
+
+func parseMockDate(value string) (time.Time, error) {
+	var d notionapi.Date
+	err := d.UnmarshalText([]byte(value))
+	return time.Time(d), err
+}
+
+// page はフィクスチャのタスクを Notion のページの JSON にする
+func (s *mockServer) page(task mockTask, now time.Time) map[string]any {
+	richText := func(text string) []any {
+		return []any{map[string]any{"type": "text", "text": map[string]any{"content": text}, "plain_text": text}}
+	}
+	selectValue := func(name string) map[string]any {
+		return map[string]any{"type": "select", "select": map[string]any{"name": name}}
+	}
+
+	props := map[string]any{
+		nameProp:           map[string]any{"id": "title", "type": "title", "title": richText(task.Title)},
+		scheduleStatusProp: map[string]any{"type": "status", "status": map[string]any{"name": task.Status}},
+	}
+	if task.Due != "" {
+		date := map[string]any{"start": resolveMockDate(task.Due, now), "end": nil}
+		if task.DueEnd != "" {
+			date["end"] = resolveMockDate(task.DueEnd, now)
+		}
+		props[dueProp] = map[string]any{"type": "date", "date": date}
+	}
+	if task.Priority != "" {
+		props[priorityProp] = selectValue(task.Priority)
+	}
+	if task.Type != "" {
+		props[typeProp] = selectValue(task.Type)
+	}
+	if task.Workload != "" {
+		props[workloadProp] = selectValue(task.Workload)
+	}
+	if task.Memo != "" {
+		props[memoProp] = map[string]any{"type": "rich_text", "rich_text": richText(task.Memo)}
+	}
+	if task.Link != "" {
+		props[linkProp] = map[string]any{"type": "url", "url": task.Link}
+	}
+	if pinnedProp != "" {
+		props[pinnedProp] = map[string]any{"type": "checkbox", "checkbox": task.Pinned}
+	}
+	if progressProp != "" && task.Progress != nil {
+		props[progressProp] = map[string]any{"type": "formula", "formula": map[string]any{"type": "number", "number": *task.Progress}}
+	}
+	if len(task.Tags) > 0 {
+		options := []any{}
+		for _, tag := range task.Tags {
+			options = append(options, map[string]any{"name": tag})
+		}
+		props[tagsProp] = map[string]any{"type": "multi_select", "multi_select": options}
+	}
+	if uniqueIDProp != "" {
+		props[uniqueIDProp] = map[string]any{"type": "unique_id", "unique_id": map[string]any{"prefix": "TASK", "number": task.Number}}
+	}
+	if projectProp != "" {
+		relations := []any{}
+		for _, id := range task.Projects {
+			relations = append(relations, map[string]any{"id": id})
+		}
+		props[projectProp] = map[string]any{"type": "relation", "relation": relations}
+	}
+	people := []any{}
+	for _, email := range task.Assignees {
+		user := s.user(email)
+		people = append(people, map[string]any{"object": "user", "id": user.SlackID, "name": user.Name, "type": "person", "person": map[string]any{"email": user.Email}})
+	}
+	props[assigneeProp] = map[string]any{"type": "people", "people": people}
+	maps.Copy(props, task.Properties)
+
+	return map[string]any{
+		"object":           "page",
+		"id":               task.ID,
+		"created_time":     startOfDay(now).AddDate(0, 0, -7).UTC().Format(time.RFC3339),
+		"last_edited_time": now.UTC().Format(time.RFC3339),
+		"url":              "https://www.notion.so/" + strings.ReplaceAll(task.ID, "-", ""),
+		"properties":       props,
+	}
+}
+
+func (s *mockServer) user(email string) mockUser {
+	for _, u := range s.fixtures.Users {
+		if u.Email == email {
+			return u
+		}
+	}
+	return mockUser{Name: email, Email: email}
+}
+
+func (s *mockServer) getDatabase(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/v1/databases/")
+	options, priorities, statuses := []any{}, []any{}, []any{}
+	for _, t := range s.fixtures.Types {
+		options = append(options, map[string]any{"name": t})
+	}
+	for _, p := range s.fixtures.Priorities {
+		priorities = append(priorities, map[string]any{"name": p})
+	}
+	// ステータスのグループは Notion の既定と同じ 3 つにし、Doing を進行中、Done を完了とする
+	groups := map[string][]any{}
+	var seen []string
+	for _, task := range s.fixtures.Tasks {
+		if !slices.Contains(seen, task.Status) {
+			seen = append(seen, task.Status)
+			statuses = append(statuses, map[string]any{"id": "status-" + task.Status, "name": task.Status})
+			group := "To-do"
+			switch task.Status {
+			case "Doing":
+				group = "In progress"
+			case "Done":
+				group = "Complete"
+			}
+			groups[group] = append(groups[group], "status-"+task.Status)
+		}
+	}
+	statusGroups := []any{}
+	for _, name := range []string{"To-do", "In progress", "Complete"} {
+		statusGroups = append(statusGroups, map[string]any{"id": "group-" + name, "name": name, "option_ids": append([]any{}, groups[name]...)})
+	}
+	// page で返すタスクのプロパティと同じ種類にする (doctor が確かめる)
+	config := func(typ string, value map[string]any) map[string]any {
+		return map[string]any{"id": typ, "type": typ, typ: value}
+	}
+	empty := map[string]any{}
+	props := map[string]any{
+		nameProp:           config("title", empty),
+		typeProp:           config("select", map[string]any{"options": options}),
+		priorityProp:       config("select", map[string]any{"options": priorities}),
+		dueProp:            config("date", empty),
+		scheduleStatusProp: config("status", map[string]any{"options": statuses, "groups": statusGroups}),
+		workloadProp:       config("select", map[string]any{"options": []any{}}),
+		memoProp:           config("rich_text", empty),
+		linkProp:           config("url", empty),
+		assigneeProp:       config("people", empty),
+		tagsProp:           config("multi_select", map[string]any{"options": []any{}}),
+	}
+	if pinnedProp != "" {
+		props[pinnedProp] = config("checkbox", empty)
+	}
+	if progressProp != "" {
+		props[progressProp] = config("formula", map[string]any{"expression": ""})
+	}
+	if uniqueIDProp != "" {
+		props[uniqueIDProp] = config("unique_id", map[string]any{"prefix": "TASK"})
+	}
+	if projectProp != "" {
+		props[projectProp] = config("relation", map[string]any{"database_id": id})
+	}
+	writeMockJSON(w, http.StatusOK, map[string]any{
+		"object":     "database",
+		"id":         id,
+		"title":      []any{map[string]any{"type": "text", "text": map[string]any{"content": "Mock Tasks"}, "plain_text": "Mock Tasks"}},
+		"properties": props,
+	})
+}
+
+// search はデータベースの検索にフィクスチャのデータベースだけを返す
+func (s *mockServer) search(w http.ResponseWriter) {
+	writeMockJSON(w, http.StatusOK, map[string]any{
+		"object": "list",
+		"results": []any{map[string]any{
+			"object": "database",
+			"id":     "00000000-0000-4000-8000-00000000db01",
+			"title":  []any{map[string]any{"type": "text", "text": map[string]any{"content": "Mock Tasks"}, "plain_text": "Mock Tasks"}},
+		}},
+		"has_more": false,
+	})
+}
+
+// getPage はタスクかプロジェクトのページを返す
+func (s *mockServer) getPage(w http.ResponseWriter, r *http.Request) {
+	id := strings.ReplaceAll(strings.TrimPrefix(r.URL.Path, "/v1/pages/"), "-", "")
+	for _, task := range s.fixtures.Tasks {
+		if strings.ReplaceAll(task.ID, "-", "") == id {
+			writeMockJSON(w, http.StatusOK, s.page(task, s.clock.Now()))
+			return
+		}
+	}
+	for _, p := range s.fixtures.Projects {
+		if strings.ReplaceAll(p.ID, "-", "") == id {
+			title := []any{map[string]any{"type": "text", "text": map[string]any{"content": p.Title}, "plain_text": p.Title}}
+			writeMockJSON(w, http.StatusOK, map[string]any{
+				"object":     "page",
+				"id":         p.ID,
+				"url":        "https://www.notion.so/" + id,
+				"properties": map[string]any{"Name": map[string]any{"id": "title", "type": "title", "title": title}},
+			})
+			return
+		}
+	}
+	writeMockJSON(w, http.StatusNotFound, map[string]any{"object": "error", "status": 404, "code": "object_not_found", "message": "page not found"})
+}
+
+// updatePage はステータスと期限日の更新をフィクスチャに反映する
+func (s *mockServer) updatePage(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/v1/pages/")
+	var req struct {
+		Properties map[string]struct {
+			Status *struct {
+				Name string `json:"name"`
+			} `json:"status"`
+			Date *struct {
+				Start *string `json:"start"`
+				End   *string `json:"end"`
+			} `json:"date"`
+		} `json:"properties"`
+		Archived bool `json:"archived"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeMockJSON(w, http.StatusBadRequest, map[string]any{"object": "error", "status": 400, "code": "invalid_json", "message": err.Error()})
+		return
+	}
+	for i := range s.fixtures.Tasks {
+		task := &s.fixtures.Tasks[i]
+		if strings.ReplaceAll(task.ID, "-", "") != strings.ReplaceAll(id, "-", "") {
+			continue
+		}
+		if req.Archived {
+			archived := *task
+			s.fixtures.Tasks = slices.Delete(s.fixtures.Tasks, i, i+1)
+			log.Printf("Notion archive: %s", archived.Title)
+			page := s.page(archived, s.clock.Now())
+			page["archived"] = true
+			writeMockJSON(w, http.StatusOK, page)
+			return
+		}
+		if p, ok := req.Properties[scheduleStatusProp]; ok && p.Status != nil {
+			task.Status = p.Status.Name
+		}
+		if p, ok := req.Properties[dueProp]; ok && p.Date != nil {
+			task.Due, task.DueEnd = "", ""
+			if p.Date.Start != nil {
+				task.Due = *p.Date.Start
+			}
+			if p.Date.End != nil {
+				task.DueEnd = *p.Date.End
+			}
+		}
+		log.Printf("Notion update: %s (status %s, due %s)", task.Title, task.Status, task.Due)
+		writeMockJSON(w, http.StatusOK, s.page(*task, s.clock.Now()))
+		return
+	}
+	writeMockJSON(w, http.StatusNotFound, map[string]any{"object": "error", "status": 404, "code": "object_not_found", "message": "page not found"})
+}
+
+// createPage はデータベースにページを作り、フィクスチャのタスクとして以後の問い合わせで返す
+func (s *mockServer) createPage(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Properties map[string]struct {
+			Title []struct {
+				Text struct {
+					Content string `json:"content"`
+				} `json:"text"`
+			} `json:"title"`
+			Status *struct {
+				Name string `json:"name"`
+			} `json:"status"`
+			Date *struct {
+				Start string `json:"start"`
+			} `json:"date"`
+		} `json:"properties"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeMockJSON(w, http.StatusBadRequest, map[string]any{"object": "error", "status": 400, "code": "invalid_json", "message": err.Error()})
+		return
+	}
+	task := mockTask{ID: fmt.Sprintf("00000000-0000-4000-8000-%012d", 900+len(s.fixtures.Tasks)), Number: len(s.fixtures.Tasks) + 1}
+	for _, t := range req.Properties[nameProp].Title {
+		task.Title += t.Text.Content
+	}
+	if p := req.Properties[scheduleStatusProp]; p.Status != nil {
+		task.Status = p.Status.Name
+	}
+	if p := req.Properties[dueProp]; p.Date != nil {
+		task.Due = p.Date.Start
+	}
+	s.fixtures.Tasks = append(s.fixtures.Tasks, task)
+	log.Printf("Notion create: %s (status %s, due %s)", task.Title, task.Status, task.Due)
+	writeMockJSON(w, http.StatusOK, s.page(task, s.clock.Now()))
+}
+
+// slackAPI は Slack の Web API を真似て、投稿されたメッセージを標準出力に表示する
+func (s *mockServer) slackAPI(w http.ResponseWriter, r *http.Request, method string) {
+	body, _ := io.ReadAll(r.Body)
+	values, _ := url.ParseQuery(string(body))
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var m map[string]any
+		if json.Unmarshal(body, &m) == nil {
+			for k, v := range m {
+				if str, ok := v.(string); ok {
+					values.Set(k, str)
+				} else if data, err := json.Marshal(v); err == nil {
+					values.Set(k, string(data))
+				}
+			}
+		}
+	}
+
+	switch method {
+	case "chat.postMessage", "chat.update", "chat.postEphemeral":
+		s.messages++
+		ts := fmt.Sprintf("%d.%06d", s.clock.Now().Unix(), s.messages)
+		if method == "chat.update" {
+			ts = values.Get("ts")
+		}
+		fmt.Printf("----- %s to %s (ts %s) -----\n", method, values.Get("channel"), ts)
+		msg := mockMessage{Channel: values.Get("channel"), TS: ts, Text: values.Get("text"), Blocks: json.RawMessage(values.Get("blocks"))}
+		if !json.Valid(msg.Blocks) {
+			msg.Blocks = nil
+		}
+		if i := slices.IndexFunc(s.posted, func(m mockMessage) bool { return m.Channel == msg.Channel && m.TS == ts }); i >= 0 {
+			s.posted[i] = msg
+		} else if method != "chat.postEphemeral" {
+			s.posted = append(s.posted, msg)
+		}
+		if text := values.Get("text"); text != "" {
+			fmt.Println(text)
+		}
+		if blocks := values.Get("blocks"); blocks != "" {
+			var parsed any
+			if json.Unmarshal([]byte(blocks), &parsed) == nil {
+				for _, text := range collectBlockTexts(parsed) {
+					fmt.Println(text)
+				}
+			}
+		}
+		writeMockJSON(w, http.StatusOK, map[string]any{"ok": true, "channel": values.Get("channel"), "ts": ts})
+	case "users.lookupByEmail":
+		email := values.Get("email")
+		for _, u := range s.fixtures.Users {
+			if u.Email == email {
+				writeMockJSON(w, http.StatusOK, map[string]any{"ok": true, "user": map[string]any{"id": u.SlackID, "name": u.Name, "profile": map[string]any{"email": u.Email}}})
+				return
+			}
+		}
+		writeMockJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "users_not_found"})
+	case "conversations.list":
+		channels := []any{}
+		for _, ch := range s.fixtures.Channels {
+			channels = append(channels, map[string]any{"id": ch.ID, "name": ch.Name, "is_channel": true})
+		}
+		writeMockJSON(w, http.StatusOK, map[string]any{"ok": true, "channels": channels, "response_metadata": map[string]any{"next_cursor": ""}})
+	case "files.getUploadURLExternal":
+		s.messages++
+		fileID := fmt.Sprintf("F%06d", s.messages)
+		writeMockJSON(w, http.StatusOK, map[string]any{"ok": true, "file_id": fileID, "upload_url": "http://" + r.Host + "/upload/" + fileID})
+	case "files.completeUploadExternal":
+		fmt.Printf("----- %s to %s (thread %s) -----\n", method, values.Get("channel_id"), values.Get("thread_ts"))
+		if comment := values.Get("initial_comment"); comment != "" {
+			fmt.Println(comment)
+		}
+		var files []map[string]any
+		_ = json.Unmarshal([]byte(values.Get("files")), &files)
+		writeMockJSON(w, http.StatusOK, map[string]any{"ok": true, "files": files})
+	case "auth.test":
+		w.Header().Set("X-OAuth-Scopes", slackInstallScopes)
+		writeMockJSON(w, http.StatusOK, map[string]any{"ok": true, "team": "Mock", "team_id": "T000MOCK", "user": "notifyer", "user_id": "U000BOT"})
+	case "conversations.history":
+		// 新しい順に返す (oldest より後のものだけ)
+		oldest, _ := strconv.ParseFloat(values.Get("oldest"), 64)
+		limit, _ := strconv.Atoi(values.Get("limit"))
+		messages := []any{}
+		for i := len(s.posted) - 1; i >= 0 && (limit == 0 || len(messages) < limit); i-- {
+			msg := s.posted[i]
+			if ts, _ := strconv.ParseFloat(msg.TS, 64); msg.Channel != values.Get("channel") || ts <= oldest {
+				continue
+			}
+			messages = append(messages, map[string]any{"type": "message", "user": "U000BOT", "bot_id": "B000BOT", "ts": msg.TS, "text": msg.Text, "blocks": msg.Blocks})
+		}
+		writeMockJSON(w, http.StatusOK, map[string]any{"ok": true, "messages": messages, "has_more": false})
+	case "chat.delete":
+		i := slices.IndexFunc(s.posted, func(m mockMessage) bool { return m.Channel == values.Get("channel") && m.TS == values.Get("ts") })
+		if i < 0 {
+			writeMockJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "message_not_found"})
+			return
+		}
+		s.posted = slices.Delete(s.posted, i, i+1)
+		fmt.Printf("----- chat.delete in %s (ts %s) -----\n", values.Get("channel"), values.Get("ts"))
+		writeMockJSON(w, http.StatusOK, map[string]any{"ok": true, "channel": values.Get("channel"), "ts": values.Get("ts")})
+	case "conversations.info":
+		for _, ch := range s.fixtures.Channels {
+			if ch.ID == values.Get("channel") {
+				writeMockJSON(w, http.StatusOK, map[string]any{"ok": true, "channel": map[string]any{"id": ch.ID, "name": ch.Name, "is_channel": true, "is_member": true}})
+				return
+			}
+		}
+		writeMockJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "channel_not_found"})
+	default:
+		log.Printf("Slack %s (not simulated, returning ok)", method)
+		writeMockJSON(w, http.StatusOK, map[string]any{"ok": true})
+	}
+}
+
+// discordWebhook は Discord の Webhook に送られたメッセージの内容を表示する
+func (s *mockServer) discordWebhook(w http.ResponseWriter, r *http.Request) {
+	var msg discordMessage
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		writeMockJSON(w, http.StatusBadRequest, map[string]any{"message": "Cannot send an empty message", "code": 50006})
+		return
+	}
+	fmt.Println("----- discord webhook -----")
+	if msg.Content != "" {
+		fmt.Println(msg.Content)
+	}
+	for _, embed := range msg.Embeds {
+		fmt.Printf("[#%06x] %s\n%s\n", embed.Color, embed.Title, embed.Description)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// teamsWebhook は Teams の Webhook (TEAMS_WEBHOOK_URL に <mockserver>/teams を設定する) に送られたカードの内容を表示する
+func (s *mockServer) teamsWebhook(w http.ResponseWriter, r *http.Request) {
+	var msg any
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	fmt.Println("----- teams webhook -----")
+	for _, text := range collectBlockTexts(msg) {
+		fmt.Println(text)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// collectBlockTexts は Block Kit の JSON から表示される文字列を取り出す
+func collectBlockTexts(v any) []string {
+	var texts []string
+	switch v := v.(type) {
+	case map[string]any:
+		if text, ok := v["text"].(string); ok && text != "" {
+			texts = append(texts, text)
+		}
+		for key, child := range v {
+			if key != "text" || !isString(child) {
+				texts = append(texts, collectBlockTexts(child)...)
+			}
+		}
+	case []any:
+		for _, child := range v {
+			texts = append(texts, collectBlockTexts(child)...)
+		}
+	}
+	return texts
+}
+
+func isString(v any) bool {
+	_, ok := v.(string)
+	return ok
+}
+
+func writeMockJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// mockTransport は Notion・Slack・Discord の API 呼び出しを mockserver に向ける http.RoundTripper
+type mockTransport struct {
+	base *url.URL
+	next http.RoundTripper
+}
+
+var mockedHosts = []string{"api.notion.com", "slack.com", "discord.com"}
+
+func (t *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !slices.Contains(mockedHosts, req.URL.Host) {
+		return t.next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.base.Scheme
+	req.URL.Host = t.base.Host
+	req.Host = t.base.Host
+	return t.next.RoundTrip(req)
+}
+
+// installMockTransport は NOTIFYER_MOCK_URL が設定されていれば API 呼び出しを mockserver に向ける
+func installMockTransport() {
+	raw := os.Getenv(mockURLEnv)
+	if raw == "" {
+		return
+	}
+	base, err := url.Parse(raw)
+	if err != nil || base.Host == "" {
+		log.Fatalf("Invalid %s: %q", mockURLEnv, raw)
+	}
+	http.DefaultTransport = &mockTransport{base: base, next: http.DefaultTransport}
+	log.Printf("Sending Notion, Slack and Discord API calls to the mock server at %s", base)
+}
+
+var mockserverCmd = &cobra.Command{
+	Use:   "mockserver",
+	Short: "Serve a fake Notion/Slack API backed by YAML fixtures for local end-to-end runs.",
+	Long: `Serve a fake Notion/Slack API backed by YAML fixtures.
+
+Run the tool against it without any credentials:
+
+  notion-notifyer mockserver &
+  NOTIFYER_MOCK_URL=http://localhost:8787 NOTION_TOKEN=mock NOTION_DB_ID=mock \
+    SLACK_BOT_TOKEN=mock SLACK_CHANNEL_ID=C000MOCK notion-notifyer -d 3`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		addr, _ := cmd.Flags().GetString("addr")
+		path, _ := cmd.Flags().GetString("fixtures")
+		fixtures, err := loadMockFixtures(path)
+		if err != nil {
+			return err
+		}
+		clock, err := clockFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		outage, _ := cmd.Flags().GetDuration("notion-outage")
+		mock := &mockServer{fixtures: fixtures, clock: clock, outageUntil: time.Now().Add(outage)}
+		server := &http.Server{Addr: addr, Handler: mock, ReadHeaderTimeout: 10 * time.Second}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = server.Shutdown(shutdownCtx)
+		}()
+
+		log.Printf("Mock Notion/Slack API listening on %s with %d tasks (set %s=http://localhost%s)", addr, len(fixtures.Tasks), mockURLEnv, addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		log.Println("Mock server stopped.")
+		return nil
+	},
+}
+
+func init() {
+	mockserverCmd.Flags().String("addr", ":8787", "Address for the mock API server")
+	mockserverCmd.Flags().String("fixtures", "", "YAML fixtures file (default: built-in sample tasks)")
+	mockserverCmd.Flags().Duration("notion-outage", 0, "Answer Notion API calls with a 503 maintenance page for this long after starting")
+	rootCmd.AddCommand(mockserverCmd)
+	cobra.OnInitialize(installMockTransport)
+}
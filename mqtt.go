@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// MQTT の送信設定
+const (
+	mqttURLEnv      = "MQTT_URL" // mqtt://host:1883 または mqtts://host:8883 (ユーザー名とパスワードを含めてもよい)
+	mqttUsernameEnv = "MQTT_USERNAME"
+	mqttPasswordEnv = "MQTT_PASSWORD"
+	mqttTopicEnv    = "MQTT_TOPIC" // 状態を送るトピックの接頭辞 (既定は notion-notifyer)
+
+	defaultMQTTTopic = "notion-notifyer"
+	mqttTimeout      = 30 * time.Second
+)
+
+// mqttTarget はタスクの件数と上位のタスクを MQTT のブローカーに送る送り先
+// Home Assistant の MQTT Discovery の設定も送り、センサーとして自動で登録されるようにする
+type mqttTarget struct {
+	Broker          *url.URL
+	Username        string
+	Password        string
+	Topic           string
+	DiscoveryPrefix string // 空なら Discovery の設定を送らない
+	TopTasks        int
+}
+
+// newMQTTTargetFromEnv は環境変数とフラグから MQTT の送り先を作る。MQTT_URL が無ければ nil を返す
+func newMQTTTargetFromEnv(flags *pflag.FlagSet) (*mqttTarget, error) {
+	raw := os.Getenv(mqttURLEnv)
+	if raw == "" {
+		return nil, nil
+	}
+	broker, err := url.Parse(raw)
+	if err != nil || (broker.Scheme != "mqtt" && broker.Scheme != "mqtts") || broker.Hostname() == "" {
+		return nil, fmt.Errorf("invalid %s: expected mqtt://host:port or mqtts://host:port", mqttURLEnv)
+	}
+	t := &mqttTarget{Broker: broker, Username: os.Getenv(mqttUsernameEnv), Password: os.Getenv(mqttPasswordEnv), Topic: os.Getenv(mqttTopicEnv)}
+	if t.Username == "" && broker.User != nil {
+		t.Username = broker.User.Username()
+		t.Password, _ = broker.User.Password()
+	}
+	if t.Topic == "" {
+		t.Topic = defaultMQTTTopic
+	}
+	t.DiscoveryPrefix, _ = flags.GetString("mqtt-discovery-prefix")
+	t.TopTasks, _ = flags.GetInt("mqtt-top")
+	if t.TopTasks < 0 {
+		return nil, fmt.Errorf("--mqtt-top must not be negative, got %d", t.TopTasks)
+	}
+	return t, nil
+}
+
+// mqttSummary は <topic>/state に保持 (retain) して送るタスクの要約
+// Home Assistant では value_json.overdue のように値を読み、top_tasks はセンサーの属性になる
+type mqttSummary struct {
+	Total     int            `json:"total"`
+	Overdue   int            `json:"overdue"`
+	Today     int            `json:"today"`
+	Sections  map[string]int `json:"sections"` // セクションの名前ごとのタスク数
+	TopTasks  []mqttTask     `json:"top_tasks"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+type mqttTask struct {
+	Title    string     `json:"title"`
+	URL      string     `json:"url"`
+	Due      *time.Time `json:"due,omitempty"`
+	Priority string     `json:"priority,omitempty"`
+	Section  string     `json:"section"`
+}
+
+// buildMQTTSummary はセクションの順に並べたタスクから件数と上位 top 件のタスクをまとめる
+func buildMQTTSummary(groups []TaskGroup, now time.Time, top int) mqttSummary {
+	s := mqttSummary{Sections: map[string]int{}, TopTasks: []mqttTask{}, UpdatedAt: now}
+	today, tomorrow := startOfDay(now), startOfDay(endOfDay(now, 1))
+	seen := map[string]bool{}
+	for _, group := range groups {
+		s.Sections[group.Name] = len(group.Tasks)
+		for _, task := range group.Tasks {
+			// プロジェクトごとに分けると、同じタスクが複数のセクションに入る
+			if seen[task.Key()] {
+				continue
+			}
+			seen[task.Key()] = true
+			s.Total++
+			// 日付だけの期限日は now のタイムゾーンの日付として数え、セクションの件数と揃える
+			switch local := task.DueDateIn(now.Location()); {
+			case local == nil:
+			case local.Before(today):
+				s.Overdue++
+			case local.Before(tomorrow):
+				s.Today++
+			}
+			due := getTargetDueDate(task)
+			if len(s.TopTasks) < top {
+				s.TopTasks = append(s.TopTasks, mqttTask{Title: taskTitle(task), URL: task.URL, Due: due, Priority: task.Priority, Section: group.Name})
+			}
+		}
+	}
+	return s
+}
+
+// mqttMessage は送る 1 つのメッセージ
+type mqttMessage struct {
+	Topic   string
+	Payload []byte
+}
+
+// Home Assistant の unique_id やトピックに使えない文字
+var mqttNodeIDPattern = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// discoveryMessages は Home Assistant の MQTT Discovery の設定を返す
+// 件数のセンサー (全体・期限切れ・今日が期限) と、期限切れがあると ON になるバイナリセンサーを作る
+func (t *mqttTarget) discoveryMessages() ([]mqttMessage, error) {
+	if t.DiscoveryPrefix == "" {
+		return nil, nil
+	}
+	node := mqttNodeIDPattern.ReplaceAllString(t.Topic, "_")
+	stateTopic := t.Topic + "/state"
+	device := map[string]any{"identifiers": []string{node}, "name": tr("digest.header"), "manufacturer": "notion-notifyer", "sw_version": version}
+	entities := []struct {
+		component, id, name, template string
+		extra                         map[string]any
+	}{
+		{"sensor", "total", tr("mqtt.total"), "{{ value_json.total }}", map[string]any{
+			"json_attributes_topic":    stateTopic,
+			"json_attributes_template": "{{ {'top_tasks': value_json.top_tasks, 'sections': value_json.sections} | tojson }}",
+			"icon":                     "mdi:format-list-checks",
+		}},
+		{"sensor", "overdue", tr("mqtt.overdue"), "{{ value_json.overdue }}", map[string]any{"icon": "mdi:alert-circle"}},
+		{"sensor", "today", tr("mqtt.today"), "{{ value_json.today }}", map[string]any{"icon": "mdi:calendar-today"}},
+		{"binary_sensor", "has_overdue", tr("mqtt.has_overdue"), "{{ 'ON' if value_json.overdue > 0 else 'OFF' }}", map[string]any{"device_class": "problem"}},
+	}
+	var messages []mqttMessage
+	for _, e := range entities {
+		config := map[string]any{
+			"name":           e.name,
+			"unique_id":      node + "_" + e.id,
+			"object_id":      node + "_" + e.id,
+			"state_topic":    stateTopic,
+			"value_template": e.template,
+			"device":         device,
+		}
+		if e.component == "sensor" {
+			config["state_class"] = "measurement"
+		}
+		for k, v := range e.extra {
+			config[k] = v
+		}
+		payload, err := json.Marshal(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode MQTT discovery config: %w", err)
+		}
+		messages = append(messages, mqttMessage{Topic: fmt.Sprintf("%s/%s/%s/%s/config", t.DiscoveryPrefix, e.component, node, e.id), Payload: payload})
+	}
+	return messages, nil
+}
+
+// Send はタスクの要約を送る (ドライランでは送らずにトピックと内容を出力する)
+// すべて保持 (retain) するので、後から接続した Home Assistant も最新の値を受け取れる
+func (t *mqttTarget) Send(ctx context.Context, run digestRun, groups []TaskGroup) error {
+	messages, err := t.discoveryMessages()
+	if err != nil {
+		return err
+	}
+	state, err := json.Marshal(buildMQTTSummary(groups, run.Now, t.TopTasks))
+	if err != nil {
+		return fmt.Errorf("failed to encode MQTT state: %w", err)
+	}
+	messages = append(messages, mqttMessage{Topic: t.Topic + "/state", Payload: state})
+	if run.DryRun {
+		for _, m := range messages {
+			fmt.Fprintf(os.Stdout, "===== mqtt %s =====\n%s\n", m.Topic, m.Payload)
+		}
+		return nil
+	}
+	if err := t.publish(ctx, messages); err != nil {
+		return err
+	}
+	log.Printf("[%s] Published the task summary to MQTT topic %s/state", run.JobName, t.Topic)
+	return nil
+}
+
+// replacesState は保持したメッセージを毎回置き換えるため、タスクが無いときも送る
+func (t *mqttTarget) replacesState() {}
+
+// MQTT 3.1.1 のパケットの種類
+const (
+	mqttConnect    = 0x10
+	mqttConnack    = 0x20
+	mqttPublish    = 0x30
+	mqttPuback     = 0x40
+	mqttDisconnect = 0xe0
+)
+
+// publish はブローカーに接続し、メッセージを QoS 1 で保持して送る (PUBACK で受け取りを確かめる)
+// 外部のライブラリを使わず、送信に必要な MQTT 3.1.1 のパケットだけを扱う
+func (t *mqttTarget) publish(ctx context.Context, messages []mqttMessage) error {
+	port := t.Broker.Port()
+	if port == "" {
+		port = "1883"
+		if t.Broker.Scheme == "mqtts" {
+			port = "8883"
+		}
+	}
+	addr := net.JoinHostPort(t.Broker.Hostname(), port)
+	dialer := &net.Dialer{Timeout: mqttTimeout}
+	var conn net.Conn
+	var err error
+	if t.Broker.Scheme == "mqtts" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: t.Broker.Hostname()}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to MQTT broker %s: %w", addr, err)
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(mqttTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+	r := bufio.NewReader(conn)
+
+	var random [4]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		return err
+	}
+	if _, err := conn.Write(mqttConnectPacket("notion-notifyer-"+hex.EncodeToString(random[:]), t.Username, t.Password)); err != nil {
+		return fmt.Errorf("failed to send MQTT CONNECT: %w", err)
+	}
+	packetType, body, err := readMQTTPacket(r)
+	if err != nil {
+		return fmt.Errorf("failed to read MQTT CONNACK: %w", err)
+	}
+	if packetType != mqttConnack || len(body) != 2 {
+		return fmt.Errorf("unexpected MQTT packet 0x%02x instead of CONNACK", packetType)
+	}
+	if code := body[1]; code != 0 {
+		return fmt.Errorf("MQTT broker refused the connection: %s", mqttConnackReason(code))
+	}
+
+	for i, m := range messages {
+		id := uint16(i + 1)
+		if _, err := conn.Write(mqttPublishPacket(m.Topic, m.Payload, id)); err != nil {
+			return fmt.Errorf("failed to publish to MQTT topic %s: %w", m.Topic, err)
+		}
+		packetType, body, err := readMQTTPacket(r)
+		if err != nil {
+			return fmt.Errorf("failed to read MQTT PUBACK for %s: %w", m.Topic, err)
+		}
+		if packetType != mqttPuback || len(body) < 2 || binary.BigEndian.Uint16(body) != id {
+			return fmt.Errorf("unexpected MQTT packet 0x%02x instead of PUBACK for %s", packetType, m.Topic)
+		}
+	}
+	_, _ = conn.Write([]byte{mqttDisconnect, 0})
+	return nil
+}
+
+// mqttConnectPacket は CONNECT パケットを作る (クリーンセッション、キープアライブ 60 秒)
+func mqttConnectPacket(clientID, username, password string) []byte {
+	var body bytes.Buffer
+	writeMQTTString(&body, "MQTT")
+	body.WriteByte(4) // MQTT 3.1.1
+	flags := byte(0x02)
+	if username != "" {
+		flags |= 0x80
+		if password != "" {
+			flags |= 0x40
+		}
+	}
+	body.WriteByte(flags)
+	body.Write([]byte{0, 60})
+	writeMQTTString(&body, clientID)
+	if username != "" {
+		writeMQTTString(&body, username)
+		if password != "" {
+			writeMQTTString(&body, password)
+		}
+	}
+	return mqttPacket(mqttConnect, body.Bytes())
+}
+
+// mqttPublishPacket は QoS 1 で保持する PUBLISH パケットを作る
+func mqttPublishPacket(topic string, payload []byte, id uint16) []byte {
+	var body bytes.Buffer
+	writeMQTTString(&body, topic)
+	body.Write(binary.BigEndian.AppendUint16(nil, id))
+	body.Write(payload)
+	return mqttPacket(mqttPublish|0x02|0x01, body.Bytes())
+}
+
+// mqttPacket は固定ヘッダー (種類と可変長の残りの長さ) を付ける
+func mqttPacket(header byte, body []byte) []byte {
+	packet := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+func writeMQTTString(b *bytes.Buffer, s string) {
+	b.Write(binary.BigEndian.AppendUint16(nil, uint16(len(s))))
+	b.WriteString(s)
+}
+
+// readMQTTPacket はパケットを 1 つ読み、種類 (上位 4 ビット) と残りの部分を返す
+func readMQTTPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("malformed MQTT remaining length")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(b&0x7f) * multiplier
+		multiplier *= 128
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header & 0xf0, body, nil
+}
+
+// mqttConnackReason は CONNACK の戻り値の意味を返す
+func mqttConnackReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "client identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad user name or password"
+	case 5:
+		return "not authorized"
+	}
+	return fmt.Sprintf("return code %d", code)
+}
+
+func init() {
+	rootCmd.Flags().String("mqtt-discovery-prefix", "homeassistant", "Home Assistant MQTT discovery prefix for --target mqtt (empty to skip discovery configs)")
+	rootCmd.Flags().Int("mqtt-top", 5, "Number of top tasks included in the MQTT state for --target mqtt")
+	RegisterNotifier(targetMQTT, func(flags *pflag.FlagSet) (Notifier, error) {
+		t, err := newMQTTTargetFromEnv(flags)
+		if err != nil {
+			return nil, err
+		}
+		if t == nil {
+			return nil, fmt.Errorf("--target mqtt requires %s", mqttURLEnv)
+		}
+		return t, nil
+	})
+}
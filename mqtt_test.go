@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jomei/notionapi"
+
+	"rainierrr/notion-notifyer/pkg/task"
+)
+
+// TestMQTTRemainingLength は残りの長さの可変長エンコードが 1〜4 バイトの境界で正しいことを確かめる
+func TestMQTTRemainingLength(t *testing.T) {
+	tests := []struct {
+		length int
+		want   []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x80, 0x01}},
+		{16383, []byte{0xff, 0x7f}},
+		{16384, []byte{0x80, 0x80, 0x01}},
+		{2097151, []byte{0xff, 0xff, 0x7f}},
+		{2097152, []byte{0x80, 0x80, 0x80, 0x01}},
+	}
+	for _, tt := range tests {
+		body := bytes.Repeat([]byte{'x'}, tt.length)
+		packet := mqttPacket(mqttPublish, body)
+		if got := packet[1 : 1+len(tt.want)]; !bytes.Equal(got, tt.want) {
+			t.Errorf("length %d encoded as % x, want % x", tt.length, got, tt.want)
+		}
+		if len(packet) != 1+len(tt.want)+tt.length {
+			t.Errorf("length %d: packet is %d bytes, want %d", tt.length, len(packet), 1+len(tt.want)+tt.length)
+		}
+		packetType, got, err := readMQTTPacket(bufio.NewReader(bytes.NewReader(packet)))
+		if err != nil {
+			t.Fatalf("length %d: %v", tt.length, err)
+		}
+		if packetType != mqttPublish || len(got) != tt.length {
+			t.Errorf("length %d decoded as type 0x%02x with %d bytes", tt.length, packetType, len(got))
+		}
+	}
+}
+
+func TestReadMQTTPacketRejectsMalformedLength(t *testing.T) {
+	for name, packet := range map[string][]byte{
+		"five length bytes": {mqttPuback, 0x80, 0x80, 0x80, 0x80, 0x01},
+		"truncated length":  {mqttPuback, 0x80},
+		"truncated body":    {mqttPuback, 0x02, 0x00},
+	} {
+		if _, _, err := readMQTTPacket(bufio.NewReader(bytes.NewReader(packet))); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestMQTTConnectPacket(t *testing.T) {
+	tests := []struct {
+		name, username, password string
+		want                     []byte
+	}{
+		{"anonymous", "", "", []byte{
+			mqttConnect, 14,
+			0, 4, 'M', 'Q', 'T', 'T', 4, 0x02, 0, 60,
+			0, 2, 'i', 'd',
+		}},
+		{"user only", "u", "", []byte{
+			mqttConnect, 17,
+			0, 4, 'M', 'Q', 'T', 'T', 4, 0x82, 0, 60,
+			0, 2, 'i', 'd',
+			0, 1, 'u',
+		}},
+		{"user and password", "u", "pw", []byte{
+			mqttConnect, 21,
+			0, 4, 'M', 'Q', 'T', 'T', 4, 0xc2, 0, 60,
+			0, 2, 'i', 'd',
+			0, 1, 'u',
+			0, 2, 'p', 'w',
+		}},
+	}
+	for _, tt := range tests {
+		if got := mqttConnectPacket("id", tt.username, tt.password); !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: got % x, want % x", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMQTTPublishPacket(t *testing.T) {
+	got := mqttPublishPacket("a/b", []byte("{}"), 0x0102)
+	want := []byte{mqttPublish | 0x03, 9, 0, 3, 'a', '/', 'b', 0x01, 0x02, '{', '}'}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got % x, want % x", got, want)
+	}
+}
+
+// TestMQTTPublishExchange はブローカーとのやり取り (CONNECT → CONNACK → PUBLISH → PUBACK → DISCONNECT) を確かめる
+func TestMQTTPublishExchange(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	type received struct {
+		topic   string
+		payload []byte
+	}
+	done := make(chan []received, 1)
+	go func() {
+		var got []received
+		defer func() { done <- got }()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		if packetType, _, err := readMQTTPacket(r); err != nil || packetType != mqttConnect {
+			return
+		}
+		_, _ = conn.Write([]byte{mqttConnack, 2, 0, 0})
+		for {
+			packetType, body, err := readMQTTPacket(r)
+			if err != nil || packetType == mqttDisconnect {
+				return
+			}
+			n := int(binary.BigEndian.Uint16(body))
+			topic, id := string(body[2:2+n]), body[2+n:4+n]
+			got = append(got, received{topic, body[4+n:]})
+			_, _ = conn.Write(append([]byte{mqttPuback, 2}, id...))
+		}
+	}()
+
+	target := &mqttTarget{Broker: &url.URL{Scheme: "mqtt", Host: ln.Addr().String()}}
+	messages := []mqttMessage{{Topic: "t/config", Payload: []byte("a")}, {Topic: "t/state", Payload: bytes.Repeat([]byte{'b'}, 200)}}
+	if err := target.publish(context.Background(), messages); err != nil {
+		t.Fatal(err)
+	}
+	got := <-done
+	if len(got) != len(messages) {
+		t.Fatalf("broker received %d messages, want %d", len(got), len(messages))
+	}
+	for i, m := range messages {
+		if got[i].topic != m.Topic || !bytes.Equal(got[i].payload, m.Payload) {
+			t.Errorf("message %d = %s (%d bytes), want %s (%d bytes)", i, got[i].topic, len(got[i].payload), m.Topic, len(m.Payload))
+		}
+	}
+}
+
+func TestMQTTPublishRefusedConnection(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _, _ = readMQTTPacket(bufio.NewReader(conn))
+		_, _ = conn.Write([]byte{mqttConnack, 2, 0, 4})
+	}()
+	target := &mqttTarget{Broker: &url.URL{Scheme: "mqtt", Host: ln.Addr().String()}}
+	err = target.publish(context.Background(), []mqttMessage{{Topic: "t", Payload: []byte("x")}})
+	if err == nil || err.Error() != "MQTT broker refused the connection: bad user name or password" {
+		t.Errorf("err = %v, want the refusal reason", err)
+	}
+}
+
+// TestBuildMQTTSummaryMatchesSections は期限切れと今日の件数が、同じ実行のセクションの件数と一致することを確かめる
+func TestBuildMQTTSummaryMatchesSections(t *testing.T) {
+	for name, loc := range map[string]*time.Location{
+		"UTC":         time.UTC,
+		"JST":         time.FixedZone("JST", 9*60*60),
+		"west of UTC": time.FixedZone("PDT", -7*60*60),
+	} {
+		now := time.Date(2026, 10, 17, 9, 0, 0, 0, loc)
+		var tasks []Task
+		for i, day := range []int{15, 16, 17, 18} {
+			d := notionapi.Date(time.Date(2026, 10, day, 0, 0, 0, 0, time.UTC))
+			tasks = append(tasks, Task{Task: task.Task{ID: notionapi.ObjectID(fmt.Sprint(i)), Title: fmt.Sprint(day), DueStart: &d}})
+		}
+		groups := buildTaskGroups(tasks, nil, nil, now, 0)
+		s := buildMQTTSummary(groups, now, 0)
+		if s.Overdue != 2 || s.Today != 1 {
+			t.Errorf("%s: overdue %d, today %d, want 2 and 1", name, s.Overdue, s.Today)
+		}
+		if s.Overdue != s.Sections["overdue"] || s.Today != s.Sections["today"] {
+			t.Errorf("%s: counts %d/%d disagree with sections %v", name, s.Overdue, s.Today, s.Sections)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+
+	"github.com/jomei/notionapi"
+
+	"rainierrr/notion-notifyer/pkg/notify"
+)
+
+// memoMrkdwn はメモを Slack の mrkdwn にする (太字・斜体・取り消し線・コード・リンク・メンションを残す)
+// 表示する文字が limit 文字 (0 なら無制限) を超える場合は、書式を崩さないよう書式を付ける前の文字を
+// できれば単語や行の区切りで切って "..." を付ける
+// slackUsers (Notion のユーザー ID → Slack ユーザー ID) にあるユーザーのメンションは Slack のメンションにする
+func memoMrkdwn(t Task, limit int, slackUsers map[string]string) string {
+	texts := t.MemoRichText
+	if len(texts) == 0 {
+		// リッチテキストの無いタスク (ペイロードから読み込んだものなど) はメモの文字列をそのまま使う
+		texts = []notionapi.RichText{{PlainText: t.Memo}}
+	}
+	f := notify.RichTextFormatter{Limit: limit, Mention: func(u *notionapi.User) string { return slackUsers[string(u.ID)] }}
+	return f.Format(texts)
+}
+
+// memoMentions はメモでメンションされたユーザーを Slack ユーザー ID にする (Notion のユーザー ID → Slack ユーザー ID)
+// 対応表かメールアドレスで見つからないユーザーは含めない (メモにはユーザー名のまま表示する)
+func memoMentions(ctx context.Context, tasks []Task, r *slackUserResolver) map[string]string {
+	users := map[string]string{}
+	for _, task := range tasks {
+		for _, rt := range task.MemoRichText {
+			if rt.Mention == nil || rt.Mention.User == nil {
+				continue
+			}
+			u := rt.Mention.User
+			if _, ok := users[string(u.ID)]; ok {
+				continue
+			}
+			a := Assignee{Name: u.Name}
+			if u.Person != nil {
+				a.Email = u.Person.Email
+			}
+			if id := r.resolve(ctx, a); id != "" {
+				users[string(u.ID)] = id
+			}
+		}
+	}
+	return users
+}
+
+// richTextMrkdwn は Notion のリッチテキストを Slack の mrkdwn にする (notify.RichTextFormatter)
+func richTextMrkdwn(texts []notionapi.RichText, limit int) string {
+	return notify.RichTextFormatter{Limit: limit}.Format(texts)
+}
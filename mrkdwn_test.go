@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jomei/notionapi"
+	"github.com/slack-go/slack"
+)
+
+// リッチテキストの要素の一覧 (Notion API の JSON)。書式の付け方がおかしいものやペイロードの欠けたものを含む
+var richTextSeeds = []string{
+	`[]`,
+	`[{"type":"text","text":{"content":"plain"}}]`,
+	`[{"type":"text","text":null,"plain_text":"no text payload"}]`,
+	`[{"type":"mention","mention":null,"plain_text":"@nobody"}]`,
+	`[{"type":"mention","mention":{"type":"page","page":null},"plain_text":"page"}]`,
+	`[{"type":"mention","mention":{"type":"user","user":null},"plain_text":"@user"}]`,
+	`[{"type":"equation","equation":null}]`,
+	`[{"type":"equation","equation":{"expression":"a<b"}}]`,
+	`[{"type":"text","text":{"content":"  all  \n  at once  "},"annotations":{"bold":true,"italic":true,"strikethrough":true,"code":true,"underline":true,"color":"red"}}]`,
+	`[{"type":"text","text":{"content":"link","link":{"url":"https://example.com/a>b|c"}},"annotations":{"bold":true}}]`,
+	`[{"type":"text","text":{"content":"\n\n"},"annotations":{"italic":true}},{"type":"text","text":{"content":""},"annotations":{"code":true}}]`,
+	`[{"type":"text","text":{"content":"日本語のメモ"},"href":"https://example.com/<x>","annotations":null}]`,
+}
+
+// mrkdwn の書式の記号
+const mrkdwnMarkers = "*_~`"
+
+// リンク <url|text> の URL の部分
+var mrkdwnLinkURL = regexp.MustCompile(`<[^|<>]*\|`)
+
+// FuzzRichTextMrkdwn は richTextMrkdwn が panic せず、リンクの括弧と書式の記号が対になっていることを確かめる
+// 書式の記号を数えるため、文字からは記号を取り除いてから変換する
+func FuzzRichTextMrkdwn(f *testing.F) {
+	for _, seed := range richTextSeeds {
+		f.Add(seed, 0)
+		f.Add(seed, 5)
+	}
+	f.Fuzz(func(t *testing.T, data string, limit int) {
+		var texts []notionapi.RichText
+		if err := json.Unmarshal([]byte(data), &texts); err != nil {
+			return
+		}
+		stripMarkers := func(s string) string {
+			return strings.Map(func(r rune) rune {
+				if strings.ContainsRune(mrkdwnMarkers, r) {
+					return -1
+				}
+				return r
+			}, s)
+		}
+		for i := range texts {
+			texts[i].PlainText = stripMarkers(texts[i].PlainText)
+			if texts[i].Text != nil {
+				texts[i].Text.Content = stripMarkers(texts[i].Text.Content)
+			}
+			if texts[i].Equation != nil {
+				texts[i].Equation.Expression = stripMarkers(texts[i].Equation.Expression)
+			}
+		}
+
+		out := richTextMrkdwn(texts, limit)
+		depth := 0
+		for _, r := range out {
+			switch r {
+			case '<':
+				depth++
+			case '>':
+				depth--
+			}
+			if depth < 0 || depth > 1 {
+				t.Fatalf("unbalanced link brackets in %q", out)
+			}
+		}
+		if depth != 0 {
+			t.Fatalf("unclosed link in %q", out)
+		}
+		visible := mrkdwnLinkURL.ReplaceAllString(out, "<")
+		for _, marker := range mrkdwnMarkers {
+			if n := strings.Count(visible, string(marker)); n%2 != 0 {
+				t.Fatalf("odd number (%d) of %q in %q", n, marker, out)
+			}
+		}
+	})
+}
+
+func TestRichTextMrkdwn(t *testing.T) {
+	text := func(content string, a *notionapi.Annotations) notionapi.RichText {
+		return notionapi.RichText{Type: notionapi.ObjectTypeText, Text: &notionapi.Text{Content: content}, Annotations: a}
+	}
+	tests := []struct {
+		name  string
+		texts []notionapi.RichText
+		limit int
+		want  string
+	}{
+		{"bold keeps outer spaces", []notionapi.RichText{text(" a ", &notionapi.Annotations{Bold: true})}, 0, " *a* "},
+		{"formats each line", []notionapi.RichText{text("a\nb", &notionapi.Annotations{Italic: true})}, 0, "_a_\n_b_"},
+		{"escapes text", []notionapi.RichText{text("a<b>&c", nil)}, 0, "a&lt;b&gt;&amp;c"},
+		{"escapes link URL", []notionapi.RichText{{Text: &notionapi.Text{Content: "x", Link: &notionapi.Link{Url: "https://e.com/?q=a|b>"}}}}, 0, "<https://e.com/?q=a%7Cb%3E|x>"},
+		{"nil mention uses plain text", []notionapi.RichText{{Type: "mention", PlainText: "@a"}}, 0, "@a"},
+		{"page mention links to Notion", []notionapi.RichText{{Type: "mention", PlainText: "P", Mention: &notionapi.Mention{Type: "page", Page: &notionapi.PageMention{ID: "ab-cd"}}}}, 0, "<https://www.notion.so/abcd|P>"},
+		{"truncates inside the format", []notionapi.RichText{text("hello world", &notionapi.Annotations{Code: true})}, 8, "`hello`..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := richTextMrkdwn(tt.texts, tt.limit); got != tt.want {
+				t.Errorf("richTextMrkdwn() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMemoMrkdwnUserMentions はメモのユーザーのメンションを、対応表かメールアドレスで見つかった Slack ユーザーのメンションにすることを確かめる
+func TestMemoMrkdwnUserMentions(t *testing.T) {
+	discardLogs(t)
+	useMockServer(t, time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC))
+
+	mention := func(id, name, email string) notionapi.RichText {
+		user := &notionapi.User{ID: notionapi.UserID(id), Name: name}
+		if email != "" {
+			user.Person = &notionapi.Person{Email: email}
+		}
+		return notionapi.RichText{Type: "mention", PlainText: "@" + name, Mention: &notionapi.Mention{Type: "user", User: user}}
+	}
+	plain := func(s string) notionapi.RichText { return notionapi.RichText{PlainText: s} }
+	task := Task{}
+	task.MemoRichText = []notionapi.RichText{
+		mention("u1", "Taro", "taro@example.com"), plain(" / "),
+		mention("u2", "Hanako", "hanako@example.com"), plain(" / "),
+		mention("u3", "Guest", "guest@example.com"), plain(" / "),
+		mention("u4", "Bot", ""),
+	}
+
+	r := &slackUserResolver{client: slack.New("xoxb-test"), mapping: map[string]string{"taro@example.com": "U0MAPPED"}, cache: map[string]string{}}
+	users := memoMentions(context.Background(), []Task{task}, r)
+	if got, want := memoMrkdwn(task, 0, users), "<@U0MAPPED> / <@U000HANAKO> / @Guest / @Bot"; got != want {
+		t.Errorf("memoMrkdwn() = %q, want %q", got, want)
+	}
+	// 切り詰めたメンションはユーザー名のままにする
+	if got, want := memoMrkdwn(task, 3, users), "@Ta..."; got != want {
+		t.Errorf("truncated memoMrkdwn() = %q, want %q", got, want)
+	}
+	if got, want := memoMrkdwn(task, 0, nil), "@Taro / @Hanako / @Guest / @Bot"; got != want {
+		t.Errorf("memoMrkdwn() without users = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/slack-go/slack"
+	"github.com/spf13/cobra"
+)
+
+// ミュートボタンの action_id
+const muteTaskActionID = "mute_task"
+
+// muteTaskValue はミュートボタンに持たせる値
+type muteTaskValue struct {
+	PageID string `json:"id"`
+	Title  string `json:"title"`
+}
+
+// muteTaskButton はタスクを今後のダイジェストに載せないようにするボタンを作る
+func muteTaskButton(task Task) (*slack.ButtonBlockElement, error) {
+	value, err := json.Marshal(muteTaskValue{PageID: string(task.ID), Title: task.Title})
+	if err != nil {
+		return nil, err
+	}
+	button := slack.NewButtonBlockElement(muteTaskActionID, string(value),
+		slack.NewTextBlockObject(slack.PlainTextType, tr("button.mute"), true, false))
+	button.Confirm = slack.NewConfirmationBlockObject(
+		slack.NewTextBlockObject(slack.PlainTextType, tr("mute.confirm_title"), false, false),
+		slack.NewTextBlockObject(slack.MarkdownType, tr("mute.confirm_text", task.Title), false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, tr("mute.confirm"), false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, tr("mute.cancel"), false, false),
+	)
+	return button, nil
+}
+
+// handleMuteTask はボタンのタスクを取り消すまでダイジェストに載せないよう記録し、押したユーザーに知らせる
+func handleMuteTask(ctx context.Context, env *interactionEnv, callback slack.InteractionCallback, action *slack.BlockAction) error {
+	var value muteTaskValue
+	if err := json.Unmarshal([]byte(action.Value), &value); err != nil {
+		return fmt.Errorf("invalid button value: %w", err)
+	}
+	now := env.clock.Now()
+	if err := muteTask(env.store, value.PageID, taskMute{Title: value.Title, By: callback.User.ID, CreatedAt: now}, now); err != nil {
+		return err
+	}
+
+	slackClient, err := env.slackClient(ctx, callback.Team.ID)
+	if err != nil {
+		return err
+	}
+	text := tr("mute.done", value.Title, strings.ReplaceAll(value.PageID, "-", ""))
+	_, err = slackClient.PostEphemeralContext(ctx, callback.Container.ChannelID, callback.User.ID, slack.MsgOptionText(text, false))
+	return err
+}
+
+// sameNotionID はハイフンの有無に関係なく Notion の ID を比べる
+func sameNotionID(a, b string) bool {
+	return strings.EqualFold(strings.ReplaceAll(a, "-", ""), strings.ReplaceAll(b, "-", ""))
+}
+
+var mutesCmd = &cobra.Command{
+	Use:   "mutes",
+	Short: "Manage tasks that are muted or snoozed from Slack.",
+}
+
+var mutesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List muted and snoozed tasks.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := stateStoreFromEnv().Load()
+		if err != nil {
+			return err
+		}
+		clock, err := clockFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		now := clock.Now()
+
+		ids := make([]string, 0, len(st.MutedTasks))
+		for id, m := range st.MutedTasks {
+			if m.active(now) {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No muted tasks.")
+			return nil
+		}
+		slices.SortFunc(ids, func(a, b string) int {
+			return st.MutedTasks[a].CreatedAt.Compare(st.MutedTasks[b].CreatedAt)
+		})
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PAGE ID\tTITLE\tUNTIL\tBY\tSINCE")
+		for _, id := range ids {
+			m := st.MutedTasks[id]
+			until := "forever"
+			if m.Until != nil {
+				until = m.Until.In(now.Location()).Format("2006-01-02 15:04")
+			}
+			by := m.By
+			if by == "" {
+				by = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", strings.ReplaceAll(id, "-", ""), m.Title, until, by, m.CreatedAt.In(now.Location()).Format("2006-01-02"))
+		}
+		return w.Flush()
+	},
+}
+
+var mutesRemoveCmd = &cobra.Command{
+	Use:   "remove <page-id>...",
+	Short: "Unmute tasks so they appear in the digest again.",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var missing []string
+		err := stateStoreFromEnv().Update(func(st *state) error {
+			for _, arg := range args {
+				found := false
+				for id, m := range st.MutedTasks {
+					if sameNotionID(id, arg) {
+						delete(st.MutedTasks, id)
+						fmt.Fprintf(cmd.OutOrStdout(), "Unmuted %s (%s)\n", arg, m.Title)
+						found = true
+					}
+				}
+				if !found {
+					missing = append(missing, arg)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("not muted: %s", strings.Join(missing, ", "))
+		}
+		return nil
+	},
+}
+
+func init() {
+	registerInteraction(muteTaskActionID, handleMuteTask)
+	mutesCmd.AddCommand(mutesListCmd, mutesRemoveCmd)
+	rootCmd.AddCommand(mutesCmd)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/spf13/pflag"
+)
+
+// Notifier はダイジェストの送り先
+// 送り先は RegisterNotifier で登録し、--target で名前を指定して選ぶ
+type Notifier interface {
+	// Send はセクションごとに分けたタスクを送る。ドライランでは送らずに内容を出力する
+	Send(ctx context.Context, run digestRun, groups []TaskGroup) error
+}
+
+// stateNotifier は送るたびに最新の状態で置き換える送り先 (MQTT など)
+// タスクが無いときも 0 件の状態を送り、前回の件数が残らないようにする
+type stateNotifier interface {
+	Notifier
+	replacesState()
+}
+
+// TaskGroup はダイジェストの 1 つのセクション
+type TaskGroup struct {
+	Name  string // セクションの名前 (overdue や pinned など)
+	Title string
+	Tasks []Task                // 優先度・期限日順
+	Parts []templateSectionPart // 時間帯の小見出しごとのタスク (--time-of-day で指定していないセクションでは Title が空の 1 つ)
+}
+
+// notifierFactory は環境変数とフラグから Notifier を作る。必要な設定が足りなければエラーを返す
+type notifierFactory func(flags *pflag.FlagSet) (Notifier, error)
+
+// 登録された送り先 (登録順)
+var (
+	notifierNames     []string
+	notifierFactories = map[string]notifierFactory{}
+)
+
+// RegisterNotifier は --target で選べる送り先を登録する。送り先のファイルの init から呼ぶ
+func RegisterNotifier(name string, factory notifierFactory) {
+	if _, ok := notifierFactories[name]; ok {
+		panic(fmt.Sprintf("notifier %q is already registered", name))
+	}
+	notifierNames = append(notifierNames, name)
+	notifierFactories[name] = factory
+}
+
+// namedNotifier はログに出すための名前を付けた Notifier
+type namedNotifier struct {
+	Name string
+	Notifier
+}
+
+// newNotifiers は --target で選ばれた送り先を登録順に作る
+func newNotifiers(targets map[string]bool, flags *pflag.FlagSet) ([]namedNotifier, error) {
+	var notifiers []namedNotifier
+	for _, name := range notifierNames {
+		if !targets[name] {
+			continue
+		}
+		n, err := notifierFactories[name](flags)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, namedNotifier{Name: name, Notifier: n})
+	}
+	return notifiers, nil
+}
+
+// withoutNotifier は name の送り先を除いた一覧を返す
+func withoutNotifier(notifiers []namedNotifier, name string) []namedNotifier {
+	return slices.DeleteFunc(slices.Clone(notifiers), func(n namedNotifier) bool { return n.Name == name })
+}
+
+// buildTaskGroups はタスクを表示するセクションごとに分ける。タスクの無いセクションは除く
+func buildTaskGroups(tasks []Task, sections, timeOfDay []string, now time.Time, withinHours int) []TaskGroup {
+	if sections == nil {
+		sections = defaultSectionOrder()
+	}
+	grouped := groupTasksBySection(tasks, now, withinHours)
+	var groups []TaskGroup
+	for _, name := range sections {
+		group := grouped[name]
+		if len(group) == 0 {
+			continue
+		}
+		parts := []templateSectionPart{{Tasks: group}}
+		if slices.Contains(timeOfDay, name) {
+			parts = splitTimeOfDay(group)
+		}
+		groups = append(groups, TaskGroup{Name: name, Title: sectionTitle(name, withinHours), Tasks: group, Parts: parts})
+	}
+	return groups
+}
+
+// groupedTasks はセクションの順にタスクを 1 列に並べる
+func groupedTasks(groups []TaskGroup) []Task {
+	var tasks []Task
+	for _, g := range groups {
+		tasks = append(tasks, g.Tasks...)
+	}
+	return tasks
+}
+
+// digestRun は 1 回の送信の情報。Send の引数で Notifier に渡す
+type digestRun struct {
+	JobName      string
+	Now          time.Time
+	RunNumber    string
+	DryRun       bool
+	Destinations []slackDestination // Slack の投稿先 (ルーティング済み)
+	usage        *apiUsage
+	blocks       func(tasks []Task) ([]slack.Block, error)                        // Slack に投稿するのと同じメッセージを組み立てる (投稿先に依らない部分)
+	slackBlocks  func(tasks []Task, dest slackDestination) ([]slack.Block, error) // 投稿先ごとのメッセージ (カレンダーや未読のタスクを含む)
+}
+
+// partialSendError は複数の宛先の一部にだけ送れたことを表す (Sent が 0 なら 1 つにも送れていない)
+type partialSendError struct {
+	Sent int
+	Err  error
+}
+
+func (e *partialSendError) Error() string { return e.Err.Error() }
+func (e *partialSendError) Unwrap() error { return e.Err }
+
+// delivered は Send の結果から、1 つ以上の宛先に送れたかを返す
+func delivered(err error) bool {
+	var partial *partialSendError
+	return err == nil || (errors.As(err, &partial) && partial.Sent > 0)
+}
+
+// httpClient は API の呼び出し回数を name で計測する HTTP クライアントを返す
+func (r digestRun) httpClient(name string) *http.Client {
+	if r.usage == nil {
+		return http.DefaultClient
+	}
+	return r.usage.Client(name)
+}
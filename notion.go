@@ -2,150 +2,90 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"log"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/jomei/notionapi"
+
+	"rainierrr/notion-notifyer/pkg/notion"
+	"rainierrr/notion-notifyer/pkg/task"
 )
 
+// Task はダイジェストに載せるタスク
+// Notion から取得した内容 (task.Task) に、掲載の前に調べた不在や Issue の状態などを足したもの
 type Task struct {
-	ID             notionapi.ObjectID
-	Title          string
-	DueStart       *notionapi.Date
-	DueEnd         *notionapi.Date
-	Priority       string // High, Medium, Low,
-	Type           string
-	ScheduleStatus string
-	Workload       float32
-	Memo           string
-	URL            string
+	task.Task
+	Absences   []AbsenceNote // 不在の担当者 (applyAbsences で設定)
+	GitHubRefs []GitHubRef   // Memo と Link に含まれる GitHub の Issue/PR (applyGitHubStatus で設定)
+	JiraRefs   []JiraRef     // タイトル・Memo・Link に含まれる Jira の課題 (applyJiraStatus で設定)
+	Streak     int           // 何日連続でダイジェストに掲載されているか (履歴がある場合のみ設定)
+	Slip       *taskSlip     // 期限が延期された回数と元の期限 (履歴がある場合のみ設定)
+	Projects   []string      // リレーション先のプロジェクトのタイトル (resolveProjects で設定)
 }
 
-// 優先度の順序マッピング
-var priorityOrder = map[string]int{
-	"High": 1,
-	"Mid":  2,
-	"Low":  3,
-	"":     4, // 空の優先度は最も低い
-}
+// Assignee は People プロパティの担当者
+type Assignee = task.Assignee
 
-var SCHEDULE_STATUSES = []string{
-	"CannotDo", "Next", "Want", "ToDo", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday", "Doing", "iPhone Task",
-}
+// ピン留めに使うチェックボックスプロパティ (--pinned-property、空なら使わない)
+// ピン留めのタスクは期限日に関係なく取得する
+var pinnedProp string
 
-func fetchNotionTasks(ctx context.Context, client *notionapi.Client, dbID string, onOrBeforeDate time.Time) ([]Task, error) {
-	var allTasks []Task
+// 1 回の問い合わせで取得する件数 (--page-size、Notion API の上限は 100)
+var queryPageSize = 100
 
-	request := &notionapi.DatabaseQueryRequest{
-		Filter: &notionapi.AndCompoundFilter{
-			&notionapi.PropertyFilter{
-				Property: dueProp,
-				Date: &notionapi.DateFilterCondition{
-					OnOrBefore: (*notionapi.Date)(&onOrBeforeDate),
-				},
-			},
-			createStatusFilter(),
-		},
-		Sorts: []notionapi.SortObject{
-			{Property: dueProp, Direction: notionapi.SortOrderASC},      // 期限日でソート
-			{Property: priorityProp, Direction: notionapi.SortOrderASC}, // ステータスでソート
+// 1 つのデータベースから取得するタスクの上限 (--max-results、0 なら上限なし)
+var maxQueryResults = 1000
+
+// notionFetcher は設定ファイルとフラグのプロパティ名でタスクを取得する Fetcher を返す
+// ステータスのグループは読み替えないので、データベースのタスクを取得するときは statusFetcher を使う
+func notionFetcher(client *notionapi.Client) *notion.Fetcher {
+	f := &notion.Fetcher{
+		Client: client,
+		Properties: notion.Properties{
+			Name:     nameProp,
+			UniqueID: uniqueIDProp,
+			Due:      dueProp,
+			Priority: priorityProp,
+			Type:     typeProp,
+			Status:   scheduleStatusProp,
+			Workload: workloadProp,
+			Memo:     memoProp,
+			Link:     linkProp,
+			Email:    emailProp,
+			Phone:    phoneProp,
+			Assignee: assigneeProp,
+			Tags:     tagsProp,
+			Project:  projectProp,
+			Pinned:   pinnedProp,
+			Progress: progressProp,
+			Files:    filesProp,
+			Extra:    extraProps,
+			Lookup:   jiraLookupProps(),
 		},
+		PageSize:   queryPageSize,
+		MaxResults: maxQueryResults,
 	}
+	configuredStatuses.apply(f, nil)
+	return f
+}
 
-	resp, err := client.Database.Query(ctx, notionapi.DatabaseID(dbID), request)
+// fetchDatabaseTasks は 1 つのデータベースから期限日が onOrBeforeDate までのタスクを取得する
+func fetchDatabaseTasks(ctx context.Context, client *notionapi.Client, dbID string, onOrBeforeDate time.Time) ([]Task, error) {
+	fetched, err := statusFetcher(ctx, client, dbID).Fetch(ctx, dbID, onOrBeforeDate)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query database: %w", err)
-	}
-
-	for _, page := range resp.Results {
-		task := parseNotionPage(page)
-		// 開始日と終了日が両方とも設定されている場合、Notion APIでは開始日が優先的にフィルターに利用されるため、終了日をチェックする
-		if task.DueEnd != nil && time.Time(*task.DueEnd).After(onOrBeforeDate) {
-			continue
-		}
-		if task != nil {
-			allTasks = append(allTasks, *task)
-		}
+		return nil, newNotionError("", err)
 	}
-
-	return allTasks, nil
-}
-
-func createStatusFilter() notionapi.OrCompoundFilter {
-	var filters []notionapi.Filter
-	for _, status := range SCHEDULE_STATUSES {
-		filters = append(filters, &notionapi.PropertyFilter{
-			Property: scheduleStatusProp,
-			Status: &notionapi.StatusFilterCondition{
-				Equals: status,
-			},
-		})
+	tasks := make([]Task, 0, len(fetched))
+	for _, t := range fetched {
+		tasks = append(tasks, Task{Task: t})
 	}
-	return notionapi.OrCompoundFilter(filters)
+	return tasks, nil
 }
 
 // Notion ページを Task 構造体に変換する
 func parseNotionPage(page notionapi.Page) *Task {
-	task := Task{
-		ID:  page.ID,
-		URL: page.URL,
-	}
-
-	// プロパティを安全に反復処理
-	for propName, propValue := range page.Properties {
-		switch propName {
-		case nameProp:
-			if p, ok := propValue.(*notionapi.TitleProperty); ok && len(p.Title) > 0 {
-				task.Title = p.Title[0].Text.Content
-			}
-		case dueProp:
-			if p, ok := propValue.(*notionapi.DateProperty); ok && p.Date != nil {
-				task.DueStart = p.Date.Start
-				task.DueEnd = p.Date.End
-			}
-		case priorityProp:
-			if p, ok := propValue.(*notionapi.SelectProperty); ok && p.Select.Name != "" {
-				task.Priority = p.Select.Name
-			}
-		case typeProp:
-			if p, ok := propValue.(*notionapi.SelectProperty); ok && p.Select.Name != "" {
-				task.Type = p.Select.Name
-			}
-		case scheduleStatusProp:
-			if p, ok := propValue.(*notionapi.StatusProperty); ok && p.Status.Name != "" {
-				task.ScheduleStatus = p.Status.Name
-			}
-		case workloadProp:
-			if p, ok := propValue.(*notionapi.SelectProperty); ok && p.Select.Name != "" {
-				workload, err := strconv.ParseFloat(p.Select.Name, 32)
-				if err == nil {
-					task.Workload = float32(workload)
-				} else {
-					log.Printf("Warning: Unable to parse workload for task ID %s: %v", task.ID, err)
-				}
-			}
-		case memoProp:
-			if p, ok := propValue.(*notionapi.RichTextProperty); ok && len(p.RichText) > 0 {
-				var memoBuilder strings.Builder
-				for i, rt := range p.RichText {
-					if i > 0 {
-						memoBuilder.WriteString("\n")
-					}
-					memoBuilder.WriteString(rt.Text.Content)
-				}
-				task.Memo = memoBuilder.String()
-			}
-		}
-	}
-
-	// 必須プロパティの検証: タイトルと期限日は必須
-	if task.Title == "" || (task.DueStart == nil && task.DueEnd == nil) {
-		log.Printf("Warning: Task with ID %s is missing required properties (Title or Due Date). Skipping.", task.ID)
+	t := notionFetcher(nil).ParsePage(page)
+	if t == nil {
 		return nil
 	}
-
-	return &task
+	return &Task{Task: *t}
 }
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/jomei/notionapi"
+	"github.com/spf13/cobra"
+)
+
+// Notion OAuth (パブリックインテグレーション) 関連
+const (
+	notionClientIDEnv     = "NOTION_CLIENT_ID"
+	notionClientSecretEnv = "NOTION_CLIENT_SECRET"
+	notionAuthorizeURL    = "https://api.notion.com/v1/oauth/authorize"
+)
+
+// notionAuth は OAuth で取得した Notion のアクセストークン
+// Notion のトークンは失効しないため、refresh token は持たない
+type notionAuth struct {
+	AccessToken   string    `json:"access_token"`
+	BotID         string    `json:"bot_id"`
+	WorkspaceID   string    `json:"workspace_id"`
+	WorkspaceName string    `json:"workspace_name"`
+	AuthorizedAt  time.Time `json:"authorized_at"`
+}
+
+// resolveNotionToken は NOTION_TOKEN (内部インテグレーション) を優先し、
+// 無ければ init で保存した OAuth トークンを返す
+// 複数のワークスペースを認可している場合は、どれを使うか決められないためエラーにする
+func resolveNotionToken(store *stateStore) (string, error) {
+	if token := os.Getenv(notionTokenEnv); token != "" {
+		return token, nil
+	}
+	st, err := store.Load()
+	if err != nil {
+		return "", err
+	}
+	switch len(st.NotionAuths) {
+	case 0:
+		return "", nil
+	case 1:
+		for _, auth := range st.NotionAuths {
+			return auth.AccessToken, nil
+		}
+	}
+	return "", fmt.Errorf("%d Notion workspaces are authorized; set %s or use tenants to choose one", len(st.NotionAuths), notionTokenEnv)
+}
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Authorize access to a Notion workspace via OAuth (public integration).",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		port, _ := cmd.Flags().GetInt("port")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		clientID := os.Getenv(notionClientIDEnv)
+		clientSecret := os.Getenv(notionClientSecretEnv)
+		if clientID == "" || clientSecret == "" {
+			return fmt.Errorf("%s and %s must be set", notionClientIDEnv, notionClientSecretEnv)
+		}
+
+		oauthState, err := randomState()
+		if err != nil {
+			return err
+		}
+		redirectURI := fmt.Sprintf("http://localhost:%d/callback", port)
+
+		q := url.Values{}
+		q.Set("client_id", clientID)
+		q.Set("response_type", "code")
+		q.Set("owner", "user")
+		q.Set("redirect_uri", redirectURI)
+		q.Set("state", oauthState)
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+		defer cancel()
+
+		code, err := waitForOAuthCode(ctx, fmt.Sprintf("127.0.0.1:%d", port), oauthState, notionAuthorizeURL+"?"+q.Encode())
+		if err != nil {
+			return err
+		}
+
+		client := notionapi.NewClient("", notionapi.WithOAuthAppCredentials(clientID, clientSecret))
+		resp, err := client.Authentication.CreateToken(ctx, &notionapi.TokenCreateRequest{
+			Code:        code,
+			GrantType:   "authorization_code",
+			RedirectUri: redirectURI,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to exchange Notion OAuth code: %w", err)
+		}
+
+		store := stateStoreFromEnv()
+		err = store.Update(func(st *state) error {
+			if st.NotionAuths == nil {
+				st.NotionAuths = map[string]*notionAuth{}
+			}
+			st.NotionAuths[resp.WorkspaceId] = &notionAuth{
+				AccessToken:   resp.AccessToken,
+				BotID:         resp.BotId,
+				WorkspaceID:   resp.WorkspaceId,
+				WorkspaceName: resp.WorkspaceName,
+				AuthorizedAt:  systemClock{}.Now(),
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Authorized Notion workspace %s. The token was saved to %s.", resp.WorkspaceName, store.path)
+		return nil
+	},
+}
+
+// waitForOAuthCode はローカルのコールバックサーバーを起動し、認可コードを受け取るまで待つ
+func waitForOAuthCode(ctx context.Context, addr, oauthState, authorizeURL string) (string, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", fmt.Errorf("failed to start callback server: %w", err)
+	}
+
+	codeCh := make(chan string, 1)
+	errCh := make(chan error, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case q.Get("error") != "":
+			http.Error(w, "Authorization was cancelled.", http.StatusBadRequest)
+			errCh <- fmt.Errorf("authorization failed: %s", q.Get("error"))
+		case q.Get("state") != oauthState:
+			http.Error(w, "Invalid OAuth state.", http.StatusBadRequest)
+		case q.Get("code") == "":
+			http.Error(w, "Missing code.", http.StatusBadRequest)
+		default:
+			fmt.Fprintln(w, "Authorization complete. You can close this window.")
+			codeCh <- q.Get("code")
+		}
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+	}()
+	defer server.Close()
+
+	fmt.Printf("Open the following URL in your browser to authorize Notion Notifyer:\n\n  %s\n\n", authorizeURL)
+
+	select {
+	case code := <-codeCh:
+		return code, nil
+	case err := <-errCh:
+		return "", err
+	case <-ctx.Done():
+		return "", fmt.Errorf("timed out waiting for authorization: %w", ctx.Err())
+	}
+}
+
+func init() {
+	initCmd.Flags().Int("port", 8765, "Local port for the OAuth callback (register http://localhost:<port>/callback as a redirect URI)")
+	initCmd.Flags().Duration("timeout", 5*time.Minute, "How long to wait for authorization")
+	rootCmd.AddCommand(initCmd)
+}
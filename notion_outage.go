@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/spf13/cobra"
+)
+
+// Notion のメンテナンスの応答を待つ時間の上限 (--notion-outage-timeout)
+var notionOutageTimeout = 10 * time.Minute
+
+// メンテナンスの応答を再試行する間隔 (倍にしながら上限まで伸ばす)
+const (
+	notionOutageBaseDelay = 15 * time.Second
+	notionOutageMaxDelay  = 2 * time.Minute
+)
+
+// --watch で --outage-retry-after を指定しなかったときに実行し直すまでの時間
+// 1 回だけの実行 (cron や GitHub Actions) では待ち続けないよう、既定では実行し直さない
+const defaultWatchOutageRetryAfter = 30 * time.Minute
+
+// notionOutageError は Notion がメンテナンスや障害で 502 か 503 を返し続けたことを表す
+type notionOutageError struct {
+	Status int
+	Since  time.Time
+}
+
+func (e *notionOutageError) Error() string {
+	return fmt.Sprintf("Notion is unavailable (HTTP %d since %s)", e.Status, e.Since.Format(time.TimeOnly))
+}
+
+// isNotionMaintenance は Notion がメンテナンスや障害のときの 502 と 503 かどうかを判定する
+func isNotionMaintenance(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil || req.URL.Host != "api.notion.com" {
+		return false
+	}
+	return resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable
+}
+
+// notionOutageDelay は n 回目のメンテナンスの応答の後に待つ時間を返す
+func notionOutageDelay(n int) time.Duration {
+	d := notionOutageBaseDelay << (n - 1)
+	if d <= 0 || d > notionOutageMaxDelay {
+		d = notionOutageMaxDelay
+	}
+	return d
+}
+
+// outageRetry は Notion の障害でダイジェストを送れなかったときに、後で実行し直す設定
+type outageRetry struct {
+	After      time.Duration // 0 なら実行し直さない
+	MaxRetries int
+}
+
+// outageRetryFromFlags は --outage-retry-after と --outage-retries を返す
+// 指定が無ければ --watch のときだけ defaultWatchOutageRetryAfter 後に実行し直す
+func outageRetryFromFlags(cmd *cobra.Command) (outageRetry, error) {
+	after, _ := cmd.Flags().GetDuration("outage-retry-after")
+	retries, _ := cmd.Flags().GetInt("outage-retries")
+	if after < 0 || retries < 0 {
+		return outageRetry{}, configErrorf("--outage-retry-after and --outage-retries must not be negative")
+	}
+	if watch, _ := cmd.Flags().GetBool("watch"); watch && !cmd.Flags().Changed("outage-retry-after") {
+		after = defaultWatchOutageRetryAfter
+	}
+	return outageRetry{After: after, MaxRetries: retries}, nil
+}
+
+// postOutageNotice は Notion の障害でダイジェストが遅れることを投稿先に知らせる (retryAt が zero なら諦めたことを知らせる)
+// 再試行の途中では知らせず、最初に遅れたときと諦めたときだけ呼ぶ
+// 知らせられなくてもダイジェストのエラーを優先するため、失敗はログに出すだけにする
+func postOutageNotice(ctx context.Context, job digestJob, outage *notionOutageError, retryAt time.Time, now time.Time) {
+	if job.DryRun || job.Output != nil {
+		return
+	}
+	text := tr("outage.gave_up", outage.Status)
+	if !retryAt.IsZero() {
+		text = tr("outage.delayed", outage.Status, retryAt.Format("15:04"))
+	}
+	for _, dest := range job.Destinations {
+		client, err := dest.Tokens.Client(ctx)
+		if err == nil {
+			err = resolveChannel(ctx, client, job.Store, now, &dest)
+		}
+		if err == nil {
+			_, _, err = client.PostMessageContext(ctx, dest.ChannelID, slack.MsgOptionText(text, false))
+		}
+		if err != nil {
+			log.Printf("[%s] Warning: Unable to post the outage notice (%s): %v", job.Name, dest.Name, err)
+		}
+	}
+}
+
+// runDigestWithOutageRetry はダイジェストを送り、Notion の障害で送れなければ遅れることを知らせて retry.After 後に実行し直す
+// retry.After が 0 なら実行し直さず、送れなかったことだけを知らせる
+// makeJob は実行のたびにジョブを作り直す (状態ファイルのトークンが更新されるため)
+func runDigestWithOutageRetry(ctx context.Context, makeJob func() (digestJob, error), clock Clock, retry outageRetry) error {
+	for attempt := 0; ; attempt++ {
+		job, err := makeJob()
+		if err != nil {
+			return err
+		}
+		// 投稿の途中では中断せず、停止の合図は待っている間だけ受け付ける
+		err = runDigest(context.WithoutCancel(ctx), job, clock.Now())
+		var outage *notionOutageError
+		if !errors.As(err, &outage) {
+			return err
+		}
+		if retry.After == 0 || attempt >= retry.MaxRetries {
+			postOutageNotice(context.WithoutCancel(ctx), job, outage, time.Time{}, clock.Now())
+			return err
+		}
+
+		retryAt := time.Now().Add(retry.After)
+		// 遅れの知らせは最初の 1 回だけにする
+		if attempt == 0 {
+			postOutageNotice(context.WithoutCancel(ctx), job, outage, retryAt, clock.Now())
+		}
+		log.Printf("[%s] %v; retrying the digest at %s (%d of %d)", job.Name, err, retryAt.Format(time.RFC3339), attempt+1, retry.MaxRetries)
+		timer := time.NewTimer(retry.After)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func init() {
+	rootCmd.PersistentFlags().DurationVar(&notionOutageTimeout, "notion-outage-timeout", notionOutageTimeout, "How long to keep retrying while Notion answers 502/503 (maintenance) before giving up on the run")
+	rootCmd.Flags().Duration("outage-retry-after", 0, fmt.Sprintf("When Notion is still unavailable after --notion-outage-timeout, post a delay notice and run the digest again after this long (default %s with --watch; 0 posts a notice and fails right away)", defaultWatchOutageRetryAfter))
+	rootCmd.Flags().Int("outage-retries", 3, "Maximum number of delayed runs after a Notion outage")
+}
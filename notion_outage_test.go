@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// TestOutageRetryOnlyDefaultsInWatchMode は --outage-retry-after を指定しなければ --watch のときだけ実行し直すことを確かめる
+func TestOutageRetryOnlyDefaultsInWatchMode(t *testing.T) {
+	tests := []struct {
+		args []string
+		want time.Duration
+	}{
+		{nil, 0},
+		{[]string{"--watch"}, defaultWatchOutageRetryAfter},
+		{[]string{"--watch", "--outage-retry-after", "0"}, 0},
+		{[]string{"--outage-retry-after", "5m"}, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		cmd := &cobra.Command{}
+		cmd.Flags().Bool("watch", false, "")
+		cmd.Flags().Duration("outage-retry-after", 0, "")
+		cmd.Flags().Int("outage-retries", 3, "")
+		if err := cmd.Flags().Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		retry, err := outageRetryFromFlags(cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if retry.After != tt.want {
+			t.Errorf("%v: retry after %s, want %s", tt.args, retry.After, tt.want)
+		}
+	}
+}
+
+// TestOneShotOutagePostsNotice は実行し直さない場合も、Notion の障害で送れなかったことを投稿先に知らせることを確かめる
+func TestOneShotOutagePostsNotice(t *testing.T) {
+	discardLogs(t)
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	mock := useMockServer(t, now)
+	mock.outageUntil = time.Now().Add(time.Hour)
+	transport := http.DefaultTransport
+	http.DefaultTransport = &retryTransport{next: transport}
+	t.Cleanup(func() { http.DefaultTransport = transport })
+	timeout := notionOutageTimeout
+	notionOutageTimeout = 0
+	t.Cleanup(func() { notionOutageTimeout = timeout })
+
+	runs := 0
+	makeJob := func() (digestJob, error) {
+		runs++
+		return digestJob{
+			Name:         "test",
+			NotionToken:  "mock",
+			DatabaseID:   "mock",
+			DaysLater:    3,
+			Destinations: []slackDestination{{Name: "test", ChannelID: "C000GENERAL", Tokens: &slackTokenSource{tokens: slackTokens{AccessToken: "xoxb-test"}}}},
+			Notifiers:    slackTarget(nil),
+		}, nil
+	}
+	err := runDigestWithOutageRetry(context.Background(), makeJob, fixedClock(now), outageRetry{MaxRetries: 3})
+	var outage *notionOutageError
+	if !errors.As(err, &outage) {
+		t.Fatalf("err = %v, want the Notion outage", err)
+	}
+	if runs != 1 {
+		t.Errorf("ran %d times, want once", runs)
+	}
+	if len(mock.posted) != 1 || mock.posted[0].Text != tr("outage.gave_up", outage.Status) {
+		t.Errorf("posted %+v, want the outage notice", mock.posted)
+	}
+}
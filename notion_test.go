@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/jomei/notionapi"
+)
+
+// discardLogs はテストの間、取得できないタスクの警告などのログを捨てる
+func discardLogs(tb testing.TB) {
+	out := log.Writer()
+	log.SetOutput(io.Discard)
+	tb.Cleanup(func() { log.SetOutput(out) })
+}
+
+// 壊れたページやペイロードの欠けたページ (Notion API の JSON)
+var malformedPageSeeds = []string{
+	`{}`,
+	`{"id":"p1","properties":null}`,
+	`{"id":"p1","properties":{"Name":{"type":"title","title":[]}}}`,
+	`{"id":"p1","properties":{"Name":{"type":"title","title":[{"type":"mention","plain_text":""}]},"Due":{"type":"date","date":null}}}`,
+	`{"id":"p1","properties":{"Name":{"type":"title","title":[{"type":"equation","equation":{"expression":"x^2"},"plain_text":"x^2"}]},"Due":{"type":"date","date":{"start":null,"end":"2024-01-02"}}}}`,
+	`{"id":"p1","properties":{"Name":{"type":"title","title":[{"type":"text","text":{"content":"Task"},"annotations":{"bold":true,"code":true}}]},"Due":{"type":"date","date":{"start":"2024-01-01T09:00:00+09:00"}},"Memo":{"type":"rich_text","rich_text":[{"type":"mention","mention":null},{"type":"text","text":null}]}}}`,
+	`{"id":"p1","properties":{"Name":{"type":"title","title":[{"plain_text":"T"}]},"Due":{"type":"formula","formula":{"type":"date","date":null}},"Workload":{"type":"rollup","rollup":{"type":"array","array":[]}},"Priority":{"type":"formula","formula":{"type":"string"}}}}`,
+	`{"id":"p1","properties":{"Name":{"type":"title","title":[{"plain_text":"T"}]},"Due":{"type":"rollup","rollup":{"type":"date","date":{"start":"2024-01-01"}}},"Assignee":{"type":"people","people":[{"object":"user"}]},"Schedule Status":{"type":"status","status":null}}}`,
+	`{"id":"p1","properties":{"Name":{"type":"rich_text","rich_text":[{"plain_text":"not a title"}]},"Due":{"type":"checkbox","checkbox":true},"Tags":{"type":"multi_select","multi_select":null}}}`,
+}
+
+// unmarshalPage は JSON をページにする。notionapi は type の無いプロパティなどで panic するため、
+// それも読めないページとして扱い、parseNotionPage の panic だけを見つけるようにする
+func unmarshalPage(data string) (page notionapi.Page, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return page, json.Unmarshal([]byte(data), &page) == nil
+}
+
+// FuzzParseNotionPage は壊れたページを読んでも parseNotionPage が panic せず、
+// 返したタスクには必ずタイトルと期限日があることを確かめる
+func FuzzParseNotionPage(f *testing.F) {
+	discardLogs(f)
+	for _, seed := range malformedPageSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data string) {
+		page, ok := unmarshalPage(data)
+		if !ok {
+			return
+		}
+		task := parseNotionPage(page)
+		if task == nil {
+			return
+		}
+		if task.Title == "" {
+			t.Errorf("parsed a task without a title from %s", data)
+		}
+		if task.DueStart == nil && task.DueEnd == nil {
+			t.Errorf("parsed a task without a due date from %s", data)
+		}
+	})
+}
+
+// ペイロードが nil の要素は JSON からは作れないことがあるため、構造体で直接確かめる
+func TestParseNotionPageNilPayloads(t *testing.T) {
+	discardLogs(t)
+	due := notionapi.Date{}
+	tests := []struct {
+		name  string
+		props notionapi.Properties
+		want  string // 空ならタスクにならない
+	}{
+		{
+			name: "title with nil text and mention",
+			props: notionapi.Properties{
+				nameProp: &notionapi.TitleProperty{Title: []notionapi.RichText{{Type: notionapi.ObjectTypeText}, {Type: "mention", PlainText: "@Alice"}}},
+				dueProp:  &notionapi.DateProperty{Date: &notionapi.DateObject{Start: &due}},
+			},
+			want: "@Alice",
+		},
+		{
+			name: "nil date",
+			props: notionapi.Properties{
+				nameProp: &notionapi.TitleProperty{Title: []notionapi.RichText{{PlainText: "Task"}}},
+				dueProp:  &notionapi.DateProperty{},
+			},
+		},
+		{
+			name: "end-only date and nil memo payloads",
+			props: notionapi.Properties{
+				nameProp: &notionapi.TitleProperty{Title: []notionapi.RichText{{Text: &notionapi.Text{Content: "Task"}}}},
+				dueProp:  &notionapi.DateProperty{Date: &notionapi.DateObject{End: &due}},
+				memoProp: &notionapi.RichTextProperty{RichText: []notionapi.RichText{{Type: "mention"}, {Type: "equation"}, {Annotations: &notionapi.Annotations{Bold: true}}}},
+			},
+			want: "Task",
+		},
+		{
+			name: "formula date without a value",
+			props: notionapi.Properties{
+				nameProp: &notionapi.TitleProperty{Title: []notionapi.RichText{{PlainText: "Task"}}},
+				dueProp:  &notionapi.FormulaProperty{Formula: notionapi.Formula{Type: notionapi.FormulaTypeDate}},
+			},
+		},
+		{
+			name: "user without person",
+			props: notionapi.Properties{
+				nameProp:     &notionapi.TitleProperty{Title: []notionapi.RichText{{PlainText: "Task"}}},
+				dueProp:      &notionapi.DateProperty{Date: &notionapi.DateObject{Start: &due}},
+				assigneeProp: &notionapi.PeopleProperty{People: []notionapi.User{{}}},
+			},
+			want: "Task",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := parseNotionPage(notionapi.Page{ID: "page", Properties: tt.props})
+			switch {
+			case tt.want == "" && task != nil:
+				t.Errorf("got task %q, want nil", task.Title)
+			case tt.want != "" && task == nil:
+				t.Errorf("got nil, want task %q", tt.want)
+			case tt.want != "" && task.Title != tt.want:
+				t.Errorf("title = %q, want %q", task.Title, tt.want)
+			}
+		})
+	}
+}
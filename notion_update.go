@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jomei/notionapi"
+
+	"rainierrr/notion-notifyer/pkg/task"
+)
+
+// 完了にしたときに設定するスケジュールステータス
+const doneStatus = "Done"
+
+// markTaskDone はタスクのスケジュールステータスを Done にする
+func markTaskDone(ctx context.Context, client *notionapi.Client, pageID notionapi.ObjectID) error {
+	return setTaskStatus(ctx, client, pageID, doneStatus)
+}
+
+// setTaskStatus はタスクのスケジュールステータスを変更する
+func setTaskStatus(ctx context.Context, client *notionapi.Client, pageID notionapi.ObjectID, status string) error {
+	_, err := client.Page.Update(ctx, notionapi.PageID(pageID), &notionapi.PageUpdateRequest{
+		Properties: notionapi.Properties{
+			scheduleStatusProp: notionapi.StatusProperty{
+				Type:   notionapi.PropertyTypeStatus,
+				Status: notionapi.Status{Name: status},
+			},
+		},
+	})
+	if err != nil {
+		return newNotionError("failed to set task status to "+status, err)
+	}
+	return nil
+}
+
+// snoozeTask はタスクの期限日を days 日後にずらし、更新後のタスクを返す
+func snoozeTask(ctx context.Context, client *notionapi.Client, task Task, days int) (Task, error) {
+	shift := func(d *notionapi.Date) *notionapi.Date {
+		if d == nil {
+			return nil
+		}
+		t := notionapi.Date(time.Time(*d).AddDate(0, 0, days))
+		return &t
+	}
+	task.DueStart = shift(task.DueStart)
+	task.DueEnd = shift(task.DueEnd)
+
+	_, err := client.Page.Update(ctx, notionapi.PageID(task.ID), &notionapi.PageUpdateRequest{
+		Properties: notionapi.Properties{
+			dueProp: dueDateProperty{Start: task.DueStart, End: task.DueEnd},
+		},
+	})
+	if err != nil {
+		return task, newNotionError("failed to snooze task", err)
+	}
+	return task, nil
+}
+
+// isDateOnly は時刻を持たない日付 (Notion で日付のみ指定された値) かどうかを判定する
+func isDateOnly(t time.Time) bool {
+	return task.IsDateOnly(t)
+}
+
+// dueDateProperty は日付プロパティを書き込むための Property
+// notionapi.Date は常に RFC3339 で書き込まれ、日付のみの値が 0:00 UTC の日時になってしまうため、
+// 日付のみの値は "2006-01-02" 形式で書き込む
+type dueDateProperty struct {
+	Start *notionapi.Date
+	End   *notionapi.Date
+}
+
+func (p dueDateProperty) GetID() string { return "" }
+
+func (p dueDateProperty) GetType() notionapi.PropertyType { return notionapi.PropertyTypeDate }
+
+func (p dueDateProperty) MarshalJSON() ([]byte, error) {
+	format := func(d *notionapi.Date) *string {
+		if d == nil {
+			return nil
+		}
+		t := time.Time(*d)
+		s := t.Format(time.RFC3339)
+		if isDateOnly(t) {
+			s = t.Format("2006-01-02")
+		}
+		return &s
+	}
+	return json.Marshal(map[string]any{
+		"date": map[string]*string{
+			"start": format(p.Start),
+			"end":   format(p.End),
+		},
+	})
+}
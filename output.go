@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/slack-go/slack"
+)
+
+// --output の形式
+const (
+	outputJSON     = "json"
+	outputMarkdown = "markdown"
+	outputTable    = "table"
+	outputBlocks   = "blocks"
+)
+
+var outputFormats = []string{outputJSON, outputMarkdown, outputTable, outputBlocks}
+
+// parseOutputFormat は --output の指定を検証する
+func parseOutputFormat(format string) (string, error) {
+	if !slices.Contains(outputFormats, format) {
+		return "", fmt.Errorf("unknown format %q (available: %s)", format, strings.Join(outputFormats, ", "))
+	}
+	return format, nil
+}
+
+// outputNotifier は Slack などに送らずに、セクションごとのタスクを標準出力に書き出す (--output)
+type outputNotifier struct {
+	Format string
+	Types  taskTypeFilter // --include-types と --exclude-types
+	W      io.Writer
+}
+
+// Send はタスクを Format の形式で書き出す。タスクが無くても JSON なら空のペイロードを書く
+func (o outputNotifier) Send(ctx context.Context, run digestRun, groups []TaskGroup) error {
+	groups = filterGroupTypes(groups, o.Types)
+	switch o.Format {
+	case outputJSON:
+		return o.writeJSON(groups, run)
+	case outputMarkdown:
+		return o.writeMarkdown(groups)
+	case outputBlocks:
+		return o.writeBlocks(groups, run)
+	default:
+		return o.writeTable(groups)
+	}
+}
+
+// writeJSON は Webhook と同じペイロード (payload-schema) を書き出す
+func (o outputNotifier) writeJSON(groups []TaskGroup, run digestRun) error {
+	payload := newDigestPayload(groups, run)
+	if payload.Tasks == nil {
+		payload.Tasks = []taskPayloadItem{}
+	}
+	enc := json.NewEncoder(o.W)
+	enc.SetIndent("", "  ")
+	return enc.Encode(payload)
+}
+
+// writeMarkdown はセクションを見出し、タスクをリンク付きの箇条書きにする
+func (o outputNotifier) writeMarkdown(groups []TaskGroup) error {
+	var b strings.Builder
+	for i, group := range groups {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "## %s\n\n", group.Title)
+		for _, task := range flattenParts(group.Parts) {
+			if task.title != "" {
+				fmt.Fprintf(&b, "\n### %s\n\n", task.title)
+			}
+			details, err := plainTaskDetails(task.task)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(&b, "- [%s](%s) — %s\n", task.task.Title, task.task.URL, details)
+		}
+	}
+	_, err := io.WriteString(o.W, b.String())
+	return err
+}
+
+// writeBlocks は Slack に投稿するのと同じメッセージを Block Kit の JSON ({"blocks": [...]}) で書き出す
+// タスクが無ければ空のメッセージにする
+func (o outputNotifier) writeBlocks(groups []TaskGroup, run digestRun) error {
+	var tasks []Task
+	seen := map[string]bool{}
+	for _, group := range groups {
+		for _, task := range group.Tasks {
+			// プロジェクトごとに分けると、同じタスクが複数のセクションに入る
+			if !seen[task.Key()] {
+				seen[task.Key()] = true
+				tasks = append(tasks, task)
+			}
+		}
+	}
+	blocks := []slack.Block{}
+	if len(tasks) > 0 {
+		if run.blocks == nil {
+			return fmt.Errorf("blocks output is not available outside a digest run")
+		}
+		var err error
+		if blocks, err = run.blocks(tasks); err != nil {
+			return fmt.Errorf("failed to build slack blocks: %w", err)
+		}
+	}
+	enc := json.NewEncoder(o.W)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]slack.Blocks{"blocks": {BlockSet: blocks}})
+}
+
+// writeTable はタスクを 1 行ずつ列を揃えて書き出す
+func (o outputNotifier) writeTable(groups []TaskGroup) error {
+	w := tabwriter.NewWriter(o.W, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SECTION\tDUE\tPRIORITY\tTYPE\tSTATUS\tTITLE\tURL")
+	for _, group := range groups {
+		for _, task := range group.Tasks {
+			due, err := formatDueDate(task)
+			if err != nil {
+				return fmt.Errorf("failed to format due date for task %s: %w", task.Title, err)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", group.Name, due,
+				orDash(task.Priority), orDash(task.Type), orDash(task.ScheduleStatus), task.Title, task.URL)
+		}
+	}
+	return w.Flush()
+}
+
+// filterGroupTypes はセクションごとにタスクを種類で絞り込む。タスクが無くなったセクションは除く
+func filterGroupTypes(groups []TaskGroup, types taskTypeFilter) []TaskGroup {
+	var filtered []TaskGroup
+	for _, group := range groups {
+		if group.Tasks = types.apply(group.Tasks); len(group.Tasks) == 0 {
+			continue
+		}
+		parts := make([]templateSectionPart, 0, len(group.Parts))
+		for _, part := range group.Parts {
+			if part.Tasks = types.apply(part.Tasks); len(part.Tasks) > 0 {
+				parts = append(parts, part)
+			}
+		}
+		group.Parts = parts
+		filtered = append(filtered, group)
+	}
+	return filtered
+}
+
+// orDash は空の値を - にする
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func init() {
+	rootCmd.Flags().String("output", "", fmt.Sprintf("Write the grouped tasks to stdout (%s) instead of sending them anywhere; nothing is posted or recorded", strings.Join(outputFormats, ", ")))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// 期限切れのタスクが多すぎるときの終了コード (送信の失敗などの 1 と区別する)
+const exitOverdue = 2
+
+// overdueGateError は期限切れのタスクが上限を超えたことを表す
+type overdueGateError struct {
+	Count     int
+	Threshold int
+}
+
+func (e *overdueGateError) Error() string {
+	return fmt.Sprintf("%d overdue tasks (more than %d allowed)", e.Count, e.Threshold)
+}
+
+// countOverdueTasks は期限日が今日より前のタスクを数える
+// 日付だけの期限日は now のタイムゾーンの日付として比べ、ダイジェストの期限切れのセクションと揃える
+func countOverdueTasks(tasks []Task, now time.Time) int {
+	today := startOfDay(now)
+	count := 0
+	for _, task := range tasks {
+		if due := task.DueDateIn(now.Location()); due != nil && due.Before(today) {
+			count++
+		}
+	}
+	return count
+}
+
+// checkOverdue は期限切れのタスクが threshold を超えていれば overdueGateError を返す
+func checkOverdue(tasks []Task, now time.Time, threshold int) error {
+	if count := countOverdueTasks(tasks, now); count > threshold {
+		return &overdueGateError{Count: count, Threshold: threshold}
+	}
+	return nil
+}
+
+// overdueThresholdFromFlags は --fail-on-overdue と --fail-threshold から期限切れのタスクの上限を返す (nil なら確認しない)
+func overdueThresholdFromFlags(cmd *cobra.Command) (*int, error) {
+	if cmd.Flags().Changed("fail-threshold") {
+		threshold, _ := cmd.Flags().GetInt("fail-threshold")
+		if threshold < 0 {
+			return nil, configErrorf("--fail-threshold must be 0 or more")
+		}
+		return &threshold, nil
+	}
+	if failOnOverdue, _ := cmd.Flags().GetBool("fail-on-overdue"); failOnOverdue {
+		threshold := 0
+		return &threshold, nil
+	}
+	return nil, nil
+}
+
+func init() {
+	rootCmd.Flags().Bool("fail-on-overdue", false, fmt.Sprintf("Exit with status %d after sending when there are overdue tasks (for CI gates)", exitOverdue))
+	rootCmd.Flags().Int("fail-threshold", 0, fmt.Sprintf("Exit with status %d after sending when there are more than this many overdue tasks", exitOverdue))
+}
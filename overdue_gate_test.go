@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jomei/notionapi"
+
+	"rainierrr/notion-notifyer/pkg/task"
+)
+
+// TestCountOverdueTasksInLocalTime は日付だけの期限日を実行したタイムゾーンの日付で比べることを確かめる
+func TestCountOverdueTasksInLocalTime(t *testing.T) {
+	dateOnly := func(day int) Task {
+		d := notionapi.Date(time.Date(2026, 10, day, 0, 0, 0, 0, time.UTC))
+		return Task{Task: task.Task{DueStart: &d}}
+	}
+	tasks := []Task{dateOnly(16), dateOnly(17), dateOnly(18)}
+	for name, loc := range map[string]*time.Location{
+		"UTC":         time.UTC,
+		"JST":         time.FixedZone("JST", 9*60*60),
+		"west of UTC": time.FixedZone("PDT", -7*60*60),
+	} {
+		now := time.Date(2026, 10, 17, 9, 0, 0, 0, loc)
+		if got := countOverdueTasks(tasks, now); got != 1 {
+			t.Errorf("%s: %d overdue tasks, want 1", name, got)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	// Windows には IANA のタイムゾーンデータが無いため、--tz で指定できるよう埋め込む
+	_ "time/tzdata"
+)
+
+const (
+	// 状態ファイルを置くディレクトリ (--state-dir が優先)
+	stateDirEnv = "NOTIFYER_STATE_DIR"
+	// 日付の境界に使うタイムゾーン (--tz が優先)。Windows では TZ が使われないためこちらで指定する
+	timezoneEnv = "NOTIFYER_TZ"
+	// OS ごとの設定ディレクトリの下に作るディレクトリ名
+	appDirName = "notion-notifyer"
+	// 設定ディレクトリで探す設定ファイル名
+	defaultConfigFile = "config.yaml"
+)
+
+// --state-dir の値
+var stateDir string
+
+// statePath は状態ファイルの相対パスを状態ディレクトリからのパスにする
+// 状態ディレクトリが指定されていなければ、これまでどおりカレントディレクトリからのパスのままにする
+func statePath(path string) string {
+	dir := stateDir
+	if dir == "" {
+		dir = os.Getenv(stateDirEnv)
+	}
+	if dir == "" || path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// discoverConfigFile は OS ごとの設定ディレクトリにある設定ファイルを探す
+// Linux では $XDG_CONFIG_HOME (~/.config)、macOS では ~/Library/Application Support、Windows では %APPDATA% を使う
+func discoverConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	path := filepath.Join(dir, appDirName, defaultConfigFile)
+	if _, err := os.Stat(path); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Warning: Unable to read %s: %v", path, err)
+		}
+		return ""
+	}
+	return path
+}
+
+// applyTimezone は --tz (未指定なら NOTIFYER_TZ) のタイムゾーンを time.Local にする
+func applyTimezone(name string) error {
+	if name == "" {
+		name = os.Getenv(timezoneEnv)
+	}
+	if name == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("invalid time zone %q: %w", name, err)
+	}
+	time.Local = loc
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// ペイロードのスキーマバージョン
+//
+// 互換性のルール:
+//   - 同じバージョンの中では、フィールドは任意項目として追加するだけにする
+//   - フィールドの削除・名前の変更・型の変更はバージョンを上げる
+//   - 受信側は知らないフィールドを無視すること
+const payloadSchemaVersion = 1
+
+// payloadSchemaV1 は v1 のペイロードの JSON Schema
+//
+//go:embed schema/payload.v1.json
+var payloadSchemaV1 string
+
+// taskPayload は Webhook やエクスポートで外部に渡すタスク一覧
+// フィールドを変更するときは schema/payload.v1.json も合わせて更新する
+type taskPayload struct {
+	SchemaVersion int                  `json:"schema_version"`
+	GeneratedAt   time.Time            `json:"generated_at"`
+	RunNumber     string               `json:"run_number,omitempty"`
+	Tasks         []taskPayloadItem    `json:"tasks"`
+	Sections      []taskPayloadSection `json:"sections,omitempty"` // ダイジェストのセクションと、そこに載るタスクの ID (表示順)
+}
+
+type taskPayloadSection struct {
+	Name    string   `json:"name"`
+	Title   string   `json:"title"`
+	TaskIDs []string `json:"task_ids"`
+}
+
+type taskPayloadItem struct {
+	ID        string     `json:"id"`
+	UniqueID  string     `json:"unique_id,omitempty"`
+	Title     string     `json:"title"`
+	URL       string     `json:"url"`
+	Due       *time.Time `json:"due,omitempty"`
+	Priority  string     `json:"priority,omitempty"`
+	Type      string     `json:"type,omitempty"`
+	Status    string     `json:"status,omitempty"`
+	Workload  float32    `json:"workload,omitempty"`
+	Memo      string     `json:"memo,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+func newTaskPayload(tasks []Task, now time.Time) taskPayload {
+	payload := taskPayload{SchemaVersion: payloadSchemaVersion, GeneratedAt: now, Tasks: []taskPayloadItem{}}
+	for _, task := range tasks {
+		payload.Tasks = append(payload.Tasks, taskPayloadItem{
+			ID:        string(task.ID),
+			UniqueID:  task.UniqueID,
+			Title:     task.Title,
+			URL:       task.URL,
+			Due:       getTargetDueDate(task),
+			Priority:  task.Priority,
+			Type:      task.Type,
+			Status:    task.ScheduleStatus,
+			Workload:  task.Workload,
+			Memo:      task.Memo,
+			CreatedAt: createdAt(task),
+		})
+	}
+	return payload
+}
+
+func createdAt(task Task) *time.Time {
+	if task.CreatedAt.IsZero() {
+		return nil
+	}
+	return &task.CreatedAt
+}
+
+// newDigestPayload はダイジェストと同じ順序 (セクション順、その中は優先度・期限日順) に並べたタスクに、セクションと Run 番号を添えたペイロードを作る
+func newDigestPayload(groups []TaskGroup, run digestRun) taskPayload {
+	payload := newTaskPayload(groupedTasks(groups), run.Now)
+	payload.RunNumber = run.RunNumber
+	for _, group := range groups {
+		section := taskPayloadSection{Name: group.Name, Title: group.Title, TaskIDs: []string{}}
+		for _, task := range group.Tasks {
+			section.TaskIDs = append(section.TaskIDs, string(task.ID))
+		}
+		payload.Sections = append(payload.Sections, section)
+	}
+	return payload
+}
+
+var payloadSchemaCmd = &cobra.Command{
+	Use:   "payload-schema",
+	Short: "Print the JSON Schema of the webhook/export task payload.",
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(payloadSchemaV1)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(payloadSchemaCmd)
+}
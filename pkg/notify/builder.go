@@ -0,0 +1,191 @@
+// Package notify は取得したタスクを Slack の Block Kit のメッセージにする
+// pkg/notion の Fetcher と pkg/task の Grouping と組み合わせて、notion-notifyer の CLI を使わずに Bot などへ組み込める
+// テンプレート・通知の状態・ボタンの操作など CLI だけの機能は含まない
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+
+	"rainierrr/notion-notifyer/pkg/task"
+)
+
+// Slack のセクションのテキストの上限
+const maxSectionText = 3000
+
+// Section はメッセージに載せるセクション (task.Group のセクションの名前と見出し)
+type Section struct {
+	Name  string
+	Title string
+}
+
+// DefaultSections は Builder.Sections を指定しないときのセクション (DefaultBuckets と対応する)
+var DefaultSections = []Section{
+	{Name: task.PinnedSection, Title: "📌 Pinned"},
+	{Name: task.HoursSection, Title: "⏰ Due soon"},
+	{Name: "overdue", Title: "🚨 Overdue"},
+	{Name: "today", Title: "📅 Today"},
+	{Name: "soon", Title: "🗓 Upcoming"},
+}
+
+// DefaultBuckets は期限切れ・今日・それ以降に分ける
+var DefaultBuckets = []task.Bucket{
+	{Name: "overdue", Days: intPtr(0)},
+	{Name: "today", Days: intPtr(1)},
+	{Name: "soon"},
+}
+
+func intPtr(n int) *int { return &n }
+
+// Builder はタスクを Slack のメッセージにする。ゼロ値は DefaultBuckets と DefaultSections で組み立てる
+// 設定を値として持つため、設定の違う複数の Builder を同時に使える
+type Builder struct {
+	// セクションの分け方と並び順 (Buckets が空なら DefaultBuckets)
+	Grouping task.Grouping
+	// 表示するセクションの順序と見出し (空なら DefaultSections)
+	Sections []Section
+	// メッセージの先頭の見出し (空なら付けない)
+	Header string
+	// メモを表示する文字数の上限 (0 ならメモを表示しない)
+	MemoLimit int
+	// 期限日を表示するロケーション (nil なら Build に渡した now のロケーション)
+	Location *time.Location
+}
+
+// Build はタスクをセクションに分けてブロックにする。載せるタスクが無ければ nil を返す
+func (b Builder) Build(tasks []task.Task, now time.Time) ([]slack.Block, error) {
+	grouping := b.Grouping
+	if len(grouping.Buckets) == 0 {
+		grouping.Buckets = DefaultBuckets
+	}
+	sections := b.Sections
+	if len(sections) == 0 {
+		sections = DefaultSections
+	}
+	loc := b.Location
+	if loc == nil {
+		loc = now.Location()
+	}
+
+	groups := task.Group(tasks, now, grouping)
+	var blocks []slack.Block
+	for _, section := range sections {
+		group := groups[section.Name]
+		if len(group) == 0 {
+			continue
+		}
+		lines := make([]string, 0, len(group))
+		for _, t := range group {
+			line, err := b.taskLine(t, loc)
+			if err != nil {
+				return nil, fmt.Errorf("task %s: %w", t.ID, err)
+			}
+			lines = append(lines, line)
+		}
+		if len(blocks) > 0 {
+			blocks = append(blocks, slack.NewDividerBlock())
+		}
+		blocks = append(blocks, slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, "*"+EscapeMrkdwn(section.Title)+"*", false, false), nil, nil))
+		for _, text := range splitLines(lines, maxSectionText) {
+			blocks = append(blocks, slack.NewSectionBlock(
+				slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil))
+		}
+	}
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+	if b.Header != "" {
+		blocks = append([]slack.Block{slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, b.Header, false, false))}, blocks...)
+	}
+	return blocks, nil
+}
+
+// MsgOptions は Build したブロックと通知に表示するテキストを client.PostMessage に渡せる形で返す
+// 載せるタスクが無ければ nil を返す
+func (b Builder) MsgOptions(tasks []task.Task, now time.Time) ([]slack.MsgOption, error) {
+	blocks, err := b.Build(tasks, now)
+	if err != nil || blocks == nil {
+		return nil, err
+	}
+	fallback := b.Header
+	if fallback == "" {
+		fallback = fmt.Sprintf("%d tasks", len(tasks))
+	}
+	return []slack.MsgOption{slack.MsgOptionBlocks(blocks...), slack.MsgOptionText(fallback, false)}, nil
+}
+
+// taskLine はタスク 1 件の行 (期限日・タイトルへのリンク・優先度・メモ)
+func (b Builder) taskLine(t task.Task, loc *time.Location) (string, error) {
+	due, err := formatDue(t, loc)
+	if err != nil {
+		return "", err
+	}
+	title := EscapeMrkdwn(t.Title)
+	if t.URL != "" {
+		title = "<" + mrkdwnURLEscaper.Replace(t.URL) + "|" + title + ">"
+	}
+	line := fmt.Sprintf("• %s  %s", due, title)
+	if t.Priority != "" {
+		line += "  `" + EscapeMrkdwn(t.Priority) + "`"
+	}
+	if b.MemoLimit > 0 && t.Memo != "" {
+		memo := RichTextFormatter{Limit: b.MemoLimit}.Format(t.MemoRichText)
+		if len(t.MemoRichText) == 0 {
+			memo = EscapeMrkdwn(t.Memo)
+			if runes := []rune(t.Memo); len(runes) > b.MemoLimit {
+				memo = EscapeMrkdwn(strings.TrimSpace(string(runes[:TruncatePoint(runes, b.MemoLimit)]))) + "..."
+			}
+		}
+		line += "\n    " + strings.ReplaceAll(memo, "\n", "\n    ")
+	}
+	return line, nil
+}
+
+// formatDue は期限日を "MM/DD" (時刻付きなら "MM/DD hh:mm") で返す。期間なら "開始 ~ 終了" にする
+func formatDue(t task.Task, loc *time.Location) (string, error) {
+	format := func(d time.Time) string {
+		if task.IsDateOnly(d) {
+			return d.UTC().Format("01/02")
+		}
+		return d.In(loc).Format("01/02 15:04")
+	}
+	switch {
+	case t.DueStart != nil && t.DueEnd != nil:
+		return format(time.Time(*t.DueStart)) + " ~ " + format(time.Time(*t.DueEnd)), nil
+	case t.DueStart != nil:
+		return format(time.Time(*t.DueStart)), nil
+	case t.DueEnd != nil:
+		return format(time.Time(*t.DueEnd)), nil
+	case t.Pinned:
+		return "-", nil
+	}
+	return "", errors.New("task has no due date")
+}
+
+// splitLines は行を limit 文字以下のテキストにまとめる (1 行が limit を超える場合はその行を切る)
+func splitLines(lines []string, limit int) []string {
+	var texts []string
+	var b strings.Builder
+	for _, line := range lines {
+		if runes := []rune(line); len(runes) > limit {
+			line = string(runes[:limit-3]) + "..."
+		}
+		if b.Len() > 0 && len([]rune(b.String()))+1+len([]rune(line)) > limit {
+			texts = append(texts, b.String())
+			b.Reset()
+		}
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(line)
+	}
+	if b.Len() > 0 {
+		texts = append(texts, b.String())
+	}
+	return texts
+}
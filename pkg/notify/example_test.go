@@ -0,0 +1,43 @@
+package notify_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jomei/notionapi"
+	"github.com/slack-go/slack"
+
+	"rainierrr/notion-notifyer/pkg/notify"
+	"rainierrr/notion-notifyer/pkg/task"
+)
+
+func ExampleBuilder() {
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	date := func(t time.Time) *notionapi.Date { d := notionapi.Date(t); return &d }
+	tasks := []task.Task{
+		{ID: "b", Title: "Write report", Priority: "Low", DueStart: date(now.Add(3 * time.Hour)), URL: "https://www.notion.so/b"},
+		{ID: "a", Title: "Pay bills", Priority: "High", DueStart: date(now.AddDate(0, 0, -1).Add(time.Hour))},
+		{ID: "c", Title: "Review <PR>", Priority: "Urgent", DueStart: date(now.Add(2 * time.Hour))},
+	}
+
+	// 優先度の順序は Builder ごとに持つので、別の順序の Builder を並行して使ってもよい
+	builder := notify.Builder{
+		Grouping: task.Grouping{Sorter: task.NewSorter([]string{"Urgent", "High", "Low"})},
+		Sections: []notify.Section{{Name: "overdue", Title: "Overdue"}, {Name: "today", Title: "Today"}},
+	}
+	blocks, err := builder.Build(tasks, now)
+	if err != nil {
+		panic(err)
+	}
+	for _, block := range blocks {
+		if section, ok := block.(*slack.SectionBlock); ok {
+			fmt.Println(section.Text.Text)
+		}
+	}
+	// Output:
+	// *Overdue*
+	// • 10/16 10:00  Pay bills  `High`
+	// *Today*
+	// • 10/17 11:00  Review &lt;PR&gt;  `Urgent`
+	// • 10/17 12:00  <https://www.notion.so/b|Write report>  `Low`
+}
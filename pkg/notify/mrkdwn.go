@@ -0,0 +1,141 @@
+package notify
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/jomei/notionapi"
+
+	"rainierrr/notion-notifyer/pkg/notion"
+)
+
+// Slack の mrkdwn で特別な意味を持つ文字のエスケープ
+var mrkdwnEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// リンク先の URL に含まれると <url|text> の区切りと見分けられない文字のエンコード
+var mrkdwnURLEscaper = strings.NewReplacer("<", "%3C", ">", "%3E", "|", "%7C")
+
+// EscapeMrkdwn は文字列を Slack の mrkdwn でそのまま表示されるようにエスケープする
+func EscapeMrkdwn(s string) string {
+	return mrkdwnEscaper.Replace(s)
+}
+
+// RichTextFormatter は Notion のリッチテキストを Slack の mrkdwn にする
+// 太字・斜体・取り消し線・コード・リンク・メンションを残す。Slack には下線が無いため、下線と色は無視する
+type RichTextFormatter struct {
+	// 表示する文字の上限 (0 なら無制限)。超える場合は書式を崩さないよう書式を付ける前の文字を
+	// できれば単語や行の区切りで切って "..." を付ける
+	Limit int
+	// Mention はメンションされた Notion のユーザーの Slack ユーザー ID を返す (nil か "" ならユーザー名のまま)
+	Mention func(user *notionapi.User) string
+}
+
+// Format はリッチテキストを mrkdwn にする
+func (f RichTextFormatter) Format(texts []notionapi.RichText) string {
+	var b strings.Builder
+	remaining := f.Limit
+	for _, rt := range texts {
+		text := notion.RichTextContent(rt)
+		if text == "" && rt.Equation != nil {
+			text = rt.Equation.Expression
+		}
+		truncated := false
+		if f.Limit > 0 {
+			runes := []rune(text)
+			if len(runes) > remaining {
+				text, truncated = strings.TrimRightFunc(string(runes[:TruncatePoint(runes, remaining)]), unicode.IsSpace), true
+			}
+			remaining -= len([]rune(text))
+		}
+		b.WriteString(f.formatElement(rt, text))
+		if truncated {
+			b.WriteString("...")
+			break
+		}
+	}
+	return b.String()
+}
+
+// formatElement は 1 つの要素の文字を書式とリンクに合わせて mrkdwn にする
+func (f RichTextFormatter) formatElement(rt notionapi.RichText, text string) string {
+	if text == "" {
+		return ""
+	}
+	s := mrkdwnEscaper.Replace(text)
+	// 切り詰めていないユーザーのメンションは、Slack のユーザーがわかれば Slack のメンションにする
+	if id := f.mentionID(rt); id != "" && text == notion.RichTextContent(rt) {
+		s = "<@" + id + ">"
+	}
+	a := rt.Annotations
+	if rt.Equation != nil || (a != nil && a.Code) {
+		s = wrapMrkdwn(s, "`")
+	}
+	if url := richTextURL(rt); url != "" {
+		s = "<" + mrkdwnURLEscaper.Replace(url) + "|" + s + ">"
+	}
+	if a != nil {
+		if a.Bold {
+			s = wrapMrkdwn(s, "*")
+		}
+		if a.Italic {
+			s = wrapMrkdwn(s, "_")
+		}
+		if a.Strikethrough {
+			s = wrapMrkdwn(s, "~")
+		}
+	}
+	return s
+}
+
+// mentionID はユーザーのメンションの Slack ユーザー ID を返す
+func (f RichTextFormatter) mentionID(rt notionapi.RichText) string {
+	if f.Mention == nil || rt.Mention == nil || rt.Mention.User == nil {
+		return ""
+	}
+	return f.Mention(rt.Mention.User)
+}
+
+// richTextURL は要素のリンク先を返す (リンク・ページとデータベースのメンション)
+func richTextURL(rt notionapi.RichText) string {
+	if rt.Href != "" {
+		return rt.Href
+	}
+	if rt.Text != nil && rt.Text.Link != nil {
+		return rt.Text.Link.Url
+	}
+	if m := rt.Mention; m != nil {
+		switch {
+		case m.Page != nil:
+			return "https://www.notion.so/" + strings.ReplaceAll(m.Page.ID.String(), "-", "")
+		case m.Database != nil:
+			return "https://www.notion.so/" + strings.ReplaceAll(m.Database.ID.String(), "-", "")
+		}
+	}
+	return ""
+}
+
+// wrapMrkdwn は行ごとに前後の空白の内側を marker で囲む
+// Slack は記号と文字の間に空白があったり、行をまたいだりすると書式として扱わない
+func wrapMrkdwn(s, marker string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		core := strings.TrimFunc(line, unicode.IsSpace)
+		if core == "" {
+			continue
+		}
+		start := strings.Index(line, core)
+		lines[i] = line[:start] + marker + core + marker + line[start+len(core):]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TruncatePoint は runes を limit 文字以下で切る位置を返す
+// 区切りを探すのは後半だけにして、区切りが前の方にしか無いときに短くなりすぎないようにする
+func TruncatePoint(runes []rune, limit int) int {
+	for i := limit; i > limit/2; i-- {
+		if r := runes[i-1]; unicode.IsSpace(r) || r == '。' || r == '、' {
+			return i
+		}
+	}
+	return limit
+}
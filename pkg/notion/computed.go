@@ -0,0 +1,99 @@
+package notion
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/jomei/notionapi"
+)
+
+// Unwrap は数式とロールアップの値を、同じ値を持つ普通のプロパティに置き換える
+// 文字列はテキスト、数値は数値、真偽値はチェックボックス、日付は日付のプロパティになる
+// 配列のロールアップ (元の値を表示) は最初の要素を使う。それ以外のプロパティと空の値はそのまま返す
+func Unwrap(value notionapi.Property) notionapi.Property {
+	switch p := value.(type) {
+	case *notionapi.FormulaProperty:
+		switch p.Formula.Type {
+		case notionapi.FormulaTypeString:
+			return textProperty(p.Formula.String)
+		case notionapi.FormulaTypeNumber:
+			return &notionapi.NumberProperty{Type: notionapi.PropertyTypeNumber, Number: p.Formula.Number}
+		case notionapi.FormulaTypeBoolean:
+			return &notionapi.CheckboxProperty{Type: notionapi.PropertyTypeCheckbox, Checkbox: p.Formula.Boolean}
+		case notionapi.FormulaTypeDate:
+			if p.Formula.Date != nil {
+				return &notionapi.DateProperty{Type: notionapi.PropertyTypeDate, Date: p.Formula.Date}
+			}
+		}
+	case *notionapi.RollupProperty:
+		switch p.Rollup.Type {
+		case notionapi.RollupTypeNumber:
+			return &notionapi.NumberProperty{Type: notionapi.PropertyTypeNumber, Number: p.Rollup.Number}
+		case notionapi.RollupTypeDate:
+			if p.Rollup.Date != nil {
+				return &notionapi.DateProperty{Type: notionapi.PropertyTypeDate, Date: p.Rollup.Date}
+			}
+		case notionapi.RollupTypeArray:
+			if len(p.Rollup.Array) > 0 {
+				return Unwrap(p.Rollup.Array[0])
+			}
+		}
+	}
+	return value
+}
+
+func textProperty(s string) *notionapi.RichTextProperty {
+	return &notionapi.RichTextProperty{
+		Type:     notionapi.PropertyTypeRichText,
+		RichText: []notionapi.RichText{{Type: notionapi.ObjectTypeText, PlainText: s, Text: &notionapi.Text{Content: s}}},
+	}
+}
+
+// PlainText はセレクト・ステータス・テキスト・タイトル・URL などの値を文字列にする (数式の文字列の結果もここで読む)
+// 文字列にできないプロパティは false を返す
+func PlainText(value notionapi.Property) (string, bool) {
+	switch p := Unwrap(value).(type) {
+	case *notionapi.SelectProperty:
+		return p.Select.Name, true
+	case *notionapi.StatusProperty:
+		return p.Status.Name, true
+	case *notionapi.RichTextProperty:
+		return joinRichText(p.RichText), true
+	case *notionapi.TitleProperty:
+		return joinRichText(p.Title), true
+	case *notionapi.URLProperty:
+		return p.URL, true
+	case *notionapi.EmailProperty:
+		return p.Email, true
+	case *notionapi.PhoneNumberProperty:
+		return p.PhoneNumber, true
+	case *notionapi.NumberProperty:
+		return strconv.FormatFloat(p.Number, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// Number は数値のプロパティと、数値の数式・ロールアップの値を返す
+// セレクトやテキストは名前を数値として読む (ワークロードを "0.5" のようなセレクトで持つデータベース向け)
+func Number(value notionapi.Property) (float64, bool) {
+	switch p := Unwrap(value).(type) {
+	case *notionapi.NumberProperty:
+		return p.Number, true
+	case *notionapi.SelectProperty, *notionapi.RichTextProperty:
+		text, _ := PlainText(p)
+		if text == "" {
+			return 0, false
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func joinRichText(texts []notionapi.RichText) string {
+	var b strings.Builder
+	for _, rt := range texts {
+		b.WriteString(RichTextContent(rt))
+	}
+	return b.String()
+}
@@ -0,0 +1,374 @@
+// Package notion は Notion のデータベースからタスクを取得する
+// プロパティ名やステータスは Fetcher に渡すため、notion-notifyer の設定ファイルが無くても使える
+package notion
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jomei/notionapi"
+
+	"rainierrr/notion-notifyer/pkg/task"
+)
+
+// DefaultStatuses は取得するタスクのステータス (これ以外のステータスのタスクは完了したものとして取得しない)
+var DefaultStatuses = []string{
+	"CannotDo", "Next", "Want", "ToDo", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday", "Doing", "iPhone Task",
+}
+
+// Properties はタスクのプロパティ名
+type Properties struct {
+	Name     string // タイトル
+	UniqueID string // ユニーク ID (空なら使わない)
+	Due      string // 日付
+	Priority string // セレクト
+	Type     string // セレクト
+	Status   string // ステータス
+	Workload string // 数値を名前にしたセレクト
+	Memo     string // テキスト
+	Link     string // URL
+	Email    string // メール (空なら使わない)
+	Phone    string // 電話 (空なら使わない)
+	Assignee string // ユーザー
+	Tags     string // マルチセレクト
+	Project  string // プロジェクトのデータベースへのリレーション (空なら使わない)
+	Pinned   string // チェックボックス (空なら使わない)。ピン留めのタスクは期限日に関係なく取得する
+	// 数値・数式・ロールアップ (空なら使わない)
+	// Notion のパーセント表示の数値は 0〜1 で返るため、1 以下の値は割合、それより大きい値はパーセントとして扱う
+	Progress string
+	Files    string // ファイル&メディア (空なら使わない)
+	// 詳細に表示する追加のプロパティ (種類を問わず Task.Extra にそのまま入れる)
+	Extra []string
+	// 表示せずに値だけを使う追加のプロパティ (種類を問わず Task.Lookup にそのまま入れる)
+	Lookup []string
+}
+
+// Fetcher はデータベースからタスクを取得する
+type Fetcher struct {
+	Client     *notionapi.Client
+	Properties Properties
+	Statuses   []string // 取得するステータス (nil なら DefaultStatuses)
+	// AnyStatus なら Statuses を使わず、ExcludeStatuses 以外のすべてのステータスのタスクを取得する
+	AnyStatus       bool
+	ExcludeStatuses []string // 取得しないステータス (Statuses にあっても除く)
+	PageSize        int      // 1 回の問い合わせで取得する件数 (0 なら 100、Notion API の上限も 100)
+	MaxResults      int      // 1 つのデータベースから取得するタスクの上限 (0 なら上限なし)
+}
+
+// Fetch は 1 つのデータベースから期限日が onOrBeforeDate までのタスクを取得する
+func (f *Fetcher) Fetch(ctx context.Context, dbID string, onOrBeforeDate time.Time) ([]task.Task, error) {
+	var allTasks []task.Task
+	// 取得するステータスが残っていなければ問い合わせない (空の or フィルターは Notion がエラーにする)
+	if !f.AnyStatus && len(f.statuses()) == 0 {
+		return nil, nil
+	}
+
+	var dueFilter notionapi.Filter = &notionapi.PropertyFilter{
+		Property: f.Properties.Due,
+		Date: &notionapi.DateFilterCondition{
+			OnOrBefore: (*notionapi.Date)(&onOrBeforeDate),
+		},
+	}
+	if f.Properties.Pinned != "" {
+		dueFilter = notionapi.OrCompoundFilter{
+			dueFilter,
+			&notionapi.PropertyFilter{
+				Property: f.Properties.Pinned,
+				Checkbox: &notionapi.CheckboxFilterCondition{Equals: true},
+			},
+		}
+	}
+
+	request := &notionapi.DatabaseQueryRequest{
+		Filter: &notionapi.AndCompoundFilter{
+			dueFilter,
+			f.StatusFilter(),
+		},
+		Sorts: []notionapi.SortObject{
+			{Property: f.Properties.Due, Direction: notionapi.SortOrderASC},      // 期限日でソート
+			{Property: f.Properties.Priority, Direction: notionapi.SortOrderASC}, // ステータスでソート
+		},
+		PageSize: f.pageSize(),
+	}
+
+	pages, err := f.queryAllPages(ctx, dbID, request)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, page := range pages {
+		t := f.ParsePage(page)
+		if t == nil {
+			continue
+		}
+		// 開始日と終了日が両方とも設定されている場合、Notion APIでは開始日が優先的にフィルターに利用されるため、終了日をチェックする
+		if !t.Pinned && t.DueEnd != nil && t.DueDateIn(onOrBeforeDate.Location()).After(onOrBeforeDate) {
+			continue
+		}
+		allTasks = append(allTasks, *t)
+	}
+
+	return allTasks, nil
+}
+
+// pageSize は 1 回の問い合わせで取得する件数を返す
+func (f *Fetcher) pageSize() int {
+	if f.PageSize == 0 {
+		return 100
+	}
+	return f.PageSize
+}
+
+// queryAllPages は next_cursor をたどってすべての結果を取得する
+// MaxResults に達したら、残りは取得せずに警告を出す
+func (f *Fetcher) queryAllPages(ctx context.Context, dbID string, request *notionapi.DatabaseQueryRequest) ([]notionapi.Page, error) {
+	var pages []notionapi.Page
+	for {
+		resp, err := f.Client.Database.Query(ctx, notionapi.DatabaseID(dbID), request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query database: %w", err)
+		}
+		pages = append(pages, resp.Results...)
+		if f.MaxResults > 0 && len(pages) >= f.MaxResults {
+			if len(pages) > f.MaxResults || resp.HasMore {
+				log.Printf("Warning: Database %s has more than %d matching pages; the rest are skipped (raise --max-results)", dbID, f.MaxResults)
+			}
+			return pages[:f.MaxResults], nil
+		}
+		if !resp.HasMore || resp.NextCursor == "" {
+			return pages, nil
+		}
+		request.StartCursor = resp.NextCursor
+	}
+}
+
+// StatusFilter は取得するステータスのいずれかに一致するフィルターを返す
+// AnyStatus なら除くステータスのどれでもないことを条件にする
+func (f *Fetcher) StatusFilter() notionapi.Filter {
+	if f.AnyStatus {
+		if len(f.ExcludeStatuses) == 0 {
+			return &notionapi.PropertyFilter{
+				Property: f.Properties.Status,
+				Status:   &notionapi.StatusFilterCondition{IsNotEmpty: true},
+			}
+		}
+		var filters notionapi.AndCompoundFilter
+		for _, status := range f.ExcludeStatuses {
+			filters = append(filters, &notionapi.PropertyFilter{
+				Property: f.Properties.Status,
+				Status:   &notionapi.StatusFilterCondition{DoesNotEqual: status},
+			})
+		}
+		return filters
+	}
+	var filters notionapi.OrCompoundFilter
+	for _, status := range f.statuses() {
+		filters = append(filters, &notionapi.PropertyFilter{
+			Property: f.Properties.Status,
+			Status: &notionapi.StatusFilterCondition{
+				Equals: status,
+			},
+		})
+	}
+	return filters
+}
+
+// statuses は取得するステータスから除くステータスを除いて返す
+func (f *Fetcher) statuses() []string {
+	statuses := f.Statuses
+	if statuses == nil {
+		statuses = DefaultStatuses
+	}
+	var kept []string
+	for _, status := range statuses {
+		if !slices.Contains(f.ExcludeStatuses, status) {
+			kept = append(kept, status)
+		}
+	}
+	return kept
+}
+
+// HasStatus はステータスが取得する (未完了として扱う) ステータスかを返す
+func (f *Fetcher) HasStatus(status string) bool {
+	if f.AnyStatus {
+		return status != "" && !slices.Contains(f.ExcludeStatuses, status)
+	}
+	return slices.Contains(f.statuses(), status)
+}
+
+// ParsePage は Notion ページを Task に変換する。タイトルか期限日が無ければ nil
+func (f *Fetcher) ParsePage(page notionapi.Page) *task.Task {
+	props := f.Properties
+	t := task.Task{
+		ID:        page.ID,
+		URL:       page.URL,
+		CreatedAt: page.CreatedTime,
+	}
+
+	// プロパティを安全に反復処理
+	// 数式とロールアップは計算結果を普通のプロパティに置き換えて、同じ型のプロパティと同じように読む
+	for propName, propValue := range page.Properties {
+		propValue = Unwrap(propValue)
+		if props.Pinned != "" && propName == props.Pinned {
+			if p, ok := propValue.(*notionapi.CheckboxProperty); ok {
+				t.Pinned = p.Checkbox
+			}
+			continue
+		}
+		if props.Progress != "" && propName == props.Progress {
+			t.Progress = ParseProgress(propValue)
+			continue
+		}
+		if props.Files != "" && propName == props.Files {
+			t.Files = ParseFiles(propValue)
+			continue
+		}
+		switch propName {
+		case props.Name:
+			if p, ok := propValue.(*notionapi.TitleProperty); ok && len(p.Title) > 0 {
+				var titleBuilder strings.Builder
+				for _, rt := range p.Title {
+					titleBuilder.WriteString(RichTextContent(rt))
+				}
+				t.Title = strings.TrimSpace(titleBuilder.String())
+			}
+		case props.Due:
+			if p, ok := propValue.(*notionapi.DateProperty); ok && p.Date != nil {
+				t.DueStart = p.Date.Start
+				t.DueEnd = p.Date.End
+			}
+		case props.Priority:
+			t.Priority, _ = PlainText(propValue)
+		case props.Type:
+			t.Type, _ = PlainText(propValue)
+		case props.Status:
+			t.ScheduleStatus, _ = PlainText(propValue)
+		case props.Workload:
+			// 数値を名前にしたセレクトのほか、数値・数式・ロールアップも読む
+			if workload, ok := Number(propValue); ok {
+				t.Workload = float32(workload)
+			} else if name, _ := PlainText(propValue); name != "" {
+				log.Printf("Warning: Unable to parse workload %q for task ID %s", name, t.ID)
+			}
+		case props.UniqueID:
+			if p, ok := propValue.(*notionapi.UniqueIDProperty); ok {
+				t.UniqueID = p.UniqueID.String()
+			}
+		case props.Link:
+			t.Link, _ = PlainText(propValue)
+		case props.Email:
+			t.Email, _ = PlainText(propValue)
+		case props.Phone:
+			t.Phone, _ = PlainText(propValue)
+		case props.Assignee:
+			if p, ok := propValue.(*notionapi.PeopleProperty); ok {
+				for _, user := range p.People {
+					assignee := task.Assignee{Name: user.Name}
+					if user.Person != nil {
+						assignee.Email = user.Person.Email
+					}
+					t.Assignees = append(t.Assignees, assignee)
+				}
+			}
+		case props.Project:
+			if p, ok := propValue.(*notionapi.RelationProperty); ok {
+				for _, rel := range p.Relation {
+					t.ProjectIDs = append(t.ProjectIDs, rel.ID.String())
+				}
+			}
+		case props.Tags:
+			if p, ok := propValue.(*notionapi.MultiSelectProperty); ok {
+				for _, option := range p.MultiSelect {
+					t.Tags = append(t.Tags, option.Name)
+				}
+			}
+		case props.Memo:
+			// 書式やリンクごとに分かれた要素は同じ段落の続きなので、区切らずにつなげる (改行は要素の文字に含まれる)
+			if p, ok := propValue.(*notionapi.RichTextProperty); ok && len(p.RichText) > 0 {
+				t.Memo = joinRichText(p.RichText)
+				t.MemoRichText = p.RichText
+			}
+		}
+	}
+
+	for _, name := range props.Extra {
+		if value, ok := page.Properties[name]; ok {
+			t.Extra = append(t.Extra, task.Field{Name: name, Value: value})
+		}
+	}
+	for _, name := range props.Lookup {
+		if value, ok := page.Properties[name]; ok {
+			t.Lookup = append(t.Lookup, task.Field{Name: name, Value: value})
+		}
+	}
+
+	// 必須プロパティの検証: タイトルと期限日は必須
+	if t.Title == "" || (t.DueStart == nil && t.DueEnd == nil) {
+		log.Printf("Warning: Task with ID %s is missing required properties (Title or Due Date). Skipping.", t.ID)
+		return nil
+	}
+
+	return &t
+}
+
+// PageTitle はページのタイトルのプロパティの文字列を返す (タイトルのプロパティの名前はデータベースごとに違う)
+func PageTitle(page notionapi.Page) string {
+	for _, value := range page.Properties {
+		if p, ok := value.(*notionapi.TitleProperty); ok {
+			var title strings.Builder
+			for _, rt := range p.Title {
+				title.WriteString(RichTextContent(rt))
+			}
+			return strings.TrimSpace(title.String())
+		}
+	}
+	return ""
+}
+
+// RichTextContent はリッチテキストの文字列を取得する
+// メンションや数式のみの要素は Text が nil になるため、PlainText を優先して利用する
+func RichTextContent(rt notionapi.RichText) string {
+	if rt.PlainText != "" {
+		return rt.PlainText
+	}
+	if rt.Text != nil {
+		return rt.Text.Content
+	}
+	return ""
+}
+
+// ParseFiles はファイル&メディアのプロパティの添付ファイルを返す (アップロードしたものと外部のリンクの両方)
+func ParseFiles(value notionapi.Property) []task.File {
+	p, ok := value.(*notionapi.FilesProperty)
+	if !ok {
+		return nil
+	}
+	var files []task.File
+	for _, f := range p.Files {
+		switch {
+		case f.File != nil && f.File.URL != "":
+			files = append(files, task.File{Name: f.Name, URL: f.File.URL})
+		case f.External != nil && f.External.URL != "":
+			files = append(files, task.File{Name: f.Name, URL: f.External.URL})
+		}
+	}
+	return files
+}
+
+// ParseProgress はプロパティの値を 0〜1 の進捗率にする。数値が無ければ nil
+func ParseProgress(value notionapi.Property) *float64 {
+	v, ok := Number(value)
+	if !ok {
+		return nil
+	}
+	if v > 1 {
+		v /= 100
+	}
+	v = math.Max(0, math.Min(1, v))
+	return &v
+}
@@ -0,0 +1,95 @@
+package task
+
+import "time"
+
+// 期限日で分けるセクション以外のセクションの名前
+const (
+	PinnedSection = "pinned" // ピン留めのタスク
+	HoursSection  = "hours"  // 時刻付きで Grouping.WithinHours 時間以内に期限が来るタスク
+)
+
+// Bucket は期限日でタスクを分けるセクション
+// 前のセクションに入らなかったタスクのうち、期限日が今日から Days 日後より前のものを入れる
+type Bucket struct {
+	Name string
+	// 0 なら期限切れ、1 なら今日までが期限のタスク。最後のセクションでは nil にでき、残りのすべてを入れる
+	Days *int
+}
+
+// Grouping はタスクをセクションに分ける規則
+type Grouping struct {
+	Buckets []Bucket
+	// 0 より大きければ、時刻付きでこの時間以内に期限が来るタスクを HoursSection にする
+	WithinHours int
+	// 各セクション内の並び順 (ゼロ値は DefaultPriorities の順序)
+	Sorter Sorter
+}
+
+// Group はタスクをセクションの名前ごとに分け、各セクション内を g.Sorter で並べる
+// ピン留めのタスクは期限日に関係なく PinnedSection に入れる
+func Group[T Item](tasks []T, now time.Time, g Grouping) map[string][]T {
+	pinned, rest := SplitPinned(tasks)
+	var hours []T
+	if g.WithinHours > 0 {
+		hours, rest = SplitDueWithinHours(rest, now, g.WithinHours)
+	}
+	groups := GroupByBucket(rest, now, g.Buckets)
+	groups[PinnedSection] = pinned
+	groups[HoursSection] = hours
+	for _, group := range groups {
+		Sort(group, g.Sorter)
+	}
+	return groups
+}
+
+// GroupByBucket は期限日でタスクを分ける。どのセクションにも入らないタスクは除く
+func GroupByBucket[T Item](tasks []T, now time.Time, buckets []Bucket) map[string][]T {
+	groups := map[string][]T{}
+	today := StartOfDay(now)
+	for _, t := range tasks {
+		due := t.Core().DueDateIn(now.Location())
+		if due == nil { // 期限日の無いピン留めのタスク
+			continue
+		}
+		for _, b := range buckets {
+			if b.Days == nil || due.Before(today.AddDate(0, 0, *b.Days)) {
+				groups[b.Name] = append(groups[b.Name], t)
+				break
+			}
+		}
+	}
+	return groups
+}
+
+// SplitPinned はピン留めのタスクとそれ以外に分ける
+func SplitPinned[T Item](tasks []T) (pinned, rest []T) {
+	for _, t := range tasks {
+		if t.Core().Pinned {
+			pinned = append(pinned, t)
+		} else {
+			rest = append(rest, t)
+		}
+	}
+	return pinned, rest
+}
+
+// SplitDueWithinHours は時刻付きの期限が now から hours 時間以内のタスクとそれ以外に分ける
+// 日付のみのタスクは時刻が分からないため含めない
+func SplitDueWithinHours[T Item](tasks []T, now time.Time, hours int) (within, rest []T) {
+	until := now.Add(time.Duration(hours) * time.Hour)
+	for _, t := range tasks {
+		due := t.Core().DueDate()
+		if due != nil && !IsDateOnly(*due) && due.After(now) && !due.After(until) {
+			within = append(within, t)
+		} else {
+			rest = append(rest, t)
+		}
+	}
+	return within, rest
+}
+
+// StartOfDay は t と同じ日の 0:00 を t のロケーションで返す
+// time.Date で組み立て直すため、夏時間の切り替え日でも日付がずれない
+func StartOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,110 @@
+package task
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultPriorities は設定も Notion の選択肢も無いときの優先度の順序 (高い順)
+var DefaultPriorities = []string{"High", "Mid", "Low"}
+
+// Sorter はタスクの表示順を決める。優先度の順序を値として持つため、優先度の違う複数の設定から同時に使える
+// ゼロ値は DefaultPriorities の順序で並べる
+type Sorter struct {
+	order map[string]int // 優先度の名前 → 順位 (小さいほど高い)
+}
+
+// NewSorter は優先度を高い順の名前で指定した Sorter を返す
+func NewSorter(priorities []string) Sorter {
+	order := make(map[string]int, len(priorities)+1)
+	for i, name := range priorities {
+		if _, ok := order[name]; !ok {
+			order[name] = i + 1
+		}
+	}
+	order[""] = len(priorities) + 2 // 空の優先度は最も低い (順序に無い優先度はその 1 つ上)
+	return Sorter{order: order}
+}
+
+var defaultSorter = NewSorter(DefaultPriorities)
+
+func (s Sorter) ranks() map[string]int {
+	if s.order == nil {
+		return defaultSorter.order
+	}
+	return s.order
+}
+
+// PriorityRank は優先度の順位を返す (小さいほど高い)。順序に無い優先度は false を返す
+func (s Sorter) PriorityRank(name string) (int, bool) {
+	rank, ok := s.ranks()[name]
+	return rank, ok
+}
+
+// sortRank は Compare で使う優先度の順位を返す
+// 順序に無い優先度は順序のあるどの優先度よりも低く、空の優先度よりは高くする
+func (s Sorter) sortRank(name string) int {
+	ranks := s.ranks()
+	if rank, ok := ranks[name]; ok {
+		return rank
+	}
+	return ranks[""] - 1
+}
+
+// PriorityNames は順序のある優先度の名前を高い順に返す (最後は空の優先度)
+func (s Sorter) PriorityNames() []string {
+	ranks := s.ranks()
+	names := make([]string, 0, len(ranks))
+	for name := range ranks {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int { return ranks[a] - ranks[b] })
+	return names
+}
+
+// Sort はタスクを s の順序で、優先度・期限日・ワークロード・タイトル・ページ ID の順に並べる
+// 同じデータからは実行のたびに同じ順序になるよう、すべてのタスクに順序を付ける (ハッシュによる重複排除や出力の比較で使う)
+func Sort[T Item](tasks []T, s Sorter) {
+	slices.SortStableFunc(tasks, func(a, b T) int { return s.Compare(a.Core(), b.Core()) })
+}
+
+// Compare はタスクの表示順を比べる
+func (s Sorter) Compare(a, b Task) int {
+	// 数値が小さいほど優先度が高い。順序の無い優先度どうしは名前で比べる
+	if c := cmp.Compare(s.sortRank(a.Priority), s.sortRank(b.Priority)); c != 0 {
+		return c
+	}
+	if c := strings.Compare(a.Priority, b.Priority); c != 0 {
+		return c
+	}
+	// 期限日の早い順 (期限日の無いピン留めのタスクは最後)
+	if c := compareDue(a.DueDate(), b.DueDate()); c != 0 {
+		return c
+	}
+	if c := compareDue(a.StartTime(), b.StartTime()); c != 0 {
+		return c
+	}
+	// ワークロードの大きい順 (早めに手を付ける必要がある)
+	if c := cmp.Compare(b.Workload, a.Workload); c != 0 {
+		return c
+	}
+	if c := strings.Compare(a.Title, b.Title); c != 0 {
+		return c
+	}
+	return strings.Compare(string(a.ID), string(b.ID))
+}
+
+// compareDue は期限日を早い順に比べる。nil は最後にする
+func compareDue(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return a.Compare(*b)
+}
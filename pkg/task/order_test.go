@@ -0,0 +1,147 @@
+package task
+
+import (
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jomei/notionapi"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// orderTasks は期限日・優先度・タイトル・ページ ID がそれぞれ同じになるタスクを組み合わせたもの
+func orderTasks() []Task {
+	date := func(s string) *notionapi.Date {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			panic(err)
+		}
+		d := notionapi.Date(t)
+		return &d
+	}
+	dayOne, dayOneMorning, dayTwo := date("2026-10-17T00:00:00Z"), date("2026-10-17T09:00:00+09:00"), date("2026-10-18T00:00:00Z")
+	return []Task{
+		{ID: "a1", Title: "Same title", Priority: "High", DueStart: dayOne},
+		{ID: "a2", Title: "Same title", Priority: "High", DueStart: dayOne},
+		{ID: "a3", Title: "Another title", Priority: "High", DueStart: dayOne},
+		{ID: "b1", Title: "Same title", Priority: "High", DueStart: dayTwo},
+		{ID: "b2", Title: "Same title", Priority: "High", DueStart: dayOne, DueEnd: dayTwo},
+		{ID: "b3", Title: "Same title", Priority: "High", DueStart: dayOneMorning, DueEnd: dayTwo},
+		{ID: "c1", Title: "Same title", Priority: "Mid", DueStart: dayOne},
+		{ID: "c2", Title: "Same title", Priority: "Low", DueStart: dayOne},
+		{ID: "c3", Title: "Same title", Priority: "", DueStart: dayOne},
+		{ID: "d1", Title: "Same title", Priority: "Urgent", DueStart: dayOne},
+		{ID: "d2", Title: "Same title", Priority: "Blocker", DueStart: dayTwo},
+		{ID: "d3", Title: "Same title", Priority: "Blocker", DueStart: dayOne},
+		{ID: "e1", Title: "Heavy", Priority: "Mid", DueStart: dayOne, Workload: 3},
+		{ID: "e2", Title: "Light", Priority: "Mid", DueStart: dayOne, Workload: 0.5},
+		{ID: "f1", Title: "Pinned without due", Priority: "High", Pinned: true},
+		{ID: "f2", Title: "Pinned without due", Priority: "High", Pinned: true},
+		{ID: "f3", Title: "Pinned without due", Priority: "", Pinned: true},
+	}
+}
+
+func formatOrder(tasks []Task) string {
+	var b strings.Builder
+	for _, t := range tasks {
+		due := "-"
+		if d := t.DueDate(); d != nil {
+			due = d.UTC().Format(time.RFC3339)
+		}
+		start := "-"
+		if d := t.StartTime(); d != nil {
+			start = d.UTC().Format(time.RFC3339)
+		}
+		priority := t.Priority
+		if priority == "" {
+			priority = "(none)"
+		}
+		fmt.Fprintf(&b, "%-3s %-8s %-20s %-20s %-4g %s\n", t.ID, priority, due, start, t.Workload, t.Title)
+	}
+	return b.String()
+}
+
+// TestSortGolden はタスクを並べた結果が testdata/order.golden と一致し、入力の順序に関係なく同じになることを確かめる
+// 期待する結果を変えるときは go test ./pkg/task -run TestSortGolden -update で書き直す
+func TestSortGolden(t *testing.T) {
+	var sorter Sorter
+	tasks := orderTasks()
+	Sort(tasks, sorter)
+	got := formatOrder(tasks)
+
+	golden := filepath.Join("testdata", "order.golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("sorted order differs from %s\ngot:\n%s\nwant:\n%s", golden, got, want)
+	}
+
+	// 全順序なので、どの順序で渡しても同じ結果になる
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := range 50 {
+		shuffled := orderTasks()
+		rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		Sort(shuffled, sorter)
+		if order := formatOrder(shuffled); order != got {
+			t.Fatalf("shuffle %d sorted differently:\n%s", i, order)
+		}
+	}
+}
+
+func TestCompareUnknownPriority(t *testing.T) {
+	sorter := NewSorter([]string{"High", "Mid", "Low"})
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"Urgent", "High", 1},
+		{"Urgent", "Low", 1},
+		{"Urgent", "", -1},
+		{"Blocker", "Urgent", -1},
+		{"Low", "", -1},
+	}
+	for _, tt := range tests {
+		if got := sorter.Compare(Task{Priority: tt.a}, Task{Priority: tt.b}); got != tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// TestSortersAreIndependent は順序の違う Sorter を並行して使っても互いに影響しないことを確かめる (go test -race で意味がある)
+func TestSortersAreIndependent(t *testing.T) {
+	orders := [][]string{{"High", "Mid", "Low"}, {"Low", "Mid", "High"}}
+	var wg sync.WaitGroup
+	for _, order := range orders {
+		sorter := NewSorter(order)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				tasks := []Task{{ID: "1", Priority: "Mid"}, {ID: "2", Priority: "Low"}, {ID: "3", Priority: "High"}}
+				Sort(tasks, sorter)
+				if tasks[0].Priority != order[0] || tasks[2].Priority != order[2] {
+					t.Errorf("sorter %v sorted %v", order, []string{tasks[0].Priority, tasks[1].Priority, tasks[2].Priority})
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
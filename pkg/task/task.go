@@ -0,0 +1,112 @@
+// Package task は Notion から取得したタスクと、その並べ替え・期限日によるセクション分けを扱う
+// notion-notifyer の CLI と同じ規則でタスクを並べたり分けたりできるよう、Bot などに組み込んで使える
+package task
+
+import (
+	"time"
+
+	"github.com/jomei/notionapi"
+)
+
+// Task は Notion のデータベースの 1 ページ
+type Task struct {
+	ID             notionapi.ObjectID
+	UniqueID       string // ユニーク ID のプロパティの値 (例: TASK-123、プロパティを設定した場合のみ)
+	Title          string
+	DueStart       *notionapi.Date
+	DueEnd         *notionapi.Date
+	Priority       string // High, Medium, Low,
+	Type           string
+	ScheduleStatus string
+	Workload       float32
+	Memo           string
+	MemoRichText   []notionapi.RichText // メモの書式・リンク・メンション (Slack の mrkdwn で表示するため)
+	URL            string
+	Link           string // 関連する URL (Issue や PR など)
+	Email          string // メールのプロパティ (プロパティを設定した場合のみ)
+	Phone          string // 電話のプロパティ (プロパティを設定した場合のみ)
+	Assignees      []Assignee
+	Tags           []string  // マルチセレクトのタグ
+	ProjectIDs     []string  // プロジェクトのリレーション先のページ ID (プロパティを設定した場合のみ)
+	Pinned         bool      // ピン留めのチェックボックスが付いている (期限日が無い場合もある)
+	Source         string    // 取得元のデータベース名 (複数のデータベースから取得した場合のみ設定)
+	Progress       *float64  // 0〜1 の進捗率 (進捗率のプロパティを設定した場合のみ)
+	CreatedAt      time.Time // ページの作成日時
+	Files          []File    // ファイル&メディアのプロパティの添付ファイル (プロパティを設定した場合のみ)
+	Extra          []Field   // 詳細に表示する追加のプロパティ (取得時に指定した順)
+	Lookup         []Field   // 表示せずに値だけを使う追加のプロパティ (Jira のキーを探すプロパティなど)
+}
+
+// Assignee は People プロパティの担当者
+type Assignee struct {
+	Name  string
+	Email string
+}
+
+// File は添付ファイル。Notion にアップロードしたファイルの URL は 1 時間で期限が切れる
+type File struct {
+	Name string
+	URL  string
+}
+
+// Field は種類を問わずにそのまま取得したプロパティ (表示のしかたは種類ごとに使う側で決める)
+type Field struct {
+	Name  string
+	Value notionapi.Property
+}
+
+// Item は Task そのものか、Task を埋め込んで情報を足した型
+// Sort や Group は Item を受け取るので、埋め込んだ型のまま並べ替えたり分けたりできる
+type Item interface {
+	Core() Task
+}
+
+// Core は Task 自身を返す (埋め込んだ型では Task の部分を返す)
+func (t Task) Core() Task {
+	return t
+}
+
+// Key は状態や履歴でタスクを見分けるキーを返す
+// ボタンの操作などページ ID しか分からない場面でも同じキーになるよう、ユニーク ID ではなくページ ID を使う
+func (t Task) Key() string {
+	return string(t.ID)
+}
+
+// DueDate はタスクの目標期限日を返す (終了日を優先する)。期限日が無ければ nil
+func (t Task) DueDate() *time.Time {
+	if t.DueEnd != nil {
+		d := time.Time(*t.DueEnd)
+		return &d
+	}
+	if t.DueStart != nil {
+		d := time.Time(*t.DueStart)
+		return &d
+	}
+	return nil
+}
+
+// DueDateIn は DueDate を loc の日付の境界と比べられるように返す
+// 日付のみの期限日は UTC の 0:00 で表されるため、loc の同じ日付の 0:00 に読み替える
+// (読み替えないと、UTC より西のタイムゾーンでは今日が期限のタスクが期限切れになる)
+func (t Task) DueDateIn(loc *time.Location) *time.Time {
+	due := t.DueDate()
+	if due != nil && IsDateOnly(*due) {
+		d := time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, loc)
+		return &d
+	}
+	return due
+}
+
+// StartTime はタスクの開始日時を返す (開始日が無ければ期限日)
+func (t Task) StartTime() *time.Time {
+	if t.DueStart != nil {
+		d := time.Time(*t.DueStart)
+		return &d
+	}
+	return t.DueDate()
+}
+
+// IsDateOnly は時刻を持たない日付 (Notion で日付のみ指定された値) かどうかを判定する
+func IsDateOnly(t time.Time) bool {
+	return t.Location() == time.UTC && t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0
+}
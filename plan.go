@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jomei/notionapi"
+	"github.com/spf13/cobra"
+)
+
+// --detailed-exitcode で変更があったときの終了コード
+const exitPlanChanged = 2
+
+// plannedTask は 1 つの設定で掲載されるタスクと、そのセクション
+type plannedTask struct {
+	Task    Task
+	Section string
+}
+
+// planTasks は今の設定で掲載されるタスクをページ ID ごとに返す
+func planTasks(ctx context.Context, cmd *cobra.Command, now time.Time) (map[string]plannedTask, error) {
+	daysLater, err := daysLaterFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	token, dbID, err := notionSourceFromEnv(stateStoreFromEnv())
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := fetchNotionTasks(ctx, notionapi.NewClient(notionapi.Token(token)), dbID, endOfDay(now, daysLater))
+	if err != nil {
+		return nil, fmt.Errorf("get Notion tasks: %w", err)
+	}
+	includeTypes, _ := cmd.Flags().GetStringSlice("include-types")
+	excludeTypes, _ := cmd.Flags().GetStringSlice("exclude-types")
+	tasks = taskTypeFilter{Include: includeTypes, Exclude: excludeTypes}.apply(tasks)
+
+	sectionNames, _ := cmd.Flags().GetStringSlice("sections")
+	sections, err := parseSections(sectionNames)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --sections: %w", err)
+	}
+	withinHours, _ := cmd.Flags().GetInt("within-hours")
+	planned := map[string]plannedTask{}
+	for _, group := range buildTaskGroups(tasks, sections, nil, now, withinHours) {
+		for _, task := range group.Tasks {
+			planned[pageIDText(task)] = plannedTask{Task: task, Section: group.Name}
+		}
+	}
+	return planned, nil
+}
+
+// plannedChanges はセクションと表示するプロパティの違いを「名前: 前 → 後」の形で返す
+func plannedChanges(before, after plannedTask) []string {
+	var changes []string
+	add := func(name, from, to string) {
+		if from != to {
+			changes = append(changes, fmt.Sprintf("%s: %s → %s", name, orDash(from), orDash(to)))
+		}
+	}
+	add("section", before.Section, after.Section)
+	add("title", before.Task.Title, after.Task.Title)
+	beforeDue, _ := formatDueDate(before.Task)
+	afterDue, _ := formatDueDate(after.Task)
+	add("due", beforeDue, afterDue)
+	add("priority", before.Task.Priority, after.Task.Priority)
+	add("type", before.Task.Type, after.Task.Type)
+	add("status", before.Task.ScheduleStatus, after.Task.ScheduleStatus)
+	add("workload", fmt.Sprint(before.Task.Workload), fmt.Sprint(after.Task.Workload))
+	return changes
+}
+
+// writePlan は追加・削除・変更されるタスクを書き出し、変更があれば true を返す
+func writePlan(w io.Writer, before, after map[string]plannedTask, order []string) (bool, error) {
+	var b strings.Builder
+	added, removed, changed := 0, 0, 0
+	for _, id := range sortedPlanIDs(before, after, order) {
+		old, inBefore := before[id]
+		cur, inAfter := after[id]
+		switch {
+		case !inBefore:
+			added++
+			fmt.Fprintf(&b, "+ [%s] %s (%s)\n", cur.Section, cur.Task.Title, cur.Task.URL)
+		case !inAfter:
+			removed++
+			fmt.Fprintf(&b, "- [%s] %s (%s)\n", old.Section, old.Task.Title, old.Task.URL)
+		default:
+			if changes := plannedChanges(old, cur); len(changes) > 0 {
+				changed++
+				fmt.Fprintf(&b, "~ [%s] %s\n", cur.Section, cur.Task.Title)
+				for _, c := range changes {
+					fmt.Fprintf(&b, "    %s\n", c)
+				}
+			}
+		}
+	}
+	if added+removed+changed == 0 {
+		b.WriteString("No changes. The candidate config matches the same tasks.\n")
+	} else {
+		fmt.Fprintf(&b, "\nPlan: %d to add, %d to remove, %d to change (%d tasks now, %d with the candidate config).\n", added, removed, changed, len(before), len(after))
+	}
+	_, err := io.WriteString(w, b.String())
+	return added+removed+changed > 0, err
+}
+
+// sortedPlanIDs は両方の設定のタスクを、セクションの順 (order に無いセクションは最後) に並べたページ ID を返す
+// 両方にあるタスクは今のセクション、候補の設定で追加されるタスクは候補のセクションで並べる
+func sortedPlanIDs(before, after map[string]plannedTask, order []string) []string {
+	var ids []string
+	for id := range before {
+		ids = append(ids, id)
+	}
+	for id := range after {
+		if _, ok := before[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	rank := func(section string) int {
+		if i := slices.Index(order, section); i >= 0 {
+			return i
+		}
+		return len(order)
+	}
+	planned := func(id string) plannedTask {
+		if p, ok := before[id]; ok {
+			return p
+		}
+		return after[id]
+	}
+	slices.SortFunc(ids, func(a, b string) int {
+		pa, pb := planned(a), planned(b)
+		if c := rank(pa.Section) - rank(pb.Section); c != 0 {
+			return c
+		}
+		return currentSorter().Compare(pa.Task.Task, pb.Task.Task)
+	})
+	return ids
+}
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Show how the tasks in the digest would change with a candidate config file, like terraform plan for notification filters.",
+	Long: `Fetch the tasks with the current config and with the candidate config (--against) and list the tasks that would be added (+),
+removed (-) or changed (~, e.g. moved to another section or read from a renamed property). Nothing is posted or recorded.
+With --detailed-exitcode the command exits with 2 when there are changes.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		against, _ := cmd.Flags().GetString("against")
+		clock, err := clockFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		now := clock.Now()
+		ctx := cmd.Context()
+
+		before, err := planTasks(ctx, cmd, now)
+		if err != nil {
+			return fmt.Errorf("current config: %w", err)
+		}
+
+		c, err := loadConfigFile(against)
+		if err != nil {
+			return err
+		}
+		if _, err := applyConfigEnv(c); err != nil {
+			return err
+		}
+		// 今の設定のセクションの順に並べ、候補の設定で増えたセクションは後ろに続ける
+		order := defaultSectionOrder()
+		pinnedFromFlag := pinnedProp
+		resetConfig()
+		if cmd.Flags().Changed("pinned-property") {
+			pinnedProp = pinnedFromFlag
+		}
+		if err := applyFileConfig(cmd, c, against); err != nil {
+			return err
+		}
+		after, err := planTasks(ctx, cmd, now)
+		if err != nil {
+			return fmt.Errorf("candidate config %s: %w", against, err)
+		}
+
+		for _, name := range defaultSectionOrder() {
+			if !slices.Contains(order, name) {
+				order = append(order, name)
+			}
+		}
+		changed, err := writePlan(cmd.OutOrStdout(), before, after, order)
+		if err != nil {
+			return err
+		}
+		if detailed, _ := cmd.Flags().GetBool("detailed-exitcode"); detailed && changed {
+			os.Exit(exitPlanChanged)
+		}
+		return nil
+	},
+}
+
+func init() {
+	planCmd.Flags().String("against", "", "Candidate config file to compare with the current one (--config, $NOTIFYER_CONFIG or the discovered config.yaml)")
+	_ = planCmd.MarkFlagRequired("against")
+	planCmd.Flags().StringSlice("sections", nil, "Sections to show, in order (as for the digest)")
+	planCmd.Flags().Int("within-hours", 0, "Show timed tasks due within this many hours in their own section (as for the digest)")
+	planCmd.Flags().StringSlice("include-types", nil, "Only count tasks of these Types")
+	planCmd.Flags().StringSlice("exclude-types", nil, "Never count tasks of these Types")
+	planCmd.Flags().Bool("detailed-exitcode", false, "Exit with status 2 when the candidate config changes the tasks")
+	rootCmd.AddCommand(planCmd)
+}
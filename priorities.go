@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jomei/notionapi"
+
+	"rainierrr/notion-notifyer/pkg/task"
+)
+
+// priorityLevel は設定ファイルの priorities の 1 つ (上にあるものほど優先度が高い)
+type priorityLevel struct {
+	Name string `yaml:"name"`
+	// 設定すると emoji のテンプレート関数で返し、タスクの詳細の優先度の前に付ける (例: 🔴 High)
+	Emoji string `yaml:"emoji"`
+}
+
+// 設定ファイルの優先度 (空なら Notion の優先度のセレクトの選択肢の順序を使う)
+var configuredPriorities []priorityLevel
+
+// タスクを並べる優先度の順序。serve や tenants serve では実行中に Notion の選択肢で置き換えるため、ロックして読む
+var (
+	sorterMu   sync.RWMutex
+	taskSorter = task.NewSorter(task.DefaultPriorities)
+)
+
+// setPriorityOrder は並び順に使う優先度を高い順の名前で置き換える
+func setPriorityOrder(names []string) {
+	sorter := task.NewSorter(names)
+	sorterMu.Lock()
+	defer sorterMu.Unlock()
+	taskSorter = sorter
+}
+
+// currentSorter は今の優先度の順序でタスクを並べる Sorter を返す
+func currentSorter() task.Sorter {
+	sorterMu.RLock()
+	defer sorterMu.RUnlock()
+	return taskSorter
+}
+
+// emoji のテンプレート関数が返す優先度の絵文字 (priorities で絵文字を設定していないとき)
+var priorityEmoji = map[string]string{
+	"High": "🔴",
+	"Mid":  "🟡",
+	"Low":  "🟢",
+}
+
+// validatePriorities は優先度の名前が空でなく重複していないことを確かめる
+func validatePriorities(levels []priorityLevel) error {
+	seen := map[string]bool{}
+	for i, level := range levels {
+		if level.Name == "" {
+			return fmt.Errorf("priorities[%d]: name is required", i)
+		}
+		if seen[level.Name] {
+			return fmt.Errorf("priorities[%d]: duplicate priority %q", i, level.Name)
+		}
+		seen[level.Name] = true
+	}
+	return nil
+}
+
+// applyPriorities は設定の優先度を並び順に反映する (空なら組み込みの順序に戻す)
+func applyPriorities(levels []priorityLevel) {
+	configuredPriorities = levels
+	if len(levels) == 0 {
+		setPriorityOrder(task.DefaultPriorities)
+		return
+	}
+	names := make([]string, len(levels))
+	for i, level := range levels {
+		names[i] = level.Name
+	}
+	setPriorityOrder(names)
+}
+
+// priorityRank は優先度の順位を返す (小さいほど高い、順序に無い優先度は false)
+func priorityRank(name string) (int, bool) { return currentSorter().PriorityRank(name) }
+
+// priorityNames は順序のある優先度を高い順に返す (最後は空の優先度)
+func priorityNames() []string { return currentSorter().PriorityNames() }
+
+// priorityEmojiFor は優先度の絵文字を返す。設定の絵文字が無ければ組み込みの絵文字を使う
+func priorityEmojiFor(name string) (string, bool) {
+	for _, level := range configuredPriorities {
+		if level.Name == name && level.Emoji != "" {
+			return level.Emoji, true
+		}
+	}
+	e, ok := priorityEmoji[name]
+	return e, ok
+}
+
+// priorityLabel はタスクの詳細に表示する優先度を返す (設定で絵文字を付けた優先度は「🔴 High」)
+func priorityLabel(name string) string {
+	for _, level := range configuredPriorities {
+		if level.Name == name && level.Emoji != "" {
+			return level.Emoji + " " + name
+		}
+	}
+	return name
+}
+
+// Notion から読んだ優先度の選択肢 (データベース ID のカンマ区切り → 高い順の名前)
+// スキーマはめったに変わらないため、プロセスの間は読み直さない
+var (
+	schemaPrioritiesMu sync.Mutex
+	schemaPriorities   = map[string][]string{}
+)
+
+// loadSchemaPriorities は優先度を設定していなければ、Notion のデータベースの優先度のセレクト (またはステータス) の
+// 選択肢の順序を並び順にする。複数のデータベースは最初に出てきた順に合わせる
+// 読めないときや、優先度が数式などで選択肢が無いときは、組み込みの順序のままにする
+func loadSchemaPriorities(ctx context.Context, client *notionapi.Client, dbs []notionDatabase) {
+	if len(configuredPriorities) > 0 {
+		return
+	}
+	ids := make([]string, len(dbs))
+	for i, db := range dbs {
+		ids[i] = db.ID
+	}
+	key := strings.Join(ids, ",")
+
+	schemaPrioritiesMu.Lock()
+	defer schemaPrioritiesMu.Unlock()
+	names, ok := schemaPriorities[key]
+	if !ok {
+		for _, id := range ids {
+			db, err := client.Database.Get(ctx, notionapi.DatabaseID(id))
+			if err != nil {
+				log.Printf("Warning: Failed to read the %s options of database %s: %v", priorityProp, id, err)
+				return
+			}
+			for _, option := range priorityOptions(db.Properties[priorityProp]) {
+				if !slices.Contains(names, option) {
+					names = append(names, option)
+				}
+			}
+		}
+		schemaPriorities[key] = names
+	}
+	if len(names) > 0 {
+		setPriorityOrder(names)
+	} else {
+		setPriorityOrder(task.DefaultPriorities)
+	}
+}
+
+// priorityOptions はセレクトとステータスのプロパティの選択肢の名前を並び順に返す
+func priorityOptions(config notionapi.PropertyConfig) []string {
+	var options []notionapi.Option
+	switch c := config.(type) {
+	case *notionapi.SelectPropertyConfig:
+		options = c.Select.Options
+	case *notionapi.StatusPropertyConfig:
+		options = c.Status.Options
+	}
+	names := make([]string, len(options))
+	for i, option := range options {
+		names[i] = option.Name
+	}
+	return names
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// 実行プロファイル (--profile が優先)
+const profileEnv = "NOTIFYER_PROFILE"
+
+// runProfile は名前付きの実行設定
+// 朝の軽い実行と週次の詳しい実行のように、重い機能の有無を切り替える
+type runProfile struct {
+	Features map[string]bool `yaml:"features"`
+	// 投稿先のチャンネル (SLACK_CHANNEL_ID と同じ形式)。空なら SLACK_CHANNEL_ID に送る
+	// --profiles でチームごとのプロファイルを 1 回の実行にまとめるときに使う
+	Channel string `yaml:"channel"`
+}
+
+// プロファイルで切り替えられる機能と、対応するフラグ
+var profileFeatures = map[string]string{
+	"calendar":        "calendar",
+	"focus":           "focus",
+	"thread_tasks":    "thread-tasks",
+	"update_in_place": "update-in-place",
+	"track_seen":      "track-seen",
+	"show_page_id":    "show-page-id",
+	"short_codes":     "short-codes",
+	"github_status":   "github-status",
+	"jira_status":     "jira-status",
+	"desktop":         "desktop",
+	"action_buttons":  "action-buttons",
+}
+
+// 既定のプロファイルと設定ファイルのプロファイル (同じ名前なら設定ファイルが優先)
+var configuredProfiles = map[string]runProfile{}
+
+// validateProfiles は知らない機能名を書き間違いとしてエラーにする
+func validateProfiles(profiles map[string]runProfile) error {
+	for name, p := range profiles {
+		for feature := range p.Features {
+			if _, ok := profileFeatures[feature]; !ok {
+				return fmt.Errorf("profile %q: unknown feature %q (known: %s)", name, feature, knownFeatureNames())
+			}
+		}
+	}
+	return nil
+}
+
+func knownFeatureNames() string {
+	var names []string
+	for name := range profileFeatures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Sprint(names)
+}
+
+// selectedProfile は --profile (未指定なら NOTIFYER_PROFILE) のプロファイルの名前を返す
+func selectedProfile(cmd *cobra.Command) string {
+	name, _ := cmd.Flags().GetString("profile")
+	if name == "" {
+		name = os.Getenv(profileEnv)
+	}
+	return name
+}
+
+// lookupProfile は名前のプロファイルを返す
+func lookupProfile(name string) (runProfile, error) {
+	profile, ok := configuredProfiles[name]
+	if !ok {
+		var names []string
+		for n := range configuredProfiles {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		return runProfile{}, fmt.Errorf("unknown profile %q (available: %v)", name, names)
+	}
+	return profile, nil
+}
+
+// profileChannels は選んだプロファイルの投稿先のチャンネルを返す (プロファイルに無ければ SLACK_CHANNEL_ID)
+func profileChannels(cmd *cobra.Command) string {
+	if name := selectedProfile(cmd); name != "" {
+		if profile, err := lookupProfile(name); err == nil && profile.Channel != "" {
+			return profile.Channel
+		}
+	}
+	return os.Getenv(slackChannelEnv)
+}
+
+// applyProfile は --profile (未指定なら NOTIFYER_PROFILE) のプロファイルの機能をフラグに反映する
+// コマンドラインで明示したフラグはプロファイルより優先する
+func applyProfile(cmd *cobra.Command) error {
+	name := selectedProfile(cmd)
+	if name == "" {
+		return nil
+	}
+	profile, err := lookupProfile(name)
+	if err != nil {
+		return err
+	}
+	for feature, enabled := range profile.Features {
+		flag := profileFeatures[feature]
+		if cmd.Flags().Changed(flag) {
+			continue
+		}
+		if err := cmd.Flags().Set(flag, strconv.FormatBool(enabled)); err != nil {
+			return fmt.Errorf("profile %q: %w", name, err)
+		}
+	}
+	log.Printf("Using profile %s", name)
+	return nil
+}
+
+// withProfile はプロファイルを選んだ状態のフラグで fn を呼び、終わったらフラグを元に戻す
+// cobra のフラグは 1 組しか無いため、並行して呼ばないこと
+func withProfile(cmd *cobra.Command, name string, fn func() error) error {
+	var restore []func()
+	defer func() {
+		for _, r := range restore {
+			r()
+		}
+	}()
+	save := func(name string) {
+		f := cmd.Flags().Lookup(name)
+		value, changed := f.Value.String(), f.Changed
+		restore = append(restore, func() {
+			_ = f.Value.Set(value)
+			f.Changed = changed
+		})
+	}
+	save("profile")
+	for _, flag := range profileFeatures {
+		save(flag)
+	}
+	if err := cmd.Flags().Set("profile", name); err != nil {
+		return err
+	}
+	if err := applyProfile(cmd); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// runProfiles は --profiles のプロファイルをそれぞれのジョブとして並行して実行する
+// 状態ファイル・履歴・API の呼び出しの記録 (レート予算の目安) はすべてのプロファイルで共有し、レポートは最後にまとめて出す
+// 1 つのプロファイルが失敗しても残りは実行し、失敗したものをまとめて返す
+func runProfiles(ctx context.Context, cmd *cobra.Command, names []string, clock Clock, retry outageRetry, runNumber string) error {
+	for _, name := range names {
+		if _, err := lookupProfile(name); err != nil {
+			return configErrorf("invalid --profiles: %w", err)
+		}
+	}
+	parallel, _ := cmd.Flags().GetInt("profiles-parallel")
+	if parallel < 0 {
+		return configErrorf("--profiles-parallel must not be negative")
+	}
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	switch {
+	case dryRun:
+		parallel = 1 // --dry-run のプレビューが混ざらないよう 1 つずつ実行する
+	case parallel == 0 || parallel > len(names):
+		parallel = len(names)
+	}
+
+	// 状態ファイルは同じパスなら同じ stateStore になる。履歴も 1 つを共有して記録が競合しないようにする
+	history := historyStoreFromEnv()
+	report, err := usageReportFromFlags(cmd, clock)
+	if err != nil {
+		return err
+	}
+	usage := newAPIUsage(time.Now())
+	defer func() { reportUsage(ctx, report, dryRun, "[profiles]", usage, time.Now()) }()
+
+	var flagsMu sync.Mutex
+	slots := make(chan struct{}, parallel)
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		// 空きを待ってから始めるので、並べた順に実行を始める
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			makeJob := func() (digestJob, error) {
+				flagsMu.Lock()
+				defer flagsMu.Unlock()
+				var job digestJob
+				err := withProfile(cmd, name, func() (err error) {
+					job, err = rootDigestJob(cmd, clock)
+					return err
+				})
+				job.Name, job.RunNumber, job.History, job.Usage = name, runNumber, history, usage
+				return job, err
+			}
+			if errs[i] = runDigestWithOutageRetry(ctx, makeJob, clock, retry); errs[i] != nil {
+				errs[i] = fmt.Errorf("profile %s: %w", name, errs[i])
+				log.Printf("[%s] Profile error: %v", name, errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func init() {
+	rootCmd.Flags().String("profile", "", "Run profile from the config file that turns features on or off (default $NOTIFYER_PROFILE)")
+	rootCmd.Flags().StringSlice("profiles", nil, "Run several profiles in one invocation, each as its own digest (e.g. morning,team-a,team-b); they share the state file and API rate budgets")
+	rootCmd.Flags().Int("profiles-parallel", 0, "Maximum number of --profiles run at the same time (0 runs them all at once)")
+}
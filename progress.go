@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// 進捗率のプロパティ (数値・数式・ロールアップ)。空なら使わない
+// Notion のパーセント表示の数値は 0〜1 で返るため、1 以下の値は割合、それより大きい値はパーセントとして扱う
+var progressProp string
+
+// 進捗バーの長さ
+const progressBarWidth = 10
+
+// progressBar は進捗率をバーにする (例: ▓▓▓▓▓▓░░░░ 60%)
+func progressBar(progress float64) string {
+	filled := int(math.Round(progress * progressBarWidth))
+	return fmt.Sprintf("%s%s %d%%", strings.Repeat("▓", filled), strings.Repeat("░", progressBarWidth-filled), int(math.Round(progress*100)))
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jomei/notionapi"
+
+	"rainierrr/notion-notifyer/pkg/notion"
+)
+
+// プロジェクトのデータベースへのリレーションのプロパティ (properties.project、空なら使わない)
+var projectProp string
+
+// 取得したプロジェクトのタイトルを使い回す期間 (名前の変更に追従できるよう、期限が切れたら取得し直す)
+const pageTitleCacheTTL = 24 * time.Hour
+
+// --group-by の値
+const (
+	groupByUrgency = "urgency" // 期限日のセクションに分ける (既定)
+	groupByProject = "project" // プロジェクトごとのセクションに分ける
+)
+
+// プロジェクトのセクションの名前の接頭辞 (セクションの見出しのブロック ID に使う)
+const projectSectionPrefix = "project:"
+
+// pageTitleCache は取得したリレーション先のページのタイトル
+type pageTitleCache struct {
+	Title      string    `json:"title"`
+	ResolvedAt time.Time `json:"resolved_at"`
+}
+
+// resolveProjects はタスクのリレーション先のページのタイトルを取得して Projects に設定する
+// タイトルは状態ファイルにキャッシュし、同じプロジェクトのページは 1 度だけ取得する
+// 取得できなかったページは警告を出して表示しない
+func resolveProjects(ctx context.Context, client *notionapi.Client, store *stateStore, tasks []Task, now time.Time) error {
+	titles := map[string]string{}
+	if store != nil {
+		st, err := store.Load()
+		if err != nil {
+			return err
+		}
+		for id, c := range st.PageTitles {
+			if now.Sub(c.ResolvedAt) < pageTitleCacheTTL {
+				titles[id] = c.Title
+			}
+		}
+	}
+
+	resolved := map[string]*pageTitleCache{}
+	for i := range tasks {
+		tasks[i].Projects = nil
+		for _, id := range tasks[i].ProjectIDs {
+			title, ok := titles[id]
+			if !ok {
+				page, err := client.Page.Get(ctx, notionapi.PageID(id))
+				if err != nil {
+					log.Printf("Warning: Unable to get project %s of task %s: %v", id, tasks[i].Title, err)
+					titles[id] = ""
+					continue
+				}
+				title = notion.PageTitle(*page)
+				titles[id] = title
+				resolved[id] = &pageTitleCache{Title: title, ResolvedAt: now}
+			}
+			if title != "" {
+				tasks[i].Projects = append(tasks[i].Projects, title)
+			}
+		}
+	}
+	if store == nil || len(resolved) == 0 {
+		return nil
+	}
+	return store.Update(func(st *state) error {
+		if st.PageTitles == nil {
+			st.PageTitles = map[string]*pageTitleCache{}
+		}
+		for id, c := range st.PageTitles {
+			if now.Sub(c.ResolvedAt) >= pageTitleCacheTTL {
+				delete(st.PageTitles, id)
+			}
+		}
+		for id, c := range resolved {
+			st.PageTitles[id] = c
+		}
+		return nil
+	})
+}
+
+// projectDetail はタスクのプロジェクトを詳細の「*プロジェクト:* 名前」にする
+func projectDetail(t Task) string {
+	if len(t.Projects) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("*%s:* %s", tr("task.project"), strings.Join(t.Projects, ", "))
+}
+
+// projectSections はタスクを最初のプロジェクトごとのセクションに分ける
+// セクションはプロジェクトの名前順に並べ、プロジェクトの無いタスクは最後のセクションにまとめる
+func projectSections(tasks []Task) []templateSection {
+	grouped := map[string][]Task{}
+	for _, t := range tasks {
+		name := ""
+		if len(t.Projects) > 0 {
+			name = t.Projects[0]
+		}
+		grouped[name] = append(grouped[name], t)
+	}
+	var sections []templateSection
+	for _, name := range slices.SortedFunc(maps.Keys(grouped), func(a, b string) int {
+		// プロジェクトの無いタスクを最後にする
+		if (a == "") != (b == "") {
+			return cmp.Compare(b, a)
+		}
+		return cmp.Compare(a, b)
+	}) {
+		group := grouped[name]
+		sortTasks(group)
+		title := "📁 " + name
+		if name == "" {
+			title = tr("section.no_project")
+		}
+		sections = append(sections, templateSection{Name: projectSectionPrefix + name, Title: title, Tasks: group})
+	}
+	return sections
+}
+
+// projectTaskGroups はプロジェクトごとのセクションを Slack 以外の送り先に渡すセクションにする
+func projectTaskGroups(tasks []Task) []TaskGroup {
+	var groups []TaskGroup
+	for _, s := range projectSections(tasks) {
+		groups = append(groups, TaskGroup{Name: s.Name, Title: s.Title, Tasks: s.Tasks, Parts: []templateSectionPart{{Tasks: s.Tasks}}})
+	}
+	return groups
+}
+
+// parseGroupBy は --group-by の値を検証する
+func parseGroupBy(value string) (string, error) {
+	switch value {
+	case "", groupByUrgency:
+		return groupByUrgency, nil
+	case groupByProject:
+		if projectProp == "" {
+			return "", fmt.Errorf("%q requires properties.project in the config file", value)
+		}
+		return groupByProject, nil
+	}
+	return "", fmt.Errorf("unknown value %q (expected %s or %s)", value, groupByUrgency, groupByProject)
+}
+
+func init() {
+	rootCmd.Flags().String("group-by", groupByUrgency, "Group tasks into sections by urgency (due date) or by project (requires properties.project)")
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jomei/notionapi"
+
+	"rainierrr/notion-notifyer/pkg/notion"
+)
+
+// 詳細に表示する追加のプロパティ (properties.extra)
+var extraProps []string
+
+// propertyRenderer はプロパティの値を Slack の mrkdwn の 1 行にする。値が空なら空文字列を返す
+type propertyRenderer func(value notionapi.Property) string
+
+// プロパティの種類ごとの表示 (種類を追加したファイルの init から registerPropertyRenderer で登録する)
+var propertyRenderers = map[notionapi.PropertyType]propertyRenderer{}
+
+// registerPropertyRenderer はプロパティの種類の表示を登録する
+func registerPropertyRenderer(typ notionapi.PropertyType, render propertyRenderer) {
+	if _, ok := propertyRenderers[typ]; ok {
+		panic(fmt.Sprintf("property renderer for %q is already registered", typ))
+	}
+	propertyRenderers[typ] = render
+}
+
+// renderProperty はプロパティを種類に合わせて表示する。表示のしかたが登録されていない種類なら false を返す
+func renderProperty(value notionapi.Property) (string, bool) {
+	if value == nil {
+		return "", false
+	}
+	render, ok := propertyRenderers[value.GetType()]
+	if !ok {
+		return "", false
+	}
+	return render(value), true
+}
+
+// extraDetails は追加のプロパティを詳細の「*名前:* 値」にする (値が空のものと表示できない種類は除く)
+func extraDetails(task Task) []string {
+	var details []string
+	for _, field := range task.Extra {
+		if text, ok := renderProperty(field.Value); ok && text != "" {
+			details = append(details, fmt.Sprintf("*%s:* %s", field.Name, text))
+		}
+	}
+	return details
+}
+
+// taskProperty はタスクの追加のプロパティを名前で探して表示する (テンプレートの property)
+func taskProperty(task Task, name string) string {
+	for _, field := range task.Extra {
+		if field.Name == name {
+			text, _ := renderProperty(field.Value)
+			return text
+		}
+	}
+	return ""
+}
+
+func renderDateProperty(value notionapi.Property) string {
+	p, ok := value.(*notionapi.DateProperty)
+	if !ok || p.Date == nil || p.Date.Start == nil {
+		return ""
+	}
+	text := timeFormat(time.Time(*p.Date.Start))
+	if p.Date.End != nil {
+		text += " ~ " + timeFormat(time.Time(*p.Date.End))
+	}
+	return text
+}
+
+func renderSelectProperty(value notionapi.Property) string {
+	if p, ok := value.(*notionapi.SelectProperty); ok {
+		return p.Select.Name
+	}
+	return ""
+}
+
+func renderPeopleProperty(value notionapi.Property) string {
+	p, ok := value.(*notionapi.PeopleProperty)
+	if !ok {
+		return ""
+	}
+	var names []string
+	for _, user := range p.People {
+		names = append(names, user.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// 数値は空でも 0 で返るため、0 も表示する
+func renderNumberProperty(value notionapi.Property) string {
+	if p, ok := value.(*notionapi.NumberProperty); ok {
+		return strconv.FormatFloat(p.Number, 'f', -1, 64)
+	}
+	return ""
+}
+
+func renderURLProperty(value notionapi.Property) string {
+	if p, ok := value.(*notionapi.URLProperty); ok && p.URL != "" {
+		return urlLink(p.URL)
+	}
+	return ""
+}
+
+func renderRichTextProperty(value notionapi.Property) string {
+	if p, ok := value.(*notionapi.RichTextProperty); ok {
+		return richTextMrkdwn(p.RichText, 0)
+	}
+	return ""
+}
+
+// 数式とロールアップは計算結果の種類 (文字列・数値・真偽値・日付) の表示を使う
+func renderComputedProperty(value notionapi.Property) string {
+	unwrapped := notion.Unwrap(value)
+	if p, ok := unwrapped.(*notionapi.CheckboxProperty); ok {
+		if p.Checkbox {
+			return "✓"
+		}
+		return "✗"
+	}
+	if unwrapped.GetType() == value.GetType() {
+		return "" // 空の日付や要素の無い配列
+	}
+	text, _ := renderProperty(unwrapped)
+	return text
+}
+
+// ファイルはファイル名のリンクにする (Notion にアップロードしたファイルの URL は 1 時間で切れる)
+func renderFilesProperty(value notionapi.Property) string {
+	var links []string
+	for _, f := range notion.ParseFiles(value) {
+		links = append(links, fileLink(f))
+	}
+	return strings.Join(links, ", ")
+}
+
+func init() {
+	registerPropertyRenderer(notionapi.PropertyTypeDate, renderDateProperty)
+	registerPropertyRenderer(notionapi.PropertyTypeSelect, renderSelectProperty)
+	registerPropertyRenderer(notionapi.PropertyTypePeople, renderPeopleProperty)
+	registerPropertyRenderer(notionapi.PropertyTypeNumber, renderNumberProperty)
+	registerPropertyRenderer(notionapi.PropertyTypeURL, renderURLProperty)
+	registerPropertyRenderer(notionapi.PropertyTypeFiles, renderFilesProperty)
+	registerPropertyRenderer(notionapi.PropertyTypeRichText, renderRichTextProperty)
+	registerPropertyRenderer(notionapi.PropertyTypeFormula, renderComputedProperty)
+	registerPropertyRenderer(notionapi.PropertyTypeRollup, renderComputedProperty)
+}
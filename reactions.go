@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jomei/notionapi"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// リアクションの絵文字名 → 変更後のスケジュールステータス
+var defaultReactionStatuses = map[string]string{
+	"construction":     "Doing",
+	"white_check_mark": doneStatus,
+	"wastebasket":      "Cancelled",
+}
+
+// postTaskThread はダイジェストのスレッドにタスクごとのメッセージを投稿し、
+// リアクションからタスクを特定できるよう状態ファイルに記録する
+func postTaskThread(ctx context.Context, client *slack.Client, dest slackDestination, threadTS string, tasks []Task, store *stateStore, now time.Time) error {
+	posted := map[string]*taskMessage{}
+	for _, task := range tasks {
+		text := fmt.Sprintf("<%s|%s>\n%s", task.URL, taskTitle(task), tr("reaction.hint", snoozeDays))
+		_, ts, err := client.PostMessageContext(ctx, dest.ChannelID,
+			slack.MsgOptionText(text, false),
+			slack.MsgOptionTS(threadTS),
+			slack.MsgOptionDisableLinkUnfurl(),
+		)
+		if err != nil {
+			return newSlackError("failed to post task thread message", err)
+		}
+		posted[taskMessageKey(dest.ChannelID, ts)] = &taskMessage{
+			TeamID:   dest.TeamID,
+			PageID:   string(task.ID),
+			Title:    task.Title,
+			URL:      task.URL,
+			PostedAt: now,
+		}
+	}
+
+	return store.Update(func(st *state) error {
+		if st.TaskMessages == nil {
+			st.TaskMessages = map[string]*taskMessage{}
+		}
+		for key, msg := range st.TaskMessages {
+			if now.Sub(msg.PostedAt) > taskMessageRetention {
+				delete(st.TaskMessages, key)
+			}
+		}
+		for key, msg := range posted {
+			st.TaskMessages[key] = msg
+		}
+		return nil
+	})
+}
+
+// eventsServer は Slack Events API のリクエストを受け取る
+// タスクごとのメッセージに付いたリアクションを Notion のステータスに反映し、ワークフローステップを実行する
+type eventsServer struct {
+	signingSecret string
+	env           *interactionEnv
+	statuses      map[string]string
+}
+
+func (s *eventsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	verifier, err := slack.NewSecretsVerifier(r.Header, s.signingSecret)
+	if err != nil {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	if _, err := verifier.Write(body); err != nil || verifier.Ensure() != nil {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	event, err := slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
+	if err != nil {
+		http.Error(w, "invalid event", http.StatusBadRequest)
+		return
+	}
+
+	if event.Type == slackevents.URLVerification {
+		var challenge slackevents.ChallengeResponse
+		if err := json.Unmarshal(body, &challenge); err != nil {
+			http.Error(w, "invalid challenge", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, challenge.Challenge)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	s.dispatch(event)
+}
+
+// dispatch はイベントを非同期で処理する (HTTP と Socket Mode で共通)
+func (s *eventsServer) dispatch(event slackevents.EventsAPIEvent) {
+	switch inner := event.InnerEvent.Data.(type) {
+	case *slackevents.ReactionAddedEvent:
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), interactionTimeout)
+			defer cancel()
+			if err := s.handleReaction(ctx, event.TeamID, inner); err != nil {
+				log.Printf("Reaction error: %v", err)
+			}
+		}()
+	case *slackevents.WorkflowStepExecuteEvent:
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), interactionTimeout)
+			defer cancel()
+			if err := executeWorkflowStep(ctx, s.env, event.TeamID, inner); err != nil {
+				log.Printf("Workflow step error: %v", err)
+			}
+		}()
+	}
+}
+
+// handleReaction はリアクションに対応するステータスにタスクを変更し、メッセージを更新する
+func (s *eventsServer) handleReaction(ctx context.Context, teamID string, reaction *slackevents.ReactionAddedEvent) error {
+	status, ok := s.statuses[reaction.Reaction]
+	snooze := !ok && reaction.Reaction == snoozeReaction
+	if (!ok && !snooze) || reaction.Item.Type != "message" {
+		return nil
+	}
+
+	st, err := s.env.store.Load()
+	if err != nil {
+		return err
+	}
+	msg, ok := st.TaskMessages[taskMessageKey(reaction.Item.Channel, reaction.Item.Timestamp)]
+	if !ok {
+		return nil
+	}
+	if snooze {
+		return s.handleSnoozeReaction(ctx, teamID, reaction, msg)
+	}
+
+	notionToken, _, err := notionSourceFromEnv(s.env.store)
+	if err != nil {
+		return err
+	}
+	notionClient := notionapi.NewClient(notionapi.Token(notionToken))
+	if err := setTaskStatus(ctx, notionClient, notionapi.ObjectID(msg.PageID), status); err != nil {
+		return err
+	}
+	log.Printf("Task %s set to %s by reaction :%s: from %s", msg.Title, status, reaction.Reaction, reaction.User)
+
+	slackClient, err := s.env.slackClient(ctx, teamID)
+	if err != nil {
+		return err
+	}
+	text := fmt.Sprintf("<%s|%s>\n%s", msg.URL, msg.Title, tr("reaction.status", status, reaction.User))
+	_, _, _, err = slackClient.UpdateMessageContext(ctx, reaction.Item.Channel, reaction.Item.Timestamp, slack.MsgOptionText(text, false))
+	return err
+}
+
+// parseReactionStatuses は "construction=Doing" 形式の指定を読み込む
+func parseReactionStatuses(entries map[string]string) map[string]string {
+	statuses := map[string]string{}
+	for emoji, status := range entries {
+		statuses[strings.Trim(emoji, ":")] = status
+	}
+	return statuses
+}
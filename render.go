@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jomei/notionapi"
+	"github.com/mattn/go-runewidth"
+	"github.com/slack-go/slack"
+	"github.com/spf13/cobra"
+
+	"rainierrr/notion-notifyer/pkg/task"
+)
+
+// 組み込みのレイアウト (Block Kit) を表すテンプレート名
+const builtinTemplateName = "builtin"
+
+// 横並びの比較で 1 つのテンプレートに使う表示幅
+const sideBySideWidth = 60
+
+// loadFixtureTasks はペイロード形式 (payload-schema) の JSON からタスクと生成日時を読み込む
+func loadFixtureTasks(path string) ([]Task, time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	var payload taskPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to parse fixtures %s: %w", path, err)
+	}
+	if payload.SchemaVersion != payloadSchemaVersion {
+		return nil, time.Time{}, fmt.Errorf("fixtures %s: unsupported schema_version %d", path, payload.SchemaVersion)
+	}
+	var tasks []Task
+	for _, item := range payload.Tasks {
+		task := Task{Task: task.Task{
+			ID:             notionapi.ObjectID(item.ID),
+			Title:          item.Title,
+			URL:            item.URL,
+			Priority:       item.Priority,
+			Type:           item.Type,
+			ScheduleStatus: item.Status,
+			Workload:       item.Workload,
+			Memo:           item.Memo,
+		}}
+		if item.CreatedAt != nil {
+			task.CreatedAt = *item.CreatedAt
+		}
+		if item.Due != nil {
+			due := notionapi.Date(*item.Due)
+			task.DueStart = &due
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, payload.GeneratedAt, nil
+}
+
+// renderWithTemplate はタスクをテンプレートで描画し、Block Kit の JSON と横並びの比較に使うプレビューを返す
+// builtin なら組み込みのテンプレートで描画する
+func renderWithTemplate(path string, tasks []Task, opts renderOptions) (out, preview string, err error) {
+	opts.Template, err = loadMessageTemplate(path)
+	if err != nil {
+		return "", "", err
+	}
+	blocks, err := buildSlackBlocks(tasks, opts)
+	if err != nil {
+		return "", "", err
+	}
+	data, err := json.MarshalIndent(map[string]slack.Blocks{"blocks": {BlockSet: blocks}}, "", "  ")
+	if err != nil {
+		return "", "", err
+	}
+	return string(data) + "\n", previewBlocks(blocks), nil
+}
+
+// renderOutputName はテンプレートの出力ファイル名を返す (同じ名前のテンプレートがあっても重ならないよう番号を付ける)
+func renderOutputName(i int, path string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return fmt.Sprintf("%d-%s.json", i+1, base)
+}
+
+// sideBySide は複数の出力を列に並べる。長い行は列の幅で切る
+func sideBySide(names, outputs []string) string {
+	columns := make([][]string, len(outputs))
+	rows := 0
+	for i, out := range outputs {
+		columns[i] = strings.Split(strings.TrimRight(out, "\n"), "\n")
+		rows = max(rows, len(columns[i]))
+	}
+	cell := func(s string) string {
+		s = strings.ReplaceAll(s, "\t", "    ")
+		return runewidth.FillRight(runewidth.Truncate(s, sideBySideWidth, "…"), sideBySideWidth)
+	}
+
+	var b strings.Builder
+	line := func(cells []string) {
+		b.WriteString(strings.TrimRight(strings.Join(cells, " │ "), " "))
+		b.WriteString("\n")
+	}
+	header := make([]string, len(names))
+	rule := make([]string, len(names))
+	for i, name := range names {
+		header[i] = cell(name)
+		rule[i] = strings.Repeat("─", sideBySideWidth)
+	}
+	line(header)
+	line(rule)
+	for r := 0; r < rows; r++ {
+		cells := make([]string, len(columns))
+		for i, col := range columns {
+			text := ""
+			if r < len(col) {
+				text = col[r]
+			}
+			cells[i] = cell(text)
+		}
+		line(cells)
+	}
+	return b.String()
+}
+
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Render the same fixture tasks with several templates and write the results side by side for review.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		templates, _ := cmd.Flags().GetStringArray("template")
+		fixtures, _ := cmd.Flags().GetString("fixtures")
+		outDir, _ := cmd.Flags().GetString("out")
+		if len(templates) == 0 {
+			return fmt.Errorf("at least one --template is required (use %q for the built-in layout)", builtinTemplateName)
+		}
+
+		tasks, generatedAt, err := loadFixtureTasks(fixtures)
+		if err != nil {
+			return err
+		}
+		// 既定では fixtures の生成日時を基準にし、期限のグループ分けが実行日で変わらないようにする
+		now := generatedAt
+		if cmd.Flags().Changed("now") {
+			clock, err := clockFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+			now = clock.Now()
+		}
+		sectionNames, _ := cmd.Flags().GetStringSlice("sections")
+		sections, err := parseSections(sectionNames)
+		if err != nil {
+			return fmt.Errorf("invalid --sections: %w", err)
+		}
+		withinHours, _ := cmd.Flags().GetInt("within-hours")
+		opts := renderOptions{Now: now, Sections: sections, WithinHours: withinHours}
+
+		if err := os.MkdirAll(outDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		var previews []string
+		for i, path := range templates {
+			out, preview, err := renderWithTemplate(path, tasks, opts)
+			if err != nil {
+				return err
+			}
+			file := filepath.Join(outDir, renderOutputName(i, path))
+			if err := os.WriteFile(file, []byte(out), 0o644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", file, err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s -> %s (%d lines)\n", path, file, strings.Count(out, "\n"))
+			previews = append(previews, preview)
+		}
+
+		file := filepath.Join(outDir, "side-by-side.txt")
+		if err := os.WriteFile(file, []byte(sideBySide(templates, previews)), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file, err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "side by side -> %s\n", file)
+		return nil
+	},
+}
+
+func init() {
+	renderCmd.Flags().StringArray("template", nil, fmt.Sprintf("Template file to render (repeatable; %q renders the built-in template)", builtinTemplateName))
+	renderCmd.Flags().String("fixtures", "", "Tasks in the payload-schema JSON format (e.g. a webhook payload)")
+	renderCmd.Flags().String("out", "render-out", "Directory for the rendered files")
+	renderCmd.Flags().StringSlice("sections", nil, "Sections to show, in order (pinned, hours and the buckets from the config file)")
+	renderCmd.Flags().Int("within-hours", 0, "Show timed tasks due within this many hours in their own section")
+	_ = renderCmd.MarkFlagRequired("fixtures")
+	rootCmd.AddCommand(renderCmd)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jomei/notionapi"
+	"github.com/slack-go/slack"
+	"github.com/spf13/cobra"
+)
+
+// 変更の各セクションに載せる件数の上限
+const reportSectionLimit = 20
+
+// buildChangesBlocks は期間中の変更をまとめたメッセージを作る
+// forecast があれば今週が期限のタスクの見込みを 1 行添える
+func buildChangesBlocks(changes backlogChanges, forecast *completionForecast, since, now time.Time) []slack.Block {
+	blocks := []slack.Block{
+		slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, tr("report.header"), true, false)),
+		slack.NewContextBlock("", slack.NewTextBlockObject(slack.PlainTextType,
+			fmt.Sprintf("%s 〜 %s", since.Format("1/2"), now.Format("1/2")), false, false)),
+	}
+	if forecast != nil {
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, forecast.text(), false, false), nil, nil))
+	}
+	if changes.empty() {
+		return append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, tr("report.empty"), false, false), nil, nil))
+	}
+
+	section := func(title string, items []taskChange) {
+		if len(items) == 0 {
+			return
+		}
+		lines := []string{fmt.Sprintf("*%s (%d)*", title, len(items))}
+		for i, c := range items {
+			if i == reportSectionLimit {
+				lines = append(lines, tr("more", len(items)-reportSectionLimit))
+				break
+			}
+			line := "• " + c.Title
+			if c.Detail != "" {
+				line += " — " + c.Detail
+			}
+			lines = append(lines, line)
+		}
+		blocks = append(blocks, slack.NewDividerBlock(),
+			slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, strings.Join(lines, "\n"), false, false), nil, nil))
+	}
+	section(tr("report.new"), changes.New)
+	section(tr("report.due_moved"), changes.DueMoved)
+	section(tr("report.priority_raised"), changes.PriorityRaised)
+	section(tr("report.completed"), changes.Completed)
+	return blocks
+}
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Post a changelog of the backlog (new, rescheduled, reprioritized and completed tasks) built from the digest history.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		days, _ := cmd.Flags().GetInt("days")
+		if days < 1 {
+			return fmt.Errorf("--days must be at least 1")
+		}
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		clock, err := clockFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		now := clock.Now()
+		since := startOfDay(now).AddDate(0, 0, 1-days)
+
+		historyStore := historyStoreFromEnv()
+		if historyStore == nil {
+			return fmt.Errorf("%s must be set", historyFileEnv)
+		}
+		h, err := historyStore.Load()
+		if err != nil {
+			return err
+		}
+		changes, gone := h.backlogChanges(since, now)
+
+		store := stateStoreFromEnv()
+		if len(gone) > 0 {
+			notionToken, _, err := notionSourceFromEnv(store)
+			if err != nil {
+				return err
+			}
+			changes.Completed = findCompletedTasks(cmd.Context(), notionapi.NewClient(notionapi.Token(notionToken)), gone)
+		}
+		forecast := h.forecastCompletion(len(changes.Completed), days, now)
+		blocks := buildChangesBlocks(changes, forecast, since, now)
+
+		var heatmap *agingHeatmap
+		var heatmapPNG []byte
+		if attach, _ := cmd.Flags().GetBool("heatmap"); attach {
+			if heatmap, err = h.agingHeatmap(now); err != nil {
+				return err
+			}
+			if heatmapPNG, err = heatmap.encode("heatmap.png"); err != nil {
+				return err
+			}
+		}
+
+		if dryRun {
+			if heatmap != nil {
+				log.Printf("Would attach heatmap.png (%d bytes): %s", len(heatmapPNG), heatmap.legend())
+			}
+			return printDryRun(os.Stdout, slackDestination{Name: "report"}, blocks)
+		}
+		destinations, err := loadSlackDestinations(clock, store, os.Getenv(slackChannelEnv))
+		if err != nil {
+			return fmt.Errorf("slack destination error: %w", err)
+		}
+		if len(destinations) == 0 {
+			return fmt.Errorf("no Slack destination: set %s or install the app with the install command", slackChannelEnv)
+		}
+		failed := 0
+		for _, dest := range destinations {
+			client, err := dest.Tokens.Client(cmd.Context())
+			if err == nil {
+				err = resolveChannel(cmd.Context(), client, store, now, &dest)
+			}
+			var ts string
+			if err == nil {
+				ts, err = postBlocks(cmd.Context(), client, dest.ChannelID, blocks)
+			}
+			if err != nil {
+				log.Printf("Slack message send error (%s): %v", dest.Name, err)
+				failed++
+				continue
+			}
+			log.Printf("Report sent to channel %s (%s)", dest.ChannelID, dest.Name)
+			// ヒートマップはレポートのスレッドに添える (添えられなくてもレポートは送れている)
+			if heatmap != nil {
+				_, err := client.UploadFileV2Context(cmd.Context(), slack.UploadFileV2Parameters{
+					Reader:          bytes.NewReader(heatmapPNG),
+					FileSize:        len(heatmapPNG),
+					Filename:        "heatmap.png",
+					Title:           tr("heatmap.title"),
+					InitialComment:  heatmap.legend(),
+					Channel:         dest.ChannelID,
+					ThreadTimestamp: ts,
+				})
+				if err != nil {
+					log.Printf("Warning: Unable to attach the heatmap (%s): %v", dest.Name, err)
+				}
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("failed to send the report to %d of %d destinations", failed, len(destinations))
+		}
+		return nil
+	},
+}
+
+func init() {
+	reportCmd.Flags().Int("days", 7, "Number of days (including today) the report covers")
+	reportCmd.Flags().Bool("heatmap", false, "Attach a PNG heatmap of overdue days by priority to the report thread (requires the files:write scope)")
+	reportCmd.Flags().Bool("dry-run", false, "Print the Block Kit JSON and a text preview instead of posting")
+	rootCmd.AddCommand(reportCmd)
+}
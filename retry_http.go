@@ -0,0 +1,153 @@
+package main
+
+import (
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// 再試行の設定 (--retry-max-attempts、--retry-base-delay)
+var (
+	retryMaxAttempts = 4
+	retryBaseDelay   = time.Second
+)
+
+// 再試行を待つ時間の上限 (Retry-After がこれより長くても、ここで打ち切る)
+const retryMaxDelay = time.Minute
+
+// 再試行するホスト
+var retryHosts = map[string]bool{
+	"api.notion.com": true,
+	"slack.com":      true,
+}
+
+// retryTransport は Notion と Slack の 429 と 5xx を指数バックオフで再試行する http.RoundTripper
+// Retry-After があればその時間を待ち、無ければ基準の時間を倍にしながらジッターを加えて待つ
+// Notion の 502 と 503 はメンテナンスとして長い間隔で待ち、--notion-outage-timeout を過ぎたら notionOutageError を返す
+type retryTransport struct {
+	next http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// ボディを作り直せないリクエストは再試行できない
+	if !retryHosts[req.URL.Host] || (req.Body != nil && req.GetBody == nil) {
+		return t.next.RoundTrip(req)
+	}
+
+	// 毎回ボディを作り直して送り、元のリクエストのボディは読まずに残す
+	// (notionapi は 429 を受け取ると同じリクエストを送り直すため)
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+	var outageStart time.Time // Notion のメンテナンスの応答を最初に受け取った時刻
+	outages := 0
+	for attempt := 1; ; {
+		attemptReq := req
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+		resp, err := t.next.RoundTrip(attemptReq)
+
+		var delay time.Duration
+		if isNotionMaintenance(req, resp, err) {
+			// メンテナンスは再試行の回数に数えず、長い間隔で --notion-outage-timeout まで待つ
+			if outageStart.IsZero() {
+				outageStart = time.Now()
+			}
+			outages++
+			delay = notionOutageDelay(outages)
+			if after, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				delay = max(delay, after)
+			}
+			resp.Body.Close()
+			if time.Since(outageStart)+delay > notionOutageTimeout {
+				return nil, &notionOutageError{Status: resp.StatusCode, Since: outageStart}
+			}
+			log.Printf("Notion returned %s (maintenance or outage); retrying %s in %s", resp.Status, req.URL.Path, delay.Round(time.Second))
+		} else {
+			if attempt >= retryMaxAttempts || !shouldRetry(resp, err) {
+				return resp, err
+			}
+			delay = backoffDelay(attempt)
+			reason := ""
+			if err != nil {
+				reason = err.Error()
+			} else {
+				reason = resp.Status
+				if after, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+					delay = after
+				}
+				resp.Body.Close()
+			}
+			delay = min(delay, retryMaxDelay)
+			attempt++
+			log.Printf("Retrying %s %s in %s (attempt %d of %d): %s", req.Method, req.URL.Host+req.URL.Path, delay.Round(time.Millisecond), attempt, retryMaxAttempts, reason)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// shouldRetry はレート制限、サーバーの一時的なエラー、接続のエラーを再試行の対象にする
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoffDelay は attempt 回目の失敗の後に待つ時間を返す (基準の時間 × 2^(attempt-1) の半分から全体までのランダムな時間)
+func backoffDelay(attempt int) time.Duration {
+	d := retryBaseDelay << (attempt - 1)
+	if d <= 0 || d > retryMaxDelay {
+		d = retryMaxDelay
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// parseRetryAfter は Retry-After の秒数または日時を待つ時間にする
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// installRetryTransport は既定の Transport を差し替え、Notion と Slack の呼び出しを再試行するようにする
+func installRetryTransport() {
+	if retryMaxAttempts <= 1 {
+		return
+	}
+	http.DefaultTransport = &retryTransport{next: http.DefaultTransport}
+}
+
+func init() {
+	rootCmd.PersistentFlags().IntVar(&retryMaxAttempts, "retry-max-attempts", retryMaxAttempts, "Maximum attempts for Notion and Slack API calls failing with 429, 5xx or a network error (1 disables retries)")
+	rootCmd.PersistentFlags().DurationVar(&retryBaseDelay, "retry-base-delay", retryBaseDelay, "Initial backoff before retrying a Notion or Slack API call; doubles on each attempt, with jitter, unless Retry-After is given")
+	cobra.OnInitialize(installRetryTransport)
+}
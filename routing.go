@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// taskRoute は優先度や種類が一致するタスクを別のチャンネルに送るルート (設定ファイルの routes)
+// priorities と types を両方指定したときは両方に一致するタスクだけを送る
+type taskRoute struct {
+	Priorities []string `yaml:"priorities"`
+	Types      []string `yaml:"types"`
+	Channel    string   `yaml:"channel"` // チャンネル ID か #チャンネル名
+}
+
+// 設定ファイルのルーティング表 (上から順に調べ、最初に一致したルートに送る)
+var configuredRoutes []taskRoute
+
+func (r taskRoute) matches(priority, taskType string) bool {
+	return (len(r.Priorities) == 0 || slices.Contains(r.Priorities, priority)) &&
+		(len(r.Types) == 0 || slices.Contains(r.Types, taskType))
+}
+
+// validateRoutes はチャンネルと条件の無いルートを書き間違いとしてエラーにする
+func validateRoutes(routes []taskRoute) error {
+	for i, r := range routes {
+		switch {
+		case r.Channel == "":
+			return fmt.Errorf("routes[%d]: channel is required", i)
+		case len(r.Priorities) == 0 && len(r.Types) == 0:
+			return fmt.Errorf("routes[%d]: priorities or types is required", i)
+		}
+	}
+	return nil
+}
+
+// routeIndex は最初に一致したルートの番号を返す。どのルートにも一致しなければ -1 を返す
+func routeIndex(routes []taskRoute, priority, taskType string) int {
+	return slices.IndexFunc(routes, func(r taskRoute) bool { return r.matches(priority, taskType) })
+}
+
+// taskRouting は投稿先にルーティング表のどのタスクを載せるか
+type taskRouting struct {
+	Routes  []taskRoute
+	Channel string // この投稿先のルートのチャンネル (空ならどのルートにも一致しないタスクを載せる既定の投稿先)
+}
+
+// allows は nil (ルーティングしない投稿先) ならすべてのタスクを載せる
+// 同じチャンネルへのルートが複数あれば、そのどれかに最初に一致したタスクを載せる
+func (r *taskRouting) allows(priority, taskType string) bool {
+	if r == nil {
+		return true
+	}
+	i := routeIndex(r.Routes, priority, taskType)
+	if i < 0 {
+		return r.Channel == ""
+	}
+	return r.Routes[i].Channel == r.Channel
+}
+
+// allows はタスクをこの投稿先に載せてよいかを種類の絞り込みとルーティングで判定する
+func (d slackDestination) allows(priority, taskType string) bool {
+	return d.Types.allowsType(taskType) && d.Routing.allows(priority, taskType)
+}
+
+// filterTasks はこの投稿先に載せるタスクだけを返す
+func (d slackDestination) filterTasks(tasks []Task) []Task {
+	if d.Routing == nil {
+		return d.Types.apply(tasks)
+	}
+	var filtered []Task
+	for _, task := range tasks {
+		if d.allows(task.Priority, task.Type) {
+			filtered = append(filtered, task)
+		}
+	}
+	return filtered
+}
+
+// routeDestinations は環境変数の投稿先にルーティング表を適用する
+// 環境変数の投稿先はどのルートにも一致しないタスクだけを載せ、ワークスペースごとにルートの投稿先を加える
+// ルートの投稿先は同じワークスペースの環境変数の投稿先のトークンと種類の絞り込みを使う
+func routeDestinations(destinations []slackDestination, routes []taskRoute) []slackDestination {
+	if len(routes) == 0 {
+		return destinations
+	}
+	var routed []slackDestination
+	var scopes []string
+	for _, dest := range destinations {
+		if !dest.FromEnv {
+			routed = append(routed, dest)
+			continue
+		}
+		dest.Routing = &taskRouting{Routes: routes}
+		routed = append(routed, dest)
+		if slices.Contains(scopes, dest.teamScope()) {
+			continue
+		}
+		scopes = append(scopes, dest.teamScope())
+		var channels []string
+		for _, r := range routes {
+			if slices.Contains(channels, r.Channel) {
+				continue
+			}
+			channels = append(channels, r.Channel)
+			routeDest := dest
+			routeDest.Name = dest.Name + ":" + r.Channel
+			routeDest.ChannelID = r.Channel
+			routeDest.Routing = &taskRouting{Routes: routes, Channel: r.Channel}
+			routed = append(routed, routeDest)
+		}
+	}
+	return routed
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jomei/notionapi"
+
+	"rainierrr/notion-notifyer/pkg/task"
+)
+
+// routingTasks は優先度と種類の組み合わせを 1 つずつ持つタスク (タイトルは "優先度-種類"、優先度が無ければ "NoPriority-種類")
+func routingTasks(now time.Time) []Task {
+	due := notionapi.Date(now)
+	var tasks []Task
+	for _, priority := range []string{"High", "Mid", "Low", ""} {
+		for _, taskType := range []string{"Work", "Personal", "Study"} {
+			title := priority + "-" + taskType
+			if priority == "" {
+				title = "NoPriority-" + taskType
+			}
+			tasks = append(tasks, Task{Task: task.Task{ID: notionapi.ObjectID("id-" + title), Title: title, Priority: priority, Type: taskType, DueStart: &due}})
+		}
+	}
+	return tasks
+}
+
+func taskTitles(tasks []Task) []string {
+	titles := make([]string, len(tasks))
+	for i, t := range tasks {
+		titles[i] = t.Title
+	}
+	slices.Sort(titles)
+	return titles
+}
+
+func TestRouteDestinationsFilterTasks(t *testing.T) {
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	env := slackDestination{Name: "env", ChannelID: "CDEFAULT", FromEnv: true}
+	tests := []struct {
+		name   string
+		dest   slackDestination
+		routes []taskRoute
+		want   map[string][]string // チャンネル → 載せるタスク
+	}{
+		{
+			name:   "priority route",
+			dest:   env,
+			routes: []taskRoute{{Priorities: []string{"High"}, Channel: "CURGENT"}},
+			want: map[string][]string{
+				"CURGENT":  {"High-Personal", "High-Study", "High-Work"},
+				"CDEFAULT": {"Low-Personal", "Low-Study", "Low-Work", "Mid-Personal", "Mid-Study", "Mid-Work", "NoPriority-Personal", "NoPriority-Study", "NoPriority-Work"},
+			},
+		},
+		{
+			name: "first matching route wins",
+			dest: env,
+			routes: []taskRoute{
+				{Priorities: []string{"High"}, Channel: "CURGENT"},
+				{Types: []string{"Work"}, Channel: "CWORK"},
+			},
+			want: map[string][]string{
+				"CURGENT":  {"High-Personal", "High-Study", "High-Work"},
+				"CWORK":    {"Low-Work", "Mid-Work", "NoPriority-Work"},
+				"CDEFAULT": {"Low-Personal", "Low-Study", "Mid-Personal", "Mid-Study", "NoPriority-Personal", "NoPriority-Study"},
+			},
+		},
+		{
+			name: "both conditions must match",
+			dest: env,
+			routes: []taskRoute{
+				{Priorities: []string{"High", "Mid"}, Types: []string{"Personal"}, Channel: "CHOME"},
+			},
+			want: map[string][]string{
+				"CHOME":    {"High-Personal", "Mid-Personal"},
+				"CDEFAULT": {"High-Study", "High-Work", "Low-Personal", "Low-Study", "Low-Work", "Mid-Study", "Mid-Work", "NoPriority-Personal", "NoPriority-Study", "NoPriority-Work"},
+			},
+		},
+		{
+			name: "routes to the same channel are merged",
+			dest: env,
+			routes: []taskRoute{
+				{Priorities: []string{"High"}, Channel: "CURGENT"},
+				{Types: []string{"Study"}, Channel: "CSTUDY"},
+				{Priorities: []string{"Mid"}, Channel: "CURGENT"},
+			},
+			want: map[string][]string{
+				"CURGENT":  {"High-Personal", "High-Study", "High-Work", "Mid-Personal", "Mid-Work"},
+				"CSTUDY":   {"Low-Study", "Mid-Study", "NoPriority-Study"},
+				"CDEFAULT": {"Low-Personal", "Low-Work", "NoPriority-Personal", "NoPriority-Work"},
+			},
+		},
+		{
+			name: "type exclusion of the source applies to every route",
+			dest: slackDestination{Name: "env", ChannelID: "CDEFAULT", FromEnv: true, Types: taskTypeFilter{Exclude: []string{"Personal"}}},
+			routes: []taskRoute{
+				{Priorities: []string{"High"}, Channel: "CURGENT"},
+			},
+			want: map[string][]string{
+				"CURGENT":  {"High-Study", "High-Work"},
+				"CDEFAULT": {"Low-Study", "Low-Work", "Mid-Study", "Mid-Work", "NoPriority-Study", "NoPriority-Work"},
+			},
+		},
+		{
+			name: "type inclusion of the source applies to every route",
+			dest: slackDestination{Name: "env", ChannelID: "CDEFAULT", FromEnv: true, Types: taskTypeFilter{Include: []string{"Work"}}},
+			routes: []taskRoute{
+				{Types: []string{"Personal"}, Channel: "CHOME"},
+				{Priorities: []string{"Low"}, Channel: "CLOW"},
+			},
+			want: map[string][]string{
+				"CHOME":    nil,
+				"CLOW":     {"Low-Work"},
+				"CDEFAULT": {"High-Work", "Mid-Work", "NoPriority-Work"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks := routingTasks(now)
+			destinations := routeDestinations([]slackDestination{tt.dest}, tt.routes)
+			if len(destinations) != len(tt.want) {
+				t.Fatalf("got %d destinations, want %d", len(destinations), len(tt.want))
+			}
+			for _, dest := range destinations {
+				want, ok := tt.want[dest.ChannelID]
+				if !ok {
+					t.Fatalf("unexpected destination %s", dest.ChannelID)
+				}
+				if got := taskTitles(dest.filterTasks(tasks)); !slices.Equal(got, want) {
+					t.Errorf("%s got %v, want %v", dest.ChannelID, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestRoutedPayloadsDoNotLeak は各投稿先のメッセージ (カレンダーを含む) に、その投稿先に載せないタスクが
+// 入らないことと、1 つのタスクが 2 つのチャンネルに載らないことを確かめる
+func TestRoutedPayloadsDoNotLeak(t *testing.T) {
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	tasks := routingTasks(now)
+	routes := []taskRoute{
+		{Priorities: []string{"High"}, Channel: "CURGENT"},
+		{Types: []string{"Personal"}, Channel: "CHOME"},
+		{Priorities: []string{"Low"}, Types: []string{"Study"}, Channel: "CHOME"},
+	}
+	env := slackDestination{Name: "env", ChannelID: "CDEFAULT", FromEnv: true, Types: taskTypeFilter{Exclude: []string{"Work"}}}
+	destinations := routeDestinations([]slackDestination{env}, routes)
+
+	posted := map[string][]string{} // タスク → 載ったチャンネル
+	for _, dest := range destinations {
+		destTasks := dest.filterTasks(tasks)
+		if len(destTasks) == 0 {
+			continue
+		}
+		blocks, err := buildSlackBlocks(destTasks, renderOptions{Now: now, CalendarTasks: dest.filterTasks(tasks)})
+		if err != nil {
+			t.Fatalf("%s: %v", dest.ChannelID, err)
+		}
+		payload, err := json.Marshal(blocks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, task := range tasks {
+			if !strings.Contains(string(payload), task.Title) {
+				continue
+			}
+			if !dest.allows(task.Priority, task.Type) {
+				t.Errorf("task %q leaked into %s", task.Title, dest.ChannelID)
+			}
+			posted[task.Title] = append(posted[task.Title], dest.ChannelID)
+		}
+	}
+	for _, task := range tasks {
+		channels := posted[task.Title]
+		switch {
+		case task.Type == "Work" && len(channels) > 0:
+			t.Errorf("excluded task %q was posted to %v", task.Title, channels)
+		case task.Type != "Work" && len(channels) != 1:
+			t.Errorf("task %q was posted to %v, want exactly one channel", task.Title, channels)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jomei/notionapi"
+	"github.com/spf13/cobra"
+)
+
+// schemaDatabase は schema で表示するデータベースの構造
+type schemaDatabase struct {
+	ID         string           `json:"id"`
+	Title      string           `json:"title"`
+	Properties []schemaProperty `json:"properties"`
+	// 設定ファイルの properties で指定したのにデータベースに無いプロパティ
+	Missing []schemaMissing `json:"missing,omitempty"`
+}
+
+type schemaMissing struct {
+	Key  string `json:"key"` // 設定ファイルの properties の項目
+	Name string `json:"name"`
+}
+
+// schemaProperty はプロパティの名前・種類と、セレクトの選択肢などの詳細
+type schemaProperty struct {
+	Name       string        `json:"name"`
+	Type       string        `json:"type"`
+	MappedTo   []string      `json:"mapped_to,omitempty"` // このプロパティを読む設定ファイルの properties の項目
+	Options    []string      `json:"options,omitempty"`
+	Groups     []schemaGroup `json:"groups,omitempty"` // ステータスのグループ
+	Expression string        `json:"expression,omitempty"`
+	Relation   string        `json:"relation,omitempty"` // リレーション先のデータベース ID
+	Rollup     string        `json:"rollup,omitempty"`
+	Format     string        `json:"format,omitempty"` // 数値の表示形式
+}
+
+type schemaGroup struct {
+	Name    string   `json:"name"`
+	Options []string `json:"options"`
+}
+
+// newSchemaDatabase はデータベースのプロパティをタイトルを先頭に名前順で並べ、設定の対応を付ける
+func newSchemaDatabase(db *notionapi.Database) schemaDatabase {
+	var title strings.Builder
+	for _, rt := range db.Title {
+		title.WriteString(rt.PlainText)
+	}
+	s := schemaDatabase{ID: string(db.ID), Title: title.String()}
+
+	mapped := map[string][]string{}
+	for _, p := range mappedProperties() {
+		if p.Name == "" {
+			continue
+		}
+		if _, ok := db.Properties[p.Name]; !ok {
+			s.Missing = append(s.Missing, schemaMissing{Key: p.Key, Name: p.Name})
+			continue
+		}
+		mapped[p.Name] = append(mapped[p.Name], p.Key)
+	}
+
+	for name, config := range db.Properties {
+		p := schemaProperty{Name: name, Type: string(config.GetType()), MappedTo: mapped[name]}
+		switch c := config.(type) {
+		case *notionapi.SelectPropertyConfig:
+			p.Options = optionNames(c.Select.Options)
+		case *notionapi.MultiSelectPropertyConfig:
+			p.Options = optionNames(c.MultiSelect.Options)
+		case *notionapi.StatusPropertyConfig:
+			p.Options = optionNames(c.Status.Options)
+			for _, g := range c.Status.Groups {
+				group := schemaGroup{Name: g.Name, Options: []string{}}
+				for _, o := range c.Status.Options {
+					if slices.Contains(g.OptionIDs, notionapi.ObjectID(o.ID)) {
+						group.Options = append(group.Options, o.Name)
+					}
+				}
+				p.Groups = append(p.Groups, group)
+			}
+		case *notionapi.FormulaPropertyConfig:
+			p.Expression = c.Formula.Expression
+		case *notionapi.RelationPropertyConfig:
+			p.Relation = string(c.Relation.DatabaseID)
+		case *notionapi.RollupPropertyConfig:
+			p.Rollup = fmt.Sprintf("%s of %s.%s", c.Rollup.Function, c.Rollup.RelationPropertyName, c.Rollup.RollupPropertyName)
+		case *notionapi.NumberPropertyConfig:
+			p.Format = string(c.Number.Format)
+		}
+		s.Properties = append(s.Properties, p)
+	}
+	slices.SortFunc(s.Properties, func(a, b schemaProperty) int {
+		if (a.Type == "title") != (b.Type == "title") {
+			if a.Type == "title" {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return s
+}
+
+func optionNames(options []notionapi.Option) []string {
+	names := make([]string, len(options))
+	for i, o := range options {
+		names[i] = o.Name
+	}
+	return names
+}
+
+// details は表で詳細の列に表示する内容を返す
+func (p schemaProperty) details() string {
+	switch {
+	case len(p.Groups) > 0:
+		groups := make([]string, len(p.Groups))
+		for i, g := range p.Groups {
+			groups[i] = g.Name + ": " + strings.Join(g.Options, ", ")
+		}
+		return strings.Join(groups, " | ")
+	case len(p.Options) > 0:
+		return strings.Join(p.Options, ", ")
+	case p.Expression != "":
+		return "= " + p.Expression
+	case p.Relation != "":
+		return "-> database " + p.Relation
+	case p.Rollup != "":
+		return p.Rollup
+	case p.Format != "":
+		return p.Format
+	}
+	return ""
+}
+
+// writeSchema はデータベースのプロパティを表にして書き出す
+func writeSchema(out io.Writer, s schemaDatabase) error {
+	fmt.Fprintf(out, "%s (%s)\n", s.Title, s.ID)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROPERTY\tTYPE\tMAPPED TO\tDETAILS")
+	for _, p := range s.Properties {
+		mappedTo := "-"
+		if len(p.MappedTo) > 0 {
+			mappedTo = strings.Join(p.MappedTo, ", ")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Name, p.Type, mappedTo, p.details())
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	for _, m := range s.Missing {
+		fmt.Fprintf(out, "Not in this database: properties.%s %q (the field stays empty)\n", m.Key, m.Name)
+	}
+	return nil
+}
+
+// fetchSchemas は dbIDs (NOTION_DB_ID の形式) のデータベースの構造を取得する
+func fetchSchemas(ctx context.Context, client *notionapi.Client, dbIDs string) ([]schemaDatabase, error) {
+	dbs, err := resolveDatabases(ctx, client, dbIDs)
+	if err != nil {
+		return nil, err
+	}
+	schemas := make([]schemaDatabase, 0, len(dbs))
+	for _, d := range dbs {
+		db, err := client.Database.Get(ctx, notionapi.DatabaseID(d.ID))
+		if err != nil {
+			return nil, newNotionError("failed to get database "+d.ID, err)
+		}
+		schemas = append(schemas, newSchemaDatabase(db))
+	}
+	return schemas, nil
+}
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the properties, types and select/status options of the Notion databases.",
+	Long: `Print the properties, types and select/status options of the Notion databases.
+
+The MAPPED TO column shows which properties.* setting of the config file reads each
+property, and mapped names missing from the database are listed below the table.
+Use it to write the property mapping or to find out why a field is empty in Slack.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := stateStoreFromEnv()
+		token, dbIDs, err := notionSourceFromEnv(store)
+		if db, _ := cmd.Flags().GetString("db"); db != "" {
+			// --db があれば NOTION_DB_ID は無くてよい
+			dbIDs = db
+			if token, err = resolveNotionToken(store); err == nil && token == "" {
+				err = fmt.Errorf("no Notion token: set %s or run init", notionTokenEnv)
+			}
+		}
+		if err != nil {
+			return asConfigError(err)
+		}
+
+		schemas, err := fetchSchemas(cmd.Context(), notionapi.NewClient(notionapi.Token(token)), dbIDs)
+		if err != nil {
+			return err
+		}
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(schemas)
+		}
+		for i, s := range schemas {
+			if i > 0 {
+				fmt.Fprintln(cmd.OutOrStdout())
+			}
+			if err := writeSchema(cmd.OutOrStdout(), s); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	schemaCmd.Flags().String("db", "", "Database ID to inspect (default NOTION_DB_ID or databases in the config file; comma-separated for several)")
+	schemaCmd.Flags().Bool("json", false, "Print the schema as JSON")
+	rootCmd.AddCommand(schemaCmd)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/slack-go/slack"
+)
+
+// 折りたたんだセクションをスレッドに表示するボタンの action_id
+const showSectionActionID = "show_section"
+
+// showSectionValue は「詳細を表示」ボタンに埋め込む、セクションのタスクを取得し直すための条件
+type showSectionValue struct {
+	Section     string `json:"section"`
+	DaysLater   int    `json:"days_later"`
+	WithinHours int    `json:"within_hours,omitempty"`
+}
+
+// parseCollapsedSections は --collapse-sections の指定を検証する
+func parseCollapsedSections(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	return parseSections(names)
+}
+
+// appendCollapsedSection はセクションを 1 行の要約と「詳細を表示」ボタンだけにして追加する
+func appendCollapsedSection(blocks []slack.Block, name, title string, tasks []Task, opts renderOptions) ([]slack.Block, error) {
+	if len(tasks) == 0 {
+		return blocks, nil
+	}
+	value, err := json.Marshal(showSectionValue{Section: name, DaysLater: opts.DaysLater, WithinHours: opts.WithinHours})
+	if err != nil {
+		return blocks, err
+	}
+	text := fmt.Sprintf("*%s*\n%s", title, tr("section.collapsed", len(tasks), tasks[0].URL, tasks[0].Title))
+	button := slack.NewButtonBlockElement(showSectionActionID, string(value),
+		slack.NewTextBlockObject(slack.PlainTextType, tr("section.show_details"), true, false))
+	return append(blocks, slack.NewDividerBlock(),
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, slack.NewAccessory(button)),
+	), nil
+}
+
+// handleShowSection はタスクを取得し直し、折りたたんだセクションの全件をスレッドに投稿する
+func handleShowSection(ctx context.Context, env *interactionEnv, callback slack.InteractionCallback, action *slack.BlockAction) error {
+	var value showSectionValue
+	if err := json.Unmarshal([]byte(action.Value), &value); err != nil {
+		return fmt.Errorf("invalid button value: %w", err)
+	}
+	if !isKnownSection(value.Section) {
+		return fmt.Errorf("unknown section %q", value.Section)
+	}
+	tasks, now, err := refetchTasks(ctx, env, value.DaysLater)
+	if err != nil {
+		return err
+	}
+	tasks = groupTasksBySection(tasks, now, value.WithinHours)[value.Section]
+
+	slackClient, err := env.slackClient(ctx, callback.Team.ID)
+	if err != nil {
+		return err
+	}
+	channelID := callback.Container.ChannelID
+	threadTS := callback.Container.MessageTs
+	if len(tasks) == 0 {
+		_, _, err = slackClient.PostMessageContext(ctx, channelID, slack.MsgOptionText(tr("section.empty", sectionTitle(value.Section, value.WithinHours)), false), slack.MsgOptionTS(threadTS))
+		return err
+	}
+	blocks, err := buildSlackBlocks(tasks, renderOptions{Now: now, Sections: []string{value.Section}, WithinHours: value.WithinHours})
+	if err != nil {
+		return err
+	}
+	_, err = postBlocks(ctx, slackClient, channelID, blocks, slack.MsgOptionTS(threadTS))
+	return err
+}
+
+func init() {
+	registerInteraction(showSectionActionID, handleShowSection)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"rainierrr/notion-notifyer/pkg/task"
+)
+
+// 期限日で分けるセクション以外のセクションの名前 (--sections で表示する順に指定する)
+const (
+	sectionPinned = task.PinnedSection
+	sectionHours  = task.HoursSection
+)
+
+// 期限日で分けるセクション以外のセクションの絵文字 (見出しはメッセージカタログの section.<名前>)
+var sectionEmojis = map[string]string{
+	sectionPinned: "📌",
+	sectionHours:  "⏰",
+}
+
+// urgencyBucket は期限日でタスクを分けるセクション (設定ファイルの buckets)
+// 前のセクションに入らなかったタスクのうち、期限日が今日から Days 日後より前のものを入れる
+type urgencyBucket struct {
+	Name  string `yaml:"name"`
+	Title string `yaml:"title,omitempty"` // 省略するとメッセージカタログの section.<名前>
+	Emoji string `yaml:"emoji,omitempty"`
+	Color string `yaml:"color,omitempty"` // Discord の embed の色 (#rrggbb)
+	// 0 なら期限切れ、1 なら今日までが期限のタスク。最後のセクションでは省略でき、残りのすべてを入れる
+	Days *int `yaml:"days,omitempty"`
+}
+
+// 期限日で分けるセクション (既定は defaults.yaml、設定ファイルの buckets で置き換える)
+var configuredBuckets []urgencyBucket
+
+// validateBuckets は期限日で分けるセクションを検証する
+func validateBuckets(buckets []urgencyBucket) error {
+	seen := map[string]bool{}
+	for i, b := range buckets {
+		switch {
+		case b.Name == "":
+			return fmt.Errorf("buckets[%d]: name is required", i)
+		case sectionEmojis[b.Name] != "":
+			return fmt.Errorf("buckets[%d]: %q is a reserved section name", i, b.Name)
+		case seen[b.Name]:
+			return fmt.Errorf("buckets[%d]: section %q is listed twice", i, b.Name)
+		case b.Title == "" && !hasMessage("section."+b.Name):
+			return fmt.Errorf("buckets[%d]: title is required", i)
+		case b.Color != "" && !validColor(b.Color):
+			return fmt.Errorf("buckets[%d]: invalid color %q (expected #rrggbb)", i, b.Color)
+		case b.Days == nil && i != len(buckets)-1:
+			return fmt.Errorf("buckets[%d]: days can be omitted only in the last bucket", i)
+		case b.Days != nil && i > 0 && *b.Days <= *buckets[i-1].Days:
+			return fmt.Errorf("buckets[%d]: days must be greater than the previous bucket's", i)
+		}
+		seen[b.Name] = true
+	}
+	return nil
+}
+
+// bucketsDayLimit は期限日で分けるセクションに入る最大の先読みの日数を返す
+// 最後のセクションが days を省略して残りのすべてを入れる場合は上限が無く、ok は false
+func bucketsDayLimit() (days int, ok bool) {
+	if len(configuredBuckets) == 0 || configuredBuckets[len(configuredBuckets)-1].Days == nil {
+		return 0, false
+	}
+	return max(*configuredBuckets[len(configuredBuckets)-1].Days-1, 0), true
+}
+
+// defaultDaysLater は --daysLater を指定しないときの先読みの日数を返す
+// days を持つ最大のセクションの最終日まで取得する (既定のセクションでは今日まで)
+func defaultDaysLater() int {
+	days := 0
+	for _, b := range configuredBuckets {
+		if b.Days != nil {
+			days = max(days, *b.Days-1)
+		}
+	}
+	return days
+}
+
+// checkDaysLater は先読みの日数のタスクが、どのセクションにも入らずに捨てられないことを確かめる
+func checkDaysLater(days int) error {
+	if limit, ok := bucketsDayLimit(); ok && days > limit {
+		last := configuredBuckets[len(configuredBuckets)-1]
+		return fmt.Errorf("daysLater %d goes past the last bucket %q (days: %d), so tasks due after %d days would be dropped; raise its days or add a last bucket without days", days, last.Name, *last.Days, limit)
+	}
+	return nil
+}
+
+// daysLaterFromFlags は --daysLater (指定が無ければ defaultDaysLater) を検証して返す
+func daysLaterFromFlags(cmd *cobra.Command) (int, error) {
+	days := defaultDaysLater()
+	if cmd.Flags().Changed("daysLater") {
+		days, _ = cmd.Flags().GetInt("daysLater")
+	}
+	if err := checkDaysLater(days); err != nil {
+		return 0, configErrorf("invalid --daysLater: %w", err)
+	}
+	return days, nil
+}
+
+// findBucket は名前の期限日で分けるセクションを返す
+func findBucket(name string) (urgencyBucket, bool) {
+	for _, b := range configuredBuckets {
+		if b.Name == name {
+			return b, true
+		}
+	}
+	return urgencyBucket{}, false
+}
+
+// defaultSectionOrder は既定のセクションの順序を返す
+// ピン留め、期限切れのセクション、「あと N 時間以内」、残りのセクションの順にする
+func defaultSectionOrder() []string {
+	sections := []string{sectionPinned}
+	var later []string
+	for _, b := range configuredBuckets {
+		if b.Days != nil && *b.Days <= 0 {
+			sections = append(sections, b.Name)
+		} else {
+			later = append(later, b.Name)
+		}
+	}
+	sections = append(sections, sectionHours)
+	return append(sections, later...)
+}
+
+// isKnownSection はセクションの名前が存在するかを返す
+func isKnownSection(name string) bool {
+	_, ok := findBucket(name)
+	return ok || sectionEmojis[name] != ""
+}
+
+// parseSections は --sections の指定を検証する。指定が無ければ既定の順序を返す
+func parseSections(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return defaultSectionOrder(), nil
+	}
+	seen := map[string]bool{}
+	var sections []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if !isKnownSection(name) {
+			return nil, fmt.Errorf("unknown section %q (available: %s)", name, strings.Join(defaultSectionOrder(), ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("section %q is listed twice", name)
+		}
+		seen[name] = true
+		sections = append(sections, name)
+	}
+	return sections, nil
+}
+
+// sectionTitle はセクションの絵文字と見出しを返す
+// 見出しを設定していないセクションはメッセージカタログの section.<名前> を使う
+func sectionTitle(name string, withinHours int) string {
+	title := ""
+	if b, ok := findBucket(name); ok {
+		title = b.Title
+	}
+	if title == "" {
+		if name == sectionHours {
+			title = tr("section."+name, withinHours)
+		} else {
+			title = tr("section." + name)
+		}
+	}
+	if emoji := sectionEmoji(name); emoji != "" {
+		return emoji + " " + title
+	}
+	return title
+}
+
+// sectionEmoji はセクションの絵文字を返す
+func sectionEmoji(name string) string {
+	if b, ok := findBucket(name); ok {
+		return b.Emoji
+	}
+	return sectionEmojis[name]
+}
+
+// groupTasksBySection はタスクをセクションごとに分け、各セクション内でソートする
+// withinHours が 0 より大きければ、時刻付きで withinHours 時間以内に期限が来るタスクを別のセクションにする
+func groupTasksBySection(tasks []Task, now time.Time, withinHours int) map[string][]Task {
+	buckets := make([]task.Bucket, 0, len(configuredBuckets))
+	for _, b := range configuredBuckets {
+		buckets = append(buckets, task.Bucket{Name: b.Name, Days: b.Days})
+	}
+	return task.Group(tasks, now, task.Grouping{Buckets: buckets, WithinHours: withinHours, Sorter: currentSorter()})
+}
+
+// filterVisibleTasks は表示するセクションに入るタスクだけを返す
+func filterVisibleTasks(tasks []Task, sections []string, now time.Time, withinHours int) []Task {
+	groups := groupTasksBySection(tasks, now, withinHours)
+	var visible []Task
+	for _, name := range sections {
+		visible = append(visible, groups[name]...)
+	}
+	return visible
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	// タスクの「開く」ボタンの action_id
+	openTaskActionID = "open_task"
+	// 掲載されなくなったタスクの追跡を続ける期間
+	seenRetention = 30 * 24 * time.Hour
+)
+
+// seenTask はダイジェストに掲載したタスクと、それを開いた Slack ユーザー
+type seenTask struct {
+	Title         string               `json:"title"`
+	URL           string               `json:"url"`
+	FirstPostedAt time.Time            `json:"first_posted_at"`
+	LastPostedAt  time.Time            `json:"last_posted_at"`
+	OwnerIDs      []string             `json:"owner_ids,omitempty"` // 担当者の Slack ユーザー ID (最初の投稿先のワークスペース)
+	OpenedBy      map[string]time.Time `json:"opened_by,omitempty"`
+	// 複数のワークスペースに投稿する場合の、ワークスペースごとの担当者の Slack ユーザー ID (キーは teamScope)
+	TeamOwnerIDs map[string][]string `json:"team_owner_ids,omitempty"`
+}
+
+// openedByOwner は担当者の誰かがタスクを開いたかを返す (どのワークスペースで開いてもよい)
+func (t *seenTask) openedByOwner() bool {
+	ids := slices.Clone(t.OwnerIDs)
+	for _, teamIDs := range t.TeamOwnerIDs {
+		ids = append(ids, teamIDs...)
+	}
+	for _, id := range ids {
+		if _, ok := t.OpenedBy[id]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// unopenedTask は担当者がまだ開いていないタスク
+type unopenedTask struct {
+	Title        string
+	Priority     string
+	Type         string
+	URL          string
+	OwnerIDs     []string
+	TeamOwnerIDs map[string][]string
+}
+
+// forTeam は投稿先のワークスペースでメンションする担当者に置き換えた unopenedTask を返す
+func (t unopenedTask) forTeam(scope string) unopenedTask {
+	if ids, ok := t.TeamOwnerIDs[scope]; ok {
+		t.OwnerIDs = ids
+	}
+	return t
+}
+
+// openTaskButton はタスクを開き、開いたことを記録するボタンを作る
+func openTaskButton(task Task) *slack.ButtonBlockElement {
+	button := slack.NewButtonBlockElement(openTaskActionID, string(task.ID),
+		slack.NewTextBlockObject(slack.PlainTextType, tr("button.open"), false, false))
+	button.URL = task.URL
+	return button
+}
+
+// findUnopenedTasks は前日までに掲載したが、担当者が一度も開いていないタスクを返す
+func findUnopenedTasks(st *state, tasks []Task, now time.Time) []unopenedTask {
+	today := startOfDay(now)
+	var unopened []unopenedTask
+	for _, task := range tasks {
+		seen, ok := st.SeenTasks[task.Key()]
+		if !ok || len(seen.OwnerIDs) == 0 || !seen.FirstPostedAt.Before(today) || seen.openedByOwner() {
+			continue
+		}
+		unopened = append(unopened, unopenedTask{Title: task.Title, Priority: task.Priority, Type: task.Type, URL: task.URL, OwnerIDs: seen.OwnerIDs, TeamOwnerIDs: seen.TeamOwnerIDs})
+	}
+	return unopened
+}
+
+func buildUnopenedSection(tasks []unopenedTask) slack.Block {
+	lines := []string{"*" + tr("unopened.title") + "*"}
+	for _, task := range tasks {
+		var mentions []string
+		for _, id := range task.OwnerIDs {
+			mentions = append(mentions, fmt.Sprintf("<@%s>", id))
+		}
+		lines = append(lines, fmt.Sprintf("• <%s|%s> (%s)", task.URL, task.Title, strings.Join(mentions, ", ")))
+	}
+	return slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, strings.Join(lines, "\n"), false, false), nil, nil)
+}
+
+// teamClient は投稿先のワークスペースとその Slack クライアント
+type teamClient struct {
+	Scope  string
+	Client *slack.Client
+}
+
+// recordSeenTasks は掲載したタスクを追跡対象として記録する
+// 担当者はメールアドレスから Slack ユーザーを引き、見つからない担当者は追跡しない
+// ユーザー ID はワークスペースごとに違うことがあるので、投稿先のワークスペースごとに引く (最初のものを OwnerIDs にする)
+func recordSeenTasks(ctx context.Context, store *stateStore, teams []teamClient, tasks []Task, now time.Time) error {
+	st, err := store.Load()
+	if err != nil {
+		return err
+	}
+
+	owners := map[string]map[string][]string{} // タスク ID → teamScope → Slack ユーザー ID
+	for _, team := range teams {
+		userIDs := map[string]string{} // メールアドレス → Slack ユーザー ID
+		for _, task := range tasks {
+			id := task.Key()
+			if seen, ok := st.SeenTasks[id]; ok && len(seen.OwnerIDs) > 0 && (len(teams) == 1 || seen.TeamOwnerIDs[team.Scope] != nil) {
+				continue
+			}
+			for _, assignee := range task.Assignees {
+				if assignee.Email == "" {
+					continue
+				}
+				userID, ok := userIDs[assignee.Email]
+				if !ok {
+					user, err := team.Client.GetUserByEmailContext(ctx, assignee.Email)
+					if err != nil {
+						log.Printf("Warning: Unable to find Slack user for %s in %s: %v", assignee.Email, team.Scope, err)
+					} else {
+						userID = user.ID
+					}
+					userIDs[assignee.Email] = userID
+				}
+				if userID != "" {
+					if owners[id] == nil {
+						owners[id] = map[string][]string{}
+					}
+					owners[id][team.Scope] = append(owners[id][team.Scope], userID)
+				}
+			}
+		}
+	}
+
+	return store.Update(func(st *state) error {
+		if st.SeenTasks == nil {
+			st.SeenTasks = map[string]*seenTask{}
+		}
+		for _, task := range tasks {
+			id := task.Key()
+			seen, ok := st.SeenTasks[id]
+			if !ok {
+				seen = &seenTask{FirstPostedAt: now}
+				st.SeenTasks[id] = seen
+			}
+			seen.Title = task.Title
+			seen.URL = task.URL
+			seen.LastPostedAt = now
+			for i, team := range teams {
+				ids, ok := owners[id][team.Scope]
+				if !ok {
+					continue
+				}
+				if i == 0 {
+					seen.OwnerIDs = ids
+				}
+				if len(teams) > 1 {
+					if seen.TeamOwnerIDs == nil {
+						seen.TeamOwnerIDs = map[string][]string{}
+					}
+					seen.TeamOwnerIDs[team.Scope] = ids
+				}
+			}
+		}
+		for id, seen := range st.SeenTasks {
+			if now.Sub(seen.LastPostedAt) > seenRetention {
+				delete(st.SeenTasks, id)
+			}
+		}
+		return nil
+	})
+}
+
+// handleOpenTask は「開く」ボタンを押したユーザーを記録する
+func handleOpenTask(ctx context.Context, env *interactionEnv, callback slack.InteractionCallback, action *slack.BlockAction) error {
+	now := env.clock.Now()
+	return env.store.Update(func(st *state) error {
+		seen, ok := st.SeenTasks[action.Value]
+		if !ok {
+			return nil
+		}
+		if seen.OpenedBy == nil {
+			seen.OpenedBy = map[string]time.Time{}
+		}
+		if _, ok := seen.OpenedBy[callback.User.ID]; !ok {
+			seen.OpenedBy[callback.User.ID] = now
+		}
+		return nil
+	})
+}
+
+func init() {
+	registerInteraction(openTaskActionID, handleOpenTask)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/spf13/cobra"
+)
+
+// タスクの短いコード (NT-4F3A)。チャットや口頭でタスクを指すのに使う
+const shortCodePrefix = "NT-"
+
+// 掲載したタスクのコードを保持する期間
+const shortCodeRetention = 90 * 24 * time.Hour
+
+// shortCodeTask はコードから開くタスク
+type shortCodeTask struct {
+	PageID     string    `json:"page_id"`
+	Title      string    `json:"title"`
+	URL        string    `json:"url"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// shortCode はページ ID から短いコードを作る。同じページからは常に同じコードになる
+// 4 桁なので別のタスクと重なることがあり、開くときは一致するタスクをすべて返す
+func shortCode(task Task) string {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(pageIDText(task))))
+	return fmt.Sprintf("%s%04X", shortCodePrefix, h.Sum32()&0xffff)
+}
+
+// normalizeShortCode は入力されたコードを NT-4F3A の形にする (nt-4f3a や 4F3A も受け付ける)
+func normalizeShortCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !strings.HasPrefix(code, shortCodePrefix) {
+		code = shortCodePrefix + code
+	}
+	return code
+}
+
+// recordShortCodes は掲載したタスクのコードを状態ファイルに記録し、古いものを消す
+func recordShortCodes(store *stateStore, tasks []Task, now time.Time) error {
+	return store.Update(func(st *state) error {
+		if st.ShortCodes == nil {
+			st.ShortCodes = map[string][]*shortCodeTask{}
+		}
+		for code, entries := range st.ShortCodes {
+			entries = slices.DeleteFunc(entries, func(e *shortCodeTask) bool { return now.Sub(e.RecordedAt) > shortCodeRetention })
+			if len(entries) == 0 {
+				delete(st.ShortCodes, code)
+			} else {
+				st.ShortCodes[code] = entries
+			}
+		}
+		for _, task := range tasks {
+			code := shortCode(task)
+			entry := &shortCodeTask{PageID: pageIDText(task), Title: task.Title, URL: task.URL, RecordedAt: now}
+			entries := slices.DeleteFunc(st.ShortCodes[code], func(e *shortCodeTask) bool { return e.PageID == entry.PageID })
+			st.ShortCodes[code] = append(entries, entry)
+		}
+		return nil
+	})
+}
+
+// lookupShortCode はコードに一致するタスクを新しく掲載した順に返す
+func lookupShortCode(store *stateStore, code string) ([]*shortCodeTask, error) {
+	st, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	entries := slices.Clone(st.ShortCodes[normalizeShortCode(code)])
+	slices.SortFunc(entries, func(a, b *shortCodeTask) int { return b.RecordedAt.Compare(a.RecordedAt) })
+	return entries, nil
+}
+
+// openInBrowser は URL を既定のブラウザで開く
+func openInBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to open browser: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+var openCmd = &cobra.Command{
+	Use:   "open <code>",
+	Short: "Print (or open) the Notion URL of a task by its short code (e.g. NT-4F3A) shown with --short-codes.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := lookupShortCode(stateStoreFromEnv(), args[0])
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return fmt.Errorf("no task with code %s (codes are recorded when a digest is posted with --short-codes)", normalizeShortCode(args[0]))
+		}
+		for _, e := range entries {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\n", e.URL, e.Title)
+		}
+		if browser, _ := cmd.Flags().GetBool("browser"); browser {
+			if len(entries) > 1 {
+				log.Printf("Warning: %d tasks share code %s; opening the most recently posted one", len(entries), normalizeShortCode(args[0]))
+			}
+			return openInBrowser(entries[0].URL)
+		}
+		return nil
+	},
+}
+
+// commandsServer は「/notifyer open NT-4F3A」のようなスラッシュコマンドに応答する
+type commandsServer struct {
+	signingSecret string
+	env           *interactionEnv
+}
+
+func (s *commandsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	verifier, err := slack.NewSecretsVerifier(r.Header, s.signingSecret)
+	if err != nil {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	r.Body = io.NopCloser(io.TeeReader(io.LimitReader(r.Body, 1<<20), &verifier))
+	command, err := slack.SlashCommandParse(r)
+	if err != nil || verifier.Ensure() != nil {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"response_type": slack.ResponseTypeEphemeral, "text": s.reply(command.Text)})
+}
+
+// reply はスラッシュコマンドの引数 (open <code>) に対する返答を作る
+func (s *commandsServer) reply(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) != 2 || fields[0] != "open" {
+		return "Usage: open <code> (e.g. open NT-4F3A)"
+	}
+	entries, err := lookupShortCode(s.env.store, fields[1])
+	if err != nil {
+		log.Printf("Slash command error: %v", err)
+		return "Failed to look up the code."
+	}
+	if len(entries) == 0 {
+		return fmt.Sprintf("No task with code %s.", normalizeShortCode(fields[1]))
+	}
+	var lines []string
+	for _, e := range entries {
+		lines = append(lines, fmt.Sprintf("<%s|%s>", e.URL, e.Title))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func init() {
+	openCmd.Flags().Bool("browser", false, "Open the task in the default browser")
+	rootCmd.AddCommand(openCmd)
+	rootCmd.Flags().Bool("short-codes", false, "Show a short code (e.g. NT-4F3A) next to each task that the open command and the /slack/commands slash command resolve to its Notion URL")
+}
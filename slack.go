@@ -3,74 +3,81 @@ package main
 import (
 	"errors"
 	"fmt"
-	"sort"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/slack-go/slack"
+
+	"rainierrr/notion-notifyer/pkg/task"
 )
 
 const (
 	MAX_MESSAGE_LENGTH = 3000 // Slack メッセージの最大長
-	MAX_MEMO_LENGTH    = 1000 // メモの最大長
 )
 
-func buildSlackBlocks(tasks []Task, runNumber string) ([]slack.Block, error) {
+// renderOptions はメッセージの描画オプション
+type renderOptions struct {
+	RunNumber string
+	Now       time.Time
+	// 設定されていれば、詳細なセクションの上に 7 日間のカレンダーを表示する
+	CalendarTasks []Task
+	// タスクに「開く」ボタンを付け、担当者が開いたかを追跡する
+	TrackSeen bool
+	// 前日までに掲載したが担当者が開いていないタスク
+	Unopened []unopenedTask
+	// 表示するセクションとその順序 (nil なら既定の順序)
+	Sections []string
+	// 0 より大きければ、時刻付きでこの時間以内に期限が来るタスクを別のセクションにする
+	WithinHours int
+	// タスクのページ ID を表示する
+	ShowPageID bool
+	// タスクの短いコード (NT-4F3A) を詳細の先頭に表示する
+	ShortCodes bool
+	// タスクに「通知しない」ボタンを付ける (TrackSeen の「開く」ボタンとは同時に使えない)
+	MuteButton bool
+	// 0 より大きければ、タスクの下に「完了」と「N 日延ばす」のボタンを並べる (N は SnoozeDays)
+	SnoozeDays int
+	// 要約の 1 行と「詳細を表示」ボタンだけにするセクション
+	CollapsedSections []string
+	// 時刻付きのタスクを午前・午後・夜の小見出しに分けるセクション
+	TimeOfDaySections []string
+	// 取得したタスクの期限の範囲 (「詳細を表示」でタスクを取得し直すときに使う)
+	DaysLater int
+	// メッセージのテンプレート (nil なら組み込みのテンプレート)
+	Template *template.Template
+	// 期限日のセクションの代わりにプロジェクトごとのセクションに分ける (--group-by project)
+	GroupByProject bool
+	// タスクをリンクと期限日だけの行にしてブロックにまとめる (ブロックが maxDigestBlocks を超えたときに使う)
+	CompactRows bool
+	// メモでメンションされた Notion のユーザー ID → 投稿先のワークスペースの Slack ユーザー ID
+	SlackUsers map[string]string
+}
+
+// buildSlackBlocks はタスクをメッセージのテンプレート (既定は templates/default.tmpl) で描画する
+func buildSlackBlocks(tasks []Task, opts renderOptions) ([]slack.Block, error) {
 	if len(tasks) == 0 {
 		return nil, errors.New("no tasks to build slack blocks")
 	}
-	// タスクを緊急度でグループ化
-	beforeday, todayTasks, threeDayTasks := groupTasksByUrgency(tasks)
-	// 各グループ内でタスクをソート
-	sortTasks(beforeday)
-	sortTasks(todayTasks)
-	sortTasks(threeDayTasks)
-
-	var blocks []slack.Block
-	var err error
-
-	// ヘッダー
-	blocks = append(blocks, slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, "🔔 Notion タスクリマインダー", true, false)))
-
-	// 各グループにタスクがある場合は、セクションを追加
-	if len(beforeday) > 0 {
-		blocks, err = appendSection(blocks, "❗️ 期限切れ", beforeday)
-		if err != nil {
-			return blocks, err
-		}
-	}
-	// 今日が期限のタスクを追加
-	if len(todayTasks) > 0 {
-		blocks, err = appendSection(blocks, "🚨 今日が期限", todayTasks)
-		if err != nil {
-			return blocks, err
-		}
-	}
-	if len(threeDayTasks) > 0 {
-		blocks, err = appendSection(blocks, "⚠️ 3 日以内に期限", threeDayTasks)
-		if err != nil {
-			return blocks, err
-		}
-	}
-
-	// フッター
-	blocks = append(blocks, slack.NewDividerBlock())
-	
-	// GitHub Actions Run Numberがある場合は追加
-	if runNumber != "" {
-		blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject(slack.PlainTextType, fmt.Sprintf("Run #%s", runNumber), false, false)))
+	t := opts.Template
+	if t == nil {
+		t = defaultMessageTemplate
 	}
-
-	return blocks, nil
+	return renderWithinBudget(func(opts renderOptions) ([]slack.Block, error) {
+		return renderTemplateBlocks(t, newTemplateData(tasks, opts), opts)
+	}, opts)
 }
 
-func groupTasksByUrgency(tasks []Task) (beforedayTasks, todayTasks, threeDayTasks []Task) {
-	now := time.Now()
-	beforeBoundary := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+// now を基準にタスクを期限切れ・今日・それ以降に分ける
+func groupTasksByUrgency(tasks []Task, now time.Time) (beforedayTasks, todayTasks, threeDayTasks []Task) {
+	beforeBoundary := startOfDay(now)
 	todayBoundary := beforeBoundary.AddDate(0, 0, 1)
 
 	for _, task := range tasks {
-		dueDate := getTargetDueDate(task)
+		dueDate := task.DueDateIn(now.Location())
+		if dueDate == nil { // 期限日の無いピン留めのタスク
+			continue
+		}
 		if dueDate.Before(beforeBoundary) { // 期限切れ
 			beforedayTasks = append(beforedayTasks, task)
 		} else if dueDate.Before(todayBoundary) { // 今日が期限
@@ -83,87 +90,139 @@ func groupTasksByUrgency(tasks []Task) (beforedayTasks, todayTasks, threeDayTask
 	return beforedayTasks, todayTasks, threeDayTasks
 }
 
-// タスクを優先度と期限日でソート
+// タスクを優先度・期限日・ワークロード・タイトル・ページ ID の順でソート (task.Sorter)
 func sortTasks(tasks []Task) {
-	sort.SliceStable(tasks, func(i, j int) bool {
-		priI := priorityOrder[tasks[i].Priority]
-		priJ := priorityOrder[tasks[j].Priority]
-		if priI != priJ {
-			return priI < priJ // 数値が小さいほど優先度が高い
-		}
-		// 優先度が同じ場合は、期限日でソート (早い順)
-		dueI := getTargetDueDate(tasks[i])
-		dueJ := getTargetDueDate(tasks[j])
-		if dueI != nil && dueJ != nil {
-			return dueI.Before(*dueJ)
-		}
-		return false // どちらかが nil の場合は、順序を変更しない
-	})
+	task.Sort(tasks, currentSorter())
 }
 
-func appendSection(blocks []slack.Block, title string, tasks []Task) ([]slack.Block, error) {
-	if len(tasks) == 0 {
-		return blocks, nil
+// sectionHeaderBlock はセクションの見出しを作る
+func sectionHeaderBlock(name, title string) slack.Block {
+	return slack.NewSectionBlock(
+		slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("*%s*", title), false, false),
+		nil, nil, slack.SectionBlockOptionBlockID(sectionHeaderBlockPrefix+name))
+}
+
+// taskDetailsAt はタスクの期限日・優先度などの詳細を level の範囲で 1 行にまとめる
+func taskDetailsAt(task Task, opts renderOptions, level detailLevel) (string, error) {
+	var details []string
+	strTime, err := formatDueDate(task)
+	if err != nil {
+		return "", fmt.Errorf("failed to format due date for task %s: %w", task.Title, err)
+	}
+	if opts.ShortCodes {
+		details = append(details, fmt.Sprintf("`%s`", shortCode(task)))
+	}
+	details = append(details, fmt.Sprintf("*%s:* %s", tr("task.due"), strTime))
+	if level >= detailDueOnly {
+		return strings.Join(details, " | "), nil
+	}
+	if task.Priority != "" {
+		details = append(details, fmt.Sprintf("*%s:* %s", tr("task.priority"), priorityLabel(task.Priority)))
+	}
+	if task.Type != "" {
+		details = append(details, fmt.Sprintf("*%s:* %s", tr("task.type"), task.Type))
+	}
+	if task.Source != "" {
+		details = append(details, fmt.Sprintf("*%s:* %s", tr("task.source"), task.Source))
+	}
+	if task.ScheduleStatus != "" {
+		details = append(details, fmt.Sprintf("*%s:* %s", tr("task.status"), task.ScheduleStatus))
+	}
+	if names := assigneeNames(task); names != "" {
+		details = append(details, fmt.Sprintf("*%s:* %s", tr("task.assignee"), names))
+	}
+	if project := projectDetail(task); project != "" {
+		details = append(details, project)
+	}
+	if len(task.Tags) > 0 {
+		details = append(details, fmt.Sprintf("*%s:* %s", tr("task.tags"), strings.Join(task.Tags, ", ")))
+	}
+	if task.Workload != 0 {
+		details = append(details, fmt.Sprintf("*%s:* %.2f", tr("task.workload"), task.Workload))
+	}
+	if task.Progress != nil {
+		details = append(details, fmt.Sprintf("*%s:* %s", tr("task.progress"), progressBar(*task.Progress)))
+	}
+	details = append(details, contactDetails(task)...)
+	if attachment := attachmentDetail(task, opts); attachment != "" {
+		details = append(details, attachment)
+	}
+	// 追加のプロパティは種類ごとに登録した表示 (property_render.go) で描画する
+	details = append(details, extraDetails(task)...)
+	if opts.ShowPageID {
+		details = append(details, fmt.Sprintf("*ID:* `%s`", pageIDText(task)))
+	}
+	if task.Streak >= 2 {
+		details = append(details, tr("task.streak", task.Streak))
+	}
+	if task.Slip != nil {
+		details = append(details, tr("task.slip", task.Slip.Count, timeFormat(task.Slip.OriginalDue)))
+	}
+	for _, ref := range task.GitHubRefs {
+		details = append(details, fmt.Sprintf("<%s|%s> %s", ref.URL, ref.Label(), ref.Chip()))
+	}
+	for _, ref := range task.JiraRefs {
+		details = append(details, fmt.Sprintf("<%s|%s> %s", ref.URL, ref.Key, ref.Chip()))
+	}
+	for _, absence := range task.Absences {
+		if absence.Delegate != "" {
+			details = append(details, fmt.Sprintf("*%s:* %s 🌴 → %s: %s", tr("task.absent"), absence.Assignee, tr("task.delegate"), absence.Delegate))
+		} else {
+			details = append(details, fmt.Sprintf("*%s:* %s 🌴", tr("task.absent"), absence.Assignee))
+		}
 	}
 
-	blocks = append(blocks, slack.NewDividerBlock())
-	blocks = append(blocks, slack.NewSectionBlock(
-		slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("*%s*", title), false, false),
-		nil, nil),
-	)
+	if task.Memo != "" && level < detailNoMemo {
+		// メモが長すぎる場合は切り捨て
+		details = append(details, fmt.Sprintf("*%s:* %s", tr("task.memo"), memoMrkdwn(task, maxMemoLength, opts.SlackUsers)))
+	}
 
-	for _, task := range tasks {
-		strTaskTitle := fmt.Sprintf("*<%s|%s>*", task.URL, task.Title) // リンク + タイトル
+	return strings.Join(details, " | "), nil
+}
 
-		var details []string
-		strTime, err := formatDueDate(task)
+// appendTaskRow はタスクの行を追加する
+// TrackSeen なら「開く」、MuteButton なら「通知しない」のボタンを付け、SnoozeDays があれば下に「完了」「延ばす」のボタンを並べる
+// CompactRows ならボタンを付けずに 1 行にして、直前の行と同じブロックにまとめる
+func appendTaskRow(blocks []slack.Block, task Task, text string, opts renderOptions) ([]slack.Block, error) {
+	if opts.CompactRows {
+		return appendCompactRow(blocks, task)
+	}
+	var accessory *slack.Accessory
+	if opts.TrackSeen {
+		accessory = slack.NewAccessory(openTaskButton(task))
+	} else if opts.MuteButton {
+		button, err := muteTaskButton(task)
 		if err != nil {
-			return blocks, fmt.Errorf("failed to format due date for task %s: %w", task.Title, err)
-		}
-		details = append(details, fmt.Sprintf("*期限日:* %s", strTime))
-		if task.Priority != "" {
-			details = append(details, fmt.Sprintf("*優先度:* %s", task.Priority))
-		}
-		if task.Type != "" {
-			details = append(details, fmt.Sprintf("*種類:* %s", task.Type))
-		}
-		if task.ScheduleStatus != "" {
-			details = append(details, fmt.Sprintf("*スケジュール:* %s", task.ScheduleStatus))
-		}
-		if task.Workload != 0 {
-			details = append(details, fmt.Sprintf("*ワークロード:* %.2f", task.Workload))
-		}
-
-		if task.Memo != "" {
-			truncatedMemo := task.Memo
-			// メモが長すぎる場合は切り捨て
-			if len(truncatedMemo) > MAX_MEMO_LENGTH {
-				truncatedMemo = truncatedMemo[:MAX_MEMO_LENGTH] + "..."
-			}
-			details = append(details, fmt.Sprintf("*メモ:* %s", truncatedMemo))
+			return blocks, err
 		}
-
-		// 文字数制限を超える場合は切り捨て
-		detailsText := strings.Join(details, " | ")
-		if len(detailsText) > MAX_MESSAGE_LENGTH {
-			detailsText = detailsText[:MAX_MESSAGE_LENGTH] + "..."
+		accessory = slack.NewAccessory(button)
+	} else if image := attachmentImage(task, opts); image != nil {
+		accessory = slack.NewAccessory(image)
+	}
+	blocks = append(blocks, slack.NewSectionBlock(
+		slack.NewTextBlockObject(slack.MarkdownType, fitRowText(task, text), false, false),
+		nil, accessory),
+	)
+	if opts.SnoozeDays > 0 {
+		actions, err := taskActionsBlock(task, opts.SnoozeDays)
+		if err != nil {
+			return blocks, err
 		}
-
-		blocks = append(blocks, slack.NewSectionBlock(
-			slack.NewTextBlockObject(slack.MarkdownType, strTaskTitle+"\n"+detailsText, false, false),
-			nil, nil),
-		)
+		blocks = append(blocks, actions)
 	}
-
 	return blocks, nil
 }
 
 // formatDueDate は表示用に期限日をフォーマットします。
+// 期限日の無いタスクはピン留めのものだけなので「なし」(task.no_due) と表示します。
 func formatDueDate(task Task) (string, error) {
 	startTime := task.DueStart
 	endTime := task.DueEnd
 
 	if startTime == nil && endTime == nil {
+		if task.Pinned {
+			return tr("task.no_due"), nil
+		}
 		return "", errors.New("startTime and endTime are both nil")
 	}
 
@@ -173,20 +232,16 @@ func formatDueDate(task Task) (string, error) {
 		return fmt.Sprintf("%s ~ %s", startTimeStr, endTimeStr), nil
 	}
 
+	if startTime == nil {
+		return timeFormat(time.Time(*endTime)), nil
+	}
+
 	return timeFormat(time.Time(*startTime)), nil
 }
 
 // タスクの目標期限日を取得 (endDate優先)
-func getTargetDueDate(task Task) *time.Time {
-	if task.DueEnd != nil {
-		t := time.Time(*task.DueEnd)
-		return &t
-	}
-	if task.DueStart != nil {
-		t := time.Time(*task.DueStart)
-		return &t
-	}
-	return nil
+func getTargetDueDate(t Task) *time.Time {
+	return t.DueDate()
 }
 
 func timeFormat(t time.Time) string {
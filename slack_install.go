@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/spf13/cobra"
+)
+
+// Slack OAuth 関連
+const (
+	slackRedirectURLEnv  = "SLACK_REDIRECT_URL"
+	slackAuthorizeURL    = "https://slack.com/oauth/v2/authorize"
+	slackInstallScopes   = "chat:write,channels:read,groups:read,incoming-webhook,workflow.steps:execute,commands,files:write"
+	oauthStateCookieName = "notifyer_oauth_state"
+)
+
+// slackInstallation は 1 つのワークスペースへのインストール情報
+// Enterprise Grid の組織全体へのインストールでは TeamID と TeamName に組織の ID と名前が入る
+type slackInstallation struct {
+	TeamID            string      `json:"team_id"`
+	TeamName          string      `json:"team_name"`
+	EnterpriseID      string      `json:"enterprise_id,omitempty"`
+	EnterpriseInstall bool        `json:"enterprise_install,omitempty"` // 組織全体へのインストール (トークンは組織内のどのワークスペースでも使える)
+	BotUserID         string      `json:"bot_user_id,omitempty"`
+	ChannelID         string      `json:"channel_id"` // インストール時に選択された投稿先チャンネル
+	ChannelName       string      `json:"channel_name,omitempty"`
+	Tokens            slackTokens `json:"tokens"`
+	InstalledAt       time.Time   `json:"installed_at"`
+}
+
+// slackDestination はダイジェストの投稿先
+type slackDestination struct {
+	Name      string
+	TeamID    string // install で追加されたワークスペースか、team ID 付きで指定した投稿先の場合のみ
+	ChannelID string
+	FromEnv   bool // SLACK_CHANNEL_ID で指定した投稿先
+	Tokens    *slackTokenSource
+	Types     taskTypeFilter // この投稿先に載せるタスクの種類
+	Routing   *taskRouting   // 設定ファイルの routes で載せるタスクを決める投稿先 (nil ならルーティングしない)
+}
+
+// installationFor はワークスペースのインストール情報とその状態ファイルのキーを返す
+// ワークスペースに直接インストールされていなければ Enterprise Grid の組織全体へのインストールを使う
+// (組織全体へのインストールが複数ある場合は、どの組織のワークスペースか判断できないので使わない)
+func (st *state) installationFor(teamID string) (string, *slackInstallation) {
+	if inst, ok := st.Installations[teamID]; ok {
+		return teamID, inst
+	}
+	key, found := "", (*slackInstallation)(nil)
+	for k, inst := range st.Installations {
+		if !inst.EnterpriseInstall {
+			continue
+		}
+		if found != nil {
+			return "", nil
+		}
+		key, found = k, inst
+	}
+	return key, found
+}
+
+// Slack のワークスペース (team) の ID
+var teamIDPattern = regexp.MustCompile(`^T[A-Z0-9]{6,}$`)
+
+// parseChannelSpec は SLACK_CHANNEL_ID の 1 つの値を team ID とチャンネルに分ける
+// Enterprise Grid では "T0123ABCD:C0456EFGH" や "T0123ABCD:#general" のようにワークスペースを指定できる
+func parseChannelSpec(spec string) (teamID, channel string, err error) {
+	team, ch, ok := strings.Cut(spec, ":")
+	if !ok {
+		return "", spec, nil
+	}
+	if !teamIDPattern.MatchString(team) || ch == "" {
+		return "", "", fmt.Errorf("invalid %s entry %q (expected CHANNEL or TEAM_ID:CHANNEL)", slackChannelEnv, spec)
+	}
+	return team, ch, nil
+}
+
+// loadSlackDestinations は channels (SLACK_CHANNEL_ID の形式) の投稿先とインストール済みワークスペースの投稿先をまとめて返す
+// channels はカンマ区切りで複数指定できる。team ID を付けた投稿先は、そのワークスペース
+// (または組織全体) のインストールがあればそのトークンを、無ければ環境変数のトークンを使う
+func loadSlackDestinations(clock Clock, store *stateStore, channels string) ([]slackDestination, error) {
+	var destinations []slackDestination
+
+	st, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	for _, spec := range strings.Split(channels, ",") {
+		if spec = strings.TrimSpace(spec); spec == "" {
+			continue
+		}
+		teamID, channel, err := parseChannelSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		dest := slackDestination{Name: "default", TeamID: teamID, ChannelID: channel, FromEnv: true}
+		if teamID != "" {
+			dest.Name = teamID
+			if key, inst := st.installationFor(teamID); inst != nil {
+				dest.Tokens = newInstallationTokenSource(clock, store, key, inst.Tokens)
+			}
+		}
+		if dest.Tokens == nil {
+			if dest.Tokens, err = newSlackTokenSourceFromEnv(clock); err != nil {
+				return nil, err
+			}
+		}
+		destinations = append(destinations, dest)
+	}
+
+	for teamID, inst := range st.Installations {
+		if inst.EnterpriseInstall {
+			// 組織全体へのインストールには既定の投稿先が無いので、SLACK_CHANNEL_ID か tenants.json で team ID 付きで指定する
+			continue
+		}
+		if inst.ChannelID == "" {
+			log.Printf("Warning: installation for team %s has no channel. Skipping.", teamID)
+			continue
+		}
+		destinations = append(destinations, slackDestination{
+			Name:      inst.TeamName,
+			TeamID:    teamID,
+			ChannelID: inst.ChannelID,
+			Tokens:    newInstallationTokenSource(clock, store, teamID, inst.Tokens),
+		})
+	}
+
+	return destinations, nil
+}
+
+// newInstallationTokenSource はインストールごとのトークンを返す slackTokenSource を作る
+// 更新したトークンは状態ファイルのインストール情報に書き戻す
+func newInstallationTokenSource(clock Clock, store *stateStore, teamID string, tokens slackTokens) *slackTokenSource {
+	return &slackTokenSource{
+		clientID:     os.Getenv(slackClientIDEnv),
+		clientSecret: os.Getenv(slackClientSecretEnv),
+		clock:        clock,
+		httpClient:   http.DefaultClient,
+		tokens:       tokens,
+		persist: func(tokens slackTokens) error {
+			return store.Update(func(st *state) error {
+				inst, ok := st.Installations[teamID]
+				if !ok {
+					return fmt.Errorf("installation for team %s was removed", teamID)
+				}
+				inst.Tokens = tokens
+				return nil
+			})
+		},
+	}
+}
+
+var installCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Run an HTTP server implementing the Slack OAuth v2 installation flow.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		addr, _ := cmd.Flags().GetString("addr")
+
+		clientID := os.Getenv(slackClientIDEnv)
+		clientSecret := os.Getenv(slackClientSecretEnv)
+		if clientID == "" || clientSecret == "" {
+			return fmt.Errorf("%s and %s must be set", slackClientIDEnv, slackClientSecretEnv)
+		}
+
+		handler := &slackInstallHandler{
+			clientID:     clientID,
+			clientSecret: clientSecret,
+			redirectURL:  os.Getenv(slackRedirectURLEnv),
+			store:        stateStoreFromEnv(),
+			clock:        systemClock{},
+		}
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/slack/install", handler.install)
+		mux.HandleFunc("/slack/oauth/callback", handler.callback)
+
+		server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = server.Shutdown(shutdownCtx)
+		}()
+
+		log.Printf("Slack install server listening on %s (open /slack/install to add the app to a workspace)", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		log.Println("Slack install server stopped.")
+		return nil
+	},
+}
+
+type slackInstallHandler struct {
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	store        *stateStore
+	clock        Clock
+}
+
+// install は CSRF 対策の state を Cookie に保存し、Slack の認可画面へリダイレクトする
+func (h *slackInstallHandler) install(w http.ResponseWriter, r *http.Request) {
+	oauthState, err := randomState()
+	if err != nil {
+		http.Error(w, "failed to start installation", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookieName,
+		Value:    oauthState,
+		Path:     "/slack/oauth",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	q := url.Values{}
+	q.Set("client_id", h.clientID)
+	q.Set("scope", slackInstallScopes)
+	q.Set("state", oauthState)
+	if h.redirectURL != "" {
+		q.Set("redirect_uri", h.redirectURL)
+	}
+	http.Redirect(w, r, slackAuthorizeURL+"?"+q.Encode(), http.StatusFound)
+}
+
+// callback は認可コードをトークンに交換し、インストール情報を保存する
+func (h *slackInstallHandler) callback(w http.ResponseWriter, r *http.Request) {
+	if errParam := r.URL.Query().Get("error"); errParam != "" {
+		http.Error(w, "installation was cancelled: "+errParam, http.StatusBadRequest)
+		return
+	}
+	cookie, err := r.Cookie(oauthStateCookieName)
+	if err != nil || cookie.Value == "" || cookie.Value != r.URL.Query().Get("state") {
+		http.Error(w, "invalid OAuth state", http.StatusBadRequest)
+		return
+	}
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		http.Error(w, "missing code", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := slack.GetOAuthV2ResponseContext(r.Context(), http.DefaultClient, h.clientID, h.clientSecret, code, h.redirectURL)
+	if err != nil {
+		log.Printf("Slack OAuth exchange error: %v", err)
+		http.Error(w, "failed to complete installation", http.StatusBadGateway)
+		return
+	}
+
+	now := h.clock.Now()
+	inst := &slackInstallation{
+		TeamID:       resp.Team.ID,
+		TeamName:     resp.Team.Name,
+		EnterpriseID: resp.Enterprise.ID,
+		BotUserID:    resp.BotUserID,
+		ChannelID:    resp.IncomingWebhook.ChannelID,
+		ChannelName:  resp.IncomingWebhook.Channel,
+		Tokens: slackTokens{
+			AccessToken:  resp.AccessToken,
+			RefreshToken: resp.RefreshToken,
+		},
+		InstalledAt: now,
+	}
+	if resp.IsEnterpriseInstall {
+		// 組織全体へのインストールにはワークスペースが無いので、組織の ID で保存する
+		inst.TeamID, inst.TeamName = resp.Enterprise.ID, resp.Enterprise.Name
+		inst.EnterpriseInstall = true
+	}
+	if resp.ExpiresIn > 0 {
+		inst.Tokens.ExpiresAt = now.Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+
+	err = h.store.Update(func(st *state) error {
+		if st.Installations == nil {
+			st.Installations = map[string]*slackInstallation{}
+		}
+		st.Installations[inst.TeamID] = inst
+		return nil
+	})
+	if err != nil {
+		log.Printf("Save installation error: %v", err)
+		http.Error(w, "failed to save installation", http.StatusInternalServerError)
+		return
+	}
+
+	if inst.EnterpriseInstall {
+		log.Printf("Installed to Enterprise Grid organization %s (%s)", inst.TeamName, inst.TeamID)
+		fmt.Fprintf(w, "Notion Notifyer was installed to the %s organization. Set team-qualified channels (TEAM_ID:CHANNEL) in %s or tenants.json to choose where digests go.\n", inst.TeamName, slackChannelEnv)
+		return
+	}
+	log.Printf("Installed to workspace %s (%s), posting to %s", inst.TeamName, inst.TeamID, inst.ChannelName)
+	fmt.Fprintf(w, "Notion Notifyer was installed to %s. Digests will be posted to %s.\n", inst.TeamName, inst.ChannelName)
+}
+
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func init() {
+	installCmd.Flags().String("addr", ":3000", "Address for the installation HTTP server")
+	rootCmd.AddCommand(installCmd)
+}
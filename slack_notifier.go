@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/slack-go/slack"
+	"github.com/spf13/pflag"
+)
+
+// slackNotifier は Slack のチャンネルにダイジェストを投稿する送り先
+// 投稿先 (ルーティング済み) と投稿先ごとのメッセージの組み立ては実行ごとに digestRun で受け取る
+type slackNotifier struct {
+	Store       *stateStore
+	ThreadTasks bool // スレッドにタスクごとのメッセージを投稿する
+	UpdateDaily bool // その日のメッセージがあれば書き換える (Store が必要)
+}
+
+// Send は投稿先ごとに種類で絞り込んだタスクを投稿する。1 つが失敗しても残りには送る
+// 一部の投稿先に送れなかった場合は partialSendError を返す
+func (n *slackNotifier) Send(ctx context.Context, run digestRun, groups []TaskGroup) error {
+	tasks := groupedTasks(groups)
+	failed := 0
+	var lastErr error // 終了コードや再試行で Slack のエラーコードを見られるよう、最後の失敗を包んで返す
+	for _, dest := range run.Destinations {
+		// 投稿先ごとの種類の絞り込みとルーティングは描画の直前に行い、絞り込んだタスク以外が載らないようにする
+		destTasks := dest.filterTasks(tasks)
+		if len(destTasks) == 0 {
+			log.Printf("[%s] No tasks for %s after type filters and routes. Skipping.", run.JobName, dest.Name)
+			continue
+		}
+		blocks, err := run.slackBlocks(destTasks, dest)
+		if err != nil {
+			return &renderError{Err: fmt.Errorf("build Slack blocks: %w", err)}
+		}
+		if run.DryRun {
+			if err := printDryRun(os.Stdout, dest, blocks); err != nil {
+				return err
+			}
+			continue
+		}
+
+		slackClient, err := dest.Tokens.Client(ctx, slack.OptionHTTPClient(run.httpClient("slack:"+dest.Name)))
+		if err != nil {
+			log.Printf("[%s] Slack client error (%s): %v", run.JobName, dest.Name, err)
+			failed++
+			lastErr = err
+			continue
+		}
+		if err := resolveChannel(ctx, slackClient, n.Store, run.Now, &dest); err != nil {
+			log.Printf("[%s] Slack channel error (%s): %v", run.JobName, dest.Name, err)
+			failed++
+			lastErr = err
+			continue
+		}
+		// 上限を超えるブロックは複数のメッセージに分けて投稿する
+		var timestamp string
+		updated := false
+		if n.UpdateDaily && n.Store != nil {
+			timestamp, updated, err = upsertDailyMessage(ctx, slackClient, n.Store, run.JobName, dest, blocks, run.Now)
+		} else {
+			timestamp, err = postBlocks(ctx, slackClient, dest.ChannelID, blocks)
+		}
+		if err != nil {
+			log.Printf("[%s] Slack message send error (%s): %v", run.JobName, dest.Name, err)
+			failed++
+			lastErr = err
+			continue
+		}
+		if updated {
+			log.Printf("[%s] Slack message updated in channel %s (%s) at %s", run.JobName, dest.ChannelID, dest.Name, timestamp)
+		} else {
+			log.Printf("[%s] Slack message sent to channel %s (%s) at %s", run.JobName, dest.ChannelID, dest.Name, timestamp)
+		}
+
+		// 書き換えたメッセージのスレッドには前回の実行でタスクを投稿している
+		if n.ThreadTasks && n.Store != nil && !updated {
+			if err := postTaskThread(ctx, slackClient, dest, timestamp, destTasks, n.Store, run.Now); err != nil {
+				log.Printf("[%s] Warning: %v", run.JobName, err)
+			}
+		}
+	}
+	if failed > 0 {
+		return &partialSendError{
+			Sent: len(run.Destinations) - failed,
+			Err:  newSlackError(fmt.Sprintf("failed to send Slack message to %d of %d destinations", failed, len(run.Destinations)), lastErr),
+		}
+	}
+	return nil
+}
+
+// slackTarget は Slack だけに送るジョブ (テナントやワークフローステップなど) の送り先の一覧を返す
+func slackTarget(store *stateStore) []namedNotifier {
+	return []namedNotifier{{Name: targetSlack, Notifier: &slackNotifier{Store: store}}}
+}
+
+func init() {
+	RegisterNotifier(targetSlack, func(flags *pflag.FlagSet) (Notifier, error) {
+		n := &slackNotifier{Store: stateStoreFromEnv()}
+		n.ThreadTasks, _ = flags.GetBool("thread-tasks")
+		n.UpdateDaily, _ = flags.GetBool("update-in-place")
+		return n, nil
+	})
+}
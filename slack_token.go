@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// Slack トークンローテーション関連の環境変数
+const (
+	slackClientIDEnv     = "SLACK_CLIENT_ID"
+	slackClientSecretEnv = "SLACK_CLIENT_SECRET"
+	slackRefreshTokenEnv = "SLACK_REFRESH_TOKEN"
+	slackTokenFileEnv    = "SLACK_TOKEN_FILE" // 更新したトークンの保存先
+)
+
+// 有効期限のこの時間前になったらトークンを更新する
+const slackTokenRefreshMargin = 5 * time.Minute
+
+// slackTokens はローテーション対象のトークン一式
+type slackTokens struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+}
+
+// slackTokenSource は有効なボットトークンを返し、期限が近ければ refresh token で更新する
+// 更新したトークンは persist で保存し、次回の実行や長時間動作するプロセスで引き継ぐ
+type slackTokenSource struct {
+	clientID     string
+	clientSecret string
+	clock        Clock
+	httpClient   *http.Client
+	persist      func(slackTokens) error
+
+	mu     sync.Mutex
+	tokens slackTokens
+}
+
+// newSlackTokenSourceFromEnv は環境変数と保存済みトークンファイルから slackTokenSource を作る
+func newSlackTokenSourceFromEnv(clock Clock) (*slackTokenSource, error) {
+	tokenFile := statePath(os.Getenv(slackTokenFileEnv))
+	src := &slackTokenSource{
+		clientID:     os.Getenv(slackClientIDEnv),
+		clientSecret: os.Getenv(slackClientSecretEnv),
+		clock:        clock,
+		httpClient:   http.DefaultClient,
+		tokens: slackTokens{
+			AccessToken:  os.Getenv(slackTokenEnv),
+			RefreshToken: os.Getenv(slackRefreshTokenEnv),
+		},
+	}
+
+	// ローテーション済みのトークンが保存されていれば、環境変数より優先する
+	if tokenFile != "" {
+		saved, err := loadSlackTokens(tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		if saved != nil && saved.AccessToken != "" {
+			src.tokens = *saved
+		}
+		src.persist = func(tokens slackTokens) error {
+			return saveSlackTokens(tokenFile, tokens)
+		}
+	}
+
+	if src.tokens.AccessToken == "" && src.tokens.RefreshToken == "" {
+		return nil, fmt.Errorf("either %s or %s must be set", slackTokenEnv, slackRefreshTokenEnv)
+	}
+	if src.tokens.RefreshToken != "" {
+		if src.clientID == "" || src.clientSecret == "" {
+			return nil, fmt.Errorf("%s and %s are required for token rotation", slackClientIDEnv, slackClientSecretEnv)
+		}
+		if tokenFile == "" {
+			log.Printf("Warning: %s is not set. Rotated Slack tokens will not be persisted and the refresh token may become invalid.", slackTokenFileEnv)
+		}
+	}
+
+	return src, nil
+}
+
+// Token は有効なボットトークンを返す
+func (s *slackTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.needsRefresh() {
+		return s.tokens.AccessToken, nil
+	}
+
+	resp, err := slack.RefreshOAuthV2TokenContext(ctx, s.httpClient, s.clientID, s.clientSecret, s.tokens.RefreshToken)
+	if err != nil {
+		return "", fmt.Errorf("failed to refresh slack token: %w", err)
+	}
+
+	s.tokens = slackTokens{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresAt:    s.clock.Now().Add(time.Duration(resp.ExpiresIn) * time.Second),
+	}
+	log.Printf("Slack token refreshed (expires at %s)", s.tokens.ExpiresAt.Format(time.RFC3339))
+
+	if s.persist != nil {
+		if err := s.persist(s.tokens); err != nil {
+			return "", err
+		}
+	}
+
+	return s.tokens.AccessToken, nil
+}
+
+// Client は有効なトークンで Slack クライアントを作る
+// 長時間動作するプロセスでは、API を呼ぶ直前に毎回取得し直すこと
+func (s *slackTokenSource) Client(ctx context.Context, opts ...slack.Option) (*slack.Client, error) {
+	token, err := s.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return slack.New(token, opts...), nil
+}
+
+func (s *slackTokenSource) needsRefresh() bool {
+	if s.tokens.RefreshToken == "" {
+		return false
+	}
+	// 有効期限が分からない場合は、念のため更新する
+	if s.tokens.AccessToken == "" || s.tokens.ExpiresAt.IsZero() {
+		return true
+	}
+	return s.clock.Now().Add(slackTokenRefreshMargin).After(s.tokens.ExpiresAt)
+}
+
+func loadSlackTokens(path string) (*slackTokens, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read slack token file: %w", err)
+	}
+	var tokens slackTokens
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse slack token file %s: %w", path, err)
+	}
+	return &tokens, nil
+}
+
+func saveSlackTokens(path string, tokens slackTokens) error {
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode slack tokens: %w", err)
+	}
+	if err := writeFileAtomic(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write slack token file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jomei/notionapi"
+	"github.com/slack-go/slack"
+	"github.com/spf13/cobra"
+)
+
+// smoke で確認を繰り返す間隔 (Notion の検索結果にページが現れるまで少しかかる)
+const smokePollInterval = 2 * time.Second
+
+// smokeTest は本物の API に対して、テスト用のページの作成から Slack への投稿までを確かめる
+// 投稿先とデータベースは本番と分けたサンドボックスを使う
+type smokeTest struct {
+	notion  *notionapi.Client
+	slack   *slack.Client
+	dest    slackDestination
+	db      string
+	token   string
+	timeout time.Duration
+}
+
+// createPage は今日が期限のテスト用のページを作り、そのページとタイトルを返す
+// ステータスは取得対象のステータスのうち、データベースの選択肢にある最初のものにする
+func (s *smokeTest) createPage(ctx context.Context, now time.Time) (*notionapi.Page, string, error) {
+	db, err := s.notion.Database.Get(ctx, notionapi.DatabaseID(s.db))
+	if err != nil {
+		return nil, "", newNotionError("failed to get database "+s.db, err)
+	}
+	status := ""
+	if config, ok := db.Properties[scheduleStatusProp].(*notionapi.StatusPropertyConfig); ok {
+		f := statusFetcher(ctx, s.notion, s.db)
+		for _, option := range config.Status.Options {
+			if f.HasStatus(option.Name) {
+				status = option.Name
+				break
+			}
+		}
+	}
+	if status == "" {
+		return nil, "", fmt.Errorf("database %s has no %q status option that the digest fetches (run doctor)", s.db, scheduleStatusProp)
+	}
+
+	nonce := make([]byte, 4)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, "", err
+	}
+	title := "notion-notifyer smoke test " + hex.EncodeToString(nonce)
+	due := notionapi.Date(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC))
+	page, err := s.notion.Page.Create(ctx, &notionapi.PageCreateRequest{
+		Parent: notionapi.Parent{Type: notionapi.ParentTypeDatabaseID, DatabaseID: notionapi.DatabaseID(s.db)},
+		Properties: notionapi.Properties{
+			nameProp: notionapi.TitleProperty{
+				Type:  notionapi.PropertyTypeTitle,
+				Title: []notionapi.RichText{{Type: notionapi.ObjectTypeText, Text: &notionapi.Text{Content: title}}},
+			},
+			dueProp: dueDateProperty{Start: &due},
+			scheduleStatusProp: notionapi.StatusProperty{
+				Type:   notionapi.PropertyTypeStatus,
+				Status: notionapi.Status{Name: status},
+			},
+		},
+	})
+	if err != nil {
+		return nil, "", newNotionError("failed to create the test page", err)
+	}
+	return page, title, nil
+}
+
+// waitForPage はテスト用のページがダイジェストの取得結果に現れるまで待つ
+func (s *smokeTest) waitForPage(ctx context.Context, pageID notionapi.ObjectID, now time.Time) error {
+	deadline := time.Now().Add(s.timeout)
+	for {
+		tasks, err := fetchNotionTasks(ctx, s.notion, s.db, endOfDay(now, 0))
+		if err != nil {
+			return err
+		}
+		if slices.ContainsFunc(tasks, func(t Task) bool { return t.ID == pageID }) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the test page did not appear in the database query within %s", s.timeout)
+		}
+		if err := sleepContext(ctx, smokePollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// latestTS はチャンネルの最新のメッセージの ts を返す (メッセージが無ければ空)
+func (s *smokeTest) latestTS(ctx context.Context) (string, error) {
+	history, err := s.slack.GetConversationHistoryContext(ctx, &slack.GetConversationHistoryParameters{ChannelID: s.dest.ChannelID, Limit: 1})
+	if err != nil {
+		return "", newSlackError("failed to read the channel history (the app needs channels:history or groups:history)", err)
+	}
+	if len(history.Messages) == 0 {
+		return "", nil
+	}
+	return history.Messages[0].Timestamp, nil
+}
+
+// postedSince は oldest より後に Bot が投稿したメッセージを返す
+func (s *smokeTest) postedSince(ctx context.Context, oldest, botUserID string) ([]slack.Message, error) {
+	history, err := s.slack.GetConversationHistoryContext(ctx, &slack.GetConversationHistoryParameters{ChannelID: s.dest.ChannelID, Oldest: oldest, Limit: 100})
+	if err != nil {
+		return nil, newSlackError("failed to read the channel history", err)
+	}
+	var messages []slack.Message
+	for _, msg := range history.Messages {
+		if msg.User == botUserID {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, nil
+}
+
+// waitForMessage は Bot の投稿にテスト用のページのタイトルが載るまで待ち、その間に Bot が投稿したメッセージを返す
+func (s *smokeTest) waitForMessage(ctx context.Context, oldest, botUserID, title string) ([]slack.Message, error) {
+	deadline := time.Now().Add(s.timeout)
+	for {
+		messages, err := s.postedSince(ctx, oldest, botUserID)
+		if err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(messages, func(msg slack.Message) bool { return messageContains(msg, title) }) {
+			return messages, nil
+		}
+		if time.Now().After(deadline) {
+			return messages, fmt.Errorf("no message in %s mentions %q within %s", s.dest.ChannelID, title, s.timeout)
+		}
+		if err := sleepContext(ctx, smokePollInterval); err != nil {
+			return messages, err
+		}
+	}
+}
+
+// messageContains はメッセージの本文かブロックに text が含まれるかを返す
+func messageContains(msg slack.Message, text string) bool {
+	if strings.Contains(msg.Text, text) {
+		return true
+	}
+	blocks, err := json.Marshal(msg.Blocks)
+	return err == nil && strings.Contains(string(blocks), text)
+}
+
+// cleanup はテスト用のページをアーカイブし、Bot の投稿を削除する。失敗しても続けて、最後のエラーを返す
+func (s *smokeTest) cleanup(ctx context.Context, pageID notionapi.ObjectID, messages []slack.Message) error {
+	var lastErr error
+	if _, err := s.notion.Page.Update(ctx, notionapi.PageID(pageID), &notionapi.PageUpdateRequest{Archived: true, Properties: notionapi.Properties{}}); err != nil {
+		lastErr = newNotionError("failed to archive the test page", err)
+		log.Printf("Warning: %v", lastErr)
+	}
+	for _, msg := range messages {
+		if _, _, err := s.slack.DeleteMessageContext(ctx, s.dest.ChannelID, msg.Timestamp); err != nil {
+			lastErr = newSlackError("failed to delete message "+msg.Timestamp, err)
+			log.Printf("Warning: %v", lastErr)
+		}
+	}
+	return lastErr
+}
+
+// sleepContext は d だけ待つ。ctx が終われば待たずにエラーを返す
+func sleepContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// run はテスト用のページを作り、ダイジェストを送り、Slack に届いたことを確かめてから片付ける
+func (s *smokeTest) run(ctx context.Context, keep bool) (err error) {
+	now := time.Now()
+	auth, err := s.slack.AuthTestContext(ctx)
+	if err != nil {
+		return newSlackError("slack token error", err)
+	}
+	oldest, err := s.latestTS(ctx)
+	if err != nil {
+		return err
+	}
+
+	page, title, err := s.createPage(ctx, now)
+	if err != nil {
+		return err
+	}
+	log.Printf("Created test page %q (%s)", title, page.URL)
+	var messages []slack.Message
+	defer func() {
+		if keep {
+			log.Printf("Keeping the test page and %d messages (--keep)", len(messages))
+			return
+		}
+		if cleanupErr := s.cleanup(context.WithoutCancel(ctx), page.ID, messages); cleanupErr != nil {
+			if err == nil {
+				err = fmt.Errorf("smoke test passed but cleanup failed: %w", cleanupErr)
+			}
+			return
+		}
+		log.Printf("Archived the test page and deleted %d messages", len(messages))
+	}()
+
+	if err := s.waitForPage(ctx, page.ID, now); err != nil {
+		return err
+	}
+	log.Printf("Test page is returned by the database query")
+
+	// 状態ファイルは使い捨てにして、本番の掲載記録や日次メッセージに影響させない
+	dir, err := os.MkdirTemp("", "notion-notifyer-smoke")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	store := newStateStore(filepath.Join(dir, "state.json"))
+	job := digestJob{
+		Name:         "smoke",
+		NotionToken:  s.token,
+		DatabaseID:   s.db,
+		Destinations: []slackDestination{s.dest},
+		Notifiers:    slackTarget(store),
+		Store:        store,
+	}
+	if err := runDigest(ctx, job, now); err != nil {
+		// 途中まで投稿したメッセージも片付ける
+		messages, _ = s.postedSince(ctx, oldest, auth.UserID)
+		return fmt.Errorf("digest failed: %w", err)
+	}
+
+	messages, err = s.waitForMessage(ctx, oldest, auth.UserID, title)
+	if err != nil {
+		return err
+	}
+	log.Printf("Found the test page in %d messages posted to %s", len(messages), s.dest.ChannelID)
+	return nil
+}
+
+var smokeCmd = &cobra.Command{
+	Use:   "smoke --db <sandbox-db> --channel <sandbox-channel>",
+	Short: "Run an end-to-end test against the real Notion and Slack APIs.",
+	Long: `Run an end-to-end test against the real Notion and Slack APIs.
+
+smoke creates a task page due today in the sandbox database, waits until the database
+query returns it, sends a digest of the database to the sandbox channel, and checks
+with conversations.history that the task was posted. The page is archived and the
+posted messages are deleted afterwards (unless --keep).
+
+The Slack app needs the chat:write and channels:history (or groups:history) scopes.
+Use a database and channel reserved for testing: every task due today in the database
+is posted, and the app's new messages in the channel are deleted.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, _ := cmd.Flags().GetString("db")
+		channel, _ := cmd.Flags().GetString("channel")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		keep, _ := cmd.Flags().GetBool("keep")
+		if timeout <= 0 {
+			return configErrorf("--timeout must be positive")
+		}
+
+		store := stateStoreFromEnv()
+		token, err := resolveNotionToken(store)
+		if err != nil {
+			return fmt.Errorf("notion token error: %w", err)
+		}
+		if token == "" {
+			return configErrorf("no Notion token: set %s or run init", notionTokenEnv)
+		}
+		destinations, err := loadSlackDestinations(systemClock{}, store, channel)
+		if err != nil {
+			return asConfigError(err)
+		}
+		// install で追加されたワークスペースの投稿先には送らない
+		destinations = slices.DeleteFunc(destinations, func(d slackDestination) bool { return !d.FromEnv })
+		if len(destinations) != 1 {
+			return configErrorf("--channel takes exactly one channel, got %q", channel)
+		}
+		dest := destinations[0]
+		client, err := dest.Tokens.Client(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if name, ok := channelName(dest.ChannelID); ok {
+			if dest.ChannelID, err = lookupChannelID(cmd.Context(), client, dest.TeamID, name); err != nil {
+				return err
+			}
+		}
+
+		s := &smokeTest{
+			notion:  notionapi.NewClient(notionapi.Token(token)),
+			slack:   client,
+			dest:    dest,
+			db:      db,
+			token:   token,
+			timeout: timeout,
+		}
+		if err := s.run(cmd.Context(), keep); err != nil {
+			return fmt.Errorf("smoke test failed: %w", err)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), "Smoke test passed")
+		return nil
+	},
+}
+
+func init() {
+	smokeCmd.Flags().String("db", "", "Sandbox Notion database ID to create the test page in")
+	smokeCmd.Flags().String("channel", "", "Sandbox Slack channel (ID or #name, optionally TEAM_ID:CHANNEL) to post the digest to")
+	smokeCmd.Flags().Duration("timeout", time.Minute, "How long to wait for the page to be queryable and for the Slack message")
+	smokeCmd.Flags().Bool("keep", false, "Keep the test page and Slack messages for inspection")
+	_ = smokeCmd.MarkFlagRequired("db")
+	_ = smokeCmd.MarkFlagRequired("channel")
+	rootCmd.AddCommand(smokeCmd)
+}
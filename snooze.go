@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jomei/notionapi"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// スヌーズに使うリアクションの絵文字名と、期限を延ばす日数
+const (
+	snoozeReaction = "zzz"
+	snoozeDays     = 1
+)
+
+// taskMute はスヌーズやミュートでダイジェストに載せないタスク
+// Notion の期限日を戻されても、Until まではダイジェストに載せない。Until が無ければ取り消すまで載せない
+type taskMute struct {
+	Title     string     `json:"title"`
+	Until     *time.Time `json:"until,omitempty"`
+	By        string     `json:"by,omitempty"` // スヌーズ・ミュートした Slack ユーザー
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// active は now の時点でまだ載せないかを返す
+func (m *taskMute) active(now time.Time) bool {
+	return m.Until == nil || m.Until.After(now)
+}
+
+// muteTask はタスクを載せないよう状態ファイルに記録する。期限の過ぎた記録は取り除く
+func muteTask(store *stateStore, pageID string, mute taskMute, now time.Time) error {
+	return store.Update(func(st *state) error {
+		if st.MutedTasks == nil {
+			st.MutedTasks = map[string]*taskMute{}
+		}
+		for id, m := range st.MutedTasks {
+			if !m.active(now) {
+				delete(st.MutedTasks, id)
+			}
+		}
+		st.MutedTasks[pageID] = &mute
+		return nil
+	})
+}
+
+// filterMutedTasks はスヌーズ中・ミュート中のタスクを除き、除いた件数を返す
+func filterMutedTasks(tasks []Task, st *state, now time.Time) ([]Task, int) {
+	if len(st.MutedTasks) == 0 {
+		return tasks, 0
+	}
+	var visible []Task
+	for _, task := range tasks {
+		if m, ok := st.MutedTasks[task.Key()]; ok && m.active(now) {
+			continue
+		}
+		visible = append(visible, task)
+	}
+	return visible, len(tasks) - len(visible)
+}
+
+// snoozePage はタスクの期限を days 日延ばし、延ばした日まで載せないよう記録する。載せない期限を返す
+func snoozePage(ctx context.Context, env *interactionEnv, pageID, title, by string, days int) (time.Time, error) {
+	notionToken, _, err := notionSourceFromEnv(env.store)
+	if err != nil {
+		return time.Time{}, err
+	}
+	notionClient := notionapi.NewClient(notionapi.Token(notionToken))
+	page, err := notionClient.Page.Get(ctx, notionapi.PageID(pageID))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get task: %w", err)
+	}
+	task := parseNotionPage(*page)
+	if task == nil {
+		return time.Time{}, fmt.Errorf("task %s has no due date", title)
+	}
+	if _, err := snoozeTask(ctx, notionClient, *task, days); err != nil {
+		return time.Time{}, err
+	}
+
+	now := env.clock.Now()
+	until := startOfDay(now).AddDate(0, 0, days)
+	if err := muteTask(env.store, pageID, taskMute{Title: title, Until: &until, By: by, CreatedAt: now}, now); err != nil {
+		return time.Time{}, err
+	}
+	return until, nil
+}
+
+// handleSnoozeReaction は 💤 のリアクションでタスクの期限を延ばし、延ばした日まで載せないよう記録する
+func (s *eventsServer) handleSnoozeReaction(ctx context.Context, teamID string, reaction *slackevents.ReactionAddedEvent, msg *taskMessage) error {
+	until, err := snoozePage(ctx, s.env, msg.PageID, msg.Title, reaction.User, snoozeDays)
+	if err != nil {
+		return err
+	}
+	log.Printf("Task %s snoozed until %s by reaction :%s: from %s", msg.Title, until.Format("2006-01-02"), reaction.Reaction, reaction.User)
+
+	slackClient, err := s.env.slackClient(ctx, teamID)
+	if err != nil {
+		return err
+	}
+	text := fmt.Sprintf("<%s|%s>\n%s", msg.URL, msg.Title, tr("reaction.snoozed", until.Format("1/2"), reaction.User))
+	_, _, _, err = slackClient.UpdateMessageContext(ctx, reaction.Item.Channel, reaction.Item.Timestamp, slack.MsgOptionText(text, false))
+	return err
+}
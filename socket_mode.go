@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// Socket Mode 用のアプリレベルトークン (xapp-)。設定すると interactions は HTTP サーバーの代わりに Socket Mode で受け取る
+const slackAppTokenEnv = "SLACK_APP_TOKEN"
+
+// runSocketMode は Slack との WebSocket 接続でインタラクション・イベント・スラッシュコマンドを受け取る
+// 公開する Request URL も署名の検証も要らない。各リクエストは受け取ってすぐ ack し、処理は HTTP と同じハンドラーで行う
+func runSocketMode(ctx context.Context, appToken string, interactions *interactionServer, events *eventsServer, commands *commandsServer) error {
+	if !strings.HasPrefix(appToken, "xapp-") {
+		return fmt.Errorf("%s must be an app-level token (xapp-...)", slackAppTokenEnv)
+	}
+	// 接続の開始にはアプリレベルトークンだけを使う (API の呼び出しはワークスペースごとのボットトークンで行う)
+	client := socketmode.New(slack.New("", slack.OptionAppLevelToken(appToken)))
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case evt := <-client.Events:
+				handleSocketModeEvent(client, evt, interactions, events, commands)
+			}
+		}
+	}()
+
+	log.Println("Slack interactions connecting over Socket Mode")
+	if err := client.RunContext(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("socket mode: %w", err)
+	}
+	log.Println("Slack Socket Mode connection closed.")
+	return nil
+}
+
+// handleSocketModeEvent は Socket Mode で受け取ったリクエストを ack し、種類ごとのハンドラーに渡す
+func handleSocketModeEvent(client *socketmode.Client, evt socketmode.Event, interactions *interactionServer, events *eventsServer, commands *commandsServer) {
+	switch evt.Type {
+	case socketmode.EventTypeConnected:
+		log.Println("Slack Socket Mode connected")
+	case socketmode.EventTypeConnectionError, socketmode.EventTypeInvalidAuth:
+		log.Printf("Warning: Slack Socket Mode %s: %v", evt.Type, evt.Data)
+	case socketmode.EventTypeInteractive:
+		callback, ok := evt.Data.(slack.InteractionCallback)
+		if !ok {
+			return
+		}
+		client.Ack(*evt.Request)
+		interactions.dispatch(callback)
+	case socketmode.EventTypeEventsAPI:
+		event, ok := evt.Data.(slackevents.EventsAPIEvent)
+		if !ok {
+			return
+		}
+		client.Ack(*evt.Request)
+		events.dispatch(event)
+	case socketmode.EventTypeSlashCommand:
+		command, ok := evt.Data.(slack.SlashCommand)
+		if !ok {
+			return
+		}
+		client.Ack(*evt.Request, map[string]string{"response_type": slack.ResponseTypeEphemeral, "text": commands.reply(command.Text)})
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 状態ファイルの保存先
+const (
+	stateFileEnv     = "NOTIFYER_STATE_FILE"
+	defaultStateFile = "notifyer-state.json"
+)
+
+// 状態ファイルのスキーマバージョン (変えるときは state_migrate.go にマイグレーションを足す)
+const stateVersion = 2
+
+// state は実行をまたいで保持するデータ
+type state struct {
+	Version int `json:"version"`
+	// Slack ワークスペースごとのインストール情報 (キーは team ID)
+	Installations map[string]*slackInstallation `json:"installations,omitempty"`
+	// init で認可した Notion ワークスペースごとの OAuth トークン (キーは workspace ID)
+	NotionAuths map[string]*notionAuth `json:"notion_auths,omitempty"`
+	// Todoist に書き出したタスク (キーは Notion のページ ID、値は Todoist のタスク ID)
+	TodoistTasks map[string]string `json:"todoist_tasks,omitempty"`
+	// スレッドに投稿したタスクごとのメッセージ (キーは "<channel>:<ts>")
+	TaskMessages map[string]*taskMessage `json:"task_messages,omitempty"`
+	// 担当者がダイジェストから開いたかを追跡しているタスク (キーは Notion のページ ID)
+	SeenTasks map[string]*seenTask `json:"seen_tasks,omitempty"`
+	// スヌーズされ、期限が来るまで載せないタスク (キーは Notion のページ ID)
+	MutedTasks map[string]*taskMute `json:"muted_tasks,omitempty"`
+	// アラートの判定のために前回確認したタスクの優先度とステータス (キーは Notion のページ ID)
+	AlertTasks map[string]*alertTask `json:"alert_tasks,omitempty"`
+	// 名前で指定された投稿先のチャンネル ID (キーは "<team ID>/<チャンネル名>")
+	ChannelIDs map[string]*channelCache `json:"channel_ids,omitempty"`
+	// --short-codes で掲載したタスクのコード (キーは NT-4F3A、コードが重なったタスクは複数入る)
+	ShortCodes map[string][]*shortCodeTask `json:"short_codes,omitempty"`
+	// --update-in-place で書き換えるその日のメッセージ (キーは "<ジョブ>:<team ID>/<チャンネル>")
+	DailyMessages map[string]*dailyMessage `json:"daily_messages,omitempty"`
+	// リレーション先のプロジェクトのページのタイトル (キーはページ ID)
+	PageTitles map[string]*pageTitleCache `json:"page_titles,omitempty"`
+}
+
+// taskMessage はタスクごとに投稿したメッセージとタスクの対応
+type taskMessage struct {
+	TeamID   string    `json:"team_id,omitempty"`
+	PageID   string    `json:"page_id"`
+	Title    string    `json:"title"`
+	URL      string    `json:"url"`
+	PostedAt time.Time `json:"posted_at"`
+}
+
+// タスクごとのメッセージを保持する期間
+const taskMessageRetention = 30 * 24 * time.Hour
+
+func taskMessageKey(channelID, ts string) string {
+	return channelID + ":" + ts
+}
+
+// stateStore は state を JSON ファイルとして読み書きする
+type stateStore struct {
+	path string
+	mu   sync.Mutex
+	// 読み込んだファイルが古いバージョンだった場合の元のバージョンと内容 (保存する前にバックアップする)
+	migratedFrom int
+	original     []byte
+}
+
+// 同じファイルの stateStore は 1 つにして、並行して実行するジョブ (--profiles) の読み書きを直列にする
+var (
+	stateStoresMu sync.Mutex
+	stateStores   = map[string]*stateStore{}
+)
+
+func newStateStore(path string) *stateStore {
+	if path == "" {
+		path = defaultStateFile
+	}
+	path = statePath(path)
+	stateStoresMu.Lock()
+	defer stateStoresMu.Unlock()
+	if s, ok := stateStores[path]; ok {
+		return s
+	}
+	s := &stateStore{path: path}
+	stateStores[path] = s
+	return s
+}
+
+// stateStoreFromEnv は NOTIFYER_STATE_FILE (未設定時は既定のファイル) の stateStore を返す
+func stateStoreFromEnv() *stateStore {
+	return newStateStore(os.Getenv(stateFileEnv))
+}
+
+// Load は現在の状態を読み込む。ファイルが無い場合は空の状態を返す
+func (s *stateStore) Load() (*state, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+// Update は状態を読み込んで fn で変更し、保存する
+func (s *stateStore) Update(fn func(*state) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.load()
+	if err != nil {
+		return err
+	}
+	if err := fn(st); err != nil {
+		return err
+	}
+	st.Version = stateVersion
+
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	if s.migratedFrom != 0 {
+		backup, err := backupStateFile(s.path, s.migratedFrom, s.original, systemClock{}.Now())
+		if err != nil {
+			return err
+		}
+		log.Printf("Migrated state file %s from v%d to v%d (backup: %s)", s.path, s.migratedFrom, stateVersion, backup)
+	}
+	if err := writeFileAtomic(s.path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	return nil
+}
+
+func (s *stateStore) load() (*state, error) {
+	st := &state{Version: stateVersion}
+	s.migratedFrom, s.original = 0, nil
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", s.path, err)
+	}
+	// 古いバージョンのファイルはメモリ上で移行し、保存するときにバックアップしてから書き換える
+	// 新しいバージョンのファイルは、知らない項目を消してしまわないよう読まない
+	if version := stateFileVersion(doc); version != stateVersion {
+		if err := migrateStateDoc(doc, version, stateVersion); err != nil {
+			return nil, fmt.Errorf("state file %s: %w", s.path, err)
+		}
+		s.migratedFrom, s.original = version, data
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, fmt.Errorf("failed to encode state: %w", err)
+		}
+	}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", s.path, err)
+	}
+	return st, nil
+}
+
+// writeFileAtomic は一時ファイルに書き込んでから置き換え、途中で失敗しても既存のファイルを壊さないようにする
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// stateMigration は状態ファイルのスキーマを From から From+1 に上げる変更と、その戻し方
+// JSON をそのまま書き換えるので、知らない項目も消さずに残る
+type stateMigration struct {
+	From        int
+	Description string
+	Up          func(doc map[string]any) error
+	Down        func(doc map[string]any) error
+}
+
+// 状態ファイルのマイグレーション (From の順に並べ、最後の From+1 が stateVersion になる)
+var stateMigrations = []stateMigration{
+	{
+		From:        1,
+		Description: "normalize Notion page IDs in task keys to the hyphenated form",
+		Up:          normalizeStatePageIDs,
+		// v1 のバイナリもハイフン付きの ID を読めるため、戻すときは何もしない
+		Down: func(doc map[string]any) error { return nil },
+	},
+}
+
+// ページ ID をキーにした状態の項目と、ページ ID を値に持つ項目
+var (
+	statePageKeyedFields = []string{"todoist_tasks", "seen_tasks", "muted_tasks", "alert_tasks", "page_titles"}
+	statePageIDFields    = []string{"task_messages", "short_codes"}
+)
+
+// ハイフンの無い Notion のページ ID
+var compactNotionIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{32}$`)
+
+// hyphenatedNotionID はハイフンの無いページ ID を 8-4-4-4-12 の形にする (それ以外はそのまま)
+func hyphenatedNotionID(id string) string {
+	if !compactNotionIDPattern.MatchString(id) {
+		return id
+	}
+	id = strings.ToLower(id)
+	return id[:8] + "-" + id[8:12] + "-" + id[12:16] + "-" + id[16:20] + "-" + id[20:]
+}
+
+// normalizeStatePageIDs はページ ID をハイフン付きにそろえる (mutes remove などで手で入れた ID と同じ形にする)
+// 同じページの項目が両方の形であれば、ハイフン付きの項目を残す
+func normalizeStatePageIDs(doc map[string]any) error {
+	for _, field := range statePageKeyedFields {
+		m, ok := doc[field].(map[string]any)
+		if !ok {
+			continue
+		}
+		for key, value := range m {
+			normalized := hyphenatedNotionID(key)
+			if normalized == key {
+				continue
+			}
+			delete(m, key)
+			if _, exists := m[normalized]; !exists {
+				m[normalized] = value
+			}
+		}
+	}
+	for _, field := range statePageIDFields {
+		m, ok := doc[field].(map[string]any)
+		if !ok {
+			continue
+		}
+		for _, value := range m {
+			entries, ok := value.([]any)
+			if !ok {
+				entries = []any{value}
+			}
+			for _, entry := range entries {
+				if e, ok := entry.(map[string]any); ok {
+					if id, ok := e["page_id"].(string); ok {
+						e["page_id"] = hyphenatedNotionID(id)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// stateFileVersion は状態ファイルのスキーマバージョンを返す (バージョンの無い古いファイルは 1)
+func stateFileVersion(doc map[string]any) int {
+	if v, ok := doc["version"].(float64); ok && v >= 1 {
+		return int(v)
+	}
+	return 1
+}
+
+// migrateStateDoc は状態ファイルの JSON を from から to のバージョンに上げるか戻す
+func migrateStateDoc(doc map[string]any, from, to int) error {
+	if to < 1 || to > stateVersion {
+		return configErrorf("unknown state version %d (this binary supports 1 to %d)", to, stateVersion)
+	}
+	if from > stateVersion {
+		return fmt.Errorf("state version %d is newer than this binary supports (%d); run `state migrate --to %d` with the newer binary first", from, stateVersion, stateVersion)
+	}
+	for v := from; v < to; v++ {
+		m := stateMigrations[v-1]
+		if err := m.Up(doc); err != nil {
+			return fmt.Errorf("failed to migrate state from v%d to v%d (%s): %w", v, v+1, m.Description, err)
+		}
+	}
+	for v := from; v > to; v-- {
+		m := stateMigrations[v-2]
+		if err := m.Down(doc); err != nil {
+			return fmt.Errorf("failed to migrate state from v%d to v%d (%s): %w", v, v-1, m.Description, err)
+		}
+	}
+	doc["version"] = to
+	return nil
+}
+
+// backupStateFile はマイグレーションの前の状態ファイルを <ファイル>.v<バージョン>.<日時>.bak に残す
+// 前のマイグレーションのバックアップを上書きしないよう毎回新しいファイルに書き、書けなければマイグレーションを止める
+func backupStateFile(path string, version int, data []byte, now time.Time) (string, error) {
+	base := fmt.Sprintf("%s.v%d.%s", path, version, now.UTC().Format("20060102T150405Z"))
+	for i := 1; i <= 100; i++ {
+		backup := base + ".bak"
+		if i > 1 {
+			backup = fmt.Sprintf("%s-%d.bak", base, i)
+		}
+		f, err := os.OpenFile(backup, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to back up state file: %w", err)
+		}
+		_, err = f.Write(data)
+		if err == nil {
+			err = f.Sync()
+		}
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(backup)
+			return "", fmt.Errorf("failed to back up state file: %w", err)
+		}
+		return backup, nil
+	}
+	return "", fmt.Errorf("failed to back up state file: too many backups named %s*.bak", base)
+}
+
+var stateCmd = &cobra.Command{
+	Use:   "state",
+	Short: "Inspect and migrate the state file (NOTIFYER_STATE_FILE).",
+}
+
+var stateMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade or roll back the state file to a schema version, keeping a timestamped backup of the original.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		to, _ := cmd.Flags().GetInt("to")
+		if to == 0 {
+			to = stateVersion
+		}
+		store := stateStoreFromEnv()
+		store.mu.Lock()
+		defer store.mu.Unlock()
+
+		data, err := os.ReadFile(store.path)
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(cmd.OutOrStdout(), "No state file at %s.\n", store.path)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read state file: %w", err)
+		}
+		var doc map[string]any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to parse state file %s: %w", store.path, err)
+		}
+		from := stateFileVersion(doc)
+		if from == to {
+			fmt.Fprintf(cmd.OutOrStdout(), "State file %s is already at v%d.\n", store.path, to)
+			return nil
+		}
+		if err := migrateStateDoc(doc, from, to); err != nil {
+			return err
+		}
+		backup, err := backupStateFile(store.path, from, data, systemClock{}.Now())
+		if err != nil {
+			return err
+		}
+		migrated, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode state: %w", err)
+		}
+		if err := writeFileAtomic(store.path, migrated, 0o600); err != nil {
+			return fmt.Errorf("failed to save state: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Migrated %s from v%d to v%d (backup: %s)\n", store.path, from, to, backup)
+		return nil
+	},
+}
+
+func init() {
+	stateMigrateCmd.Flags().Int("to", 0, "Schema version to migrate to (default: the version this binary writes)")
+	stateCmd.AddCommand(stateMigrateCmd)
+	rootCmd.AddCommand(stateCmd)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestBackupStateFileKeepsEveryBackup は同じバージョンから何度マイグレーションしても、前のバックアップを上書きしないことを確かめる
+func TestBackupStateFileKeepsEveryBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	contents := []string{`{"version":1,"a":1}`, `{"version":1,"a":2}`, `{"version":1,"a":3}`}
+	backups := map[string]string{}
+	for i, content := range contents {
+		at := now
+		if i == 2 {
+			at = now.Add(time.Hour)
+		}
+		backup, err := backupStateFile(path, 1, []byte(content), at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := backups[backup]; ok {
+			t.Fatalf("backup %s was reused", backup)
+		}
+		backups[backup] = content
+	}
+	for backup, want := range backups {
+		got, err := os.ReadFile(backup)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %s, want %s", backup, got, want)
+		}
+	}
+}
+
+func TestBackupStateFileFailsWhenUnwritable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "state.json")
+	if _, err := backupStateFile(path, 1, []byte(`{}`), time.Now()); err == nil {
+		t.Fatal("expected an error when the backup cannot be written")
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jomei/notionapi"
+	"github.com/spf13/cobra"
+
+	"rainierrr/notion-notifyer/pkg/notion"
+)
+
+// statusConfig は取得する (未完了として扱う) スケジュールステータス (設定ファイルの statuses)
+// include と groups がどちらも空なら exclude 以外のすべてを取得し、3 つとも空なら組み込みの一覧 (notion.DefaultStatuses) を使う
+type statusConfig struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+	// ステータスのグループの名前 (To-do・In progress など)。データベースのスキーマから選択肢の名前に読み替える
+	Groups []string `yaml:"groups"`
+}
+
+// 設定ファイル (または --statuses などのフラグ) の取得するステータス
+var configuredStatuses statusConfig
+
+func (c statusConfig) empty() bool {
+	return len(c.Include) == 0 && len(c.Exclude) == 0 && len(c.Groups) == 0
+}
+
+func validateStatuses(c statusConfig) error {
+	if len(c.Include) > 0 && len(c.Groups) == 0 && !slices.ContainsFunc(c.Include, func(s string) bool { return !slices.Contains(c.Exclude, s) }) {
+		return fmt.Errorf("statuses: every status in include is also in exclude, so no task would be fetched")
+	}
+	return nil
+}
+
+// apply は Fetcher に取得するステータスを設定する。groupOptions はグループから読み替えたステータス
+func (c statusConfig) apply(f *notion.Fetcher, groupOptions []string) {
+	switch {
+	case c.empty():
+		f.Statuses, f.AnyStatus = notion.DefaultStatuses, false
+	case len(c.Include) == 0 && len(c.Groups) == 0:
+		f.Statuses, f.AnyStatus = nil, true
+	default:
+		statuses := slices.Clone(c.Include)
+		for _, option := range groupOptions {
+			if !slices.Contains(statuses, option) {
+				statuses = append(statuses, option)
+			}
+		}
+		// グループがデータベースに無くても、全件を取得しないよう空の一覧にする
+		f.Statuses, f.AnyStatus = append([]string{}, statuses...), false
+	}
+	f.ExcludeStatuses = c.Exclude
+}
+
+// ステータスのグループから読み替えたステータス (データベース ID → 名前)
+// スキーマはめったに変わらないため、プロセスの間は読み直さない
+var (
+	statusGroupsMu    sync.Mutex
+	statusGroupsCache = map[string][]string{}
+)
+
+// statusGroupOptions はステータスのプロパティの、groups のいずれかに属する選択肢の名前を返す
+// グループの名前は大文字と小文字を区別しない。見つからないグループの名前も返す
+func statusGroupOptions(config *notionapi.StatusPropertyConfig, groups []string) (options, unknown []string) {
+	for _, name := range groups {
+		i := slices.IndexFunc(config.Status.Groups, func(g notionapi.GroupConfig) bool { return strings.EqualFold(g.Name, name) })
+		if i < 0 {
+			unknown = append(unknown, name)
+			continue
+		}
+		for _, o := range config.Status.Options {
+			if slices.Contains(config.Status.Groups[i].OptionIDs, notionapi.ObjectID(o.ID)) && !slices.Contains(options, o.Name) {
+				options = append(options, o.Name)
+			}
+		}
+	}
+	return options, unknown
+}
+
+// loadStatusGroups はデータベースのスキーマを読み、設定のグループに属するステータスを返す
+// 読めないときは警告を出し、グループを使わずに include のステータスだけを取得する
+func loadStatusGroups(ctx context.Context, client *notionapi.Client, dbID string) []string {
+	statusGroupsMu.Lock()
+	defer statusGroupsMu.Unlock()
+	if options, ok := statusGroupsCache[dbID]; ok {
+		return options
+	}
+	db, err := client.Database.Get(ctx, notionapi.DatabaseID(dbID))
+	if err != nil {
+		log.Printf("Warning: Failed to read the %s groups of database %s: %v", scheduleStatusProp, dbID, err)
+		return nil
+	}
+	config, ok := db.Properties[scheduleStatusProp].(*notionapi.StatusPropertyConfig)
+	if !ok {
+		log.Printf("Warning: %s of database %s is not a status property; status groups are ignored", scheduleStatusProp, dbID)
+		statusGroupsCache[dbID] = nil
+		return nil
+	}
+	options, unknown := statusGroupOptions(config, configuredStatuses.Groups)
+	if len(unknown) > 0 {
+		log.Printf("Warning: Database %s has no status groups %s", dbID, strings.Join(unknown, ", "))
+	}
+	statusGroupsCache[dbID] = options
+	return options
+}
+
+// statusFetcher はデータベースのステータスのグループを読み替えた Fetcher を返す
+// グループを設定していなければスキーマを読まない
+func statusFetcher(ctx context.Context, client *notionapi.Client, dbID string) *notion.Fetcher {
+	f := notionFetcher(client)
+	if len(configuredStatuses.Groups) > 0 {
+		configuredStatuses.apply(f, loadStatusGroups(ctx, client, dbID))
+	}
+	return f
+}
+
+// applyStatusFlags は --statuses・--exclude-statuses・--status-groups のどれかが指定されていれば、
+// 設定ファイルの statuses をフラグの内容で置き換える
+func applyStatusFlags(cmd *cobra.Command) error {
+	flags := cmd.Flags()
+	if !flags.Changed("statuses") && !flags.Changed("exclude-statuses") && !flags.Changed("status-groups") {
+		return nil
+	}
+	var c statusConfig
+	c.Include, _ = flags.GetStringSlice("statuses")
+	c.Exclude, _ = flags.GetStringSlice("exclude-statuses")
+	c.Groups, _ = flags.GetStringSlice("status-groups")
+	if err := validateStatuses(c); err != nil {
+		return err
+	}
+	configuredStatuses = c
+	return nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringSlice("statuses", nil, "Schedule statuses of the tasks to fetch (replaces statuses in the config file; default: a built-in list)")
+	rootCmd.PersistentFlags().StringSlice("exclude-statuses", nil, "Schedule statuses to leave out (e.g. Done,Cancelled); alone, fetches every other status")
+	rootCmd.PersistentFlags().StringSlice("status-groups", nil, "Fetch the statuses in these status groups of the database (e.g. \"To-do,In progress\")")
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/jomei/notionapi"
+)
+
+// tagFilter はタグ (マルチセレクト) の絞り込み (--include-tag / --exclude-tag)
+// Include が空でなければそのいずれかのタグが付いたタスクだけを載せ、Exclude のタグが付いたタスクは常に載せない
+type tagFilter struct {
+	Include []string
+	Exclude []string
+}
+
+func (f tagFilter) active() bool {
+	return len(f.Include) > 0 || len(f.Exclude) > 0
+}
+
+// allowsTags はタグの付いたタスクを載せてよいかを返す
+func (f tagFilter) allowsTags(tags []string) bool {
+	hasAny := func(want []string) bool {
+		return slices.ContainsFunc(tags, func(tag string) bool { return slices.Contains(want, tag) })
+	}
+	if hasAny(f.Exclude) {
+		return false
+	}
+	return len(f.Include) == 0 || hasAny(f.Include)
+}
+
+// apply は載せてよいタスクだけを返す
+func (f tagFilter) apply(tasks []Task) []Task {
+	if !f.active() {
+		return tasks
+	}
+	var filtered []Task
+	for _, task := range tasks {
+		if f.allowsTags(task.Tags) {
+			filtered = append(filtered, task)
+		}
+	}
+	return filtered
+}
+
+func renderMultiSelectProperty(value notionapi.Property) string {
+	p, ok := value.(*notionapi.MultiSelectProperty)
+	if !ok {
+		return ""
+	}
+	var names []string
+	for _, option := range p.MultiSelect {
+		names = append(names, option.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func init() {
+	registerPropertyRenderer(notionapi.PropertyTypeMultiSelect, renderMultiSelectProperty)
+	rootCmd.Flags().StringSlice("include-tag", nil, "Only notify tasks with at least one of these tags (multi-select property, e.g. client-work)")
+	rootCmd.Flags().StringSlice("exclude-tag", nil, "Never notify tasks with any of these tags")
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// 組み込みの通知の送り先 (--target)。それぞれのファイルの init で RegisterNotifier に登録している
+const (
+	targetSlack   = "slack"
+	targetDiscord = "discord"
+	targetTeams   = "teams"
+	targetEmail   = "email"
+	targetWebhook = "webhook"
+	targetMQTT    = "mqtt"
+)
+
+// availableTargets は --target に指定できる名前を返す
+func availableTargets() []string {
+	return notifierNames
+}
+
+// parseTargets は --target の指定を検証する
+func parseTargets(names []string) (map[string]bool, error) {
+	targets := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if !slices.Contains(availableTargets(), name) {
+			return nil, fmt.Errorf("unknown target %q (available: %s)", name, strings.Join(availableTargets(), ", "))
+		}
+		targets[name] = true
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("at least one target is required")
+	}
+	return targets, nil
+}
+
+// targetsFromFlags は --target の送り先を返す
+// --target を指定しなければ、WEBHOOK_URL があるときは既定の送り先に加えて Webhook にも送る
+func targetsFromFlags(flags *pflag.FlagSet) (map[string]bool, error) {
+	names, _ := flags.GetStringSlice("target")
+	targets, err := parseTargets(names)
+	if err != nil {
+		return nil, err
+	}
+	if !flags.Changed("target") && os.Getenv(webhookURLEnv) != "" {
+		targets[targetWebhook] = true
+		log.Printf("%s is set, so the digest is also sent to %s (set --target to choose the targets explicitly)", webhookURLEnv, targetWebhook)
+	}
+	return targets, nil
+}
+
+// plainTaskDetails は Slack の mrkdwn を使わずに期限日・優先度などの詳細を 1 行にまとめる (Discord と Teams で使う)
+func plainTaskDetails(task Task) (string, error) {
+	strTime, err := formatDueDate(task)
+	if err != nil {
+		return "", fmt.Errorf("failed to format due date for task %s: %w", task.Title, err)
+	}
+	details := []string{fmt.Sprintf("%s: %s", tr("task.due"), strTime)}
+	if task.Priority != "" {
+		details = append(details, fmt.Sprintf("%s: %s", tr("task.priority"), priorityLabel(task.Priority)))
+	}
+	if task.Type != "" {
+		details = append(details, fmt.Sprintf("%s: %s", tr("task.type"), task.Type))
+	}
+	if task.ScheduleStatus != "" {
+		details = append(details, fmt.Sprintf("%s: %s", tr("task.status"), task.ScheduleStatus))
+	}
+	if task.Workload != 0 {
+		details = append(details, fmt.Sprintf("%s: %.2f", tr("task.workload"), task.Workload))
+	}
+	return strings.Join(details, " | "), nil
+}
+
+// partTask は時間帯の小見出しを付けて 1 列に並べたタスク
+type partTask struct {
+	task  Task
+	title string // 時間帯の最初のタスクにだけ小見出しを付ける
+}
+
+// flattenParts は時間帯ごとのタスクを 1 列に並べる
+func flattenParts(parts []templateSectionPart) []partTask {
+	var tasks []partTask
+	for _, part := range parts {
+		for i, task := range part.Tasks {
+			t := partTask{task: task}
+			if i == 0 {
+				t.title = part.Title
+			}
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks
+}
+
+func init() {
+	rootCmd.Flags().StringSlice("target", []string{targetSlack}, fmt.Sprintf("Where to send the digest (%s, or a registered notifier); discord posts to $%s, teams to $%s, email through $%s to $%s, webhook POSTs the JSON payload to $%s and mqtt publishes task counts for Home Assistant to $%s", strings.Join([]string{targetSlack, targetDiscord, targetTeams, targetEmail, targetWebhook, targetMQTT}, ", "), discordWebhookEnv, teamsWebhookEnv, smtpHostEnv, emailToEnv, webhookURLEnv, mqttURLEnv))
+}
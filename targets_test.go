@@ -0,0 +1,42 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+// TestTargetsFromFlagsWebhookURL は WEBHOOK_URL で Webhook に送るのが --target を指定しないときだけであることを確かめる
+func TestTargetsFromFlagsWebhookURL(t *testing.T) {
+	discardLogs(t)
+	tests := []struct {
+		name       string
+		args       []string
+		webhookURL string
+		want       []string
+	}{
+		{"default", nil, "", []string{targetSlack}},
+		{"default with WEBHOOK_URL", nil, "https://example.com/hook", []string{targetSlack, targetWebhook}},
+		{"explicit target ignores WEBHOOK_URL", []string{"--target", "slack"}, "https://example.com/hook", []string{targetSlack}},
+		{"explicit webhook", []string{"--target", "discord,webhook"}, "https://example.com/hook", []string{targetDiscord, targetWebhook}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(webhookURLEnv, tt.webhookURL)
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			flags.StringSlice("target", []string{targetSlack}, "")
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			targets, err := targetsFromFlags(flags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := slices.Sorted(maps.Keys(targets)); !slices.Equal(got, tt.want) {
+				t.Errorf("targets = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
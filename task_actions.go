@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/jomei/notionapi"
+	"github.com/slack-go/slack"
+)
+
+// タスクの「完了」「スヌーズ」ボタンの action_id
+const (
+	markDoneActionID   = "mark_done"
+	snoozeTaskActionID = "snooze_task"
+	// ボタンを並べる actions ブロックの block_id の接頭辞
+	taskActionsBlockPrefix = "task_actions:"
+)
+
+// taskActionValue は完了・スヌーズボタンに持たせる値
+type taskActionValue struct {
+	PageID string `json:"id"`
+	Title  string `json:"title"`
+	Days   int    `json:"days,omitempty"` // スヌーズで期限を延ばす日数
+}
+
+// taskActionsBlock はタスクを完了にするボタンと、期限を snoozeDays 日延ばすボタンを並べたブロックを作る
+func taskActionsBlock(task Task, snoozeDays int) (*slack.ActionBlock, error) {
+	doneValue, err := json.Marshal(taskActionValue{PageID: string(task.ID), Title: task.Title})
+	if err != nil {
+		return nil, err
+	}
+	snoozeValue, err := json.Marshal(taskActionValue{PageID: string(task.ID), Title: task.Title, Days: snoozeDays})
+	if err != nil {
+		return nil, err
+	}
+	done := slack.NewButtonBlockElement(markDoneActionID, string(doneValue),
+		slack.NewTextBlockObject(slack.PlainTextType, tr("button.done"), true, false)).WithStyle(slack.StylePrimary)
+	snooze := slack.NewButtonBlockElement(snoozeTaskActionID, string(snoozeValue),
+		slack.NewTextBlockObject(slack.PlainTextType, tr("button.snooze", snoozeDays), true, false))
+	return slack.NewActionBlock(taskActionsBlockPrefix+string(task.ID), done, snooze), nil
+}
+
+// handleMarkDone はボタンのタスクのスケジュールステータスを Done にし、メッセージのボタンを結果に置き換える
+func handleMarkDone(ctx context.Context, env *interactionEnv, callback slack.InteractionCallback, action *slack.BlockAction) error {
+	var value taskActionValue
+	if err := json.Unmarshal([]byte(action.Value), &value); err != nil {
+		return fmt.Errorf("invalid button value: %w", err)
+	}
+	notionToken, _, err := notionSourceFromEnv(env.store)
+	if err != nil {
+		return err
+	}
+	if err := markTaskDone(ctx, notionapi.NewClient(notionapi.Token(notionToken)), notionapi.ObjectID(value.PageID)); err != nil {
+		return err
+	}
+	log.Printf("Task %s marked as %s by %s", value.Title, doneStatus, callback.User.ID)
+	return replaceTaskActions(ctx, env, callback, action.BlockID, tr("action.done", callback.User.ID))
+}
+
+// handleSnoozeButton はボタンのタスクの期限を延ばし、メッセージのボタンを結果に置き換える
+func handleSnoozeButton(ctx context.Context, env *interactionEnv, callback slack.InteractionCallback, action *slack.BlockAction) error {
+	var value taskActionValue
+	if err := json.Unmarshal([]byte(action.Value), &value); err != nil {
+		return fmt.Errorf("invalid button value: %w", err)
+	}
+	days := value.Days
+	if days < 1 {
+		days = snoozeDays
+	}
+	until, err := snoozePage(ctx, env, value.PageID, value.Title, callback.User.ID, days)
+	if err != nil {
+		return err
+	}
+	log.Printf("Task %s snoozed until %s by %s", value.Title, until.Format("2006-01-02"), callback.User.ID)
+	return replaceTaskActions(ctx, env, callback, action.BlockID, tr("action.snoozed", days, until.Format("1/2"), callback.User.ID))
+}
+
+// replaceTaskActions はボタンが押されたメッセージの actions ブロックを text の context ブロックに置き換える
+func replaceTaskActions(ctx context.Context, env *interactionEnv, callback slack.InteractionCallback, blockID, text string) error {
+	blocks := callback.Message.Blocks.BlockSet
+	replaced := false
+	for i, block := range blocks {
+		if b, ok := block.(*slack.ActionBlock); ok && b.BlockID == blockID {
+			blocks[i] = slack.NewContextBlock(blockID, slack.NewTextBlockObject(slack.MarkdownType, text, false, false))
+			replaced = true
+		}
+	}
+	if !replaced {
+		return fmt.Errorf("block %s not found in the message", blockID)
+	}
+
+	slackClient, err := env.slackClient(ctx, callback.Team.ID)
+	if err != nil {
+		return err
+	}
+	_, _, _, err = slackClient.UpdateMessageContext(ctx, callback.Container.ChannelID, callback.Container.MessageTs,
+		slack.MsgOptionBlocks(blocks...), slack.MsgOptionText(callback.Message.Text, false))
+	return err
+}
+
+func init() {
+	registerInteraction(markDoneActionID, handleMarkDone)
+	registerInteraction(snoozeTaskActionID, handleSnoozeButton)
+}
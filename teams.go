@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"unicode/utf8"
+
+	"github.com/spf13/pflag"
+)
+
+// Microsoft Teams 関連
+const (
+	teamsWebhookEnv = "TEAMS_WEBHOOK_URL"
+
+	// Incoming Webhook のメッセージは 28KB までなので、1 枚のカードの文字数をこれ以下に抑える
+	maxTeamsCardChars = 12000
+)
+
+// teamsElement は Adaptive Card の本文の要素 (TextBlock や Container)
+type teamsElement map[string]any
+
+// teamsMessage は Incoming Webhook に送る、Adaptive Card を 1 枚添付したメッセージ
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string         `json:"$schema"`
+	Type    string         `json:"type"`
+	Version string         `json:"version"`
+	Body    []teamsElement `json:"body"`
+	MSTeams map[string]any `json:"msteams,omitempty"`
+}
+
+func newTeamsMessage(body []teamsElement) teamsMessage {
+	return teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: teamsCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body:    body,
+				MSTeams: map[string]any{"width": "Full"},
+			},
+		}},
+	}
+}
+
+func teamsText(text string, attrs ...any) teamsElement {
+	e := teamsElement{"type": "TextBlock", "text": text, "wrap": true}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		e[attrs[i].(string)] = attrs[i+1]
+	}
+	return e
+}
+
+// sectionTeamsColor はセクションの見出しの色を返す
+// 期限切れのセクションは attention (赤)、今日までは warning (黄)、それ以外は accent (青)
+func sectionTeamsColor(name string) string {
+	if b, ok := findBucket(name); ok && b.Days != nil {
+		switch {
+		case *b.Days <= 0:
+			return "attention"
+		case *b.Days == 1:
+			return "warning"
+		}
+	}
+	if name == sectionHours {
+		return "warning"
+	}
+	return "accent"
+}
+
+// teamsSectionHeader はセクションの見出しを区切り線付きで作る
+func teamsSectionHeader(name, title string) teamsElement {
+	return teamsText(title, "weight", "Bolder", "size", "Medium", "color", sectionTeamsColor(name), "separator", true, "spacing", "Large")
+}
+
+// buildTeamsMessages はセクションごとに分けた Adaptive Card にする
+// 大きすぎる場合は複数のカードに分け、途中で分けたセクションには見出しを繰り返す
+func buildTeamsMessages(groups []TaskGroup, runNumber string) ([]teamsMessage, error) {
+	var cards [][]teamsElement
+	body := []teamsElement{teamsText(tr("digest.header"), "weight", "Bolder", "size", "Large")}
+	chars := 0
+	add := func(name, title string, elements ...teamsElement) {
+		size := 0
+		for _, e := range elements {
+			size += utf8.RuneCountInString(e["text"].(string))
+		}
+		if chars+size > maxTeamsCardChars && len(body) > 0 {
+			cards = append(cards, body)
+			body = []teamsElement{teamsSectionHeader(name, tr("section.continued", title))}
+			chars = 0
+		}
+		body = append(body, elements...)
+		chars += size
+	}
+
+	for _, group := range groups {
+		name, title := group.Name, group.Title
+		add(name, title, teamsSectionHeader(name, title))
+		for _, t := range flattenParts(group.Parts) {
+			details, err := plainTaskDetails(t.task)
+			if err != nil {
+				return nil, err
+			}
+			var elements []teamsElement
+			if t.title != "" {
+				elements = append(elements, teamsText(t.title, "isSubtle", true, "weight", "Bolder"))
+			}
+			elements = append(elements,
+				teamsText(fmt.Sprintf("**[%s](%s)**", taskTitle(t.task), t.task.URL), "spacing", "Small"),
+				teamsText(details, "isSubtle", true, "size", "Small", "spacing", "None"))
+			add(name, title, elements...)
+		}
+	}
+	if footer := footerText(runNumber); footer != "" {
+		body = append(body, teamsText(footer, "isSubtle", true, "size", "Small", "separator", true))
+	}
+	cards = append(cards, body)
+
+	messages := make([]teamsMessage, 0, len(cards))
+	for _, card := range cards {
+		messages = append(messages, newTeamsMessage(card))
+	}
+	return messages, nil
+}
+
+// teamsTarget は Teams の Incoming Webhook (Workflows の Webhook を含む) の送信先
+type teamsTarget struct {
+	URL string
+}
+
+func newTeamsTargetFromEnv() *teamsTarget {
+	url := os.Getenv(teamsWebhookEnv)
+	if url == "" {
+		return nil
+	}
+	return &teamsTarget{URL: url}
+}
+
+// Send はダイジェストを Teams に送る (ドライランでは JSON を出力する)
+func (t *teamsTarget) Send(ctx context.Context, run digestRun, groups []TaskGroup) error {
+	messages, err := buildTeamsMessages(groups, run.RunNumber)
+	if err != nil {
+		return fmt.Errorf("build Teams messages: %w", err)
+	}
+	if run.DryRun {
+		return printTeamsDryRun(os.Stdout, messages)
+	}
+	if err := t.post(ctx, run.httpClient("teams"), messages); err != nil {
+		return err
+	}
+	log.Printf("[%s] Teams message sent", run.JobName)
+	return nil
+}
+
+// post はカードを順に Webhook に POST する
+func (t *teamsTarget) post(ctx context.Context, client *http.Client, messages []teamsMessage) error {
+	for _, msg := range messages {
+		body, err := json.Marshal(msg)
+		if err != nil {
+			return fmt.Errorf("failed to encode Teams message: %w", err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to post to Teams: %w", err)
+		}
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("teams returned %s: %s", resp.Status, bytes.TrimSpace(errBody))
+		}
+	}
+	return nil
+}
+
+// printTeamsDryRun は Teams に送るカードの JSON を出力する
+func printTeamsDryRun(w io.Writer, messages []teamsMessage) error {
+	for i, msg := range messages {
+		part := ""
+		if len(messages) > 1 {
+			part = fmt.Sprintf(" [%d/%d]", i+1, len(messages))
+		}
+		fmt.Fprintf(w, "===== teams%s =====\n", part)
+		data, err := json.MarshalIndent(msg, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode Teams message: %w", err)
+		}
+		fmt.Fprintln(w, string(data))
+	}
+	return nil
+}
+
+func init() {
+	RegisterNotifier(targetTeams, func(flags *pflag.FlagSet) (Notifier, error) {
+		t := newTeamsTargetFromEnv()
+		if t == nil {
+			return nil, fmt.Errorf("--target teams requires %s", teamsWebhookEnv)
+		}
+		return t, nil
+	})
+}
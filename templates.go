@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/spf13/cobra"
+)
+
+// 組み込みのメッセージのテンプレート (--template を指定しなければこれで描画する)
+//
+//go:embed templates/default.tmpl
+var defaultTemplateText string
+
+var defaultMessageTemplate = template.Must(parseMessageTemplate("default.tmpl", defaultTemplateText))
+
+// templateSection はテンプレートに渡すセクション
+type templateSection struct {
+	Name  string // overdue、today などのセクション名
+	Title string // 見出し (例: "❗️ 期限切れ")
+	Tasks []Task
+	Parts []templateSectionPart // --time-of-day のセクションでは時間帯ごとに分けたタスク (それ以外は nil)
+}
+
+// templateData はメッセージのテンプレートに渡す値
+type templateData struct {
+	Now       time.Time
+	RunNumber string
+	Build     string // リリースビルドのバージョン (例: "notion-notifyer v1.2.0"、開発中のビルドでは空)
+	Tasks     []Task
+	Sections  []templateSection // タスクのあるセクションだけを表示順に並べたもの
+}
+
+// newTemplateData はタスクをセクションに分けてテンプレートに渡す値を作る
+func newTemplateData(tasks []Task, opts renderOptions) templateData {
+	groups := groupTasksBySection(tasks, opts.Now, opts.WithinHours)
+	sections := opts.Sections
+	if sections == nil {
+		sections = defaultSectionOrder()
+	}
+	data := templateData{Now: opts.Now, RunNumber: opts.RunNumber, Build: buildFooter(), Tasks: tasks}
+	if opts.GroupByProject {
+		data.Sections = projectSections(tasks)
+		return data
+	}
+	for _, name := range sections {
+		if len(groups[name]) == 0 {
+			continue
+		}
+		section := templateSection{Name: name, Title: sectionTitle(name, opts.WithinHours), Tasks: groups[name]}
+		if slices.Contains(opts.TimeOfDaySections, name) {
+			section.Parts = splitTimeOfDay(section.Tasks)
+		}
+		data.Sections = append(data.Sections, section)
+	}
+	return data
+}
+
+// テンプレートで使える関数
+var templateFuncs = template.FuncMap{
+	// json は値を JSON にする (Block Kit の JSON を組み立てるときの文字列のエスケープに使う)
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	// formatDate はタスクの期限日を表示用にする
+	"formatDate": func(task Task) (string, error) {
+		return formatDueDate(task)
+	},
+	"join": strings.Join,
+	// title はユニーク ID を前に付けたタイトルを返す (properties.unique_id が無ければタイトルのまま)
+	"title": taskTitle,
+	// shortCode はタスクの短いコード (NT-4F3A) を返す
+	"shortCode": shortCode,
+	// t は --lang の言語のメッセージを返す ({{t "digest.header"}})
+	"t": tr,
+	// progress はタスクの進捗バーを返す (進捗率が無ければ空)
+	"progress": func(task Task) string {
+		if task.Progress == nil {
+			return ""
+		}
+		return progressBar(*task.Progress)
+	},
+	// property は追加のプロパティ (properties.extra) を種類に合わせて表示する ({{property . "Estimate"}})
+	"property": taskProperty,
+	// truncate は n 文字を超える部分を、できれば単語や行の区切りで切り捨てる ({{.Memo | truncate 100}})
+	"truncate": func(n int, s string) string { return truncateText(s, n, "...") },
+	// emoji は優先度 (High など) やセクション名 (overdue など) の絵文字を返す。それ以外は Slack の :name: にする
+	"emoji": func(name string) string {
+		if e, ok := priorityEmojiFor(name); ok {
+			return e
+		}
+		if isKnownSection(name) {
+			return sectionEmoji(name)
+		}
+		if name == "" {
+			return ""
+		}
+		return ":" + name + ":"
+	},
+}
+
+// blockBuilder はテンプレートの関数で追加されたブロックを集める
+type blockBuilder struct {
+	opts   renderOptions
+	blocks []slack.Block
+}
+
+// funcs はブロックを追加するテンプレート関数と、描画のオプションを使うテンプレート関数を返す
+func (b *blockBuilder) funcs() template.FuncMap {
+	add := func(blocks ...slack.Block) string {
+		b.blocks = append(b.blocks, blocks...)
+		return ""
+	}
+	return template.FuncMap{
+		"header": func(text string) string {
+			return add(slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, text, true, false)))
+		},
+		"divider": func() string { return add(slack.NewDividerBlock()) },
+		"section": func(text string) string {
+			return add(slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil))
+		},
+		"context": func(text string) string {
+			return add(slack.NewContextBlock("", slack.NewTextBlockObject(slack.PlainTextType, text, false, false)))
+		},
+		// sectionHeader はセクションの見出しを追加する (メッセージを分けたときに続きにも付く)
+		"sectionHeader": func(s templateSection) string {
+			return add(sectionHeaderBlock(s.Name, s.Title))
+		},
+		// taskRow はタスクの行を追加し、オプションに応じてボタンを付ける
+		"taskRow": func(task Task, text string) (string, error) {
+			var err error
+			b.blocks, err = appendTaskRow(b.blocks, task, text, b.opts)
+			return "", err
+		},
+		// details は期限日・優先度などの詳細を 1 行にまとめる (行が長すぎればメモ → 詳細の順に省く)
+		"details": func(task Task) (string, error) {
+			return fitTaskDetails(task, b.opts)
+		},
+		// memo はメモを書式とリンクを残した Slack の mrkdwn にする ({{memo .}}、.Memo は書式の無い文字列)
+		// ユーザーのメンションは投稿先の Slack ユーザーがわかれば Slack のメンションにする
+		"memo": func(task Task) string {
+			return memoMrkdwn(task, 0, b.opts.SlackUsers)
+		},
+		"collapsed": func(name string) bool {
+			return slices.Contains(b.opts.CollapsedSections, name)
+		},
+		"collapsedSection": func(s templateSection) (string, error) {
+			var err error
+			b.blocks, err = appendCollapsedSection(b.blocks, s.Name, s.Title, s.Tasks, b.opts)
+			return "", err
+		},
+		"calendar": func() string {
+			if b.opts.CalendarTasks == nil {
+				return ""
+			}
+			return add(buildCalendarStrip(b.opts.CalendarTasks, b.opts.Now)...)
+		},
+		"unopened": func() string {
+			if len(b.opts.Unopened) == 0 {
+				return ""
+			}
+			return add(slack.NewDividerBlock(), buildUnopenedSection(b.opts.Unopened))
+		},
+	}
+}
+
+// parseMessageTemplate はメッセージのテンプレートを読み込む
+func parseMessageTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(templateFuncs).Funcs((&blockBuilder{}).funcs()).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	return t, nil
+}
+
+// loadMessageTemplate はテンプレートのファイルを読み込む。builtin なら組み込みのテンプレートを返す
+// ファイルは組み込みのテンプレートに重ねて読み込むため、{{define "task"}} などの部品だけを書けばその部品だけを置き換えられる
+// 部品の定義以外の本文があれば、その本文でレイアウト全体を置き換える (組み込みの部品は呼び出せる)
+func loadMessageTemplate(path string) (*template.Template, error) {
+	if path == builtinTemplateName {
+		return defaultMessageTemplate, nil
+	}
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+	base, err := defaultMessageTemplate.Clone()
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Base(path)
+	t, err := base.New(name).Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	if t.Tree == nil || parse.IsEmptyTree(t.Tree.Root) {
+		return base, nil
+	}
+	return t, nil
+}
+
+// renderTemplateBlocks はテンプレートを実行してメッセージのブロックを作る
+// ブロックを追加する関数を使っていればそのブロックを、使っていなければ出力を本文にする
+// 出力が { で始まれば Block Kit の JSON ({"blocks": [...]}) として読み込む
+func renderTemplateBlocks(t *template.Template, data templateData, opts renderOptions) ([]slack.Block, error) {
+	b := &blockBuilder{opts: opts}
+	clone, err := t.Clone()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := clone.Funcs(b.funcs()).Execute(&buf, data); err != nil {
+		return nil, &renderError{Err: fmt.Errorf("failed to render template %s: %w", t.Name(), err)}
+	}
+	text := strings.TrimSpace(buf.String())
+
+	switch {
+	case len(b.blocks) > 0:
+		if text != "" {
+			return nil, fmt.Errorf("template %s writes text as well as blocks (use the section function for text)", t.Name())
+		}
+		return b.blocks, nil
+	case text == "":
+		return nil, fmt.Errorf("template %s rendered an empty message", t.Name())
+	case strings.HasPrefix(text, "{"):
+		var message struct {
+			Blocks slack.Blocks `json:"blocks"`
+		}
+		if err := json.Unmarshal([]byte(text), &message); err != nil {
+			return nil, fmt.Errorf("template %s rendered invalid Block Kit JSON: %w", t.Name(), err)
+		}
+		return message.Blocks.BlockSet, nil
+	default:
+		return textSectionBlocks(text), nil
+	}
+}
+
+// textSectionBlocks はテキストを行の区切りで maxTextLength 以下のセクションに分ける
+func textSectionBlocks(text string) []slack.Block {
+	var blocks []slack.Block
+	var current []string
+	size := 0
+	flush := func() {
+		if len(current) > 0 {
+			blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, strings.Join(current, "\n"), false, false), nil, nil))
+		}
+		current, size = nil, 0
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if len([]rune(line)) > maxTextLength {
+			line = truncateText(line, maxTextLength-3, "...")
+		}
+		if size+len([]rune(line))+1 > maxTextLength {
+			flush()
+		}
+		current = append(current, line)
+		size += len([]rune(line)) + 1
+	}
+	flush()
+	return blocks
+}
+
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Print the built-in message template as a starting point for --template.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprint(cmd.OutOrStdout(), defaultTemplateText)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(templateCmd)
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// テナント定義ファイル
+const (
+	tenantsFileEnv     = "NOTIFYER_TENANTS_FILE"
+	defaultTenantsFile = "tenants.json"
+)
+
+// tenantsFile はテナント定義ファイルの内容
+type tenantsFile struct {
+	Tenants []tenant `json:"tenants"`
+}
+
+// tenant は独立して評価される通知の単位
+// 取得元の Notion、投稿先の Slack、スケジュールをそれぞれ持つ
+type tenant struct {
+	Name      string              `json:"name"`
+	Notion    tenantNotion        `json:"notion"`
+	Slack     []tenantDestination `json:"slack"`
+	DaysLater int                 `json:"days_later"`
+	// serve で実行する間隔 (例: "1h")。未設定のテナントは serve では実行しない
+	Interval string `json:"interval,omitempty"`
+	// メッセージのテンプレートのファイル (--template と同じ形式)。未設定なら組み込みのテンプレート
+	Template string `json:"template,omitempty"`
+
+	messageTemplate *template.Template // loadTenants で読み込んだ Template
+}
+
+// tenantNotion はテナントの取得元
+// トークンは token_env の環境変数か、init で認可した workspace_id のトークンを使う
+type tenantNotion struct {
+	TokenEnv    string `json:"token_env,omitempty"`
+	WorkspaceID string `json:"workspace_id,omitempty"`
+	DatabaseID  string `json:"database_id"`
+}
+
+// tenantDestination はテナントの投稿先
+// トークンは token_env の環境変数か、install で追加された team_id のトークンを使う
+type tenantDestination struct {
+	TokenEnv  string `json:"token_env,omitempty"`
+	TeamID    string `json:"team_id,omitempty"`
+	ChannelID string `json:"channel_id,omitempty"` // チャンネル ID か #チャンネル名
+	// この投稿先に載せる種類 (Type) と載せない種類
+	IncludeTypes []string `json:"include_types,omitempty"`
+	ExcludeTypes []string `json:"exclude_types,omitempty"`
+}
+
+func loadTenants(path string) ([]tenant, error) {
+	if path == "" {
+		path = defaultTenantsFile
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenants file: %w", err)
+	}
+	var f tenantsFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse tenants file %s: %w", path, err)
+	}
+
+	seen := map[string]bool{}
+	for i := range f.Tenants {
+		t := &f.Tenants[i]
+		if t.Name == "" {
+			return nil, fmt.Errorf("tenant #%d has no name", i+1)
+		}
+		if seen[t.Name] {
+			return nil, fmt.Errorf("duplicate tenant name %q", t.Name)
+		}
+		seen[t.Name] = true
+		if t.Notion.DatabaseID == "" {
+			return nil, fmt.Errorf("tenant %q: notion.database_id is required", t.Name)
+		}
+		if len(t.Slack) == 0 {
+			return nil, fmt.Errorf("tenant %q: at least one slack destination is required", t.Name)
+		}
+		if err := checkDaysLater(t.DaysLater); err != nil {
+			return nil, fmt.Errorf("tenant %q: %w", t.Name, err)
+		}
+		if t.Interval != "" {
+			interval, err := time.ParseDuration(t.Interval)
+			if err != nil {
+				return nil, fmt.Errorf("tenant %q: invalid interval %q: %w", t.Name, t.Interval, err)
+			}
+			if interval <= 0 {
+				return nil, fmt.Errorf("tenant %q: interval must be positive, got %q", t.Name, t.Interval)
+			}
+		}
+		if t.Template != "" {
+			if t.messageTemplate, err = loadMessageTemplate(t.Template); err != nil {
+				return nil, fmt.Errorf("tenant %q: %w", t.Name, err)
+			}
+		}
+	}
+	return f.Tenants, nil
+}
+
+// digestJob はテナントの設定からトークンを解決してジョブを作る
+// 状態ファイルはトークン更新のたびに変わるため、実行の直前に呼ぶこと
+func (t tenant) digestJob(clock Clock, store *stateStore) (digestJob, error) {
+	st, err := store.Load()
+	if err != nil {
+		return digestJob{}, err
+	}
+
+	job := digestJob{
+		Name:       t.Name,
+		DatabaseID: t.Notion.DatabaseID,
+		DaysLater:  t.DaysLater,
+		Template:   t.messageTemplate,
+		Store:      store,
+	}
+
+	job.NotionToken = t.notionToken(st)
+	if job.NotionToken == "" {
+		return digestJob{}, fmt.Errorf("tenant %q: no Notion token (set notion.token_env or authorize notion.workspace_id with init)", t.Name)
+	}
+
+	for _, d := range t.Slack {
+		switch {
+		case d.TokenEnv != "":
+			token := os.Getenv(d.TokenEnv)
+			if token == "" || d.ChannelID == "" {
+				return digestJob{}, fmt.Errorf("tenant %q: %s and channel_id are required", t.Name, d.TokenEnv)
+			}
+			job.Destinations = append(job.Destinations, slackDestination{
+				Name:      d.TokenEnv,
+				ChannelID: d.ChannelID,
+				Tokens:    &slackTokenSource{clock: clock, tokens: slackTokens{AccessToken: token}},
+				Types:     taskTypeFilter{Include: d.IncludeTypes, Exclude: d.ExcludeTypes},
+			})
+		case d.TeamID != "":
+			key, inst := st.installationFor(d.TeamID)
+			if inst == nil {
+				return digestJob{}, fmt.Errorf("tenant %q: Slack team %s is not installed", t.Name, d.TeamID)
+			}
+			channelID := d.ChannelID
+			if channelID == "" {
+				channelID = inst.ChannelID
+			}
+			if channelID == "" {
+				return digestJob{}, fmt.Errorf("tenant %q: channel_id is required for team %s (installed organization-wide)", t.Name, d.TeamID)
+			}
+			name := inst.TeamName
+			if inst.EnterpriseInstall {
+				name = inst.TeamName + "/" + d.TeamID
+			}
+			job.Destinations = append(job.Destinations, slackDestination{
+				Name:      name,
+				TeamID:    d.TeamID,
+				ChannelID: channelID,
+				Tokens:    newInstallationTokenSource(clock, store, key, inst.Tokens),
+				Types:     taskTypeFilter{Include: d.IncludeTypes, Exclude: d.ExcludeTypes},
+			})
+		default:
+			return digestJob{}, fmt.Errorf("tenant %q: slack destination needs token_env or team_id", t.Name)
+		}
+	}
+
+	if len(job.Destinations) > 0 {
+		job.Notifiers = slackTarget(store)
+	}
+	return job, nil
+}
+
+// notionToken はテナントの Notion トークンを返す。見つからなければ空文字列を返す
+func (t tenant) notionToken(st *state) string {
+	switch {
+	case t.Notion.TokenEnv != "":
+		return os.Getenv(t.Notion.TokenEnv)
+	case t.Notion.WorkspaceID != "":
+		if auth := st.NotionAuths[t.Notion.WorkspaceID]; auth != nil {
+			return auth.AccessToken
+		}
+	}
+	return ""
+}
+
+// runTenant はテナントを 1 回評価する
+func runTenant(ctx context.Context, t tenant, clock Clock, store *stateStore) error {
+	job, err := t.digestJob(clock, store)
+	if err != nil {
+		return err
+	}
+	return runDigest(ctx, job, clock.Now())
+}
+
+var tenantsCmd = &cobra.Command{
+	Use:   "tenants",
+	Short: "Evaluate multiple tenants, each with its own sources, destinations and schedule.",
+}
+
+var tenantsRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Evaluate every tenant (or the ones given by --tenant) once.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tenants, err := tenantsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		clock, err := clockFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		store := stateStoreFromEnv()
+
+		// テナントは独立しているので、1 つが失敗しても残りは評価する
+		failed := 0
+		for _, t := range tenants {
+			if err := runTenant(cmd.Context(), t, clock, store); err != nil {
+				log.Printf("[%s] Tenant error: %v", t.Name, err)
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d tenants failed", failed, len(tenants))
+		}
+		return nil
+	},
+}
+
+var tenantsServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Evaluate each tenant on its own interval until interrupted.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tenants, err := tenantsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		store := stateStoreFromEnv()
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		var wg sync.WaitGroup
+		for _, t := range tenants {
+			if t.Interval == "" {
+				log.Printf("[%s] No interval configured. Skipping in serve mode.", t.Name)
+				continue
+			}
+			interval, _ := time.ParseDuration(t.Interval)
+			wg.Add(1)
+			go func(t tenant) {
+				defer wg.Done()
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+				for {
+					if err := runTenant(ctx, t, systemClock{}, store); err != nil {
+						log.Printf("[%s] Tenant error: %v", t.Name, err)
+					}
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+					}
+				}
+			}(t)
+		}
+
+		wg.Wait()
+		log.Println("All tenants stopped.")
+		return nil
+	},
+}
+
+// tenantsFromFlags はテナント定義を読み込み、--tenant で絞り込む
+func tenantsFromFlags(cmd *cobra.Command) ([]tenant, error) {
+	path, _ := cmd.Flags().GetString("file")
+	if path == "" {
+		path = os.Getenv(tenantsFileEnv)
+	}
+	tenants, err := loadTenants(path)
+	if err != nil {
+		return nil, err
+	}
+
+	names, _ := cmd.Flags().GetStringSlice("tenant")
+	if len(names) == 0 {
+		return tenants, nil
+	}
+	byName := map[string]tenant{}
+	for _, t := range tenants {
+		byName[t.Name] = t
+	}
+	var selected []tenant
+	for _, name := range names {
+		t, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown tenant %q", name)
+		}
+		selected = append(selected, t)
+	}
+	return selected, nil
+}
+
+func init() {
+	tenantsCmd.PersistentFlags().String("file", "", "Tenants definition file (default $NOTIFYER_TENANTS_FILE or tenants.json)")
+	tenantsCmd.PersistentFlags().StringSlice("tenant", nil, "Only evaluate the given tenants")
+	tenantsCmd.AddCommand(tenantsRunCmd, tenantsServeCmd)
+	rootCmd.AddCommand(tenantsCmd)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// 時間帯の区切り (時)。正午までが午前、17 時までが午後、それ以降が夜
+const (
+	afternoonStartHour = 12
+	eveningStartHour   = 17
+)
+
+// 時間帯の名前 (見出しはメッセージカタログの section.<名前>)
+const (
+	partAllDay    = "all_day"
+	partMorning   = "morning"
+	partAfternoon = "afternoon"
+	partEvening   = "evening"
+)
+
+// 時間帯の表示順。時刻の無いタスクは終日として先頭にまとめる
+var timeOfDayParts = []string{partAllDay, partMorning, partAfternoon, partEvening}
+
+// templateSectionPart はセクションを時間帯で分けた小見出しとタスク
+type templateSectionPart struct {
+	Name  string // all_day、morning、afternoon、evening
+	Title string
+	Tasks []Task
+}
+
+// taskStartTime は時間帯を決める時刻を返す。期間のタスクは始まる時刻を使う
+func taskStartTime(t Task) *time.Time {
+	return t.StartTime()
+}
+
+// taskTimeOfDay はタスクの時間帯を返す
+func taskTimeOfDay(task Task) string {
+	t := taskStartTime(task)
+	switch {
+	case t == nil || isDateOnly(*t):
+		return partAllDay
+	case t.Hour() < afternoonStartHour:
+		return partMorning
+	case t.Hour() < eveningStartHour:
+		return partAfternoon
+	default:
+		return partEvening
+	}
+}
+
+// splitTimeOfDay はタスクを時間帯ごとに分け、タスクのある時間帯だけを返す
+// 時刻のあるタスクは 1 日の予定として見られるよう時刻順に並べる
+func splitTimeOfDay(tasks []Task) []templateSectionPart {
+	groups := map[string][]Task{}
+	for _, task := range tasks {
+		part := taskTimeOfDay(task)
+		groups[part] = append(groups[part], task)
+	}
+	var parts []templateSectionPart
+	for _, name := range timeOfDayParts {
+		group := groups[name]
+		if len(group) == 0 {
+			continue
+		}
+		if name != partAllDay {
+			sort.SliceStable(group, func(i, j int) bool {
+				return taskStartTime(group[i]).Before(*taskStartTime(group[j]))
+			})
+		}
+		parts = append(parts, templateSectionPart{Name: name, Title: tr("section." + name), Tasks: group})
+	}
+	return parts
+}
+
+// parseTimeOfDaySections は --time-of-day の指定を検証する
+func parseTimeOfDaySections(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	return parseSections(names)
+}
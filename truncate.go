@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"rainierrr/notion-notifyer/pkg/notify"
+)
+
+// メモの最大の文字数 (--memo-length、0 なら詳細の長さの上限まで)
+var maxMemoLength = 1000
+
+// Slack の 1 つのテキストに載せる最大の文字数 (--text-length、Slack の上限の MAX_MESSAGE_LENGTH 以下)
+var maxTextLength = MAX_MESSAGE_LENGTH
+
+// truncateText は s を limit 文字 (バイトではなく rune) 以下に切り詰め、切ったときは suffix を付ける
+// 後半に改行・空白・句読点の区切りがあればそこで切り、Slack のリンク (<url|text>) や &amp; の途中では切らない
+func truncateText(s string, limit int, suffix string) string {
+	runes := []rune(s)
+	if limit < 0 || len(runes) <= limit {
+		return s
+	}
+	cut := limit
+	// 閉じていないリンクや文字参照の途中なら、その手前まで戻す
+	for i := cut - 1; i >= 0; i-- {
+		if runes[i] == '>' || runes[i] == ';' {
+			break
+		}
+		if runes[i] == '<' || (runes[i] == '&' && cut-i < len("&amp;")) {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimRightFunc(string(runes[:truncatePoint(runes, cut)]), unicode.IsSpace) + suffix
+}
+
+// truncatePoint は runes を limit 文字以下で切る位置を返す (notify.TruncatePoint)
+func truncatePoint(runes []rune, limit int) int {
+	return notify.TruncatePoint(runes, limit)
+}
+
+func init() {
+	rootCmd.PersistentFlags().IntVar(&maxMemoLength, "memo-length", maxMemoLength, "Maximum characters of a task memo in Slack messages (0 for no limit other than --text-length)")
+	rootCmd.PersistentFlags().IntVar(&maxTextLength, "text-length", maxTextLength, "Maximum characters per Slack text block (1-3000); longer task rows drop details")
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jomei/notionapi"
+	"github.com/spf13/cobra"
+)
+
+// スヌーズで期限日をずらす日数
+const tuiSnoozeDays = 1
+
+// tuiTask は一覧の 1 行
+type tuiTask struct {
+	Task
+	group  string
+	status string // 操作結果 (完了、スヌーズなど)
+}
+
+type tuiModel struct {
+	ctx     context.Context
+	client  *notionapi.Client
+	tasks   []tuiTask
+	cursor  int
+	message string
+	busy    bool
+}
+
+// tuiResultMsg は Notion への操作が終わったことを表す
+type tuiResultMsg struct {
+	index  int
+	task   Task
+	status string
+	err    error
+}
+
+func newTUIModel(ctx context.Context, client *notionapi.Client, tasks []Task, now time.Time) tuiModel {
+	m := tuiModel{ctx: ctx, client: client}
+	overdue, today, upcoming := groupTasksByUrgency(tasks, now)
+	for _, group := range []struct {
+		label string
+		tasks []Task
+	}{
+		{sectionTitle("overdue", 0), overdue},
+		{sectionTitle("today", 0), today},
+		{sectionTitle("soon", 0), upcoming},
+	} {
+		sortTasks(group.tasks)
+		for _, task := range group.tasks {
+			m.tasks = append(m.tasks, tuiTask{Task: task, group: group.label})
+		}
+	}
+	return m
+}
+
+func (m tuiModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tuiResultMsg:
+		m.busy = false
+		if msg.err != nil {
+			m.message = "Error: " + msg.err.Error()
+			return m, nil
+		}
+		m.tasks[msg.index].Task = msg.task
+		m.tasks[msg.index].status = msg.status
+		m.message = fmt.Sprintf("%s: %s", msg.task.Title, msg.status)
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c", "esc":
+			return m, tea.Quit
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.tasks)-1 {
+				m.cursor++
+			}
+		}
+		if len(m.tasks) == 0 || m.busy {
+			return m, nil
+		}
+
+		index := m.cursor
+		task := m.tasks[index].Task
+		switch msg.String() {
+		case "d":
+			m.busy = true
+			m.message = "Marking as done..."
+			return m, func() tea.Msg {
+				err := markTaskDone(m.ctx, m.client, task.ID)
+				return tuiResultMsg{index: index, task: task, status: "✅ 完了", err: err}
+			}
+		case "s":
+			m.busy = true
+			m.message = "Snoozing..."
+			return m, func() tea.Msg {
+				snoozed, err := snoozeTask(m.ctx, m.client, task, tuiSnoozeDays)
+				return tuiResultMsg{index: index, task: snoozed, status: fmt.Sprintf("💤 %d日後に延期", tuiSnoozeDays), err: err}
+			}
+		case "o", "enter":
+			if err := openBrowser(task.URL); err != nil {
+				m.message = "Error: " + err.Error()
+			} else {
+				m.message = "Opened " + task.URL
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m tuiModel) View() string {
+	var b strings.Builder
+	b.WriteString("🔔 Notion タスク\n\n")
+	if len(m.tasks) == 0 {
+		b.WriteString("No tasks found.\n")
+	}
+
+	group := ""
+	for i, task := range m.tasks {
+		if task.group != group {
+			group = task.group
+			fmt.Fprintf(&b, "%s\n", group)
+		}
+		cursor := "  "
+		if i == m.cursor {
+			cursor = "> "
+		}
+		due, _ := formatDueDate(task.Task)
+		line := fmt.Sprintf("%s%s  [%s]", cursor, taskTitle(task.Task), due)
+		if task.Priority != "" {
+			line += " " + task.Priority
+		}
+		if task.status != "" {
+			line += "  " + task.status
+		}
+		b.WriteString(line + "\n")
+	}
+
+	b.WriteString("\n")
+	if m.message != "" {
+		b.WriteString(m.message + "\n")
+	}
+	b.WriteString("↑/↓ move • d done • s snooze • o open • q quit\n")
+	return b.String()
+}
+
+// openBrowser は URL を既定のブラウザで開く
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Review due tasks in an interactive terminal UI.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		daysLater, _ := cmd.Flags().GetInt("daysLater")
+		clock, err := clockFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		now := clock.Now()
+		notionToken, dbID, err := notionSourceFromEnv(stateStoreFromEnv())
+		if err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		client := notionapi.NewClient(notionapi.Token(notionToken))
+		tasks, err := fetchNotionTasks(ctx, client, dbID, endOfDay(now, daysLater))
+		if err != nil {
+			return fmt.Errorf("get Notion tasks: %w", err)
+		}
+
+		_, err = tea.NewProgram(newTUIModel(ctx, client, tasks, now)).Run()
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tuiCmd)
+}
@@ -0,0 +1,32 @@
+package main
+
+import "slices"
+
+// taskTypeFilter は投稿先ごとの種類 (Type) の絞り込み
+// Include が空でなければその種類だけを載せ、Exclude の種類は常に載せない
+type taskTypeFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// allowsType は種類のタスクを載せてよいかを返す
+func (f taskTypeFilter) allowsType(taskType string) bool {
+	if slices.Contains(f.Exclude, taskType) {
+		return false
+	}
+	return len(f.Include) == 0 || slices.Contains(f.Include, taskType)
+}
+
+// apply は載せてよいタスクだけを返す
+func (f taskTypeFilter) apply(tasks []Task) []Task {
+	if len(f.Include) == 0 && len(f.Exclude) == 0 {
+		return tasks
+	}
+	var filtered []Task
+	for _, task := range tasks {
+		if f.allowsType(task.Type) {
+			filtered = append(filtered, task)
+		}
+	}
+	return filtered
+}
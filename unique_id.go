@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/jomei/notionapi"
+)
+
+// チケットのような番号を振るユニーク ID のプロパティ (properties.unique_id、空なら使わない)
+// 設定するとタイトルの前に表示し、履歴とアラートの記録のキーをページ ID からこの ID に替える
+var uniqueIDProp string
+
+// taskTitle はタスクの行に表示するタイトルを返す (ユニーク ID があれば「TASK-123 タイトル」)
+func taskTitle(t Task) string {
+	if t.UniqueID == "" {
+		return t.Title
+	}
+	return t.UniqueID + " " + t.Title
+}
+
+func renderUniqueIDProperty(value notionapi.Property) string {
+	if p, ok := value.(*notionapi.UniqueIDProperty); ok {
+		return p.UniqueID.String()
+	}
+	return ""
+}
+
+func init() {
+	registerPropertyRenderer(notionapi.PropertyTypeUniqueID, renderUniqueIDProperty)
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/spf13/cobra"
+)
+
+// 各サービスの公開されているレート制限 (1 分あたりのリクエスト数)
+// Notion は平均 3 req/s、Slack の chat.postMessage はチャンネルあたり 1 req/s が目安
+var apiRateLimitsPerMinute = map[string]int{
+	"notion": 180,
+	"slack":  60,
+}
+
+// apiUsage は 1 回の実行で呼び出した API の回数をラベルごとに記録する
+// ラベルは "notion" や "slack:<投稿先>" のように サービス:投稿先 の形式にする
+type apiUsage struct {
+	mu      sync.Mutex
+	started time.Time
+	calls   map[string]*apiUsageEntry
+	clients map[string]*http.Client
+}
+
+type apiUsageEntry struct {
+	Calls       int
+	RateLimited int
+	Errors      int
+	RetryAfter  string // 最後に受け取った Retry-After ヘッダー
+}
+
+func newAPIUsage(now time.Time) *apiUsage {
+	return &apiUsage{started: now, calls: map[string]*apiUsageEntry{}, clients: map[string]*http.Client{}}
+}
+
+// Client は呼び出しを label として記録する http.Client を返す (同じラベルには同じ http.Client を返す)
+func (u *apiUsage) Client(label string) *http.Client {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if c, ok := u.clients[label]; ok {
+		return c
+	}
+	c := &http.Client{Transport: &usageTransport{usage: u, label: label, next: http.DefaultTransport}}
+	u.clients[label] = c
+	return c
+}
+
+func (u *apiUsage) record(label string, resp *http.Response, err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	entry, ok := u.calls[label]
+	if !ok {
+		entry = &apiUsageEntry{}
+		u.calls[label] = entry
+	}
+	entry.Calls++
+	switch {
+	case err != nil:
+		entry.Errors++
+	case resp.StatusCode == http.StatusTooManyRequests:
+		entry.RateLimited++
+		entry.RetryAfter = resp.Header.Get("Retry-After")
+	case resp.StatusCode >= 400:
+		entry.Errors++
+	}
+}
+
+// Report はサービス・投稿先ごとの呼び出し回数と、残りのレート予算の目安を返す
+// 予算は実行時間を 1 分単位に切り上げ、その間に許される回数から使った回数を引いて見積もる
+func (u *apiUsage) Report(now time.Time) []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	minutes := int(now.Sub(u.started)/time.Minute) + 1
+	serviceCalls := map[string]int{}
+
+	labels := make([]string, 0, len(u.calls))
+	for label, entry := range u.calls {
+		labels = append(labels, label)
+		serviceCalls[serviceOf(label)] += entry.Calls
+	}
+	sort.Strings(labels)
+
+	var lines []string
+	for _, label := range labels {
+		entry := u.calls[label]
+		line := fmt.Sprintf("%s: %d calls", label, entry.Calls)
+		if entry.Errors > 0 {
+			line += fmt.Sprintf(", %d errors", entry.Errors)
+		}
+		if entry.RateLimited > 0 {
+			line += fmt.Sprintf(", %d rate limited (last Retry-After: %ss)", entry.RateLimited, entry.RetryAfter)
+		}
+		lines = append(lines, line)
+	}
+
+	services := make([]string, 0, len(serviceCalls))
+	for service := range serviceCalls {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	for _, service := range services {
+		limit, ok := apiRateLimitsPerMinute[service]
+		if !ok {
+			continue
+		}
+		budget := limit * minutes
+		lines = append(lines, fmt.Sprintf("%s budget: ~%d of %d requests left in the last %d min", service, budget-serviceCalls[service], budget, minutes))
+	}
+	return lines
+}
+
+// Log はレポートをログに出力する
+func (u *apiUsage) Log(prefix string, now time.Time) {
+	for _, line := range u.Report(now) {
+		log.Printf("%s API usage: %s", prefix, line)
+	}
+}
+
+// usageReport は API の呼び出し回数のレポートを投稿する運用チャンネル (--usage-channel)
+type usageReport struct {
+	Channel string
+	Tokens  *slackTokenSource
+}
+
+// usageReportFromFlags は --usage-channel の投稿先を返す。指定されていなければ nil を返す
+// 投稿には SLACK_BOT_TOKEN (または保存済みのローテーションしたトークン) を使う
+func usageReportFromFlags(cmd *cobra.Command, clock Clock) (*usageReport, error) {
+	channel, _ := cmd.Flags().GetString("usage-channel")
+	if channel == "" {
+		return nil, nil
+	}
+	if !channelIDPattern.MatchString(channel) {
+		return nil, configErrorf("invalid --usage-channel %q: expected a channel ID (e.g. C0123456789)", channel)
+	}
+	tokens, err := newSlackTokenSourceFromEnv(clock)
+	if err != nil {
+		return nil, configErrorf("--usage-channel: %w", err)
+	}
+	return &usageReport{Channel: channel, Tokens: tokens}, nil
+}
+
+// Post はレポートを運用チャンネルに投稿する (投稿自体の呼び出しはレポートに含めない)
+func (r *usageReport) Post(ctx context.Context, prefix string, usage *apiUsage, now time.Time) error {
+	lines := usage.Report(now)
+	if len(lines) == 0 {
+		return nil
+	}
+	client, err := r.Tokens.Client(ctx)
+	if err != nil {
+		return err
+	}
+	text := fmt.Sprintf("%s API usage\n```\n%s\n```", prefix, strings.Join(lines, "\n"))
+	if _, _, err := client.PostMessageContext(ctx, r.Channel, slack.MsgOptionText(text, false)); err != nil {
+		return fmt.Errorf("failed to post the API usage report to %s: %w", r.Channel, err)
+	}
+	return nil
+}
+
+// reportUsage はレポートをログに出し、運用チャンネルが設定されていれば投稿する (ドライランでは投稿しない)
+// 投稿できなくても実行は失敗にしない
+func reportUsage(ctx context.Context, report *usageReport, dryRun bool, prefix string, usage *apiUsage, now time.Time) {
+	usage.Log(prefix, now)
+	if report == nil || dryRun {
+		return
+	}
+	if err := report.Post(ctx, prefix, usage, now); err != nil {
+		log.Printf("%s Warning: %v", prefix, err)
+		return
+	}
+	log.Printf("%s Posted the API usage report to %s", prefix, report.Channel)
+}
+
+func serviceOf(label string) string {
+	service, _, _ := strings.Cut(label, ":")
+	return service
+}
+
+// usageTransport はリクエストごとに apiUsage へ記録する http.RoundTripper
+type usageTransport struct {
+	usage *apiUsage
+	label string
+	next  http.RoundTripper
+}
+
+func (t *usageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	t.usage.record(t.label, resp, err)
+	return resp, err
+}
+
+func init() {
+	rootCmd.Flags().String("usage-channel", "", "Also post the API usage and rate budget report to this Slack channel ID (e.g. an ops channel) after the run")
+}
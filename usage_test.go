@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestReportUsagePostsToOpsChannel は --usage-channel のチャンネルにレポートを投稿し、ドライランでは投稿しないことを確かめる
+func TestReportUsagePostsToOpsChannel(t *testing.T) {
+	discardLogs(t)
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	mock := useMockServer(t, now)
+
+	usage := newAPIUsage(now)
+	usage.record("notion", &http.Response{StatusCode: http.StatusOK}, nil)
+	usage.record("slack:default", &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"30"}}}, nil)
+	report := &usageReport{Channel: "C000GENERAL", Tokens: &slackTokenSource{tokens: slackTokens{AccessToken: "xoxb-test"}}}
+
+	reportUsage(context.Background(), report, true, "[test]", usage, now)
+	if len(mock.posted) != 0 {
+		t.Fatalf("dry run posted %d messages, want none", len(mock.posted))
+	}
+	reportUsage(context.Background(), report, false, "[test]", usage, now)
+	if len(mock.posted) != 1 || mock.posted[0].Channel != "C000GENERAL" {
+		t.Fatalf("posted %+v, want one message to C000GENERAL", mock.posted)
+	}
+	for _, want := range []string{"[test] API usage", "notion: 1 calls", "slack:default: 1 calls, 1 rate limited (last Retry-After: 30s)", "slack budget: ~59 of 60"} {
+		if !strings.Contains(mock.posted[0].Text, want) {
+			t.Errorf("report %q does not contain %q", mock.posted[0].Text, want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// ビルド情報 (リリースでは goreleaser が -ldflags "-X main.version=..." で設定する)
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
+
+// buildInfo は実行中のバイナリのバージョン・コミット・ビルド日時を返す
+// -ldflags で設定されていなければ go build が埋め込む VCS の情報を使う
+func buildInfo() (ver, rev, built string) {
+	ver, rev, built = version, commit, date
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ver, rev, built
+	}
+	if ver == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		ver = info.Main.Version
+	}
+	for _, s := range info.Settings {
+		switch {
+		case s.Key == "vcs.revision" && rev == "":
+			rev = s.Value
+		case s.Key == "vcs.time" && built == "":
+			built = s.Value
+		}
+	}
+	return ver, rev, built
+}
+
+// versionString は --version で表示する文字列を返す
+func versionString() string {
+	ver, rev, built := buildInfo()
+	details := []string{}
+	if rev != "" {
+		if len(rev) > 7 {
+			rev = rev[:7]
+		}
+		details = append(details, "commit "+rev)
+	}
+	if built != "" {
+		details = append(details, "built "+built)
+	}
+	details = append(details, fmt.Sprintf("%s %s/%s", runtime.Version(), runtime.GOOS, runtime.GOARCH))
+	return fmt.Sprintf("%s (%s)", ver, strings.Join(details, ", "))
+}
+
+// buildFooter はメッセージの末尾に載せるバージョンを返す。開発中のビルドでは空
+func buildFooter() string {
+	if version == "dev" {
+		return ""
+	}
+	return "notion-notifyer " + version
+}
+
+// footerText は Run 番号とバージョンをまとめたメッセージの末尾の文字列を返す
+func footerText(runNumber string) string {
+	var parts []string
+	if runNumber != "" {
+		parts = append(parts, "Run #"+runNumber)
+	}
+	if build := buildFooter(); build != "" {
+		parts = append(parts, build)
+	}
+	return strings.Join(parts, " | ")
+}
+
+func init() {
+	rootCmd.Version = versionString()
+	rootCmd.SetVersionTemplate("notion-notifyer {{.Version}}\n")
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// scheduleFromFlags は --cron (優先) または --interval から実行のスケジュールを作る
+func scheduleFromFlags(cmd *cobra.Command) (schedule, error) {
+	if expr, _ := cmd.Flags().GetString("cron"); expr != "" {
+		s, err := parseCron(expr)
+		if err != nil {
+			return nil, configErrorf("invalid --cron: %w", err)
+		}
+		return s, nil
+	}
+	interval, _ := cmd.Flags().GetDuration("interval")
+	if interval < time.Minute {
+		return nil, configErrorf("--interval must be at least 1m, got %s", interval)
+	}
+	return intervalSchedule(interval), nil
+}
+
+// watchDigest は SIGINT/SIGTERM を受けるまで、スケジュールに従ってダイジェストを投稿し続ける
+// --interval では起動時にも 1 回投稿し、--cron では次に一致する時刻まで待つ
+// --alert-interval を指定すると、ダイジェストとは別にその間隔で優先度とステータスの変化を確認して知らせる
+// 停止の合図を受けたら、実行中の投稿は最後まで行ってから終了する
+// --serve-addr を指定すると、その間の現在のダイジェストを GET /digest で返す
+func watchDigest(cmd *cobra.Command) error {
+	if cmd.Flags().Changed("now") {
+		return configErrorf("--now cannot be used with --watch")
+	}
+	sched, err := scheduleFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	alerts, err := alertConfigFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if addr, _ := cmd.Flags().GetString("serve-addr"); addr != "" {
+		if err := serveDigestAPI(ctx, cmd, addr); err != nil {
+			return err
+		}
+	}
+
+	retry, err := outageRetryFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	outageRetries := 0
+	// runOnce はダイジェストを送り、Notion の障害で送れなければ実行し直す時刻を返す
+	runOnce := func() time.Time {
+		job, err := rootDigestJob(cmd, systemClock{})
+		if err == nil {
+			// 投稿の途中で止めると状態ファイルと投稿がずれるため、停止の合図では中断しない
+			err = runDigest(context.WithoutCancel(ctx), job, time.Now())
+		}
+		if err != nil {
+			log.Printf("Digest error: %v", err)
+		}
+		var outage *notionOutageError
+		if !errors.As(err, &outage) {
+			outageRetries = 0
+			return time.Time{}
+		}
+		if retry.After == 0 || outageRetries >= retry.MaxRetries {
+			outageRetries = 0
+			postOutageNotice(context.WithoutCancel(ctx), job, outage, time.Time{}, time.Now())
+			return time.Time{}
+		}
+		outageRetries++
+		retryAt := time.Now().Add(retry.After)
+		if outageRetries == 1 {
+			postOutageNotice(context.WithoutCancel(ctx), job, outage, retryAt, time.Now())
+		}
+		return retryAt
+	}
+
+	// アラートの確認は起動時に 1 回行い、比べる元の記録を作る
+	var alertTick <-chan time.Time
+	checkOnce := func() {
+		job, err := rootDigestJob(cmd, systemClock{})
+		if err == nil {
+			err = checkAlerts(context.WithoutCancel(ctx), job, alerts, time.Now())
+		}
+		if err != nil {
+			log.Printf("Alert error: %v", err)
+		}
+	}
+	if alerts.Interval > 0 {
+		ticker := time.NewTicker(alerts.Interval)
+		defer ticker.Stop()
+		alertTick = ticker.C
+		checkOnce()
+	}
+
+	next := time.Now()
+	if _, ok := sched.(intervalSchedule); !ok {
+		next = sched.Next(next)
+	}
+	for {
+		if next.IsZero() {
+			return fmt.Errorf("the schedule never fires")
+		}
+		log.Printf("Next run at %s", next.Format(time.RFC3339))
+		timer := time.NewTimer(time.Until(next))
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				log.Println("Received shutdown signal.")
+				return nil
+			case <-alertTick:
+				checkOnce()
+			case <-timer.C:
+				break wait
+			}
+		}
+		retryAt := runOnce()
+		// 実行に時間がかかっても、実行の終了時刻から次の時刻を決めるので重ならない
+		next = sched.Next(time.Now())
+		// Notion の障害で送れなかったときは、次の予定より前なら早めに実行し直す
+		if !retryAt.IsZero() && (next.IsZero() || retryAt.Before(next)) {
+			next = retryAt
+		}
+	}
+}
+
+func init() {
+	rootCmd.Flags().Bool("watch", false, "Keep running and post the digest on a schedule until SIGINT/SIGTERM")
+	rootCmd.Flags().Duration("interval", time.Hour, "Time between runs with --watch")
+	rootCmd.Flags().String("cron", "", "Cron expression for runs with --watch (minute hour day month weekday, e.g. \"0 9 * * 1-5\"); overrides --interval")
+}
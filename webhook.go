@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// 汎用 Webhook 関連
+const (
+	webhookURLEnv    = "WEBHOOK_URL"
+	webhookSecretEnv = "WEBHOOK_SECRET"
+	webhookTokenEnv  = "WEBHOOK_TOKEN" // 設定されていれば Authorization: Bearer <token> を付ける
+
+	// 署名ヘッダー。受信側は "<timestamp>.<body>" の HMAC-SHA256 を計算して比較する
+	webhookSignatureHeader = "X-Notifyer-Signature"
+	webhookTimestampHeader = "X-Notifyer-Timestamp"
+)
+
+// webhookTarget は JSON を POST する汎用 Webhook の送信先
+type webhookTarget struct {
+	URL     string
+	Secret  string      // 空なら署名しない
+	Headers http.Header // リクエストに付けるヘッダー (認証など)
+}
+
+func newWebhookTargetFromEnv() *webhookTarget {
+	url := os.Getenv(webhookURLEnv)
+	if url == "" {
+		return nil
+	}
+	w := &webhookTarget{URL: url, Secret: os.Getenv(webhookSecretEnv), Headers: http.Header{}}
+	if token := os.Getenv(webhookTokenEnv); token != "" {
+		w.Headers.Set("Authorization", "Bearer "+token)
+	}
+	return w
+}
+
+// parseWebhookHeaders は --webhook-header の "Name: value" をヘッダーにする
+// 値の $VAR は環境変数に展開するので、API キーをコマンドラインに書かずに済む
+func parseWebhookHeaders(values []string) (http.Header, error) {
+	headers := http.Header{}
+	for _, v := range values {
+		name, value, ok := strings.Cut(v, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q (expected \"Name: value\")", v)
+		}
+		headers.Add(name, os.ExpandEnv(strings.TrimSpace(value)))
+	}
+	return headers, nil
+}
+
+// signWebhookPayload は "<timestamp>.<body>" の HMAC-SHA256 を "sha256=<hex>" 形式で返す
+// タイムスタンプを含めることで、受信側がリプレイ攻撃を検出できる
+func signWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// post はペイロードを JSON で POST する。Secret があれば署名ヘッダーを付ける
+// 署名のタイムスタンプは受信側が現在時刻と比較するため、基準時刻 (--now) ではなく実際の時刻を使う
+func (w *webhookTarget) post(ctx context.Context, client *http.Client, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, values := range w.Headers {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.Secret != "" {
+		timestamp := time.Now().Unix()
+		req.Header.Set(webhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(webhookSignatureHeader, signWebhookPayload(w.Secret, timestamp, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Send はタスクのペイロードを Webhook に送る (ドライランでは JSON を出力する)
+func (w *webhookTarget) Send(ctx context.Context, run digestRun, groups []TaskGroup) error {
+	payload := newDigestPayload(groups, run)
+	if run.DryRun {
+		data, err := json.MarshalIndent(payload, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode webhook payload: %w", err)
+		}
+		fmt.Fprintf(os.Stdout, "===== webhook to %s =====\n%s\n", w.URL, data)
+		return nil
+	}
+	if err := w.post(ctx, run.httpClient("webhook"), payload); err != nil {
+		return err
+	}
+	log.Printf("[%s] Webhook sent to %s", run.JobName, w.URL)
+	return nil
+}
+
+func init() {
+	rootCmd.Flags().StringArray("webhook-header", nil, fmt.Sprintf("Extra header for $%s as \"Name: value\" (repeatable; $VAR in the value is expanded from the environment)", webhookURLEnv))
+	RegisterNotifier(targetWebhook, func(flags *pflag.FlagSet) (Notifier, error) {
+		w := newWebhookTargetFromEnv()
+		if w == nil {
+			return nil, fmt.Errorf("--target webhook requires %s", webhookURLEnv)
+		}
+		values, _ := flags.GetStringArray("webhook-header")
+		headers, err := parseWebhookHeaders(values)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --webhook-header: %w", err)
+		}
+		for name, v := range headers {
+			w.Headers[name] = v
+		}
+		return w, nil
+	})
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// Slack ワークフローのステップ (Workflow Builder) 関連
+// アプリ設定の Workflow Steps でこの callback_id のステップを追加する
+const workflowStepCallbackID = "notion_digest"
+
+// ステップの入力項目
+const (
+	workflowInputChannel   = "channel"
+	workflowInputDaysLater = "days_later"
+	workflowInputFormat    = "format"
+	workflowInputDatabase  = "database_id"
+)
+
+// メッセージの形式
+const (
+	workflowFormatFull  = "full"
+	workflowFormatFocus = "focus"
+)
+
+// openWorkflowStepConfig はステップの設定画面を開く
+// 保存済みの入力があれば初期値にする
+func openWorkflowStepConfig(ctx context.Context, env *interactionEnv, callback slack.InteractionCallback) error {
+	inputs := slack.WorkflowStepInputs{}
+	if callback.WorkflowStep.Inputs != nil {
+		inputs = *callback.WorkflowStep.Inputs
+	}
+
+	channel := slack.NewOptionsSelectBlockElement(slack.OptTypeConversations, nil, workflowInputChannel)
+	channel.DefaultToCurrentConversation = inputs[workflowInputChannel].Value == ""
+	if v := inputs[workflowInputChannel].Value; v != "" {
+		channel.InitialConversation = v
+	}
+
+	daysLater := slack.NewPlainTextInputBlockElement(slack.NewTextBlockObject(slack.PlainTextType, strconv.Itoa(defaultDaysLater()), false, false), workflowInputDaysLater)
+	daysLater.InitialValue = inputs[workflowInputDaysLater].Value
+
+	formats := []*slack.OptionBlockObject{
+		slack.NewOptionBlockObject(workflowFormatFull, slack.NewTextBlockObject(slack.PlainTextType, tr("workflow.format_full"), false, false), nil),
+		slack.NewOptionBlockObject(workflowFormatFocus, slack.NewTextBlockObject(slack.PlainTextType, tr("workflow.format_focus"), false, false), nil),
+	}
+	format := slack.NewOptionsSelectBlockElement(slack.OptTypeStatic, nil, workflowInputFormat, formats...)
+	format.InitialOption = formats[0]
+	if inputs[workflowInputFormat].Value == workflowFormatFocus {
+		format.InitialOption = formats[1]
+	}
+
+	database := slack.NewPlainTextInputBlockElement(slack.NewTextBlockObject(slack.PlainTextType, tr("workflow.database_placeholder"), false, false), workflowInputDatabase)
+	database.InitialValue = inputs[workflowInputDatabase].Value
+
+	label := func(text string) *slack.TextBlockObject {
+		return slack.NewTextBlockObject(slack.PlainTextType, text, false, false)
+	}
+	blocks := slack.Blocks{BlockSet: []slack.Block{
+		slack.NewInputBlock(workflowInputChannel, label(tr("workflow.channel")), nil, channel),
+		slack.NewInputBlock(workflowInputDaysLater, label(tr("workflow.days_later")), nil, daysLater),
+		slack.NewInputBlock(workflowInputFormat, label(tr("workflow.format")), nil, format),
+		optionalInput(slack.NewInputBlock(workflowInputDatabase, label(tr("workflow.database")), nil, database)),
+	}}
+
+	client, err := env.slackClient(ctx, callback.Team.ID)
+	if err != nil {
+		return err
+	}
+	req := slack.NewConfigurationModalRequest(blocks, "", "")
+	if _, err := client.OpenViewContext(ctx, callback.TriggerID, req.ModalViewRequest); err != nil {
+		return fmt.Errorf("failed to open workflow step configuration: %w", err)
+	}
+	return nil
+}
+
+func optionalInput(block *slack.InputBlock) *slack.InputBlock {
+	block.Optional = true
+	return block
+}
+
+// saveWorkflowStepConfig は設定画面の入力をステップの入力として保存する
+func saveWorkflowStepConfig(ctx context.Context, env *interactionEnv, callback slack.InteractionCallback) error {
+	if callback.View.State == nil {
+		return fmt.Errorf("workflow step configuration has no state")
+	}
+	values := callback.View.State.Values
+
+	inputs := slack.WorkflowStepInputs{
+		workflowInputChannel:   {Value: values[workflowInputChannel][workflowInputChannel].SelectedConversation},
+		workflowInputDaysLater: {Value: values[workflowInputDaysLater][workflowInputDaysLater].Value},
+		workflowInputFormat:    {Value: values[workflowInputFormat][workflowInputFormat].SelectedOption.Value},
+		workflowInputDatabase:  {Value: values[workflowInputDatabase][workflowInputDatabase].Value},
+	}
+	outputs := []slack.WorkflowStepOutput{}
+
+	client, err := env.slackClient(ctx, callback.Team.ID)
+	if err != nil {
+		return err
+	}
+	if err := client.SaveWorkflowStepConfigurationContext(ctx, callback.WorkflowStep.WorkflowStepEditID, &inputs, &outputs); err != nil {
+		return fmt.Errorf("failed to save workflow step configuration: %w", err)
+	}
+	return nil
+}
+
+// executeWorkflowStep はワークフローから呼ばれたステップとしてダイジェストを投稿する
+// 結果はワークフローに完了または失敗として返す
+func executeWorkflowStep(ctx context.Context, env *interactionEnv, teamID string, event *slackevents.WorkflowStepExecuteEvent) error {
+	if event.CallbackID != workflowStepCallbackID {
+		return nil
+	}
+	client, err := env.slackClient(ctx, teamID)
+	if err != nil {
+		return err
+	}
+
+	if err := runWorkflowStep(ctx, env, teamID, event.WorkflowStep); err != nil {
+		log.Printf("Workflow step %s failed: %v", event.WorkflowStep.WorkflowStepExecuteID, err)
+		return client.WorkflowStepFailed(event.WorkflowStep.WorkflowStepExecuteID, err.Error())
+	}
+	return client.WorkflowStepCompleted(event.WorkflowStep.WorkflowStepExecuteID)
+}
+
+// runWorkflowStep はステップの入力からジョブを作って実行する
+func runWorkflowStep(ctx context.Context, env *interactionEnv, teamID string, step slackevents.EventWorkflowStep) error {
+	inputs := slack.WorkflowStepInputs{}
+	if step.Inputs != nil {
+		inputs = *step.Inputs
+	}
+
+	channelID := inputs[workflowInputChannel].Value
+	if channelID == "" {
+		return fmt.Errorf("no channel is configured")
+	}
+	// 未入力なら --daysLater と同じく最後のバケットまでの日数にする
+	daysLater := defaultDaysLater()
+	if v := inputs[workflowInputDaysLater].Value; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid days_later %q", v)
+		}
+		daysLater = n
+	}
+	if err := checkDaysLater(daysLater); err != nil {
+		return fmt.Errorf("invalid days_later: %w", err)
+	}
+
+	notionToken, dbID, err := notionSourceFromEnv(env.store)
+	if err != nil {
+		return err
+	}
+	if v := inputs[workflowInputDatabase].Value; v != "" {
+		dbID = v
+	}
+	tokens, err := env.tokenSource(teamID)
+	if err != nil {
+		return err
+	}
+
+	job := digestJob{
+		Name:         "workflow",
+		NotionToken:  notionToken,
+		DatabaseID:   dbID,
+		DaysLater:    daysLater,
+		Destinations: []slackDestination{{Name: "workflow", TeamID: teamID, ChannelID: channelID, Tokens: tokens}},
+		Notifiers:    slackTarget(env.store),
+		Focus:        inputs[workflowInputFormat].Value == workflowFormatFocus,
+		Store:        env.store,
+	}
+	return runDigest(ctx, job, env.clock.Now())
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// TestRunWorkflowStepChecksDaysLater はステップの days_later を切り詰めずに、最後のバケットを超えればエラーにすることを確かめる
+func TestRunWorkflowStepChecksDaysLater(t *testing.T) {
+	buckets := configuredBuckets
+	t.Cleanup(func() { configuredBuckets = buckets })
+	three := 3
+	configuredBuckets = []urgencyBucket{{Name: "overdue", Days: new(int)}, {Name: "soon", Days: &three}}
+
+	for _, tt := range []struct{ daysLater, want string }{
+		{"10", "goes past the last bucket"},
+		{"-1", "invalid days_later"},
+		{"x", "invalid days_later"},
+	} {
+		inputs := slack.WorkflowStepInputs{
+			workflowInputChannel:   {Value: "C0123456"},
+			workflowInputDaysLater: {Value: tt.daysLater},
+		}
+		err := runWorkflowStep(context.Background(), nil, "T1", slackevents.EventWorkflowStep{Inputs: &inputs})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("days_later %s: err = %v, want %q", tt.daysLater, err, tt.want)
+		}
+	}
+}